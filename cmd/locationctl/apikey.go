@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/allansbo/goapi/internal/pkg/validation"
+)
+
+// runAPIKey issues an API key, like the first admin key that manages the others through the API.
+// The key is printed to the standard output, it can't be retrieved later.
+func runAPIKey(args []string) error {
+	apiKeyDataIn := new(dto.APIKeyInApp)
+	var scopes, vehicles, groups, expires string
+
+	flags := flag.NewFlagSet("apikey", flag.ExitOnError)
+	flags.StringVar(&apiKeyDataIn.Name, "name", "", "name of the client of the key")
+	flags.StringVar(&apiKeyDataIn.Tenant, "tenant", "", "tenant of the key, the default tenant when empty")
+	flags.StringVar(&scopes, "scopes", "read", "comma separated scopes: read, write, delete or admin")
+	flags.StringVar(&vehicles, "vehicles", "", "comma separated vehicles that the key can access, all by default")
+	flags.StringVar(&groups, "groups", "", "comma separated fleet groups that the key can access")
+	flags.StringVar(&expires, "expires", "", "expiry of the key, RFC 3339, never by default")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	apiKeyDataIn.Scopes = strings.Split(scopes, ",")
+	if vehicles != "" {
+		apiKeyDataIn.Vehicles = strings.Split(vehicles, ",")
+	}
+	if groups != "" {
+		apiKeyDataIn.Groups = strings.Split(groups, ",")
+	}
+	if expires != "" {
+		expiresAt, err := time.Parse(time.RFC3339, expires)
+		if err != nil {
+			return fmt.Errorf("invalid apikey flags: %w", err)
+		}
+		apiKeyDataIn.ExpiresAt = &expiresAt
+	}
+
+	if err := validation.Validate(apiKeyDataIn); err != nil {
+		return fmt.Errorf("invalid apikey flags: %w", err)
+	}
+
+	principal, err := tenantPrincipal(apiKeyDataIn.Tenant)
+	if err != nil {
+		return err
+	}
+
+	// The key is printed when it is saved without its audit entry, it can't be retrieved later.
+	apiKeyDataOut, err := usecase.IssueAPIKey(principal, apiKeyDataIn)
+	if err != nil && !errors.Is(err, usecase.ErrAuditNotRecorded) {
+		return err
+	}
+
+	fmt.Printf("id:     %s\ntenant: %s\nscopes: %s\nkey:    %s\n", apiKeyDataOut.ID, apiKeyDataOut.Tenant, strings.Join(apiKeyDataOut.Scopes, ","), apiKeyDataOut.Key)
+	return err
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/allansbo/goapi/internal/pkg/validation"
+)
+
+// runExport streams the locations matching the filters to a file or to the standard output.
+func runExport(args []string) error {
+	exportRequest := new(dto.ExportLocationRequest)
+	var output, tenant string
+
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	flags.StringVar(&exportRequest.Format, "format", "csv", "file format, csv or parquet")
+	flags.StringVar(&exportRequest.VehicleId, "vehicle-id", "", "filter by vehicle id")
+	flags.StringVar(&exportRequest.Status, "status", "", "filter by status, moving, stopped or offline")
+	flags.StringVar(&exportRequest.From, "from", "", "start of the period, RFC 3339")
+	flags.StringVar(&exportRequest.To, "to", "", "end of the period, RFC 3339")
+	flags.StringVar(&output, "output", "", "output file, the standard output when empty")
+	flags.StringVar(&tenant, "tenant", "", "tenant of the locations, the default tenant when empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if err := validation.Validate(exportRequest); err != nil {
+		return fmt.Errorf("invalid export flags: %w", err)
+	}
+	principal, err := tenantPrincipal(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid export flags: %w", err)
+	}
+
+	file := os.Stdout
+	if output != "" {
+		if file, err = os.Create(output); err != nil {
+			return err
+		}
+		defer file.Close()
+	}
+
+	w := bufio.NewWriter(file)
+	if err := usecase.ExportLocations(principal, exportRequest.NewQueryLocationRequest(), exportRequest.Format, w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if output != "" {
+		return file.Sync()
+	}
+	return nil
+}
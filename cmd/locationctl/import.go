@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/allansbo/goapi/internal/app/ingestion"
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/pkg/importfile"
+	"github.com/allansbo/goapi/internal/pkg/validation"
+)
+
+// runImport imports the history of locations from a CSV or GPX file, printing the report as JSON.
+func runImport(args []string) error {
+	importRequest := new(dto.ImportLocationRequest)
+	var input, tenant string
+
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	flags.StringVar(&importRequest.Format, "format", "csv", "file format, csv or gpx")
+	flags.StringVar(&importRequest.VehicleId, "vehicle-id", "", "vehicle id of the rows that don't have one, like the gpx tracks")
+	flags.StringVar(&importRequest.Mapping, "mapping", "", "csv column mapping, like latitude=lat,longitude=lng")
+	flags.StringVar(&importRequest.TimestampLayout, "timestamp-layout", "", "csv timestamp layout, RFC 3339 by default, unix or unixms for epochs")
+	flags.BoolVar(&importRequest.DryRun, "dry-run", false, "only validate the file")
+	flags.StringVar(&input, "file", "", "file to import")
+	flags.StringVar(&tenant, "tenant", "", "tenant of the locations, the default tenant when empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if input == "" {
+		return fmt.Errorf("the -file flag is required")
+	}
+	if err := validation.Validate(importRequest); err != nil {
+		return fmt.Errorf("invalid import flags: %w", err)
+	}
+	principal, err := tenantPrincipal(tenant)
+	if err != nil {
+		return fmt.Errorf("invalid import flags: %w", err)
+	}
+
+	mapping, err := importfile.ParseMapping(importRequest.Mapping)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader, err := importfile.NewReader(importRequest.Format, bufio.NewReader(file), importfile.Options{
+		VehicleId:       importRequest.VehicleId,
+		Mapping:         mapping,
+		TimestampLayout: importRequest.TimestampLayout,
+	})
+	if err != nil {
+		return err
+	}
+
+	report, importErr := ingestion.ImportLocations(principal, reader, importRequest.DryRun)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+
+	return importErr
+}
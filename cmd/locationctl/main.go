@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/allansbo/goapi/internal/config"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/allansbo/goapi/internal/pkg/logs"
+	"github.com/allansbo/goapi/internal/pkg/validation"
+	"github.com/allansbo/goapi/internal/provider/db"
+)
+
+// command is a subcommand of the locationctl tool.
+type command struct {
+	description string
+	run         func(args []string) error
+}
+
+var commands = map[string]command{
+	"apikey": {description: "issue an api key, like the first admin key", run: runAPIKey},
+	"export": {description: "export the location history as csv or parquet", run: runExport},
+	"import": {description: "import the location history from csv or gpx", run: runImport},
+	"rollup": {description: "compute the daily statistics of the vehicles", run: runRollup},
+}
+
+// locationctl runs the maintenance tasks of the Location API from the command line,
+// using the same .env configuration of the API.
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	logs.ConfigLog(os.Stderr)
+
+	repository, err := loadRepository()
+	if err != nil {
+		slog.Error("error on loading repository", "error", err.Error())
+		os.Exit(1)
+	}
+	defer repository.Stop()
+
+	if err := cmd.run(os.Args[2:]); err != nil {
+		slog.Error("error running command", "command", os.Args[1], "error", err.Error())
+		repository.Stop()
+		os.Exit(1)
+	}
+}
+
+func loadRepository() (db.Repository, error) {
+	cfg, err := config.LoadEnvConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	repository := db.NewMongoDBRepository(cfg)
+	if err := repository.Ping(); err != nil {
+		return nil, err
+	}
+
+	usecase.LoadLocationUseCase(repository)
+
+	return repository, nil
+}
+
+// tenantPrincipal returns the principal of the commands run on the data of a tenant, the default one when empty.
+// It is the actor of the changes of the commands in the audit log.
+func tenantPrincipal(tenant string) (*entity.Principal, error) {
+	if tenant != "" && !validation.IsTenant(tenant) {
+		return nil, fmt.Errorf("invalid tenant %q", tenant)
+	}
+	return entity.NewInternalPrincipal("locationctl", tenant), nil
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: locationctl <command> [flags]\n\ncommands:\n")
+	for name, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, cmd.description)
+	}
+}
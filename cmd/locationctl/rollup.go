@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/allansbo/goapi/internal/pkg/validation"
+)
+
+// runRollup computes the daily statistics of the days in a period, like to backfill the history.
+func runRollup(args []string) error {
+	now := time.Now().UTC()
+	period := new(dto.DailyStatsRequest)
+
+	flags := flag.NewFlagSet("rollup", flag.ExitOnError)
+	flags.StringVar(&period.From, "from", now.AddDate(0, 0, -1).Format(time.DateOnly), "first day, YYYY-MM-DD")
+	flags.StringVar(&period.To, "to", now.Format(time.DateOnly), "last day, YYYY-MM-DD")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if err := validation.Validate(period); err != nil {
+		return fmt.Errorf("invalid rollup flags: %w", err)
+	}
+
+	from, _ := time.Parse(time.DateOnly, period.From)
+	to, _ := time.Parse(time.DateOnly, period.To)
+	if to.Before(from) {
+		return fmt.Errorf("invalid rollup flags: the last day is before the first one")
+	}
+
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if err := usecase.RollUpDailyStats(day); err != nil {
+			return fmt.Errorf("error rolling up %s: %w", day.Format(time.DateOnly), err)
+		}
+		slog.Info("rolled up daily stats", "day", day.Format(time.DateOnly))
+	}
+	return nil
+}
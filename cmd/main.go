@@ -2,14 +2,24 @@ package main
 
 import (
 	"fmt"
+	"github.com/allansbo/goapi/internal/app/ingestion"
+	"github.com/allansbo/goapi/internal/app/ingestion/mqtt"
+	"github.com/allansbo/goapi/internal/app/ingestion/tcp"
+	"github.com/allansbo/goapi/internal/app/purge"
+	"github.com/allansbo/goapi/internal/app/rollup"
 	"github.com/allansbo/goapi/internal/app/server"
+	"github.com/allansbo/goapi/internal/app/server/middleware"
 	"github.com/allansbo/goapi/internal/config"
 	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/allansbo/goapi/internal/pkg/gazetteer"
+	"github.com/allansbo/goapi/internal/pkg/jwt"
 	"github.com/allansbo/goapi/internal/pkg/logs"
+	"github.com/allansbo/goapi/internal/pkg/ratelimit"
 	"github.com/allansbo/goapi/internal/provider/db"
 	"log/slog"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 )
@@ -18,6 +28,11 @@ type locationService struct {
 	cfg        *config.EnvConfig
 	repository db.Repository
 	server     *server.AppServer
+	mqtt       *mqtt.Gateway
+	tcp        *tcp.Server
+	rollup     *rollup.Job
+	purge      *purge.Job
+	limiter    *ratelimit.Limiter
 	quit       chan os.Signal
 }
 
@@ -40,23 +55,131 @@ func init() {
 		slog.Error("error on handling mongodb", "error", err.Error())
 		panic(err)
 	}
+	if err := service.repository.EnsureIndexes(); err != nil {
+		slog.Error("error on handling mongodb", "error", err.Error())
+		panic(err)
+	}
 	slog.Info("loaded mongodb")
 
 	usecase.LoadLocationUseCase(service.repository)
+	usecase.LoadAnomalyUseCase(usecase.DefaultLocationChecks(service.cfg.AnomalyMaxSpeed), service.cfg.AnomalyReject)
+	if err := usecase.LoadFleetGroups(service.cfg.FleetGroups); err != nil {
+		slog.Error("error on loading fleet groups", "error", err.Error())
+		panic(err)
+	}
 	slog.Info("loaded use cases")
+
+	store := ratelimit.NewMemoryStore()
+	service.limiter, err = ratelimit.NewLimiter(store, service.cfg.RateLimits, slices.Concat(middleware.RateLimitGroups, []string{ingestion.RateLimitDevice})...)
+	if err != nil {
+		slog.Error("error on loading rate limits", "error", err.Error())
+		panic(err)
+	}
+	ingestion.LoadRateLimiter(service.limiter)
+	if err := usecase.LoadQuotaUseCase(store, service.cfg.DefaultTenant, service.cfg.TenantDailyQuota, service.cfg.TenantQuotas); err != nil {
+		slog.Error("error on loading tenant quotas", "error", err.Error())
+		panic(err)
+	}
+	slog.Info("loaded rate limits")
+
+	if service.cfg.JWTJWKS != "" {
+		verifier, err := jwt.NewVerifier(jwt.Config{
+			JWKS:     service.cfg.JWTJWKS,
+			Issuer:   service.cfg.JWTIssuer,
+			Audience: service.cfg.JWTAudience,
+			Refresh:  service.cfg.JWTJWKSRefresh,
+			Leeway:   service.cfg.JWTLeeway,
+		})
+		if err != nil {
+			slog.Error("error on loading jwks", "error", err.Error())
+			panic(err)
+		}
+		if err := usecase.LoadTokenUseCase(verifier, service.cfg.JWTRolesClaim, service.cfg.JWTVehiclesClaim, service.cfg.JWTGroupsClaim, service.cfg.JWTTenantClaim, service.cfg.JWTRoleMap); err != nil {
+			slog.Error("error on loading jwks", "error", err.Error())
+			panic(err)
+		}
+		slog.Info("loaded jwks", "keys", verifier.Len())
+	}
+
+	if service.cfg.GeocoderFile != "" {
+		places, err := gazetteer.LoadFile(service.cfg.GeocoderFile)
+		if err != nil {
+			slog.Error("error on loading gazetteer", "error", err.Error())
+			panic(err)
+		}
+		usecase.LoadGeocoderUseCase(places, service.cfg.GeocoderMaxDistance)
+		slog.Info("loaded gazetteer", "places", places.Len())
+	}
+
+	if err := ingestion.LoadDevices(service.cfg.DeviceVehicles, service.cfg.DeviceVehicleFallback); err != nil {
+		slog.Error("error on loading devices", "error", err.Error())
+		panic(err)
+	}
+	if err := ingestion.LoadVehicleTenants(service.cfg.VehicleTenants); err != nil {
+		slog.Error("error on loading vehicle tenants", "error", err.Error())
+		panic(err)
+	}
+	slog.Info("loaded devices")
 }
 
-// @title			Location API
-// @version		1.0
-// @description	API to manage locations from vehicles
-// @host			localhost:8080
-// @BasePatch		/api/v1/
+// @title						Location API
+// @version					1.0
+// @description				API to manage locations from vehicles
+// @host						localhost:8080
+// @BasePatch					/api/v1/
+//
+// @securityDefinitions.apikey	ApiKeyAuth
+// @in							header
+// @name						X-API-Key
+//
+// @securityDefinitions.apikey	BearerAuth
+// @in							header
+// @name						Authorization
+// @description				JWT of the identity provider, in the format Bearer <token>
 func main() {
 	service.quit = make(chan os.Signal, 1)
 	signal.Notify(service.quit, syscall.SIGTERM, syscall.SIGINT)
 	go service.shutdown()
 
-	service.server = server.NewAppServer(service.cfg.AppPort)
+	if service.cfg.MQTTBroker != "" {
+		service.mqtt = mqtt.NewGateway(service.cfg)
+		if err := service.mqtt.Start(); err != nil {
+			slog.Error("error on starting mqtt gateway", "error", err.Error())
+			panic(err)
+		}
+		slog.Info("loaded mqtt gateway")
+	}
+
+	var err error
+	service.tcp, err = tcp.NewServer(service.cfg)
+	if err != nil {
+		slog.Error("error on loading tcp server", "error", err.Error())
+		panic(err)
+	}
+	if service.tcp.Enabled() {
+		if err := service.tcp.Start(); err != nil {
+			slog.Error("error on starting tcp server", "error", err.Error())
+			panic(err)
+		}
+		slog.Info("loaded tcp server")
+	}
+
+	service.rollup = rollup.NewJob(service.cfg)
+	if service.rollup.Enabled() {
+		service.rollup.Start()
+		slog.Info("loaded stats rollup job")
+	}
+
+	service.purge = purge.NewJob(service.cfg)
+	if service.purge.Enabled() {
+		service.purge.Start()
+		slog.Info("loaded deleted locations purge job")
+	}
+
+	if !service.cfg.AuthEnabled {
+		slog.Warn("authentication disabled, the api routes are public")
+	}
+	service.server = server.NewAppServer(service.cfg.AppPort, service.cfg.AuthEnabled, service.limiter)
 	service.server.Start()
 }
 
@@ -65,6 +188,22 @@ func (s *locationService) shutdown() {
 	fmt.Println("\nClosing tasks. Please wait.")
 	slog.Info("Shutdown routine, closing tasks.")
 
+	if s.mqtt != nil {
+		s.mqtt.Stop()
+	}
+
+	if s.tcp != nil {
+		s.tcp.Stop()
+	}
+
+	if s.rollup != nil && s.rollup.Enabled() {
+		s.rollup.Stop()
+	}
+
+	if s.purge != nil && s.purge.Enabled() {
+		s.purge.Stop()
+	}
+
 	slog.Info("Closing Context")
 	if s.repository != nil {
 		s.repository.Stop()
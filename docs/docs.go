@@ -15,17 +15,52 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/locations": {
+        "/api/v1/audit": {
             "get": {
-                "description": "Get all locations data from database based on query parameters",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the creations, updates and deletions of the locations and the changes of the API keys of the tenant,\nfrom the newest, with the client that made them, the IP and the ID of the request and the documents\nbefore and after every change. The X-Request-ID header of the responses is the ID of their requests.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Locations"
+                    "Audit"
                 ],
-                "summary": "Get all locations data",
+                "summary": "Get the audit log",
                 "parameters": [
+                    {
+                        "enum": [
+                            "create",
+                            "update",
+                            "delete",
+                            "restore",
+                            "rotate",
+                            "revoke"
+                        ],
+                        "type": "string",
+                        "example": "delete",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "665f1c2b8e4a3b2d1c0f9e8a",
+                        "description": "Actor is the subject of the client that made the changes, like the ID of an API key or the sub claim of a token.",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2025-01-01T00:00:00Z",
+                        "name": "from",
+                        "in": "query"
+                    },
                     {
                         "maximum": 100,
                         "minimum": 1,
@@ -39,27 +74,40 @@ const docTemplate = `{
                         "name": "page",
                         "in": "query"
                     },
+                    {
+                        "type": "string",
+                        "example": "0b7c3a52-7d2e-4c1f-9a8b-2f1e0d3c4b5a",
+                        "name": "requestId",
+                        "in": "query"
+                    },
                     {
                         "enum": [
-                            "moving",
-                            "stopped",
-                            "offline"
+                            "location",
+                            "api_key"
                         ],
                         "type": "string",
-                        "name": "status",
+                        "example": "location",
+                        "name": "resource",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "name": "vehicleId",
+                        "example": "665f1c2b8e4a3b2d1c0f9e8d",
+                        "name": "resourceId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2025-01-02T00:00:00Z",
+                        "name": "to",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "located documents",
+                        "description": "audit log entries",
                         "schema": {
-                            "$ref": "#/definitions/dto.QueryLocationResponse"
+                            "$ref": "#/definitions/dto.AuditResponseOut"
                         }
                     },
                     "400": {
@@ -68,10 +116,62 @@ const docTemplate = `{
                             "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
                         }
                     },
-                    "404": {
-                        "description": "no locations found",
+                    "401": {
+                        "description": "authentication required",
                         "schema": {
-                            "$ref": "#/definitions/dto.DefaultResponseMessageOut"
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "403": {
+                        "description": "permission denied",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/keys": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get all the API keys with their scopes, expiry and revocation, without their secrets.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Keys"
+                ],
+                "summary": "Get all API keys",
+                "responses": {
+                    "200": {
+                        "description": "api keys",
+                        "schema": {
+                            "$ref": "#/definitions/dto.APIKeyListOut"
+                        }
+                    },
+                    "401": {
+                        "description": "authentication required",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "403": {
+                        "description": "permission denied",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
                         }
                     },
                     "500": {
@@ -83,7 +183,15 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Insert location data into database",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Issue an API key with the scopes read, write, delete or admin, and an optional expiry.\nThe key is returned only in this response, the database keeps its hash.\nThe key belongs to the tenant of the client, the tenant field is only used without authentication.",
                 "consumes": [
                     "application/json"
                 ],
@@ -91,25 +199,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Locations"
+                    "Keys"
                 ],
-                "summary": "Insert location data",
+                "summary": "Issue an API key",
                 "parameters": [
                     {
-                        "description": "Request of creating location object",
+                        "description": "Request of issuing an api key",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.LocationInApp"
+                            "$ref": "#/definitions/dto.APIKeyInApp"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "document created",
+                        "description": "api key issued",
                         "schema": {
-                            "$ref": "#/definitions/dto.LocationCreatedResponseOut"
+                            "$ref": "#/definitions/dto.APIKeyIssuedOut"
                         }
                     },
                     "400": {
@@ -118,6 +226,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
                         }
                     },
+                    "401": {
+                        "description": "authentication required",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "403": {
+                        "description": "permission denied or tenant not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
                     "500": {
                         "description": "internal server error",
                         "schema": {
@@ -127,20 +247,28 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/locations/{id}": {
-            "get": {
-                "description": "Get location data from database based on a document_id",
+        "/api/v1/keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revoke an API key, which can't be used nor rotated anymore. The key is kept in the list.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Locations"
+                    "Keys"
                 ],
-                "summary": "Get location data",
+                "summary": "Revoke an API key",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "id from document",
+                        "description": "id of the api key",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -148,17 +276,35 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "located document",
+                        "description": "api key revoked",
                         "schema": {
-                            "$ref": "#/definitions/dto.LocationOutApp"
+                            "$ref": "#/definitions/dto.DefaultResponseMessageOut"
+                        }
+                    },
+                    "401": {
+                        "description": "authentication required",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "403": {
+                        "description": "permission denied",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
                         }
                     },
                     "404": {
-                        "description": "document not found",
+                        "description": "api key not found",
                         "schema": {
                             "$ref": "#/definitions/dto.DefaultResponseMessageOut"
                         }
                     },
+                    "409": {
+                        "description": "api key already revoked",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
                     "500": {
                         "description": "internal server error",
                         "schema": {
@@ -166,53 +312,66 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "Update location data into database based on a document_id",
+            }
+        },
+        "/api/v1/keys/{id}/rotate": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace the secret of an API key, keeping its name, scopes and expiry.\nThe previous key stops working and the new one is returned only in this response.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Locations"
+                    "Keys"
                 ],
-                "summary": "Update location data",
+                "summary": "Rotate an API key",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "id from document",
+                        "description": "id of the api key",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Request of updating location object",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.LocationInApp"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "updated document",
+                        "description": "api key rotated",
                         "schema": {
-                            "$ref": "#/definitions/dto.DefaultResponseMessageOut"
+                            "$ref": "#/definitions/dto.APIKeyIssuedOut"
                         }
                     },
-                    "400": {
-                        "description": "validation error",
+                    "401": {
+                        "description": "authentication required",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "403": {
+                        "description": "permission denied",
                         "schema": {
                             "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
                         }
                     },
                     "404": {
-                        "description": "document not found",
+                        "description": "api key not found",
                         "schema": {
                             "$ref": "#/definitions/dto.DefaultResponseMessageOut"
                         }
                     },
+                    "409": {
+                        "description": "api key revoked",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
                     "500": {
                         "description": "internal server error",
                         "schema": {
@@ -220,34 +379,122 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete location data from database based on a document_id",
+            }
+        },
+        "/api/v1/locations": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get all locations data from database based on query parameters\nThe deleted locations are listed only with include_deleted, for the admin role.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Locations"
                 ],
-                "summary": "Delete location data",
+                "summary": "Get all locations data",
                 "parameters": [
                     {
+                        "enum": [
+                            "null_island",
+                            "impossible_speed",
+                            "duplicate"
+                        ],
                         "type": "string",
-                        "description": "id from document",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "example": "impossible_speed",
+                        "name": "flag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "example": true,
+                        "description": "Flagged filters the locations flagged by the anomaly checks, Flag filters a specific flag.",
+                        "name": "flagged",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2025-01-01T00:00:00Z",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "example": true,
+                        "description": "IncludeDeleted includes the deleted locations that were not purged yet, only for the admin role.",
+                        "name": "includeDeleted",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 10000,
+                        "type": "number",
+                        "example": 10,
+                        "description": "Simplify is the tolerance in meters to simplify the track of every vehicle in the result.",
+                        "name": "simplify",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "moving",
+                            "stopped",
+                            "offline"
+                        ],
+                        "type": "string",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2025-01-02T00:00:00Z",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "vehicleId",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "deleted document",
+                        "description": "located documents",
                         "schema": {
-                            "$ref": "#/definitions/dto.DefaultResponseMessageOut"
+                            "$ref": "#/definitions/dto.QueryLocationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "validation error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "403": {
+                        "description": "vehicle or deleted locations not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
                         }
                     },
                     "404": {
-                        "description": "document not found",
+                        "description": "no locations found",
                         "schema": {
                             "$ref": "#/definitions/dto.DefaultResponseMessageOut"
                         }
@@ -259,21 +506,1618 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "dto.CoordinatesOutApp": {
-            "type": "object",
-            "properties": {
-                "latitude": {
-                    "type": "string"
-                },
-                "longitude": {
-                    "type": "string"
-                }
-            }
-        },
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Insert location data into database",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Locations"
+                ],
+                "summary": "Insert location data",
+                "parameters": [
+                    {
+                        "description": "Request of creating location object",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.LocationInApp"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "document created",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LocationCreatedResponseOut"
+                        }
+                    },
+                    "400": {
+                        "description": "validation error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "403": {
+                        "description": "vehicle not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "422": {
+                        "description": "location rejected by the anomaly checks",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit or daily quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stream all locations matching the filters, ordered by timestamp, as a CSV or Parquet file",
+                "produces": [
+                    "text/csv",
+                    "application/vnd.apache.parquet"
+                ],
+                "tags": [
+                    "Locations"
+                ],
+                "summary": "Export locations data",
+                "parameters": [
+                    {
+                        "enum": [
+                            "null_island",
+                            "impossible_speed",
+                            "duplicate"
+                        ],
+                        "type": "string",
+                        "example": "impossible_speed",
+                        "name": "flag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "example": true,
+                        "name": "flagged",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "parquet"
+                        ],
+                        "type": "string",
+                        "example": "csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2025-01-01T00:00:00Z",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "moving",
+                            "stopped",
+                            "offline"
+                        ],
+                        "type": "string",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2025-01-02T00:00:00Z",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "vehicleId",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "exported file",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "validation error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "403": {
+                        "description": "vehicle not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations/heatmap": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Bucket the locations matching the filters in a period by their geohash at the requested precision,\nreturning the count and average speed of every cell, from the densest, to draw a heatmap layer.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Locations"
+                ],
+                "summary": "Get the density of locations by geohash cell",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "2025-01-01T00:00:00Z",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "maximum": 10000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "example": 1000,
+                        "description": "Limit is the maximum number of cells returned, the densest ones, 1000 by default.",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 9,
+                        "minimum": 1,
+                        "type": "integer",
+                        "example": 6,
+                        "description": "Precision is the length of the geohash of the cells, from 1 (about 5000 km) to 9 (about 5 m).",
+                        "name": "precision",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "moving",
+                            "stopped",
+                            "offline"
+                        ],
+                        "type": "string",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2025-01-02T00:00:00Z",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "name": "vehicleId",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "heatmap cells",
+                        "schema": {
+                            "$ref": "#/definitions/dto.HeatmapResponseOut"
+                        }
+                    },
+                    "400": {
+                        "description": "validation error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "403": {
+                        "description": "vehicle not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations/import": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Import the history of locations from a CSV or GPX file sent as the request body.\nEvery row is validated with the same rules of the location data and the valid ones are inserted in bulk.\nThe CSV must have a header line, the columns are mapped to the fields with the mapping parameter.\nOn a dry run the file is only validated. Large files should be imported with the locationctl tool.\nThe rows of the vehicles that the client can't access are rejected.",
+                "consumes": [
+                    "text/csv",
+                    "application/gpx+xml"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Locations"
+                ],
+                "summary": "Import location history",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "example": true,
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "gpx"
+                        ],
+                        "type": "string",
+                        "example": "csv",
+                        "name": "format",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "latitude=lat,longitude=lng,timestamp=time",
+                        "name": "mapping",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "unixms",
+                        "name": "timestampLayout",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "ABC1234",
+                        "name": "vehicleId",
+                        "in": "query"
+                    },
+                    {
+                        "description": "CSV or GPX file",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "import report",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ImportReportOut"
+                        }
+                    },
+                    "400": {
+                        "description": "validation error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit or daily quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations/nmea": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Insert the positions of the $GPRMC and $GPGGA sentences forwarded by a vehicle.\nThe checksums are verified, the RMC and GGA sentences of the same epoch are merged\nand the status is derived from the speed, so the GGA fixes without the RMC sentence of their epoch are rejected.\nThe rejected sentences are reported with their line, starting at 1. The positions over the daily quota of the tenant are rejected.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Locations"
+                ],
+                "summary": "Insert location data from NMEA sentences",
+                "parameters": [
+                    {
+                        "description": "NMEA sentences of a vehicle",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.NMEALocationInApp"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "documents created",
+                        "schema": {
+                            "$ref": "#/definitions/dto.NMEALocationResponseOut"
+                        }
+                    },
+                    "400": {
+                        "description": "validation error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "403": {
+                        "description": "vehicle not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "422": {
+                        "description": "no valid positions",
+                        "schema": {
+                            "$ref": "#/definitions/dto.NMEALocationResponseOut"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit or daily quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get location data from database based on a document_id\nThe deleted locations are found only with include_deleted, for the admin role.\nThe ETag header has the version of the location, and 304 is returned when it matches If-None-Match.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Locations"
+                ],
+                "summary": "Get location data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "id from document",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "include the deleted location",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached version",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "located document",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LocationOutApp"
+                        }
+                    },
+                    "304": {
+                        "description": "document not modified"
+                    },
+                    "403": {
+                        "description": "vehicle or deleted locations not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "404": {
+                        "description": "document not found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.DefaultResponseMessageOut"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update location data into database based on a document_id\nA change of the status is recorded in the status transitions of the vehicle.\nWith If-Match, the location is updated only on the version of the ETag, and the ETag of the new one is returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Locations"
+                ],
+                "summary": "Update location data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "id from document",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Request of updating location object",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.LocationInApp"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the expected version",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "updated document",
+                        "schema": {
+                            "$ref": "#/definitions/dto.DefaultResponseMessageOut"
+                        }
+                    },
+                    "400": {
+                        "description": "validation error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "403": {
+                        "description": "vehicle not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "404": {
+                        "description": "document not found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.DefaultResponseMessageOut"
+                        }
+                    },
+                    "412": {
+                        "description": "version mismatch",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete location data from database based on a document_id\nThe location is kept as deleted, and can be restored, until it is purged after the retention period.\nWith If-Match, the location is deleted only on the version of the ETag.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Locations"
+                ],
+                "summary": "Delete location data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "id from document",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the expected version",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "deleted document",
+                        "schema": {
+                            "$ref": "#/definitions/dto.DefaultResponseMessageOut"
+                        }
+                    },
+                    "403": {
+                        "description": "vehicle not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "404": {
+                        "description": "document not found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.DefaultResponseMessageOut"
+                        }
+                    },
+                    "412": {
+                        "description": "version mismatch",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Change only some fields of a location based on a document_id, returning the patched location.\nThe body is a JSON merge patch (RFC 7396), with the application/merge-patch+json or application/json content type,\nor a JSON patch (RFC 6902), with the application/json-patch+json content type, of the fields of the update request.\nThe timestamp is kept, the patched location is validated and only the changed fields are saved.\nA change of the status is recorded in the status transitions of the vehicle.\nWith If-Match, the location is patched only on the version of the ETag, and the ETag of the new one is returned.",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json-patch+json",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Locations"
+                ],
+                "summary": "Patch location data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "id from document",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Merge patch of the location fields, or list of JSON patch operations",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the expected version",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "patched document",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LocationOutApp"
+                        }
+                    },
+                    "400": {
+                        "description": "malformed patch",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "403": {
+                        "description": "vehicle not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "404": {
+                        "description": "document not found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.DefaultResponseMessageOut"
+                        }
+                    },
+                    "409": {
+                        "description": "patch can't be applied",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "412": {
+                        "description": "version mismatch",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "422": {
+                        "description": "patched location not valid",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/locations/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Restore a deleted location that was not purged yet, based on a document_id. It requires the admin role.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Locations"
+                ],
+                "summary": "Restore location data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "id from document",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "restored document",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LocationOutApp"
+                        }
+                    },
+                    "403": {
+                        "description": "vehicle or deleted locations not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "404": {
+                        "description": "document not found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.DefaultResponseMessageOut"
+                        }
+                    },
+                    "409": {
+                        "description": "document not deleted",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/stats/daily": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the per-vehicle, per-day statistics computed by the roll-up job, with the point count, distance in meters,\nmax and average speed, moving, stopped and offline durations in seconds and the first and last seen times.\nThe days are in UTC.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Stats"
+                ],
+                "summary": "Get the daily statistics of the vehicles",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "2025-01-01",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "2025-01-31",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "ABC1234",
+                        "name": "vehicleId",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "daily statistics",
+                        "schema": {
+                            "$ref": "#/definitions/dto.DailyStatsResponseOut"
+                        }
+                    },
+                    "400": {
+                        "description": "validation error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "403": {
+                        "description": "vehicle not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/tiles/{z}/{x}/{y}.mvt": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Encode the locations inside a tile of the XYZ scheme as a Mapbox Vector Tile, with a layer named locations.\nIn the latest mode only the last position of every vehicle is drawn, in the history mode all the locations\nin the period, which is required and at most 7 days long. Up to the cluster zoom, and past 10000 points deeper,\nthe close points are merged into points with the cluster and point_count properties.",
+                "produces": [
+                    "application/vnd.mapbox-vector-tile"
+                ],
+                "tags": [
+                    "Tiles"
+                ],
+                "summary": "Get a vector tile of the locations",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "zoom level",
+                        "name": "z",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "tile column",
+                        "name": "x",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "tile row",
+                        "name": "y",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "filter by vehicle id",
+                        "name": "vehicle_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "moving",
+                            "stopped",
+                            "offline"
+                        ],
+                        "type": "string",
+                        "description": "filter by status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "start of the period, RFC 3339, required by the history mode",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "end of the period, RFC 3339, required by the history mode",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "latest",
+                            "history"
+                        ],
+                        "type": "string",
+                        "default": "latest",
+                        "description": "positions drawn",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 12,
+                        "description": "deepest zoom level where the points are clustered",
+                        "name": "cluster_zoom",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "vector tile",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "validation error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "403": {
+                        "description": "vehicle not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/vehicles/{id}/playback": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the positions of a vehicle at every interval of a period, linearly interpolated between its locations,\nwith the heading of the movement. The positions where the vehicle was offline or without locations for longer\nthan max_gap seconds are marked as gaps. A period can have at most 10000 positions.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Vehicles"
+                ],
+                "summary": "Replay the movement of a vehicle",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "vehicle id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "start of the period, RFC 3339",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "end of the period, RFC 3339",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "time step in seconds",
+                        "name": "interval",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 600,
+                        "description": "time in seconds without locations after which the vehicle is in a gap",
+                        "name": "max_gap",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "positions of the vehicle",
+                        "schema": {
+                            "$ref": "#/definitions/dto.PlaybackResponseOut"
+                        }
+                    },
+                    "400": {
+                        "description": "validation error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "403": {
+                        "description": "vehicle not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/vehicles/{id}/stops": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get where and how long a vehicle stopped in a period. A stop is a sequence of locations with the stopped status\nor a low speed within a radius, and the idle duration is the time stopped with the ignition on.\nThe durations are in seconds.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Vehicles"
+                ],
+                "summary": "Get the stops of a vehicle",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "vehicle id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "start of the period, RFC 3339",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "end of the period, RFC 3339",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "number",
+                        "default": 50,
+                        "description": "distance in meters that the vehicle can drift while stopped",
+                        "name": "radius",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 120,
+                        "description": "minimum duration of a stop in seconds",
+                        "name": "min_duration",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 5,
+                        "description": "speed in km/h up to which the vehicle is considered stopped",
+                        "name": "max_speed",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "stops of the vehicle",
+                        "schema": {
+                            "$ref": "#/definitions/dto.StopReportOut"
+                        }
+                    },
+                    "400": {
+                        "description": "validation error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "403": {
+                        "description": "vehicle not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/vehicles/{id}/track": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stream the locations of a vehicle in a period, ordered by timestamp, as a GPX, KML or GeoJSON file\nwith the speed and status as attributes of the points. With simplify, the points not needed to draw the track\nwithin the tolerance are removed by the Douglas-Peucker algorithm.",
+                "produces": [
+                    "application/gpx+xml",
+                    "application/vnd.google-earth.kml+xml",
+                    "application/geo+json"
+                ],
+                "tags": [
+                    "Vehicles"
+                ],
+                "summary": "Export the track of a vehicle",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "vehicle id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "start of the period, RFC 3339",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "end of the period, RFC 3339",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "gpx",
+                            "kml",
+                            "geojson"
+                        ],
+                        "type": "string",
+                        "default": "gpx",
+                        "description": "file format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "tolerance in meters to simplify the track, keeping the status changes and stops",
+                        "name": "simplify",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "track file",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "validation error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "403": {
+                        "description": "vehicle not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/vehicles/{id}/transitions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the changes of the status of a vehicle in a period, with the time, the source that reported them\nand the location that caused them, and the time in seconds spent on every status in the period.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Vehicles"
+                ],
+                "summary": "Get the status transitions of a vehicle",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "vehicle id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "start of the period, RFC 3339",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "end of the period, RFC 3339",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "status transitions of the vehicle",
+                        "schema": {
+                            "$ref": "#/definitions/dto.StatusTransitionResponseOut"
+                        }
+                    },
+                    "400": {
+                        "description": "validation error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "403": {
+                        "description": "vehicle not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GlobalErrorHandlerResp"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "dto.APIKeyInApp": {
+            "type": "object",
+            "required": [
+                "groups",
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "expires_at": {
+                    "type": "string",
+                    "example": "2026-01-01T00:00:00Z"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "acme"
+                    ]
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "mqtt-gateway"
+                },
+                "scopes": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "read",
+                        "write"
+                    ]
+                },
+                "tenant": {
+                    "description": "Tenant is the tenant of the key, the one of the client that issues it and the default one when empty.",
+                    "type": "string",
+                    "example": "acme"
+                },
+                "vehicles": {
+                    "description": "Vehicles and Groups are the only vehicles and fleet groups the key can access, all of them when both are empty.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "ABC1234"
+                    ]
+                }
+            }
+        },
+        "dto.APIKeyIssuedOut": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "gk_1a2b3c4d5e6f.c2VjcmV0"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tenant": {
+                    "type": "string"
+                },
+                "vehicles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "dto.APIKeyListOut": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.APIKeyOutApp"
+                    }
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "dto.APIKeyOutApp": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tenant": {
+                    "type": "string"
+                },
+                "vehicles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "dto.AuditActorOutApp": {
+            "type": "object",
+            "properties": {
+                "method": {
+                    "type": "string",
+                    "example": "api_key"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AuditEntryOutApp": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "example": "delete"
+                },
+                "actor": {
+                    "$ref": "#/definitions/dto.AuditActorOutApp"
+                },
+                "after": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "at": {
+                    "type": "string"
+                },
+                "before": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "id": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "resource": {
+                    "type": "string",
+                    "example": "location"
+                },
+                "resource_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AuditResponseOut": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.AuditEntryOutApp"
+                    }
+                },
+                "pagination_info": {
+                    "$ref": "#/definitions/dto.PaginationInfoResponse"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "dto.CoordinatesOutApp": {
+            "type": "object",
+            "properties": {
+                "latitude": {
+                    "type": "string"
+                },
+                "longitude": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.DailyStatsOutApp": {
+            "type": "object",
+            "properties": {
+                "avg_speed": {
+                    "type": "number"
+                },
+                "day": {
+                    "type": "string"
+                },
+                "distance": {
+                    "type": "number"
+                },
+                "first_seen": {
+                    "type": "string"
+                },
+                "last_seen": {
+                    "type": "string"
+                },
+                "max_speed": {
+                    "type": "integer"
+                },
+                "moving_duration": {
+                    "type": "integer"
+                },
+                "offline_duration": {
+                    "type": "integer"
+                },
+                "points": {
+                    "type": "integer"
+                },
+                "stopped_duration": {
+                    "type": "integer"
+                },
+                "vehicle_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.DailyStatsResponseOut": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.DailyStatsOutApp"
+                    }
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
         "dto.DefaultResponseMessageOut": {
             "type": "object",
             "properties": {
@@ -282,6 +2126,80 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.HeatmapCellOut": {
+            "type": "object",
+            "properties": {
+                "avg_speed": {
+                    "type": "number"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "geohash": {
+                    "type": "string"
+                },
+                "latitude": {
+                    "type": "number"
+                },
+                "longitude": {
+                    "type": "number"
+                }
+            }
+        },
+        "dto.HeatmapResponseOut": {
+            "type": "object",
+            "properties": {
+                "cells": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.HeatmapCellOut"
+                    }
+                },
+                "precision": {
+                    "type": "integer"
+                },
+                "total_cells": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ImportRejectedRowOut": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "line": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ImportReportOut": {
+            "type": "object",
+            "properties": {
+                "accepted": {
+                    "type": "integer"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "inserted": {
+                    "type": "integer"
+                },
+                "rejected": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ImportRejectedRowOut"
+                    }
+                },
+                "rejected_count": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
         "dto.LocationCreatedResponseOut": {
             "type": "object",
             "properties": {
@@ -299,6 +2217,11 @@ const docTemplate = `{
                 "vehicle_id"
             ],
             "properties": {
+                "attributes": {
+                    "description": "Attributes are the telemetry reported by the device, like ignition or battery voltage.",
+                    "type": "object",
+                    "additionalProperties": {}
+                },
                 "latitude": {
                     "type": "string",
                     "example": "-23.55052"
@@ -330,12 +2253,28 @@ const docTemplate = `{
         "dto.LocationOutApp": {
             "type": "object",
             "properties": {
+                "attributes": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "flags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "id": {
                     "type": "string"
                 },
                 "location": {
                     "$ref": "#/definitions/dto.CoordinatesOutApp"
                 },
+                "place": {
+                    "$ref": "#/definitions/dto.PlaceOutApp"
+                },
                 "speed": {
                     "type": "integer"
                 },
@@ -347,6 +2286,76 @@ const docTemplate = `{
                 },
                 "vehicle_id": {
                     "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.NMEALocationInApp": {
+            "type": "object",
+            "required": [
+                "sentences",
+                "vehicle_id"
+            ],
+            "properties": {
+                "sentences": {
+                    "type": "array",
+                    "maxItems": 500,
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "$GPRMC",
+                        "123519",
+                        "A",
+                        "4807.038",
+                        "N",
+                        "01131.000",
+                        "E",
+                        "022.4",
+                        "084.4",
+                        "230394",
+                        "003.1",
+                        "W*6A"
+                    ]
+                },
+                "vehicle_id": {
+                    "type": "string",
+                    "example": "ABC1234"
+                }
+            }
+        },
+        "dto.NMEALocationResponseOut": {
+            "type": "object",
+            "properties": {
+                "document_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "rejected": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.NMEARejectedSentenceOut"
+                    }
+                }
+            }
+        },
+        "dto.NMEARejectedSentenceOut": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "line": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "sentence": {
+                    "type": "string"
                 }
             }
         },
@@ -361,6 +2370,72 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.PlaceOutApp": {
+            "type": "object",
+            "properties": {
+                "country": {
+                    "type": "string",
+                    "example": "BR"
+                },
+                "distance": {
+                    "type": "number",
+                    "example": 35.2
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Av. Paulista, São Paulo"
+                }
+            }
+        },
+        "dto.PlaybackFrameOut": {
+            "type": "object",
+            "properties": {
+                "gap": {
+                    "type": "boolean"
+                },
+                "heading": {
+                    "type": "number"
+                },
+                "interpolated": {
+                    "type": "boolean"
+                },
+                "location": {
+                    "$ref": "#/definitions/dto.CoordinatesOutApp"
+                },
+                "speed": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.PlaybackResponseOut": {
+            "type": "object",
+            "properties": {
+                "frames": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.PlaybackFrameOut"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "interval": {
+                    "type": "integer"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "vehicle_id": {
+                    "type": "string"
+                }
+            }
+        },
         "dto.QueryLocationResponse": {
             "type": "object",
             "properties": {
@@ -378,6 +2453,113 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.StatusTransitionOutApp": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string",
+                    "example": "moving"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "location_id": {
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string",
+                    "example": "api"
+                },
+                "to": {
+                    "type": "string",
+                    "example": "stopped"
+                }
+            }
+        },
+        "dto.StatusTransitionResponseOut": {
+            "type": "object",
+            "properties": {
+                "durations": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "transitions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.StatusTransitionOutApp"
+                    }
+                },
+                "vehicle_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.StopOut": {
+            "type": "object",
+            "properties": {
+                "duration": {
+                    "type": "integer"
+                },
+                "ended_at": {
+                    "type": "string"
+                },
+                "idle_duration": {
+                    "type": "integer"
+                },
+                "location": {
+                    "$ref": "#/definitions/dto.CoordinatesOutApp"
+                },
+                "locations": {
+                    "type": "integer"
+                },
+                "place": {
+                    "$ref": "#/definitions/dto.PlaceOutApp"
+                },
+                "started_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.StopReportOut": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "stops": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.StopOut"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                },
+                "total_duration": {
+                    "type": "integer"
+                },
+                "total_idle_duration": {
+                    "type": "integer"
+                },
+                "vehicle_id": {
+                    "type": "string"
+                }
+            }
+        },
         "handler.GlobalErrorHandlerResp": {
             "type": "object",
             "properties": {
@@ -392,6 +2574,19 @@ const docTemplate = `{
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "BearerAuth": {
+            "description": "JWT of the identity provider, in the format Bearer \u003ctoken\u003e",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
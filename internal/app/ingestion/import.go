@@ -0,0 +1,105 @@
+package ingestion
+
+import (
+	"errors"
+	"io"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/allansbo/goapi/internal/pkg/importfile"
+	"github.com/allansbo/goapi/internal/pkg/validation"
+)
+
+const (
+	// importBatchSize is the number of locations saved in every bulk insert.
+	importBatchSize = 1000
+	// maxRejectedRows limits the rejected rows listed in the report.
+	maxRejectedRows = 1000
+)
+
+// ImportLocations reads the locations of a file, validates every row with the rules of the location data
+// and saves the valid ones in bulk. The rows without a status have it derived from the speed.
+// On a dry run the file is only validated. When saving fails, the report has what was inserted until then,
+// and usecase.ErrAuditNotRecorded is returned after the whole file when a batch was saved without its audit entries.
+// The locations are saved on the tenant of the principal, and the rows of the vehicles it can't access are rejected.
+func ImportLocations(principal *entity.Principal, reader importfile.Reader, dryRun bool) (*dto.ImportReportOut, error) {
+	report := &dto.ImportReportOut{DryRun: dryRun, Rejected: make([]*dto.ImportRejectedRowOut, 0)}
+	batch := make([]*dto.TimedLocationInApp, 0, importBatchSize)
+
+	reject := func(line int, err error) {
+		report.RejectedCount++
+		if len(report.Rejected) < maxRejectedRows {
+			report.Rejected = append(report.Rejected, &dto.ImportRejectedRowOut{Line: line, Error: err.Error()})
+		}
+	}
+
+	// The batches saved without their audit entries don't stop the import, the failure is returned at its end.
+	var auditErr error
+	flush := func() error {
+		if dryRun || len(batch) == 0 {
+			batch = batch[:0]
+			return nil
+		}
+
+		ids, err := usecase.SaveLocationsAt(principal, batch)
+		report.Inserted += len(ids)
+		batch = batch[:0]
+		if errors.Is(err, usecase.ErrAuditNotRecorded) {
+			auditErr = err
+			return nil
+		}
+		return err
+	}
+
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var rowErr *importfile.RowError
+		if errors.As(err, &rowErr) {
+			report.Total++
+			reject(rowErr.Line, rowErr.Err)
+			continue
+		} else if err != nil {
+			return report, err
+		}
+		report.Total++
+
+		locationDataIn := &dto.LocationInApp{
+			VehicleId: record.VehicleId,
+			Latitude:  record.Latitude,
+			Longitude: record.Longitude,
+			Status:    record.Status,
+			Speed:     record.Speed,
+		}
+		if locationDataIn.Status == "" {
+			locationDataIn.Status = entity.StatusFromSpeed(record.Speed)
+		}
+
+		if err := validation.Validate(locationDataIn); err != nil {
+			reject(record.Line, err)
+			continue
+		}
+		if err := usecase.CheckVehicleAccess(principal, locationDataIn.VehicleId); err != nil {
+			reject(record.Line, err)
+			continue
+		}
+
+		report.Accepted++
+		batch = append(batch, &dto.TimedLocationInApp{Location: locationDataIn, Timestamp: record.Timestamp})
+
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return report, err
+	}
+	return report, auditErr
+}
@@ -0,0 +1,106 @@
+package ingestion
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/allansbo/goapi/internal/pkg/ratelimit"
+	"github.com/allansbo/goapi/internal/pkg/validation"
+)
+
+// RateLimitDevice is the rate limit group of the locations received from every device by the transports.
+const RateLimitDevice = "device"
+
+// ErrRateLimited is returned when a device sends locations faster than its rate limit.
+var ErrRateLimited = errors.New("the rate limit of the device was exceeded")
+
+// limiter limits the locations of every device, identified by its vehicle ID.
+var limiter *ratelimit.Limiter
+
+// LoadRateLimiter sets the limiter of the locations received from the devices, by the RateLimitDevice group.
+func LoadRateLimiter(l *ratelimit.Limiter) {
+	limiter = l
+}
+
+// SaveLocation validates the location received by an ingestion transport
+// with the same rules applied to the HTTP requests and saves it through the location use case.
+// A zero timestamp means that the device did not report the time of the fix, so the current time is used.
+// The source identifies the transport in the status transitions, like mqtt or tcp:gt06.
+// The location is saved on the tenant of the vehicle, the default one when it is not mapped.
+// The locations over the rate limit of the device or the daily quota of the tenant are discarded.
+func SaveLocation(locationDataIn *dto.LocationInApp, timestamp time.Time, source string) (*dto.LocationOutApp, error) {
+	if err := validation.Validate(locationDataIn); err != nil {
+		return nil, &ValidationError{Err: err}
+	}
+
+	// The locations are not discarded when the store of the limits is unavailable.
+	if wait, err := limiter.Allow(RateLimitDevice, locationDataIn.VehicleId); err != nil {
+		slog.Error("error checking rate limit", "error", err.Error(), "vehicleID", locationDataIn.VehicleId)
+	} else if wait > 0 {
+		return nil, &ValidationError{Err: ErrRateLimited}
+	}
+
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	// The transport is the actor of the location in the audit log.
+	principal := entity.NewInternalPrincipal(source, vehicleTenants[locationDataIn.VehicleId])
+
+	locationDataOut, err := usecase.SaveLocationAt(principal, locationDataIn, timestamp, source)
+	if errors.Is(err, usecase.ErrAuditNotRecorded) {
+		// The location is saved and the failure is logged, a retry of the device would save it again.
+		return locationDataOut, nil
+	}
+
+	// The locations rejected by the anomaly checks or the quota would be rejected again on a retry.
+	var rejectedErr *usecase.LocationRejectedError
+	var quotaErr *usecase.QuotaExceededError
+	if errors.As(err, &rejectedErr) || errors.As(err, &quotaErr) {
+		return nil, &ValidationError{Err: err}
+	}
+
+	return locationDataOut, err
+}
+
+// vehicleTenants maps the vehicle IDs to the tenants of the locations received by the transports.
+var vehicleTenants = map[string]string{}
+
+// LoadVehicleTenants loads the vehicle to tenant mapping from entries in the format <vehicle id>=<tenant>.
+func LoadVehicleTenants(entries []string) error {
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		vehicleID, tenant, found := strings.Cut(entry, "=")
+		tenant = strings.TrimSpace(tenant)
+		if !found || vehicleID == "" || !validation.IsTenant(tenant) {
+			return fmt.Errorf("invalid vehicle tenant %q, expected <vehicle id>=<tenant>", entry)
+		}
+		vehicleTenants[strings.TrimSpace(vehicleID)] = tenant
+	}
+	return nil
+}
+
+// ValidationError is returned when the data received by a transport is not a valid location.
+// The transports use it to tell apart bad payloads, which must be discarded,
+// from storage failures, which can be retried.
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid location data: " + e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,182 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Control packet types of MQTT 3.1.1.
+const (
+	packetConnect    = 1
+	packetConnAck    = 2
+	packetPublish    = 3
+	packetPubAck     = 4
+	packetSubscribe  = 8
+	packetSubAck     = 9
+	packetPingReq    = 12
+	packetPingResp   = 13
+	packetDisconnect = 14
+)
+
+// localBroker is a MQTT 3.1.1 broker listening on the loopback interface, with the packets needed
+// by the gateway: it accepts one client at a time, acknowledges its subscriptions, publishes to it
+// and records its acknowledgements. The sessions are not kept, so nothing is delivered again.
+type localBroker struct {
+	listener   net.Listener
+	mu         sync.Mutex
+	conn       net.Conn
+	subscribed chan []string
+	acks       chan uint16
+}
+
+func newLocalBroker(t *testing.T) *localBroker {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("the loopback interface is not available: %v", err)
+	}
+
+	b := &localBroker{listener: listener, subscribed: make(chan []string, 1), acks: make(chan uint16, 16)}
+	t.Cleanup(func() {
+		_ = listener.Close()
+		b.mu.Lock()
+		if b.conn != nil {
+			_ = b.conn.Close()
+		}
+		b.mu.Unlock()
+	})
+
+	go b.serve()
+	return b
+}
+
+// URL returns the address of the broker in the format of MQTT_BROKER.
+func (b *localBroker) URL() string {
+	return "tcp://" + b.listener.Addr().String()
+}
+
+func (b *localBroker) serve() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		b.mu.Lock()
+		b.conn = conn
+		b.mu.Unlock()
+		b.handle(conn)
+	}
+}
+
+func (b *localBroker) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	for {
+		header, body, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+
+		switch header >> 4 {
+		case packetConnect:
+			b.write(packetConnAck<<4, []byte{0, 0})
+		case packetSubscribe:
+			id, filters, qos := parseSubscribe(body)
+			b.write(packetSubAck<<4, append(binary.BigEndian.AppendUint16(nil, id), qos...))
+			b.subscribed <- filters
+		case packetPubAck:
+			b.acks <- binary.BigEndian.Uint16(body)
+		case packetPingReq:
+			b.write(packetPingResp<<4, nil)
+		case packetDisconnect:
+			return
+		}
+	}
+}
+
+// publish sends a QoS 1 message to the client.
+func (b *localBroker) publish(id uint16, topic, payload string) {
+	body := binary.BigEndian.AppendUint16(nil, uint16(len(topic)))
+	body = append(body, topic...)
+	body = binary.BigEndian.AppendUint16(body, id)
+	b.write(packetPublish<<4|1<<1, append(body, payload...))
+}
+
+// ack waits for the acknowledgement of a message by the client.
+func (b *localBroker) ack(t *testing.T, timeout time.Duration) (uint16, bool) {
+	t.Helper()
+	select {
+	case id := <-b.acks:
+		return id, true
+	case <-time.After(timeout):
+		return 0, false
+	}
+}
+
+func (b *localBroker) write(header byte, body []byte) {
+	packet := []byte{header}
+	for n := len(body); ; {
+		digit := byte(n % 128)
+		if n /= 128; n > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if n == 0 {
+			break
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn != nil {
+		_, _ = b.conn.Write(append(packet, body...))
+	}
+}
+
+// readPacket reads the fixed header and the body of a control packet.
+func readPacket(reader *bufio.Reader) (byte, []byte, error) {
+	header, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length, multiplier := 0, 1
+	for {
+		digit, err := reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		if multiplier *= 128; multiplier > 128*128*128 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+	}
+
+	body := make([]byte, length)
+	_, err = io.ReadFull(reader, body)
+	return header, body, err
+}
+
+// parseSubscribe returns the packet ID, the topic filters and the granted QoS of a SUBSCRIBE packet.
+func parseSubscribe(body []byte) (uint16, []string, []byte) {
+	id := binary.BigEndian.Uint16(body)
+	var filters []string
+	var qos []byte
+	for rest := body[2:]; len(rest) > 2; {
+		n := int(binary.BigEndian.Uint16(rest))
+		filters = append(filters, string(rest[2:2+n]))
+		qos = append(qos, rest[2+n])
+		rest = rest[3+n:]
+	}
+	return id, filters, qos
+}
@@ -0,0 +1,199 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/ingestion"
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/config"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Retries of the locations that could not be saved: the first one after saveBackoff,
+// every next one after twice the previous delay, up to maxSaveBackoff.
+const (
+	saveRetries    = 5
+	saveBackoff    = 500 * time.Millisecond
+	maxSaveBackoff = 10 * time.Second
+)
+
+// ErrVehicleMismatch is returned when the vehicle of a payload is not the one of the topic it was published on.
+var ErrVehicleMismatch = errors.New("the vehicle of the payload doesn't match the vehicle of the topic")
+
+// locationMessage is the payload published by the trackers.
+// The timestamp is optional and the vehicle_id can be omitted
+// when the topic carries it, like in fleet/ABC1234/location.
+type locationMessage struct {
+	dto.LocationInApp
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Gateway subscribes to the configured topics on a MQTT broker
+// and saves every location published by the trackers.
+type Gateway struct {
+	client paho.Client
+	topics []string
+	qos    byte
+	// retries and backoff are saveRetries and saveBackoff outside of the tests.
+	retries int
+	backoff time.Duration
+	// done is closed when the gateway stops, ending the retries.
+	done chan struct{}
+	// save saves the locations of the messages, ingestion.SaveLocation outside of the tests.
+	save func(locationDataIn *dto.LocationInApp, timestamp time.Time, source string) (*dto.LocationOutApp, error)
+}
+
+// NewGateway creates a new instance of Gateway with the provided configuration.
+// The acknowledgements are sent only after a message is handled, and the session is persistent,
+// so the broker delivers again, on the next connection, the QoS 1 and 2 messages not acknowledged
+// when the gateway stopped. A broker never delivers them again during the same connection.
+func NewGateway(cfg *config.EnvConfig) *Gateway {
+	g := &Gateway{
+		topics:  cfg.MQTTTopics,
+		qos:     cfg.MQTTQoS,
+		retries: saveRetries,
+		backoff: saveBackoff,
+		done:    make(chan struct{}),
+		save:    ingestion.SaveLocation,
+	}
+
+	clientOpts := paho.NewClientOptions().
+		AddBroker(cfg.MQTTBroker).
+		SetClientID(cfg.MQTTClientID).
+		SetUsername(cfg.MQTTUser).
+		SetPassword(cfg.MQTTPass).
+		SetCleanSession(false).
+		SetAutoAckDisabled(true).
+		SetOrderMatters(false).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(5 * time.Second).
+		SetOnConnectHandler(g.subscribe).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			slog.Error("mqtt connection lost", "error", err.Error())
+		})
+
+	g.client = paho.NewClient(clientOpts)
+
+	return g
+}
+
+// Start connects to the broker. The subscriptions are made on every (re)connection.
+func (g *Gateway) Start() error {
+	token := g.client.Connect()
+	if !token.WaitTimeout(10 * time.Second) {
+		slog.Warn("mqtt broker not reachable yet, retrying in background")
+		return nil
+	}
+	if token.Error() != nil {
+		return fmt.Errorf("mqtt connect failed: %w", token.Error())
+	}
+	return nil
+}
+
+// Stop disconnects from the broker, waiting for the messages in flight.
+// The messages still being retried are not acknowledged.
+func (g *Gateway) Stop() {
+	close(g.done)
+	g.client.Disconnect(250)
+}
+
+func (g *Gateway) subscribe(client paho.Client) {
+	filters := make(map[string]byte, len(g.topics))
+	for _, topic := range g.topics {
+		filters[strings.TrimSpace(topic)] = g.qos
+	}
+
+	token := client.SubscribeMultiple(filters, g.handleMessage)
+	if token.Wait() && token.Error() != nil {
+		slog.Error("error subscribing mqtt topics", "error", token.Error().Error(), "topics", g.topics)
+		return
+	}
+	slog.Info("mqtt topics subscribed", "topics", g.topics, "qos", g.qos)
+}
+
+// handleMessage saves the location published on a topic and acknowledges the message.
+// Invalid payloads are discarded, as a redelivery would fail the same way. The messages that could
+// not be saved are saved again with a backoff, since an unacknowledged message is not delivered again
+// during the connection and holds a slot of the in-flight window of the broker. They are discarded
+// after the last retry, and left unacknowledged when the gateway stops during the retries.
+func (g *Gateway) handleMessage(_ paho.Client, msg paho.Message) {
+	err := g.handlePayload(msg.Topic(), msg.Payload())
+
+	var validationErr *ingestion.ValidationError
+	delay := g.backoff
+	for retry := 1; err != nil && !errors.As(err, &validationErr) && retry <= g.retries; retry++ {
+		slog.Error("error saving mqtt message, retrying", "error", err.Error(), "topic", msg.Topic(), "retry", retry, "delay", delay)
+		select {
+		case <-g.done:
+			slog.Warn("mqtt message not acknowledged, the gateway is stopping", "topic", msg.Topic(), "qos", msg.Qos())
+			return
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, maxSaveBackoff)
+		err = g.handlePayload(msg.Topic(), msg.Payload())
+	}
+
+	switch {
+	case err == nil:
+	case errors.As(err, &validationErr):
+		slog.Error("discarding mqtt message", "error", err.Error(), "topic", msg.Topic())
+	default:
+		slog.Error("discarding mqtt message, it could not be saved", "error", err.Error(), "topic", msg.Topic(), "retries", g.retries)
+	}
+	msg.Ack()
+}
+
+func (g *Gateway) handlePayload(topic string, payload []byte) error {
+	message := new(locationMessage)
+	if err := json.Unmarshal(payload, message); err != nil {
+		return &ingestion.ValidationError{Err: err}
+	}
+
+	// A device can only publish the locations of the vehicle of its topic.
+	topicVehicleID := g.vehicleFromTopic(topic)
+	if message.VehicleId == "" {
+		message.VehicleId = topicVehicleID
+	} else if topicVehicleID != "" && message.VehicleId != topicVehicleID {
+		return &ingestion.ValidationError{Err: fmt.Errorf("%w: %s on %s", ErrVehicleMismatch, message.VehicleId, topic)}
+	}
+
+	_, err := g.save(&message.LocationInApp, message.Timestamp, entity.SourceMQTT)
+	return err
+}
+
+// vehicleFromTopic returns the topic level matched by the first single-level
+// wildcard of the subscribed filters, like ABC1234 on fleet/ABC1234/location for fleet/+/location.
+func (g *Gateway) vehicleFromTopic(topic string) string {
+	levels := strings.Split(topic, "/")
+
+	for _, filter := range g.topics {
+		filterLevels := strings.Split(strings.TrimSpace(filter), "/")
+		vehicleID, matched := "", true
+
+		for i, filterLevel := range filterLevels {
+			if filterLevel == "#" {
+				break
+			}
+			if i >= len(levels) || (filterLevel != "+" && filterLevel != levels[i]) {
+				matched = false
+				break
+			}
+			if filterLevel == "+" && vehicleID == "" {
+				vehicleID = levels[i]
+			}
+		}
+
+		if matched && vehicleID != "" {
+			return vehicleID
+		}
+	}
+
+	return ""
+}
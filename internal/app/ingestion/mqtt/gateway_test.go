@@ -0,0 +1,355 @@
+package mqtt
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/config"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// fakeBroker is a client that delivers the published messages to its own subscriptions, in memory.
+type fakeBroker struct {
+	paho.Client
+	filters  map[string]byte
+	callback paho.MessageHandler
+}
+
+func (b *fakeBroker) SubscribeMultiple(filters map[string]byte, callback paho.MessageHandler) paho.Token {
+	b.filters, b.callback = filters, callback
+	return doneToken{}
+}
+
+// publish delivers the message to the subscription when the topic matches one of its filters,
+// and returns the message to check its acknowledgement.
+func (b *fakeBroker) publish(topic, payload string) *fakeMessage {
+	msg := &fakeMessage{topic: topic, payload: []byte(payload)}
+	for filter := range b.filters {
+		if topicMatches(filter, topic) {
+			b.callback(b, msg)
+			break
+		}
+	}
+	return msg
+}
+
+func topicMatches(filter, topic string) bool {
+	filterLevels, levels := strings.Split(filter, "/"), strings.Split(topic, "/")
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(levels) || (level != "+" && level != levels[i]) {
+			return false
+		}
+	}
+	return len(filterLevels) == len(levels)
+}
+
+type doneToken struct{}
+
+func (doneToken) Wait() bool                     { return true }
+func (doneToken) WaitTimeout(time.Duration) bool { return true }
+func (doneToken) Done() <-chan struct{} {
+	done := make(chan struct{})
+	close(done)
+	return done
+}
+func (doneToken) Error() error { return nil }
+
+type fakeMessage struct {
+	topic   string
+	payload []byte
+	acked   bool
+}
+
+func (m *fakeMessage) Duplicate() bool   { return false }
+func (m *fakeMessage) Qos() byte         { return 1 }
+func (m *fakeMessage) Retained() bool    { return false }
+func (m *fakeMessage) Topic() string     { return m.topic }
+func (m *fakeMessage) MessageID() uint16 { return 1 }
+func (m *fakeMessage) Payload() []byte   { return m.payload }
+func (m *fakeMessage) Ack()              { m.acked = true }
+
+// savedLocation is a location saved by the gateway.
+type savedLocation struct {
+	location  *dto.LocationInApp
+	timestamp time.Time
+	source    string
+}
+
+func TestGatewayMessages(t *testing.T) {
+	storageErr := errors.New("storage unavailable")
+
+	tests := []struct {
+		name      string
+		topic     string
+		payload   string
+		saveErr   error
+		wantSaved string
+		wantAcked bool
+	}{
+		{
+			name:      "vehicle from the topic",
+			topic:     "fleet/ABC1234/location",
+			payload:   `{"latitude": "-23.55052", "longitude": "-46.633308", "status": "moving", "speed": 80}`,
+			wantSaved: "ABC1234",
+			wantAcked: true,
+		},
+		{
+			name:      "vehicle of the payload matching the topic",
+			topic:     "fleet/ABC1234/location",
+			payload:   `{"vehicle_id": "ABC1234", "latitude": "-23.55052", "longitude": "-46.633308", "status": "moving", "speed": 80, "timestamp": "2025-06-01T10:00:00Z"}`,
+			wantSaved: "ABC1234",
+			wantAcked: true,
+		},
+		{
+			name:      "vehicle of the payload not matching the topic",
+			topic:     "fleet/ABC1234/location",
+			payload:   `{"vehicle_id": "XYZ9876", "latitude": "-23.55052", "longitude": "-46.633308", "status": "moving", "speed": 80}`,
+			wantAcked: true,
+		},
+		{
+			name:      "invalid payload",
+			topic:     "fleet/ABC1234/location",
+			payload:   `{"vehicle_id": `,
+			wantAcked: true,
+		},
+		{
+			name:      "storage failure without retries",
+			topic:     "fleet/ABC1234/location",
+			payload:   `{"latitude": "-23.55052", "longitude": "-46.633308", "status": "moving", "speed": 80}`,
+			saveErr:   storageErr,
+			wantSaved: "ABC1234",
+			wantAcked: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var saved []savedLocation
+			broker := &fakeBroker{}
+			g := &Gateway{
+				client: broker,
+				topics: []string{"fleet/+/location"},
+				qos:    1,
+				save: func(locationDataIn *dto.LocationInApp, timestamp time.Time, source string) (*dto.LocationOutApp, error) {
+					saved = append(saved, savedLocation{location: locationDataIn, timestamp: timestamp, source: source})
+					return &dto.LocationOutApp{VehicleId: locationDataIn.VehicleId}, tt.saveErr
+				},
+			}
+			g.subscribe(broker)
+
+			msg := broker.publish(tt.topic, tt.payload)
+
+			if msg.acked != tt.wantAcked {
+				t.Errorf("acked = %v, want %v", msg.acked, tt.wantAcked)
+			}
+			if tt.wantSaved == "" {
+				if len(saved) != 0 {
+					t.Fatalf("saved %d locations, want none", len(saved))
+				}
+				return
+			}
+			if len(saved) != 1 {
+				t.Fatalf("saved %d locations, want 1", len(saved))
+			}
+			if saved[0].location.VehicleId != tt.wantSaved {
+				t.Errorf("vehicle = %q, want %q", saved[0].location.VehicleId, tt.wantSaved)
+			}
+			if saved[0].source != entity.SourceMQTT {
+				t.Errorf("source = %q, want %q", saved[0].source, entity.SourceMQTT)
+			}
+		})
+	}
+}
+
+func TestGatewayRetriesStorageFailures(t *testing.T) {
+	storageErr := errors.New("storage unavailable")
+
+	tests := []struct {
+		name      string
+		failures  int
+		wantSaves int
+	}{
+		{name: "saved on the first attempt", failures: 0, wantSaves: 1},
+		{name: "saved on a retry", failures: 2, wantSaves: 3},
+		{name: "discarded after the last retry", failures: 10, wantSaves: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saves := 0
+			broker := &fakeBroker{}
+			g := &Gateway{
+				client:  broker,
+				topics:  []string{"fleet/+/location"},
+				qos:     1,
+				retries: 3,
+				backoff: time.Millisecond,
+				done:    make(chan struct{}),
+				save: func(locationDataIn *dto.LocationInApp, timestamp time.Time, source string) (*dto.LocationOutApp, error) {
+					if saves++; saves <= tt.failures {
+						return nil, storageErr
+					}
+					return &dto.LocationOutApp{}, nil
+				},
+			}
+			g.subscribe(broker)
+
+			msg := broker.publish("fleet/ABC1234/location", `{"latitude": "-23.55052", "longitude": "-46.633308", "status": "moving", "speed": 80}`)
+
+			if saves != tt.wantSaves {
+				t.Errorf("saves = %d, want %d", saves, tt.wantSaves)
+			}
+			if !msg.acked {
+				t.Errorf("acked = false, want true")
+			}
+		})
+	}
+}
+
+func TestGatewayStopsRetrying(t *testing.T) {
+	broker := &fakeBroker{}
+	g := &Gateway{
+		client:  broker,
+		topics:  []string{"fleet/+/location"},
+		qos:     1,
+		retries: 3,
+		backoff: time.Hour,
+		done:    make(chan struct{}),
+		save: func(locationDataIn *dto.LocationInApp, timestamp time.Time, source string) (*dto.LocationOutApp, error) {
+			return nil, errors.New("storage unavailable")
+		},
+	}
+	g.subscribe(broker)
+	close(g.done)
+
+	// The message is left to be delivered again on the next connection of the persistent session.
+	msg := broker.publish("fleet/ABC1234/location", `{"latitude": "-23.55052", "longitude": "-46.633308", "status": "moving", "speed": 80}`)
+	if msg.acked {
+		t.Errorf("acked = true, want false")
+	}
+}
+
+func TestGatewayLocalBroker(t *testing.T) {
+	broker := newLocalBroker(t)
+
+	var mu sync.Mutex
+	var saved []string
+	failures := 1
+
+	g := NewGateway(&config.EnvConfig{
+		MQTTBroker:   broker.URL(),
+		MQTTClientID: "goapi-test",
+		MQTTTopics:   []string{"fleet/+/location"},
+		MQTTQoS:      1,
+	})
+	g.backoff = time.Millisecond
+	g.save = func(locationDataIn *dto.LocationInApp, timestamp time.Time, source string) (*dto.LocationOutApp, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		saved = append(saved, locationDataIn.VehicleId)
+		if locationDataIn.VehicleId == "DEF5678" && failures > 0 {
+			failures--
+			return nil, errors.New("storage unavailable")
+		}
+		return &dto.LocationOutApp{}, nil
+	}
+
+	if err := g.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(g.Stop)
+
+	select {
+	case filters := <-broker.subscribed:
+		if len(filters) != 1 || filters[0] != "fleet/+/location" {
+			t.Fatalf("subscribed filters = %v, want [fleet/+/location]", filters)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the gateway didn't subscribe")
+	}
+
+	tests := []struct {
+		name    string
+		id      uint16
+		topic   string
+		payload string
+	}{
+		{name: "saved location", id: 1, topic: "fleet/ABC1234/location", payload: `{"latitude": "-23.55052", "longitude": "-46.633308", "status": "moving", "speed": 80}`},
+		{name: "invalid payload", id: 2, topic: "fleet/ABC1234/location", payload: `{"vehicle_id": `},
+		{name: "location saved on a retry", id: 3, topic: "fleet/DEF5678/location", payload: `{"latitude": "-23.55052", "longitude": "-46.633308", "status": "moving", "speed": 80}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker.publish(tt.id, tt.topic, tt.payload)
+			id, ok := broker.ack(t, 5*time.Second)
+			if !ok {
+				t.Fatalf("message %d was not acknowledged", tt.id)
+			}
+			if id != tt.id {
+				t.Errorf("acknowledged message = %d, want %d", id, tt.id)
+			}
+		})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"ABC1234", "DEF5678", "DEF5678"}; !slices.Equal(saved, want) {
+		t.Errorf("saved = %v, want %v", saved, want)
+	}
+}
+
+func TestGatewayMessageTimestamp(t *testing.T) {
+	var saved *savedLocation
+	broker := &fakeBroker{}
+	g := &Gateway{
+		client: broker,
+		topics: []string{"fleet/+/location"},
+		qos:    1,
+		save: func(locationDataIn *dto.LocationInApp, timestamp time.Time, source string) (*dto.LocationOutApp, error) {
+			saved = &savedLocation{location: locationDataIn, timestamp: timestamp, source: source}
+			return &dto.LocationOutApp{}, nil
+		},
+	}
+	g.subscribe(broker)
+
+	broker.publish("fleet/ABC1234/location", `{"latitude": "-23.55052", "longitude": "-46.633308", "status": "stopped", "speed": 0, "timestamp": "2025-06-01T10:00:00Z", "attributes": {"ignition": false}}`)
+
+	if saved == nil {
+		t.Fatal("the location was not saved")
+	}
+	want := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	if !saved.timestamp.Equal(want) {
+		t.Errorf("timestamp = %v, want %v", saved.timestamp, want)
+	}
+	if saved.location.Latitude != "-23.55052" || saved.location.Status != "stopped" || saved.location.Attributes["ignition"] != false {
+		t.Errorf("location = %+v", saved.location)
+	}
+}
+
+func TestVehicleFromTopic(t *testing.T) {
+	g := &Gateway{topics: []string{"fleet/+/location", " devices/+/+/gps "}}
+
+	tests := map[string]string{
+		"fleet/ABC1234/location":   "ABC1234",
+		"devices/XYZ9876/raw/gps":  "XYZ9876",
+		"fleet/ABC1234/status":     "",
+		"other/ABC1234/location":   "",
+		"fleet":                    "",
+		"devices/XYZ9876/raw/gps2": "",
+	}
+	for topic, want := range tests {
+		if got := g.vehicleFromTopic(topic); got != want {
+			t.Errorf("vehicleFromTopic(%q) = %q, want %q", topic, got, want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package ingestion
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+)
+
+// Position is a fix decoded from a tracker protocol, before being mapped into a location.
+type Position struct {
+	DeviceID  string
+	Timestamp time.Time
+	Valid     bool
+	Latitude  float64
+	Longitude float64
+	Speed     float64 // km/h
+	Course    float64
+
+	// Attributes are the telemetry values sent along with the fix.
+	Attributes map[string]any
+}
+
+// ErrUnknownDevice is returned when the positions of a device not mapped to a vehicle are received.
+var ErrUnknownDevice = errors.New("the device is not mapped to a vehicle")
+
+// devices maps the device identifiers reported by the trackers, usually the IMEI, to vehicle IDs.
+var devices = map[string]string{}
+
+// deviceFallback accepts the devices not mapped, with a vehicle ID derived from their identifier.
+var deviceFallback bool
+
+// LoadDevices loads the device to vehicle mapping from entries in the format <device id>=<vehicle id>.
+// With the fallback, the devices not mapped are accepted too.
+func LoadDevices(entries []string, fallback bool) error {
+	deviceFallback = fallback
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		deviceID, vehicleID, found := strings.Cut(entry, "=")
+		if !found || deviceID == "" || vehicleID == "" {
+			return fmt.Errorf("invalid device mapping %q, expected <device id>=<vehicle id>", entry)
+		}
+		devices[strings.TrimSpace(deviceID)] = strings.TrimSpace(vehicleID)
+	}
+	return nil
+}
+
+// VehicleForDevice returns the vehicle ID of a device and reports if the device is known.
+// The devices not mapped are unknown, unless the fallback is enabled,
+// where they use the last 7 characters of their identifier, so different devices can share a vehicle.
+func VehicleForDevice(deviceID string) (string, bool) {
+	if vehicleID, ok := devices[deviceID]; ok {
+		return vehicleID, true
+	}
+	if !deviceFallback || deviceID == "" {
+		return "", false
+	}
+	if len(deviceID) > 7 {
+		return deviceID[len(deviceID)-7:], true
+	}
+	return deviceID, true
+}
+
+// NewLocationInApp maps a position into the location data of the vehicle accepted by the application.
+func (p *Position) NewLocationInApp(vehicleID string) *dto.LocationInApp {
+	speed := int(math.Round(p.Speed))
+
+	return &dto.LocationInApp{
+		VehicleId:  vehicleID,
+		Latitude:   strconv.FormatFloat(p.Latitude, 'f', 6, 64),
+		Longitude:  strconv.FormatFloat(p.Longitude, 'f', 6, 64),
+		Status:     entity.StatusFromSpeed(speed),
+		Speed:      speed,
+		Attributes: p.Attributes,
+	}
+}
+
+// SavePosition validates and saves a position decoded by a tracker protocol.
+// Positions without a valid fix or of unknown devices are discarded, returning a ValidationError.
+// The source identifies the transport in the status transitions, like tcp:gt06.
+func SavePosition(position *Position, source string) (*dto.LocationOutApp, error) {
+	if !position.Valid {
+		return nil, &ValidationError{Err: fmt.Errorf("position from device %s has no valid fix", position.DeviceID)}
+	}
+
+	vehicleID, ok := VehicleForDevice(position.DeviceID)
+	if !ok {
+		return nil, &ValidationError{Err: fmt.Errorf("%w: %s", ErrUnknownDevice, position.DeviceID)}
+	}
+
+	return SaveLocation(position.NewLocationInApp(vehicleID), position.Timestamp, source)
+}
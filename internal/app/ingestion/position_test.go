@@ -0,0 +1,67 @@
+package ingestion
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVehicleForDevice(t *testing.T) {
+	t.Cleanup(func() {
+		devices, deviceFallback = map[string]string{}, false
+	})
+
+	tests := []struct {
+		name      string
+		fallback  bool
+		deviceID  string
+		wantID    string
+		wantKnown bool
+	}{
+		{name: "mapped device", deviceID: "359586015829802", wantID: "ABC1234", wantKnown: true},
+		{name: "mapped device with fallback", fallback: true, deviceID: "359586015829802", wantID: "ABC1234", wantKnown: true},
+		{name: "unknown device", deviceID: "359586015829803", wantKnown: false},
+		{name: "unknown device with fallback", fallback: true, deviceID: "359586015829803", wantID: "5829803", wantKnown: true},
+		{name: "short unknown device with fallback", fallback: true, deviceID: "ABC12", wantID: "ABC12", wantKnown: true},
+		{name: "empty device with fallback", fallback: true, deviceID: "", wantKnown: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			devices = map[string]string{}
+			if err := LoadDevices([]string{" 359586015829802 = ABC1234 ", ""}, tt.fallback); err != nil {
+				t.Fatalf("LoadDevices() error = %v", err)
+			}
+
+			vehicleID, known := VehicleForDevice(tt.deviceID)
+			if vehicleID != tt.wantID || known != tt.wantKnown {
+				t.Errorf("VehicleForDevice(%q) = %q, %v, want %q, %v", tt.deviceID, vehicleID, known, tt.wantID, tt.wantKnown)
+			}
+		})
+	}
+}
+
+func TestLoadDevicesInvalid(t *testing.T) {
+	t.Cleanup(func() {
+		devices, deviceFallback = map[string]string{}, false
+	})
+
+	for _, entry := range []string{"359586015829802", "=ABC1234", "359586015829802="} {
+		if err := LoadDevices([]string{entry}, false); err == nil {
+			t.Errorf("LoadDevices(%q) error = nil, want an error", entry)
+		}
+	}
+}
+
+func TestSavePositionUnknownDevice(t *testing.T) {
+	t.Cleanup(func() {
+		devices, deviceFallback = map[string]string{}, false
+	})
+	devices, deviceFallback = map[string]string{}, false
+
+	_, err := SavePosition(&Position{DeviceID: "359586015829803", Valid: true}, "tcp:gt06")
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || !errors.Is(err, ErrUnknownDevice) {
+		t.Errorf("SavePosition() error = %v, want a ValidationError of %v", err, ErrUnknownDevice)
+	}
+}
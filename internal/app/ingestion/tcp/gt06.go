@@ -0,0 +1,179 @@
+package tcp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/ingestion"
+)
+
+// GT06 message types.
+const (
+	gt06Login       byte = 0x01
+	gt06GPS         byte = 0x10
+	gt06GPSLBS      byte = 0x12
+	gt06Status      byte = 0x13
+	gt06Alarm       byte = 0x16
+	gt06GPSLBS2     byte = 0x22
+	gt06Heartbeat   byte = 0x23
+	gt06AlarmGT06N  byte = 0x26
+	gt06StatusShort byte = 0x1A
+)
+
+// GT06 decodes the binary protocol of the Concox GT06 family of trackers.
+//
+// Every packet is framed as 0x78 0x78 | length | type | content | serial (2) | CRC (2) | 0x0D 0x0A,
+// or 0x79 0x79 with a two bytes length for the extended packets. The CRC is a CRC-ITU
+// over the bytes from the length to the serial. The device logs in with its IMEI
+// and expects a reply to the login, status and alarm packets, echoing the type and serial.
+type GT06 struct{}
+
+// Decode reads the next GT06 packet. The bytes before the start bits are skipped, so the
+// decoder resynchronises on the next packet after a stray byte or a corrupted packet.
+func (g *GT06) Decode(reader *bufio.Reader, session *Session) (*Frame, error) {
+	start, err := gt06Start(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var length int
+	var lengthBytes []byte
+	if start == 0x78 {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		length, lengthBytes = int(b), []byte{b}
+	} else {
+		lengthBytes = make([]byte, 2)
+		if _, err := io.ReadFull(reader, lengthBytes); err != nil {
+			return nil, err
+		}
+		length = int(binary.BigEndian.Uint16(lengthBytes))
+	}
+
+	// type + content + serial + crc + stop bits
+	body := make([]byte, length+2)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, err
+	}
+	if length < 5 || body[length] != 0x0D || body[length+1] != 0x0A {
+		return nil, fmt.Errorf("%w: malformed gt06 packet", ErrInvalidFrame)
+	}
+
+	checked := append(lengthBytes, body[:length-2]...)
+	if crc := binary.BigEndian.Uint16(body[length-2 : length]); crc != crcITU(checked) {
+		return nil, fmt.Errorf("%w: gt06 crc mismatch", ErrInvalidFrame)
+	}
+
+	msgType := body[0]
+	content := body[1 : length-4]
+	serial := body[length-4 : length-2]
+	frame := new(Frame)
+
+	switch msgType {
+	case gt06Login:
+		if len(content) < 8 {
+			return nil, fmt.Errorf("%w: short gt06 login", ErrInvalidFrame)
+		}
+		session.DeviceID = strings.TrimLeft(hex.EncodeToString(content[:8]), "0")
+		frame.Reply = gt06Reply(msgType, serial)
+	case gt06Status, gt06Heartbeat, gt06StatusShort:
+		frame.Reply = gt06Reply(msgType, serial)
+	case gt06GPS, gt06GPSLBS, gt06GPSLBS2, gt06Alarm, gt06AlarmGT06N:
+		if session.DeviceID == "" {
+			return nil, fmt.Errorf("%w: gt06 position before login", ErrInvalidFrame)
+		}
+
+		position, err := decodeGT06Position(content)
+		if err != nil {
+			return nil, err
+		}
+		position.DeviceID = session.DeviceID
+		frame.Positions = append(frame.Positions, position)
+
+		if msgType == gt06Alarm || msgType == gt06AlarmGT06N {
+			frame.Reply = gt06Reply(msgType, serial)
+		}
+	}
+
+	return frame, nil
+}
+
+// decodeGT06Position decodes the GPS block shared by the location and alarm packets:
+// date time (6) | GPS info length and satellites (1) | latitude (4) | longitude (4) | speed (1) | course and status (2).
+func decodeGT06Position(content []byte) (*ingestion.Position, error) {
+	if len(content) < 18 {
+		return nil, fmt.Errorf("%w: short gt06 gps block", ErrInvalidFrame)
+	}
+
+	timestamp := time.Date(
+		2000+int(content[0]), time.Month(content[1]), int(content[2]),
+		int(content[3]), int(content[4]), int(content[5]), 0, time.UTC,
+	)
+
+	// The coordinates are sent in 1/30000 of minute.
+	latitude := float64(binary.BigEndian.Uint32(content[7:11])) / 1800000
+	longitude := float64(binary.BigEndian.Uint32(content[11:15])) / 1800000
+	flags := binary.BigEndian.Uint16(content[16:18])
+
+	if flags&0x0400 == 0 {
+		latitude = -latitude
+	}
+	if flags&0x0800 != 0 {
+		longitude = -longitude
+	}
+
+	return &ingestion.Position{
+		Timestamp: timestamp,
+		Valid:     flags&0x1000 != 0,
+		Latitude:  latitude,
+		Longitude: longitude,
+		Speed:     float64(content[15]),
+		Course:    float64(flags & 0x03FF),
+	}, nil
+}
+
+// gt06Reply builds the acknowledgement of a packet, echoing its type and serial.
+func gt06Reply(msgType byte, serial []byte) []byte {
+	reply := []byte{0x78, 0x78, 0x05, msgType, serial[0], serial[1]}
+	reply = binary.BigEndian.AppendUint16(reply, crcITU(reply[2:]))
+	return append(reply, 0x0D, 0x0A)
+}
+
+// gt06Start reads up to the start bits of the next packet, 0x78 0x78 or 0x79 0x79,
+// one byte at a time, and returns the start byte.
+func gt06Start(reader *bufio.Reader) (byte, error) {
+	var previous byte
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b == previous && (b == 0x78 || b == 0x79) {
+			return b, nil
+		}
+		previous = b
+	}
+}
+
+// crcITU computes the CRC-16/X-25 used by the GT06 protocol.
+func crcITU(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = (crc >> 1) ^ 0x8408
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return ^crc
+}
@@ -0,0 +1,187 @@
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+// decodeHex decodes the hex samples of the tests, ignoring the spaces.
+func decodeHex(t *testing.T, sample string) []byte {
+	t.Helper()
+	data, err := hex.DecodeString(string(bytes.ReplaceAll([]byte(sample), []byte(" "), nil)))
+	if err != nil {
+		t.Fatalf("invalid hex sample %q: %v", sample, err)
+	}
+	return data
+}
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestCRCITU(t *testing.T) {
+	tests := []struct {
+		data string
+		want uint16
+	}{
+		// The check value of CRC-16/X-25.
+		{data: hex.EncodeToString([]byte("123456789")), want: 0x906E},
+		// The login and its reply of the GT06 protocol documentation.
+		{data: "0D 01 01 23 45 67 89 01 23 45 00 01", want: 0x8CDD},
+		{data: "05 01 00 01", want: 0xD9DC},
+	}
+
+	for _, tt := range tests {
+		if got := crcITU(decodeHex(t, tt.data)); got != tt.want {
+			t.Errorf("crcITU(%s) = %04X, want %04X", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestGT06Login(t *testing.T) {
+	reader := bufio.NewReader(bytes.NewReader(decodeHex(t, "78 78 0D 01 01 23 45 67 89 01 23 45 00 01 8C DD 0D 0A")))
+	session := new(Session)
+
+	frame, err := new(GT06).Decode(reader, session)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if session.DeviceID != "123456789012345" {
+		t.Errorf("DeviceID = %q, want 123456789012345", session.DeviceID)
+	}
+	if want := decodeHex(t, "78 78 05 01 00 01 D9 DC 0D 0A"); !bytes.Equal(frame.Reply, want) {
+		t.Errorf("Reply = % X, want % X", frame.Reply, want)
+	}
+	if len(frame.Positions) != 0 {
+		t.Errorf("Positions = %d, want 0", len(frame.Positions))
+	}
+}
+
+func TestGT06Resynchronise(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+	}{
+		{name: "stray leading byte", stream: "00 78 78 0D 01 01 23 45 67 89 01 23 45 00 01 8C DD 0D 0A"},
+		{name: "single start byte", stream: "78 0D 78 78 0D 01 01 23 45 67 89 01 23 45 00 01 8C DD 0D 0A"},
+		{name: "extended start bits", stream: "79 78 78 0D 01 01 23 45 67 89 01 23 45 00 01 8C DD 0D 0A"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := new(Session)
+			if _, err := new(GT06).Decode(bufio.NewReader(bytes.NewReader(decodeHex(t, tt.stream))), session); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if session.DeviceID != "123456789012345" {
+				t.Errorf("DeviceID = %q, want 123456789012345", session.DeviceID)
+			}
+		})
+	}
+}
+
+func TestGT06ResynchroniseAfterInvalidPacket(t *testing.T) {
+	// A packet with a bad CRC followed by a valid one: the next call reads the valid packet.
+	stream := "78 78 0D 01 01 23 45 67 89 01 23 45 00 01 8C DE 0D 0A 78 78 0D 01 01 23 45 67 89 01 23 45 00 01 8C DD 0D 0A"
+	reader := bufio.NewReader(bytes.NewReader(decodeHex(t, stream)))
+	session := new(Session)
+
+	if _, err := new(GT06).Decode(reader, session); !errors.Is(err, ErrInvalidFrame) {
+		t.Fatalf("Decode() error = %v, want %v", err, ErrInvalidFrame)
+	}
+	if _, err := new(GT06).Decode(reader, session); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if session.DeviceID != "123456789012345" {
+		t.Errorf("DeviceID = %q, want 123456789012345", session.DeviceID)
+	}
+}
+
+func TestGT06Position(t *testing.T) {
+	// The GPS and LBS packet of the GT06 protocol documentation.
+	packet := "78 78 1F 12 0B 08 1D 11 2E 10 CF 02 7A C7 EB 0C 46 58 49 00 14 8F 01 CC 00 28 7D 00 1F B8 00 03 80 81 0D 0A"
+	session := &Session{DeviceID: "123456789012345"}
+
+	frame, err := new(GT06).Decode(bufio.NewReader(bytes.NewReader(decodeHex(t, packet))), session)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if len(frame.Positions) != 1 {
+		t.Fatalf("Positions = %d, want 1", len(frame.Positions))
+	}
+	if frame.Reply != nil {
+		t.Errorf("Reply = % X, want none", frame.Reply)
+	}
+
+	position := frame.Positions[0]
+	if want := time.Date(2011, 8, 29, 17, 46, 16, 0, time.UTC); !position.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", position.Timestamp, want)
+	}
+	if !position.Valid {
+		t.Error("Valid = false, want true")
+	}
+	if !closeTo(position.Latitude, 23.111668) || !closeTo(position.Longitude, 114.409285) {
+		t.Errorf("coordinates = %f, %f, want 23.111668, 114.409285", position.Latitude, position.Longitude)
+	}
+	if position.Speed != 0 || position.Course != 143 {
+		t.Errorf("speed and course = %v, %v, want 0, 143", position.Speed, position.Course)
+	}
+	if position.DeviceID != session.DeviceID {
+		t.Errorf("DeviceID = %q, want %q", position.DeviceID, session.DeviceID)
+	}
+}
+
+func TestGT06InvalidFrames(t *testing.T) {
+	tests := []struct {
+		name    string
+		packet  string
+		session *Session
+		wantErr error
+	}{
+		{
+			name:    "crc mismatch",
+			packet:  "78 78 0D 01 01 23 45 67 89 01 23 45 00 01 8C DE 0D 0A",
+			session: new(Session),
+			wantErr: ErrInvalidFrame,
+		},
+		{
+			name:    "no start bits",
+			packet:  "78 77 0D 01",
+			session: new(Session),
+			wantErr: io.EOF,
+		},
+		{
+			name:    "missing stop bits",
+			packet:  "78 78 0D 01 01 23 45 67 89 01 23 45 00 01 8C DD 0D 0B",
+			session: new(Session),
+			wantErr: ErrInvalidFrame,
+		},
+		{
+			name:    "position before the login",
+			packet:  "78 78 1F 12 0B 08 1D 11 2E 10 CF 02 7A C7 EB 0C 46 58 49 00 14 8F 01 CC 00 28 7D 00 1F B8 00 03 80 81 0D 0A",
+			session: new(Session),
+			wantErr: ErrInvalidFrame,
+		},
+		{
+			name:    "truncated packet",
+			packet:  "78 78 0D 01 01 23 45",
+			session: new(Session),
+			wantErr: io.ErrUnexpectedEOF,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := new(GT06).Decode(bufio.NewReader(bytes.NewReader(decodeHex(t, tt.packet))), tt.session)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Decode() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package tcp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/allansbo/goapi/internal/app/ingestion"
+)
+
+// ErrInvalidFrame is returned by the protocols when a frame is malformed or fails its checksum.
+// The frame is discarded and the connection keeps being read.
+var ErrInvalidFrame = errors.New("invalid frame")
+
+// Session holds the state of a device connection, shared by the frames read from it.
+type Session struct {
+	// DeviceID is the identifier sent by the device on the login, usually the IMEI.
+	DeviceID string
+}
+
+// Frame is the result of decoding a message sent by a device.
+type Frame struct {
+	// Positions are the fixes carried by the frame.
+	Positions []*ingestion.Position
+	// Reply is sent back to the device after the positions are saved. It can be empty.
+	Reply []byte
+	// Close ends the connection after the reply, when the device is rejected.
+	Close bool
+}
+
+// Protocol decodes the frames of a tracker protocol from a connection.
+// A new instance is created for every connection.
+type Protocol interface {
+	// Decode reads the next frame from the reader, updating the session when the device logs in.
+	// A frame can be returned along with ErrInvalidFrame, to answer the device that is rejected.
+	Decode(reader *bufio.Reader, session *Session) (*Frame, error)
+}
+
+// protocols are the available decoders, by the name used in the TCP_LISTENERS configuration.
+var protocols = map[string]func() Protocol{
+	"gt06":      func() Protocol { return &GT06{} },
+	"tk103":     func() Protocol { return &TK103{} },
+	"teltonika": func() Protocol { return &Teltonika{} },
+}
+
+// newProtocol returns the constructor of a protocol by its name.
+func newProtocol(name string) (func() Protocol, error) {
+	constructor, ok := protocols[name]
+	if !ok {
+		names := make([]string, 0, len(protocols))
+		for n := range protocols {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown tcp protocol %q, available: %v", name, names)
+	}
+	return constructor, nil
+}
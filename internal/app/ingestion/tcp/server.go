@@ -0,0 +1,189 @@
+package tcp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/ingestion"
+	"github.com/allansbo/goapi/internal/config"
+	"github.com/allansbo/goapi/internal/domain/entity"
+)
+
+// idleTimeout is the time a connection can stay without sending any frame.
+// The trackers send heartbeats every few minutes, so a silent connection is a dead one.
+const idleTimeout = 10 * time.Minute
+
+type listener struct {
+	protocol    string
+	address     string
+	newProtocol func() Protocol
+	listener    net.Listener
+}
+
+// Server accepts the connections of trackers that speak binary or ASCII protocols over TCP,
+// with one listener per protocol, and saves the positions they send.
+type Server struct {
+	listeners []*listener
+	conns     sync.Map
+	wg        sync.WaitGroup
+}
+
+// NewServer creates a new instance of Server from the TCP_LISTENERS configuration,
+// where every entry is in the format <protocol>:<port>, like gt06:5023.
+func NewServer(cfg *config.EnvConfig) (*Server, error) {
+	s := new(Server)
+
+	for _, entry := range cfg.TCPListeners {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, port, found := strings.Cut(entry, ":")
+		if !found || port == "" {
+			return nil, fmt.Errorf("invalid tcp listener %q, expected <protocol>:<port>", entry)
+		}
+
+		constructor, err := newProtocol(strings.ToLower(name))
+		if err != nil {
+			return nil, err
+		}
+
+		s.listeners = append(s.listeners, &listener{
+			protocol:    strings.ToLower(name),
+			address:     fmt.Sprintf(":%s", port),
+			newProtocol: constructor,
+		})
+	}
+
+	return s, nil
+}
+
+// Enabled reports if there is any listener configured.
+func (s *Server) Enabled() bool {
+	return len(s.listeners) > 0
+}
+
+// Start opens the listeners and accepts the connections in background.
+func (s *Server) Start() error {
+	for _, l := range s.listeners {
+		var err error
+		l.listener, err = net.Listen("tcp", l.address)
+		if err != nil {
+			s.Stop()
+			return fmt.Errorf("error listening %s for %s: %w", l.address, l.protocol, err)
+		}
+
+		slog.Info("TCP listener running", "protocol", l.protocol, "address", l.address)
+		go s.accept(l)
+	}
+	return nil
+}
+
+// Stop closes the listeners and the open connections, waiting for the frames being handled.
+func (s *Server) Stop() {
+	for _, l := range s.listeners {
+		if l.listener != nil {
+			_ = l.listener.Close()
+		}
+	}
+
+	s.conns.Range(func(key, _ any) bool {
+		_ = key.(net.Conn).Close()
+		return true
+	})
+
+	s.wg.Wait()
+}
+
+func (s *Server) accept(l *listener) {
+	for {
+		conn, err := l.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			slog.Error("error accepting tcp connection", "error", err.Error(), "protocol", l.protocol)
+			continue
+		}
+
+		s.wg.Add(1)
+		s.conns.Store(conn, struct{}{})
+		go s.handle(conn, l)
+	}
+}
+
+func (s *Server) handle(conn net.Conn, l *listener) {
+	defer s.wg.Done()
+	defer s.conns.Delete(conn)
+	defer conn.Close()
+
+	protocol := l.newProtocol()
+	session := new(Session)
+	reader := bufio.NewReader(conn)
+	remote := conn.RemoteAddr().String()
+
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(idleTimeout))
+
+		frame, err := protocol.Decode(reader, session)
+		if errors.Is(err, ErrInvalidFrame) {
+			slog.Error("discarding tcp frame", "error", err.Error(), "protocol", l.protocol, "remote", remote)
+			if frame != nil && !reply(conn, frame, l.protocol, remote) {
+				return
+			}
+			continue
+		} else if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			slog.Error("error reading tcp connection", "error", err.Error(), "protocol", l.protocol, "remote", remote)
+			return
+		}
+
+		if !s.savePositions(frame, l.protocol, session) {
+			// Without the reply the device keeps the positions and sends them again.
+			continue
+		}
+
+		if !reply(conn, frame, l.protocol, remote) {
+			return
+		}
+	}
+}
+
+// reply sends the reply of a frame, if any, and reports if the connection can keep being read.
+func reply(conn net.Conn, frame *Frame, protocol, remote string) bool {
+	if len(frame.Reply) > 0 {
+		if _, err := conn.Write(frame.Reply); err != nil {
+			slog.Error("error replying tcp frame", "error", err.Error(), "protocol", protocol, "remote", remote)
+			return false
+		}
+	}
+	return !frame.Close
+}
+
+// savePositions saves the positions of a frame and reports if the frame can be acknowledged.
+// Invalid positions are discarded, only storage failures prevent the acknowledgement.
+func (s *Server) savePositions(frame *Frame, protocol string, session *Session) bool {
+	acknowledge := true
+
+	for _, position := range frame.Positions {
+		_, err := ingestion.SavePosition(position, entity.SourceTCP+":"+protocol)
+
+		var validationErr *ingestion.ValidationError
+		if errors.As(err, &validationErr) {
+			slog.Warn("discarding tcp position", "error", err.Error(), "protocol", protocol, "device", session.DeviceID)
+		} else if err != nil {
+			slog.Error("error saving tcp position", "error", err.Error(), "protocol", protocol, "device", session.DeviceID)
+			acknowledge = false
+		}
+	}
+
+	return acknowledge
+}
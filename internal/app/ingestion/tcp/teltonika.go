@@ -0,0 +1,272 @@
+package tcp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/ingestion"
+)
+
+// Teltonika codec IDs.
+const (
+	teltonikaCodec8  byte = 0x08
+	teltonikaCodec8E byte = 0x8E
+)
+
+// maxTeltonikaPacket limits the data field length read from a connection.
+const maxTeltonikaPacket = 64 * 1024
+
+// teltonikaIO names the IO elements of the FMB devices that are mapped into telemetry attributes.
+// The elements not listed are kept as io<id>.
+var teltonikaIO = map[uint16]string{
+	1:   "din1",
+	2:   "din2",
+	3:   "din3",
+	9:   "ain1",
+	10:  "ain2",
+	16:  "total_odometer",
+	21:  "gsm_signal",
+	24:  "gnss_speed",
+	66:  "external_voltage",
+	67:  "battery_voltage",
+	68:  "battery_current",
+	69:  "gnss_status",
+	72:  "temperature1",
+	179: "dout1",
+	180: "dout2",
+	181: "gnss_pdop",
+	182: "gnss_hdop",
+	199: "trip_odometer",
+	200: "sleep_mode",
+	239: "ignition",
+	240: "movement",
+	241: "active_gsm_operator",
+}
+
+// Teltonika decodes the codec 8 and codec 8 extended protocols of the Teltonika FMB devices.
+//
+// The device starts with the IMEI handshake, two bytes length followed by the IMEI, accepted with 0x01
+// or rejected with 0x00. Then it sends AVL packets framed as preamble (4, zeros) | data length (4) |
+// codec (1) | records count (1) | records | records count (1) | CRC-16/IBM (4), computed from the codec
+// to the second records count. The server acknowledges with the number of records accepted (4).
+type Teltonika struct{}
+
+// Decode reads the IMEI handshake or the next AVL packet.
+func (t *Teltonika) Decode(reader *bufio.Reader, session *Session) (*Frame, error) {
+	if session.DeviceID == "" {
+		return t.decodeIMEI(reader, session)
+	}
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	if binary.BigEndian.Uint32(header[:4]) != 0 {
+		return nil, fmt.Errorf("%w: unexpected teltonika preamble %x", ErrInvalidFrame, header[:4])
+	}
+
+	length := binary.BigEndian.Uint32(header[4:])
+	if length < 3 || length > maxTeltonikaPacket {
+		return nil, fmt.Errorf("%w: invalid teltonika data length %d", ErrInvalidFrame, length)
+	}
+
+	data := make([]byte, length+4)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, err
+	}
+
+	if crc := binary.BigEndian.Uint32(data[length:]); crc != uint32(crc16IBM(data[:length])) {
+		return nil, fmt.Errorf("%w: teltonika crc mismatch", ErrInvalidFrame)
+	}
+
+	positions, err := decodeAVLData(data[:length])
+	if err != nil {
+		return nil, err
+	}
+	for _, position := range positions {
+		position.DeviceID = session.DeviceID
+	}
+
+	return &Frame{
+		Positions: positions,
+		Reply:     binary.BigEndian.AppendUint32(nil, uint32(len(positions))),
+	}, nil
+}
+
+// decodeIMEI reads the IMEI handshake. The malformed IMEIs and the devices not mapped to a vehicle
+// are rejected, answering 0x00 and closing the connection.
+func (t *Teltonika) decodeIMEI(reader *bufio.Reader, session *Session) (*Frame, error) {
+	rejected := &Frame{Reply: []byte{0x00}, Close: true}
+
+	lengthBytes := make([]byte, 2)
+	if _, err := io.ReadFull(reader, lengthBytes); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint16(lengthBytes)
+	if length == 0 || length > 32 {
+		return rejected, fmt.Errorf("%w: invalid teltonika imei length %d", ErrInvalidFrame, length)
+	}
+
+	imei := make([]byte, length)
+	if _, err := io.ReadFull(reader, imei); err != nil {
+		return nil, err
+	}
+	if _, err := strconv.ParseUint(string(imei), 10, 64); err != nil {
+		return rejected, fmt.Errorf("%w: invalid teltonika imei %q", ErrInvalidFrame, imei)
+	}
+	if _, ok := ingestion.VehicleForDevice(string(imei)); !ok {
+		return rejected, fmt.Errorf("%w: %w: %s", ErrInvalidFrame, ingestion.ErrUnknownDevice, imei)
+	}
+
+	session.DeviceID = string(imei)
+	return &Frame{Reply: []byte{0x01}}, nil
+}
+
+// decodeAVLData decodes the records of the data field, from the codec ID to the second records count.
+func decodeAVLData(data []byte) ([]*ingestion.Position, error) {
+	r := &avlReader{data: data}
+
+	codec := r.uint8()
+	if codec != teltonikaCodec8 && codec != teltonikaCodec8E {
+		return nil, fmt.Errorf("%w: unsupported teltonika codec 0x%02X", ErrInvalidFrame, codec)
+	}
+	extended := codec == teltonikaCodec8E
+
+	count := int(r.uint8())
+	positions := make([]*ingestion.Position, 0, count)
+
+	for i := 0; i < count && r.err == nil; i++ {
+		positions = append(positions, decodeAVLRecord(r, extended))
+	}
+
+	trailingCount := r.uint8()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if trailingCount != byte(count) {
+		return nil, fmt.Errorf("%w: teltonika records count mismatch, %d and %d", ErrInvalidFrame, count, trailingCount)
+	}
+	if r.offset != len(data) {
+		return nil, fmt.Errorf("%w: %d bytes after the teltonika avl data", ErrInvalidFrame, len(data)-r.offset)
+	}
+
+	return positions, nil
+}
+
+// decodeAVLRecord decodes a record:
+// timestamp (8, ms) | priority (1) | longitude (4) | latitude (4) | altitude (2) | angle (2) |
+// satellites (1) | speed (2) | IO elements, whose IDs and counts take 2 bytes on the codec 8E instead of 1.
+func decodeAVLRecord(r *avlReader, extended bool) *ingestion.Position {
+	position := &ingestion.Position{
+		Timestamp:  time.UnixMilli(int64(r.uint64())).UTC(),
+		Attributes: map[string]any{},
+	}
+	position.Attributes["priority"] = r.uint8()
+
+	position.Longitude = float64(int32(r.uint32())) / 1e7
+	position.Latitude = float64(int32(r.uint32())) / 1e7
+	position.Attributes["altitude"] = int16(r.uint16())
+	position.Course = float64(r.uint16())
+	satellites := r.uint8()
+	position.Attributes["satellites"] = satellites
+	position.Speed = float64(r.uint16())
+	position.Valid = satellites > 0
+
+	// The count sized fields grow to 2 bytes on the codec 8E.
+	field := r.uint8AsInt
+	if extended {
+		field = r.uint16AsInt
+	}
+
+	if eventID := field(); eventID != 0 {
+		position.Attributes["event_io"] = eventID
+	}
+	field() // total IO count
+
+	for _, size := range []int{1, 2, 4, 8} {
+		for n := field(); n > 0 && r.err == nil; n-- {
+			id := uint16(field())
+			position.Attributes[teltonikaIOName(id)] = ioValue(r.unsigned(size))
+		}
+	}
+
+	if extended {
+		for n := field(); n > 0 && r.err == nil; n-- {
+			id := uint16(field())
+			length := field()
+			position.Attributes[teltonikaIOName(id)] = fmt.Sprintf("%x", r.bytes(length))
+		}
+	}
+
+	return position
+}
+
+// ioValue keeps the IO values as signed integers, which every storage supports.
+// The 8 bytes values that don't fit are kept as text.
+func ioValue(value uint64) any {
+	if value > math.MaxInt64 {
+		return strconv.FormatUint(value, 10)
+	}
+	return int64(value)
+}
+
+func teltonikaIOName(id uint16) string {
+	if name, ok := teltonikaIO[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("io%d", id)
+}
+
+// avlReader reads the big endian fields of the AVL data, keeping the first out of bounds error.
+type avlReader struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+func (r *avlReader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || r.offset+n > len(r.data) {
+		r.err = fmt.Errorf("%w: truncated teltonika avl data", ErrInvalidFrame)
+		return make([]byte, max(n, 0))
+	}
+	b := r.data[r.offset : r.offset+n]
+	r.offset += n
+	return b
+}
+
+func (r *avlReader) unsigned(size int) uint64 {
+	var value uint64
+	for _, b := range r.bytes(size) {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+func (r *avlReader) uint8() byte      { return byte(r.unsigned(1)) }
+func (r *avlReader) uint16() uint16   { return uint16(r.unsigned(2)) }
+func (r *avlReader) uint32() uint32   { return uint32(r.unsigned(4)) }
+func (r *avlReader) uint64() uint64   { return r.unsigned(8) }
+func (r *avlReader) uint8AsInt() int  { return int(r.unsigned(1)) }
+func (r *avlReader) uint16AsInt() int { return int(r.unsigned(2)) }
+
+// crc16IBM computes the CRC-16/ARC used by the Teltonika protocols.
+func crc16IBM(data []byte) uint16 {
+	crc := uint16(0)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = (crc >> 1) ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,321 @@
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/ingestion"
+)
+
+// Packets of the Teltonika codec 8 and codec 8E documentation, captured from FMB devices.
+const (
+	teltonikaCodec8Sample  = "00 00 00 00 00 00 00 36 08 01 00 00 01 6B 40 D8 EA 30 01 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 01 05 02 15 03 01 01 01 42 5E 0F 01 F1 00 00 60 1A 01 4E 00 00 00 00 00 00 00 00 01 00 00 C7 CF"
+	teltonikaCodec8Sample2 = "00 00 00 00 00 00 00 28 08 01 00 00 01 6B 40 D9 AD 80 01 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 01 03 02 15 03 01 01 01 42 5E 10 00 00 01 00 00 F2 2A"
+	teltonikaCodec8ESample = "00 00 00 00 00 00 00 4A 8E 01 00 00 01 6B 41 2C EE 00 01 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 01 00 05 00 01 00 01 01 00 01 00 11 00 1D 00 01 00 10 01 5E 2C 88 00 02 00 0B 00 00 00 00 35 44 C8 7A 00 0E 00 00 00 00 1D D7 E0 6A 00 00 01 00 00 29 94"
+	teltonikaIMEISample    = "00 0F 33 35 36 33 30 37 30 34 32 34 34 31 30 31 33"
+)
+
+// teltonikaPacket frames the data field, from the codec to the second records count, with its CRC.
+func teltonikaPacket(data []byte) []byte {
+	packet := make([]byte, 4, 12+len(data))
+	packet = binary.BigEndian.AppendUint32(packet, uint32(len(data)))
+	packet = append(packet, data...)
+	return binary.BigEndian.AppendUint32(packet, uint32(crc16IBM(data)))
+}
+
+// teltonikaData returns the data field of a packet.
+func teltonikaData(packet []byte) []byte {
+	return bytes.Clone(packet[8 : len(packet)-4])
+}
+
+func decodeTeltonika(t *testing.T, packet []byte) (*Frame, error) {
+	t.Helper()
+	return new(Teltonika).Decode(bufio.NewReader(bytes.NewReader(packet)), &Session{DeviceID: "356307042441013"})
+}
+
+func TestCRC16IBM(t *testing.T) {
+	// The check value of CRC-16/ARC.
+	if got := crc16IBM([]byte("123456789")); got != 0xBB3D {
+		t.Errorf("crc16IBM(123456789) = %04X, want BB3D", got)
+	}
+	if got := crc16IBM(teltonikaData(decodeHex(t, teltonikaCodec8Sample))); got != 0xC7CF {
+		t.Errorf("crc16IBM(codec 8 sample) = %04X, want C7CF", got)
+	}
+}
+
+func TestTeltonikaIMEI(t *testing.T) {
+	t.Cleanup(func() { _ = ingestion.LoadDevices(nil, false) })
+	if err := ingestion.LoadDevices([]string{"356307042441013=ABC1234"}, false); err != nil {
+		t.Fatalf("LoadDevices() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		handshake  []byte
+		wantDevice string
+		wantReply  []byte
+		wantClose  bool
+		wantErr    error
+	}{
+		{
+			name:       "known device",
+			handshake:  decodeHex(t, teltonikaIMEISample),
+			wantDevice: "356307042441013",
+			wantReply:  []byte{0x01},
+		},
+		{
+			name:      "device not mapped",
+			handshake: append([]byte{0x00, 0x0F}, "356307042441014"...),
+			wantReply: []byte{0x00},
+			wantClose: true,
+			wantErr:   ingestion.ErrUnknownDevice,
+		},
+		{
+			name:      "imei not numeric",
+			handshake: append([]byte{0x00, 0x0F}, "35630704244101A"...),
+			wantReply: []byte{0x00},
+			wantClose: true,
+			wantErr:   ErrInvalidFrame,
+		},
+		{
+			name:      "invalid imei length",
+			handshake: []byte{0x00, 0x00},
+			wantReply: []byte{0x00},
+			wantClose: true,
+			wantErr:   ErrInvalidFrame,
+		},
+		{
+			name:      "truncated imei",
+			handshake: append([]byte{0x00, 0x0F}, "3563070"...),
+			wantErr:   io.ErrUnexpectedEOF,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := new(Session)
+			frame, err := new(Teltonika).Decode(bufio.NewReader(bytes.NewReader(tt.handshake)), session)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decode() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && !errors.Is(err, ErrInvalidFrame) && !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("Decode() error = %v, want it wrapped by %v", err, ErrInvalidFrame)
+			}
+			if session.DeviceID != tt.wantDevice {
+				t.Errorf("DeviceID = %q, want %q", session.DeviceID, tt.wantDevice)
+			}
+			if tt.wantReply == nil {
+				if frame != nil {
+					t.Errorf("frame = %+v, want nil", frame)
+				}
+				return
+			}
+			if frame == nil {
+				t.Fatal("frame = nil, want a reply")
+			}
+			if !bytes.Equal(frame.Reply, tt.wantReply) || frame.Close != tt.wantClose {
+				t.Errorf("Reply = % X and Close = %v, want % X and %v", frame.Reply, frame.Close, tt.wantReply, tt.wantClose)
+			}
+		})
+	}
+}
+
+func TestTeltonikaCodec8(t *testing.T) {
+	frame, err := decodeTeltonika(t, decodeHex(t, teltonikaCodec8Sample))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !bytes.Equal(frame.Reply, []byte{0x00, 0x00, 0x00, 0x01}) {
+		t.Errorf("Reply = % X, want 00 00 00 01", frame.Reply)
+	}
+	if len(frame.Positions) != 1 {
+		t.Fatalf("Positions = %d, want 1", len(frame.Positions))
+	}
+
+	position := frame.Positions[0]
+	if want := time.Date(2019, 6, 10, 10, 4, 46, 0, time.UTC); !position.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", position.Timestamp, want)
+	}
+	if position.Valid {
+		t.Error("Valid = true, want false without satellites")
+	}
+	if position.DeviceID != "356307042441013" {
+		t.Errorf("DeviceID = %q, want 356307042441013", position.DeviceID)
+	}
+
+	want := map[string]any{
+		"priority":            byte(1),
+		"altitude":            int16(0),
+		"satellites":          byte(0),
+		"event_io":            1,
+		"gsm_signal":          int64(3),
+		"din1":                int64(1),
+		"external_voltage":    int64(24079),
+		"active_gsm_operator": int64(24602),
+		"io78":                int64(0),
+	}
+	assertAttributes(t, position.Attributes, want)
+
+	frame, err = decodeTeltonika(t, decodeHex(t, teltonikaCodec8Sample2))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if len(frame.Positions) != 1 || frame.Positions[0].Attributes["external_voltage"] != int64(24080) {
+		t.Errorf("Positions = %+v, want one with external_voltage 24080", frame.Positions)
+	}
+}
+
+func TestTeltonikaCodec8E(t *testing.T) {
+	frame, err := decodeTeltonika(t, decodeHex(t, teltonikaCodec8ESample))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if len(frame.Positions) != 1 {
+		t.Fatalf("Positions = %d, want 1", len(frame.Positions))
+	}
+
+	position := frame.Positions[0]
+	if want := time.Date(2019, 6, 10, 11, 36, 32, 0, time.UTC); !position.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", position.Timestamp, want)
+	}
+
+	want := map[string]any{
+		"priority":       byte(1),
+		"event_io":       1,
+		"din1":           int64(1),
+		"io17":           int64(29),
+		"total_odometer": int64(22949000),
+		"io11":           int64(893700218),
+		"io14":           int64(500686954),
+	}
+	assertAttributes(t, position.Attributes, want)
+}
+
+func TestTeltonikaGPSRecord(t *testing.T) {
+	record := binary.BigEndian.AppendUint64(nil, uint64(time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC).UnixMilli()))
+	longitude, latitude := int32(-466333080), int32(-235505200)
+	record = append(record, 0x00)
+	record = binary.BigEndian.AppendUint32(record, uint32(longitude))
+	record = binary.BigEndian.AppendUint32(record, uint32(latitude))
+	record = binary.BigEndian.AppendUint16(record, 760)
+	record = binary.BigEndian.AppendUint16(record, 90)
+	record = append(record, 9)
+	record = binary.BigEndian.AppendUint16(record, 80)
+	// The ignition in the 1 byte IO elements.
+	record = append(record, 0xEF, 0x01, 0x01, 0xEF, 0x01, 0x00, 0x00, 0x00)
+
+	data := append([]byte{teltonikaCodec8, 0x01}, record...)
+	data = append(data, 0x01)
+
+	frame, err := decodeTeltonika(t, teltonikaPacket(data))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	position := frame.Positions[0]
+	if !position.Valid {
+		t.Error("Valid = false, want true with satellites")
+	}
+	if !closeTo(position.Latitude, -23.55052) || !closeTo(position.Longitude, -46.633308) {
+		t.Errorf("coordinates = %f, %f, want -23.55052, -46.633308", position.Latitude, position.Longitude)
+	}
+	if position.Speed != 80 || position.Course != 90 {
+		t.Errorf("speed and course = %v, %v, want 80, 90", position.Speed, position.Course)
+	}
+	assertAttributes(t, position.Attributes, map[string]any{"altitude": int16(760), "satellites": byte(9), "ignition": int64(1), "event_io": 239})
+}
+
+func TestTeltonikaInvalidPackets(t *testing.T) {
+	sample := decodeHex(t, teltonikaCodec8Sample)
+
+	crcMismatch := bytes.Clone(sample)
+	crcMismatch[len(crcMismatch)-1] ^= 0xFF
+
+	// The records end before their IO elements, with a valid CRC.
+	data := teltonikaData(sample)
+	truncated := teltonikaPacket(append(data[:30:30], data[len(data)-1]))
+
+	// The second records count doesn't match the first one.
+	data = teltonikaData(sample)
+	data[len(data)-1] = 0x02
+	countMismatch := teltonikaPacket(data)
+
+	data = teltonikaData(sample)
+	data[0] = 0x10
+	unsupportedCodec := teltonikaPacket(data)
+
+	preamble := bytes.Clone(sample)
+	preamble[0] = 0x01
+
+	tests := []struct {
+		name    string
+		packet  []byte
+		wantErr error
+	}{
+		{name: "crc mismatch", packet: crcMismatch, wantErr: ErrInvalidFrame},
+		{name: "truncated avl data", packet: truncated, wantErr: ErrInvalidFrame},
+		{name: "records count mismatch", packet: countMismatch, wantErr: ErrInvalidFrame},
+		{name: "unsupported codec", packet: unsupportedCodec, wantErr: ErrInvalidFrame},
+		{name: "unexpected preamble", packet: preamble, wantErr: ErrInvalidFrame},
+		{name: "data length too long", packet: []byte{0, 0, 0, 0, 0x7F, 0xFF, 0xFF, 0xFF}, wantErr: ErrInvalidFrame},
+		{name: "truncated packet", packet: sample[:40], wantErr: io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := decodeTeltonika(t, tt.packet)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Decode() error = %v, want %v", err, tt.wantErr)
+			}
+			if frame != nil {
+				t.Errorf("frame = %+v, want nil", frame)
+			}
+		})
+	}
+}
+
+func TestTeltonikaServerRejectsIMEI(t *testing.T) {
+	t.Cleanup(func() { _ = ingestion.LoadDevices(nil, false) })
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	s := new(Server)
+	s.wg.Add(1)
+	go s.handle(server, &listener{protocol: "teltonika", newProtocol: func() Protocol { return &Teltonika{} }})
+
+	_ = client.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := client.Write(append([]byte{0x00, 0x0F}, "356307042441099"...)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(client, reply); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if reply[0] != 0x00 {
+		t.Errorf("reply = %02X, want 00", reply[0])
+	}
+
+	// The connection is closed after the rejection.
+	if _, err := client.Read(reply); !errors.Is(err, io.EOF) {
+		t.Errorf("Read() error = %v, want %v", err, io.EOF)
+	}
+	s.wg.Wait()
+}
+
+func assertAttributes(t *testing.T, got, want map[string]any) {
+	t.Helper()
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("attribute %s = %v (%T), want %v (%T)", name, got[name], got[name], value, value)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package tcp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/ingestion"
+	"github.com/allansbo/goapi/internal/pkg/nmea"
+)
+
+// maxTK103Frame limits the size of a TK103 frame, which has less than 100 characters.
+const maxTK103Frame = 512
+
+// TK103 decodes the ASCII protocol of the TK103 family of trackers.
+//
+// Every message is framed as (<12 digits device id><command><data>). The device logs in with BP05,
+// sends heartbeats with BP00, positions with BR00 and alarms with BO01, where the GPS block is
+// YYMMDD | A/V | ddmm.mmmm N/S | dddmm.mmmm E/W | speed (5, km/h) | hhmmss | course (6) | ...
+type TK103 struct{}
+
+// Decode reads the next TK103 message. The bytes before the start of a message are discarded,
+// and a message longer than maxTK103Frame is discarded without being kept whole in memory.
+func (t *TK103) Decode(reader *bufio.Reader, session *Session) (*Frame, error) {
+	if err := skipTK103Garbage(reader); err != nil {
+		return nil, err
+	}
+
+	message, err := readTK103Message(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(message) < 16 {
+		return nil, fmt.Errorf("%w: malformed tk103 message", ErrInvalidFrame)
+	}
+
+	deviceID, command, data := message[:12], message[12:16], message[16:]
+	session.DeviceID = strings.TrimLeft(deviceID, "0")
+	frame := new(Frame)
+
+	switch command {
+	case "BP05":
+		// The login carries the IMEI (15) followed by the GPS block.
+		frame.Reply = tk103Reply(deviceID, "AP05", "")
+		if len(data) > 15 {
+			data = data[15:]
+		} else {
+			return frame, nil
+		}
+	case "BP00":
+		frame.Reply = tk103Reply(deviceID, "AP01", strings.TrimLeft(data, "0123456789"))
+		return frame, nil
+	case "BO01":
+		// The alarm carries its code (1) followed by the GPS block.
+		if len(data) < 1 {
+			return nil, fmt.Errorf("%w: short tk103 alarm", ErrInvalidFrame)
+		}
+		frame.Reply = tk103Reply(deviceID, "AS01", data[:1])
+		data = data[1:]
+	case "BR00", "BR01":
+	default:
+		return frame, nil
+	}
+
+	position, err := decodeTK103Position(data)
+	if err != nil {
+		return nil, err
+	}
+	position.DeviceID = session.DeviceID
+	frame.Positions = append(frame.Positions, position)
+
+	return frame, nil
+}
+
+// skipTK103Garbage discards the bytes up to the opening parenthesis of a message,
+// holding at most the buffer of the reader.
+func skipTK103Garbage(reader *bufio.Reader) error {
+	for {
+		_, err := reader.ReadSlice('(')
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return err
+		}
+	}
+}
+
+// readTK103Message reads a message up to its closing parenthesis, which is not returned.
+// It stops reading at maxTK103Frame bytes, returning ErrInvalidFrame.
+func readTK103Message(reader *bufio.Reader) (string, error) {
+	var message strings.Builder
+	for message.Len() < maxTK103Frame {
+		c, err := reader.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if c == ')' {
+			return message.String(), nil
+		}
+		message.WriteByte(c)
+	}
+	return "", fmt.Errorf("%w: tk103 message longer than %d bytes", ErrInvalidFrame, maxTK103Frame)
+}
+
+// decodeTK103Position decodes the GPS block, like 080612A2232.9828N11404.9297E000.0022828000.00.
+func decodeTK103Position(data string) (*ingestion.Position, error) {
+	if len(data) < 45 {
+		return nil, fmt.Errorf("%w: short tk103 gps block", ErrInvalidFrame)
+	}
+
+	timestamp, err := time.Parse("060102150405", data[0:6]+data[33:39])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid tk103 date: %v", ErrInvalidFrame, err)
+	}
+
+	latitude, err := nmea.DegreesMinutesToDecimal(data[7:16], data[16:17])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFrame, err)
+	}
+
+	longitude, err := nmea.DegreesMinutesToDecimal(data[17:27], data[27:28])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFrame, err)
+	}
+
+	speed, err := strconv.ParseFloat(data[28:33], 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid tk103 speed: %v", ErrInvalidFrame, err)
+	}
+
+	course, err := strconv.ParseFloat(data[39:45], 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid tk103 course: %v", ErrInvalidFrame, err)
+	}
+
+	return &ingestion.Position{
+		Timestamp: timestamp,
+		Valid:     data[6] == 'A',
+		Latitude:  latitude,
+		Longitude: longitude,
+		Speed:     speed,
+		Course:    course,
+	}, nil
+}
+
+// tk103Reply builds the acknowledgement of a message.
+func tk103Reply(deviceID, command, data string) []byte {
+	return []byte(fmt.Sprintf("(%s%s%s)", deviceID, command, data))
+}
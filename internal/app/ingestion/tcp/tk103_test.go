@@ -0,0 +1,160 @@
+package tcp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTK103Messages(t *testing.T) {
+	tests := []struct {
+		name          string
+		message       string
+		wantDevice    string
+		wantReply     string
+		wantPositions int
+	}{
+		{
+			name:          "login",
+			message:       "(027028258309BP05000027028258309080612A2232.9828N11404.9297E000.0022828000.0000000000L0000021D)",
+			wantDevice:    "27028258309",
+			wantReply:     "(027028258309AP05)",
+			wantPositions: 1,
+		},
+		{
+			name:       "heartbeat",
+			message:    "(027028258309BP00000027028258309HSO)",
+			wantDevice: "27028258309",
+			wantReply:  "(027028258309AP01HSO)",
+		},
+		{
+			name:          "position",
+			message:       "(027028258309BR00080612A2232.9828N11404.9297E000.0022828000.0000000000L0000021D)",
+			wantDevice:    "27028258309",
+			wantPositions: 1,
+		},
+		{
+			name:          "alarm",
+			message:       "(027028258309BO012080612A2232.9828N11404.9297E000.0022828000.0000000000L0000021D)",
+			wantDevice:    "27028258309",
+			wantReply:     "(027028258309AS012)",
+			wantPositions: 1,
+		},
+		{
+			name:          "garbage before the message",
+			message:       "\r\nnoise(027028258309BR00080612A2232.9828N11404.9297E000.0022828000.0000000000L0000021D)",
+			wantDevice:    "27028258309",
+			wantPositions: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := new(Session)
+			frame, err := new(TK103).Decode(bufio.NewReader(strings.NewReader(tt.message)), session)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if session.DeviceID != tt.wantDevice {
+				t.Errorf("DeviceID = %q, want %q", session.DeviceID, tt.wantDevice)
+			}
+			if string(frame.Reply) != tt.wantReply {
+				t.Errorf("Reply = %q, want %q", frame.Reply, tt.wantReply)
+			}
+			if len(frame.Positions) != tt.wantPositions {
+				t.Fatalf("Positions = %d, want %d", len(frame.Positions), tt.wantPositions)
+			}
+		})
+	}
+}
+
+func TestTK103Position(t *testing.T) {
+	message := "(027028258309BR00080612A2232.9828N11404.9297E050.5022828123.45000000000L0000021D)"
+
+	frame, err := new(TK103).Decode(bufio.NewReader(strings.NewReader(message)), new(Session))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	position := frame.Positions[0]
+	if want := time.Date(2008, 6, 12, 2, 28, 28, 0, time.UTC); !position.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v, want %v", position.Timestamp, want)
+	}
+	if !position.Valid {
+		t.Error("Valid = false, want true")
+	}
+	if !closeTo(position.Latitude, 22+32.9828/60) || !closeTo(position.Longitude, 114+4.9297/60) {
+		t.Errorf("coordinates = %f, %f", position.Latitude, position.Longitude)
+	}
+	if position.Speed != 50.5 || position.Course != 123.45 {
+		t.Errorf("speed and course = %v, %v, want 50.5, 123.45", position.Speed, position.Course)
+	}
+	if position.DeviceID != "27028258309" {
+		t.Errorf("DeviceID = %q, want 27028258309", position.DeviceID)
+	}
+}
+
+func TestTK103InvalidMessages(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		wantErr error
+	}{
+		{name: "short message", message: "(0270282583)", wantErr: ErrInvalidFrame},
+		{name: "short gps block", message: "(027028258309BR00080612A2232.9828N)", wantErr: ErrInvalidFrame},
+		{name: "invalid date", message: "(027028258309BR00089912A2232.9828N11404.9297E000.0022828000.0000000000L0000021D)", wantErr: ErrInvalidFrame},
+		{name: "unterminated message", message: "(027028258309BR00080612A", wantErr: io.EOF},
+		{name: "no message", message: "noise without a message", wantErr: io.EOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := new(TK103).Decode(bufio.NewReader(strings.NewReader(tt.message)), new(Session))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Decode() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// countingReader counts the bytes read from it.
+type countingReader struct {
+	reader io.Reader
+	read   int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += n
+	return n, err
+}
+
+func TestTK103OversizedMessage(t *testing.T) {
+	// A client sending bytes without the closing parenthesis must not be read without limit.
+	endless := &countingReader{reader: io.MultiReader(strings.NewReader("("), strings.NewReader(strings.Repeat("1", 1<<20)))}
+	reader := bufio.NewReader(endless)
+
+	_, err := new(TK103).Decode(reader, new(Session))
+	if !errors.Is(err, ErrInvalidFrame) {
+		t.Fatalf("Decode() error = %v, want %v", err, ErrInvalidFrame)
+	}
+	if endless.read > maxTK103Frame+reader.Size() {
+		t.Errorf("read %d bytes, want at most %d", endless.read, maxTK103Frame+reader.Size())
+	}
+
+	// The next message is decoded after the oversized one.
+	reader = bufio.NewReader(strings.NewReader("(" + strings.Repeat("1", maxTK103Frame+10) + ")(027028258309BR00080612A2232.9828N11404.9297E000.0022828000.0000000000L0000021D)"))
+	if _, err := new(TK103).Decode(reader, new(Session)); !errors.Is(err, ErrInvalidFrame) {
+		t.Fatalf("Decode() error = %v, want %v", err, ErrInvalidFrame)
+	}
+	frame, err := new(TK103).Decode(reader, new(Session))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if len(frame.Positions) != 1 {
+		t.Errorf("Positions = %d, want 1", len(frame.Positions))
+	}
+}
@@ -0,0 +1,65 @@
+package purge
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/allansbo/goapi/internal/config"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+)
+
+// Job removes permanently the locations deleted for longer than the retention period, periodically in background.
+type Job struct {
+	interval  time.Duration
+	retention time.Duration
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewJob creates a new instance of Job from the PURGE_INTERVAL and DELETED_RETENTION configuration.
+func NewJob(cfg *config.EnvConfig) *Job {
+	return &Job{interval: cfg.PurgeInterval, retention: cfg.DeletedRetention, done: make(chan struct{})}
+}
+
+// Enabled reports if the job has an interval configured.
+func (j *Job) Enabled() bool {
+	return j.interval > 0
+}
+
+// Start runs the purge right away and then at every interval, in background.
+func (j *Job) Start() {
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			j.run()
+
+			select {
+			case <-j.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop waits for the running purge to finish and stops the job.
+func (j *Job) Stop() {
+	close(j.done)
+	j.wg.Wait()
+}
+
+func (j *Job) run() {
+	purged, err := usecase.PurgeDeletedLocations(j.retention)
+	if err != nil {
+		slog.Error("error purging deleted locations", "error", err.Error())
+	}
+	if purged > 0 {
+		slog.Info("purged deleted locations", "count", purged)
+	}
+}
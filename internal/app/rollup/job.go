@@ -0,0 +1,64 @@
+package rollup
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/allansbo/goapi/internal/config"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+)
+
+// Job recomputes the daily statistics of the vehicles periodically in background.
+// Every run rolls up the current day and the previous one, which can still receive
+// the locations buffered by the trackers while offline.
+type Job struct {
+	interval time.Duration
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewJob creates a new instance of Job from the STATS_ROLLUP_INTERVAL configuration.
+func NewJob(cfg *config.EnvConfig) *Job {
+	return &Job{interval: cfg.StatsRollupInterval, done: make(chan struct{})}
+}
+
+// Enabled reports if the job has an interval configured.
+func (j *Job) Enabled() bool {
+	return j.interval > 0
+}
+
+// Start runs the roll-up right away and then at every interval, in background.
+func (j *Job) Start() {
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			j.run(time.Now())
+
+			select {
+			case <-j.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop waits for the running roll-up to finish and stops the job.
+func (j *Job) Stop() {
+	close(j.done)
+	j.wg.Wait()
+}
+
+func (j *Job) run(now time.Time) {
+	for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+		if err := usecase.RollUpDailyStats(day); err != nil {
+			slog.Error("error rolling up daily stats", "error", err.Error(), "day", usecase.Day(day).Format(time.DateOnly))
+		}
+	}
+}
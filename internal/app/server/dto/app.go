@@ -12,6 +12,9 @@ type LocationInApp struct {
 	Longitude string `validate:"required,longitude" json:"longitude" example:"-46.633308"`
 	Status    string `validate:"required,oneof=moving stopped offline" json:"status" example:"moving"`
 	Speed     int    `validate:"gte=0" json:"speed" example:"80"`
+
+	// Attributes are the telemetry reported by the device, like ignition or battery voltage.
+	Attributes map[string]any `json:"attributes,omitempty"`
 }
 
 // CoordinatesOutApp is the output data for the location endpoints
@@ -24,12 +27,25 @@ type CoordinatesOutApp struct {
 // LocationOutApp is the output data for the location endpoints
 // that will be used to return a location.
 type LocationOutApp struct {
-	ID        string             `json:"id"`
-	VehicleId string             `json:"vehicle_id"`
-	Timestamp time.Time          `json:"timestamp"`
-	Location  *CoordinatesOutApp `json:"location"`
-	Speed     int                `json:"speed"`
-	Status    string             `json:"status"`
+	ID         string             `json:"id"`
+	VehicleId  string             `json:"vehicle_id"`
+	Timestamp  time.Time          `json:"timestamp"`
+	Location   *CoordinatesOutApp `json:"location"`
+	Speed      int                `json:"speed"`
+	Status     string             `json:"status"`
+	Attributes map[string]any     `json:"attributes,omitempty"`
+	Flags      []string           `json:"flags,omitempty"`
+	Place      *PlaceOutApp       `json:"place,omitempty"`
+	DeletedAt  *time.Time         `json:"deleted_at,omitempty"`
+	Version    int64              `json:"version"`
+}
+
+// PlaceOutApp is the nearest place of a location found by the reverse geocoding,
+// with its distance in meters.
+type PlaceOutApp struct {
+	Name     string  `json:"name" example:"Av. Paulista, São Paulo"`
+	Country  string  `json:"country,omitempty" example:"BR"`
+	Distance float64 `json:"distance" example:"35.2"`
 }
 
 // LocationCreatedResponseOut response when a document is created
@@ -47,6 +63,15 @@ type QueryLocationRequest struct {
 	Page      int    `query:"page" validate:"omitempty,gte=1"`
 	VehicleId string `query:"vehicle_id" validate:"omitempty,alphanum,len=7"`
 	Status    string `query:"status" validate:"omitempty,oneof=moving stopped offline"`
+	From      string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2025-01-01T00:00:00Z"`
+	To        string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2025-01-02T00:00:00Z"`
+	// Simplify is the tolerance in meters to simplify the track of every vehicle in the result.
+	Simplify float64 `query:"simplify" validate:"omitempty,gt=0,lte=10000" example:"10"`
+	// Flagged filters the locations flagged by the anomaly checks, Flag filters a specific flag.
+	Flagged bool   `query:"flagged" example:"true"`
+	Flag    string `query:"flag" validate:"omitempty,oneof=null_island impossible_speed duplicate" example:"impossible_speed"`
+	// IncludeDeleted includes the deleted locations that were not purged yet, only for the admin role.
+	IncludeDeleted bool `query:"include_deleted" example:"true"`
 }
 
 // PaginationInfoResponse contains pagination information for the response.
@@ -61,3 +86,360 @@ type QueryLocationResponse struct {
 	Data       []*LocationOutApp       `json:"data"`
 	Pagination *PaginationInfoResponse `json:"pagination_info,omitempty"`
 }
+
+// NMEALocationInApp is the input data for the NMEA endpoint,
+// with the raw sentences forwarded by the unit installed on a vehicle.
+type NMEALocationInApp struct {
+	VehicleId string   `validate:"required,alphanum,len=7" json:"vehicle_id" example:"ABC1234"`
+	Sentences []string `validate:"required,min=1,max=500" json:"sentences" example:"$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A"`
+}
+
+// NMEARejectedSentenceOut is a sentence that was not saved and the reason.
+// Line is the position of the sentence in the request, starting at 1.
+type NMEARejectedSentenceOut struct {
+	Line     int    `json:"line" example:"1"`
+	Sentence string `json:"sentence"`
+	Error    string `json:"error"`
+}
+
+// NMEALocationResponseOut response when the NMEA sentences are processed.
+type NMEALocationResponseOut struct {
+	DocumentIDs []string                   `json:"document_ids"`
+	Rejected    []*NMEARejectedSentenceOut `json:"rejected,omitempty"`
+}
+
+// TrackRequest is the request structure for exporting the track of a vehicle.
+type TrackRequest struct {
+	VehicleId string  `params:"id" validate:"required,alphanum,len=7"`
+	From      string  `query:"from" validate:"required,datetime=2006-01-02T15:04:05Z07:00" example:"2025-01-01T00:00:00Z"`
+	To        string  `query:"to" validate:"required,datetime=2006-01-02T15:04:05Z07:00" example:"2025-01-02T00:00:00Z"`
+	Format    string  `query:"format" validate:"omitempty,oneof=gpx kml geojson" example:"gpx"`
+	Simplify  float64 `query:"simplify" validate:"omitempty,gt=0,lte=10000" example:"10"`
+}
+
+// StopReportRequest is the request structure for the stops of a vehicle in a period.
+type StopReportRequest struct {
+	VehicleId string `params:"id" validate:"required,alphanum,len=7"`
+	From      string `query:"from" validate:"required,datetime=2006-01-02T15:04:05Z07:00" example:"2025-01-01T00:00:00Z"`
+	To        string `query:"to" validate:"required,datetime=2006-01-02T15:04:05Z07:00" example:"2025-01-02T00:00:00Z"`
+	// Radius is the distance in meters that the vehicle can drift while stopped, 50 by default.
+	Radius float64 `query:"radius" validate:"omitempty,gt=0,lte=1000" example:"50"`
+	// MinDuration is the minimum duration in seconds of a stop, 120 by default.
+	MinDuration int `query:"min_duration" validate:"omitempty,gte=1,lte=86400" example:"120"`
+	// MaxSpeed is the speed in km/h up to which the vehicle is considered stopped, 5 by default.
+	MaxSpeed int `query:"max_speed" validate:"omitempty,gte=1,lte=30" example:"5"`
+}
+
+// StopOut is a period that a vehicle stayed at the same place.
+// The durations are in seconds, the idle duration is the time with the engine on.
+type StopOut struct {
+	StartedAt    time.Time          `json:"started_at"`
+	EndedAt      time.Time          `json:"ended_at"`
+	Location     *CoordinatesOutApp `json:"location"`
+	Place        *PlaceOutApp       `json:"place,omitempty"`
+	Duration     int64              `json:"duration"`
+	IdleDuration int64              `json:"idle_duration"`
+	Locations    int                `json:"locations"`
+}
+
+// StopReportOut is the response with the stops of a vehicle in a period and their total durations in seconds.
+type StopReportOut struct {
+	VehicleId         string     `json:"vehicle_id"`
+	From              time.Time  `json:"from"`
+	To                time.Time  `json:"to"`
+	Count             int        `json:"count"`
+	TotalDuration     int64      `json:"total_duration"`
+	TotalIdleDuration int64      `json:"total_idle_duration"`
+	Stops             []*StopOut `json:"stops"`
+}
+
+// PlaybackRequest is the request structure for replaying the movement of a vehicle at a fixed time step.
+type PlaybackRequest struct {
+	VehicleId string `params:"id" validate:"required,alphanum,len=7"`
+	From      string `query:"from" validate:"required,datetime=2006-01-02T15:04:05Z07:00" example:"2025-01-01T00:00:00Z"`
+	To        string `query:"to" validate:"required,datetime=2006-01-02T15:04:05Z07:00" example:"2025-01-01T01:00:00Z"`
+	// Interval is the time step of the positions in seconds.
+	Interval int `query:"interval" validate:"required,gte=1,lte=3600" example:"10"`
+	// MaxGap is the time in seconds without locations after which the vehicle is in a gap, 600 by default.
+	MaxGap int `query:"max_gap" validate:"omitempty,gte=1,lte=86400" example:"600"`
+}
+
+// PlaybackFrameOut is the position of a vehicle at a step of the playback. The heading is in degrees clockwise
+// from the north. In a gap, the vehicle was offline or not reporting and the position is the last known one, if any.
+type PlaybackFrameOut struct {
+	Timestamp    time.Time          `json:"timestamp"`
+	Location     *CoordinatesOutApp `json:"location,omitempty"`
+	Heading      float64            `json:"heading"`
+	Speed        int                `json:"speed"`
+	Status       string             `json:"status,omitempty"`
+	Interpolated bool               `json:"interpolated"`
+	Gap          bool               `json:"gap"`
+}
+
+// PlaybackResponseOut is the response with the positions of a vehicle resampled at the interval in seconds.
+type PlaybackResponseOut struct {
+	VehicleId string              `json:"vehicle_id"`
+	From      time.Time           `json:"from"`
+	To        time.Time           `json:"to"`
+	Interval  int                 `json:"interval"`
+	Frames    []*PlaybackFrameOut `json:"frames"`
+}
+
+// StatusTransitionRequest is the request structure for the status transitions of a vehicle in a period.
+type StatusTransitionRequest struct {
+	VehicleId string `params:"id" validate:"required,alphanum,len=7"`
+	From      string `query:"from" validate:"required,datetime=2006-01-02T15:04:05Z07:00" example:"2025-01-01T00:00:00Z"`
+	To        string `query:"to" validate:"required,datetime=2006-01-02T15:04:05Z07:00" example:"2025-01-02T00:00:00Z"`
+}
+
+// StatusTransitionOutApp is a change of the status of a vehicle, with the source that reported it,
+// like api, mqtt, tcp:gt06 or update, and the location that caused it.
+type StatusTransitionOutApp struct {
+	ID         string    `json:"id"`
+	From       string    `json:"from" example:"moving"`
+	To         string    `json:"to" example:"stopped"`
+	At         time.Time `json:"at"`
+	Source     string    `json:"source" example:"api"`
+	LocationId string    `json:"location_id"`
+}
+
+// StatusTransitionResponseOut is the response with the status transitions of a vehicle in a period
+// and the time in seconds spent on every status, counted from the transitions.
+type StatusTransitionResponseOut struct {
+	VehicleId   string                    `json:"vehicle_id"`
+	From        time.Time                 `json:"from"`
+	To          time.Time                 `json:"to"`
+	Durations   map[string]int64          `json:"durations"`
+	Transitions []*StatusTransitionOutApp `json:"transitions"`
+}
+
+// APIKeyInApp is the input data for issuing an API key.
+type APIKeyInApp struct {
+	Name   string   `validate:"required,max=100" json:"name" example:"mqtt-gateway"`
+	Scopes []string `validate:"required,min=1,dive,oneof=read write delete admin" json:"scopes" example:"read,write"`
+	// Tenant is the tenant of the key, the one of the client that issues it and the default one when empty.
+	Tenant string `validate:"omitempty,tenant" json:"tenant,omitempty" example:"acme"`
+	// Vehicles and Groups are the only vehicles and fleet groups the key can access, all of them when both are empty.
+	Vehicles  []string   `validate:"omitempty,dive,alphanum,len=7" json:"vehicles,omitempty" example:"ABC1234"`
+	Groups    []string   `validate:"omitempty,dive,required" json:"groups,omitempty" example:"acme"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2026-01-01T00:00:00Z"`
+}
+
+// APIKeyOutApp is an API key, without its secret.
+type APIKeyOutApp struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Scopes    []string   `json:"scopes"`
+	Vehicles  []string   `json:"vehicles,omitempty"`
+	Groups    []string   `json:"groups,omitempty"`
+	Tenant    string     `json:"tenant,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKeyIssuedOut response when an API key is issued or rotated, the only time the key is shown.
+type APIKeyIssuedOut struct {
+	*APIKeyOutApp
+	Key string `json:"key" example:"gk_1a2b3c4d5e6f.c2VjcmV0"`
+}
+
+// APIKeyListOut is the response with all the API keys.
+type APIKeyListOut struct {
+	Success bool            `json:"success"`
+	Data    []*APIKeyOutApp `json:"data"`
+}
+
+// AuditRequest is the request structure for querying the audit log.
+type AuditRequest struct {
+	Limit      int    `query:"limit" validate:"omitempty,gte=1,lte=100"`
+	Page       int    `query:"page" validate:"omitempty,gte=1"`
+	Action     string `query:"action" validate:"omitempty,oneof=create update delete restore rotate revoke" example:"delete"`
+	Resource   string `query:"resource" validate:"omitempty,oneof=location api_key" example:"location"`
+	ResourceId string `query:"resource_id" validate:"omitempty,hexadecimal,len=24" example:"665f1c2b8e4a3b2d1c0f9e8d"`
+	// Actor is the subject of the client that made the changes, like the ID of an API key or the sub claim of a token.
+	Actor     string `query:"actor" example:"665f1c2b8e4a3b2d1c0f9e8a"`
+	RequestId string `query:"request_id" example:"0b7c3a52-7d2e-4c1f-9a8b-2f1e0d3c4b5a"`
+	From      string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2025-01-01T00:00:00Z"`
+	To        string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2025-01-02T00:00:00Z"`
+}
+
+// AuditActorOutApp is the client that made a change: the ID of its API key or the subject of its token,
+// its name and the authentication method, like api_key, jwt, internal or none.
+type AuditActorOutApp struct {
+	Subject string `json:"subject"`
+	Name    string `json:"name"`
+	Method  string `json:"method" example:"api_key"`
+}
+
+// AuditEntryOutApp is a change of a resource, with the documents before and after it,
+// the client that made it and the IP and the ID of its request.
+type AuditEntryOutApp struct {
+	ID         string            `json:"id"`
+	Action     string            `json:"action" example:"delete"`
+	Resource   string            `json:"resource" example:"location"`
+	ResourceId string            `json:"resource_id"`
+	Actor      *AuditActorOutApp `json:"actor,omitempty"`
+	IP         string            `json:"ip,omitempty" example:"203.0.113.7"`
+	RequestId  string            `json:"request_id,omitempty"`
+	At         time.Time         `json:"at"`
+	Before     map[string]any    `json:"before,omitempty"`
+	After      map[string]any    `json:"after,omitempty"`
+}
+
+// AuditResponseOut is the response with the entries of the audit log, from the newest.
+type AuditResponseOut struct {
+	Success    bool                    `json:"success"`
+	Data       []*AuditEntryOutApp     `json:"data"`
+	Pagination *PaginationInfoResponse `json:"pagination_info,omitempty"`
+}
+
+// DailyStatsRequest is the request structure for the daily statistics of the vehicles in a period of days.
+type DailyStatsRequest struct {
+	VehicleId string `query:"vehicle_id" validate:"omitempty,alphanum,len=7" example:"ABC1234"`
+	From      string `query:"from" validate:"required,datetime=2006-01-02" example:"2025-01-01"`
+	To        string `query:"to" validate:"required,datetime=2006-01-02" example:"2025-01-31"`
+}
+
+// DailyStatsOutApp is the statistics of a vehicle in a day.
+// The distance is in meters, the speeds in km/h and the durations in seconds.
+type DailyStatsOutApp struct {
+	VehicleId       string    `json:"vehicle_id"`
+	Day             string    `json:"day"`
+	Points          int       `json:"points"`
+	Distance        float64   `json:"distance"`
+	MaxSpeed        int       `json:"max_speed"`
+	AvgSpeed        float64   `json:"avg_speed"`
+	MovingDuration  int64     `json:"moving_duration"`
+	StoppedDuration int64     `json:"stopped_duration"`
+	OfflineDuration int64     `json:"offline_duration"`
+	FirstSeen       time.Time `json:"first_seen"`
+	LastSeen        time.Time `json:"last_seen"`
+}
+
+// DailyStatsResponseOut is the response structure for the daily statistics.
+type DailyStatsResponseOut struct {
+	Success bool                `json:"success"`
+	Data    []*DailyStatsOutApp `json:"data"`
+}
+
+// HeatmapRequest is the request structure for the density of the locations by geohash cell.
+type HeatmapRequest struct {
+	VehicleId string `query:"vehicle_id" validate:"omitempty,alphanum,len=7"`
+	Status    string `query:"status" validate:"omitempty,oneof=moving stopped offline"`
+	From      string `query:"from" validate:"required,datetime=2006-01-02T15:04:05Z07:00" example:"2025-01-01T00:00:00Z"`
+	To        string `query:"to" validate:"required,datetime=2006-01-02T15:04:05Z07:00" example:"2025-01-02T00:00:00Z"`
+	// Precision is the length of the geohash of the cells, from 1 (about 5000 km) to 9 (about 5 m).
+	Precision int `query:"precision" validate:"required,gte=1,lte=9" example:"6"`
+	// Limit is the maximum number of cells returned, the densest ones, 1000 by default.
+	Limit int `query:"limit" validate:"omitempty,gte=1,lte=10000" example:"1000"`
+}
+
+// NewQueryLocationRequest returns the query of the locations matching the heatmap filters.
+func (e *HeatmapRequest) NewQueryLocationRequest() *QueryLocationRequest {
+	return &QueryLocationRequest{
+		VehicleId: e.VehicleId,
+		Status:    e.Status,
+		From:      e.From,
+		To:        e.To,
+	}
+}
+
+// HeatmapCellOut is a geohash cell with the number of locations and their average speed in km/h.
+// The latitude and longitude are the center of the cell.
+type HeatmapCellOut struct {
+	Geohash   string  `json:"geohash"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Count     int     `json:"count"`
+	AvgSpeed  float64 `json:"avg_speed"`
+}
+
+// HeatmapResponseOut is the response with the cells of the heatmap, ordered from the densest.
+type HeatmapResponseOut struct {
+	Precision  int               `json:"precision"`
+	TotalCells int               `json:"total_cells"`
+	Cells      []*HeatmapCellOut `json:"cells"`
+}
+
+// TileRequest is the request structure for a vector tile of the locations.
+type TileRequest struct {
+	Z         int    `params:"z" validate:"gte=0,lte=22"`
+	X         int    `params:"x" validate:"gte=0"`
+	Y         int    `params:"y" validate:"gte=0"`
+	VehicleId string `query:"vehicle_id" validate:"omitempty,alphanum,len=7"`
+	Status    string `query:"status" validate:"omitempty,oneof=moving stopped offline"`
+	From      string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2025-01-01T00:00:00Z"`
+	To        string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2025-01-02T00:00:00Z"`
+	// Mode is latest for the last position of every vehicle or history for all the locations, latest by default.
+	Mode string `query:"mode" validate:"omitempty,oneof=latest history" example:"latest"`
+	// ClusterZoom is the deepest zoom level where the points are clustered, 12 by default.
+	ClusterZoom *int `query:"cluster_zoom" validate:"omitempty,gte=0,lte=22" example:"12"`
+}
+
+// NewQueryLocationRequest returns the query of the locations matching the tile filters.
+func (e *TileRequest) NewQueryLocationRequest() *QueryLocationRequest {
+	return &QueryLocationRequest{
+		VehicleId: e.VehicleId,
+		Status:    e.Status,
+		From:      e.From,
+		To:        e.To,
+	}
+}
+
+// ExportLocationRequest is the request structure for exporting the locations that match the filters.
+type ExportLocationRequest struct {
+	VehicleId string `query:"vehicle_id" validate:"omitempty,alphanum,len=7"`
+	Status    string `query:"status" validate:"omitempty,oneof=moving stopped offline"`
+	From      string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2025-01-01T00:00:00Z"`
+	To        string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" example:"2025-01-02T00:00:00Z"`
+	Format    string `query:"format" validate:"omitempty,oneof=csv parquet" example:"csv"`
+	Flagged   bool   `query:"flagged" example:"true"`
+	Flag      string `query:"flag" validate:"omitempty,oneof=null_island impossible_speed duplicate" example:"impossible_speed"`
+}
+
+// NewQueryLocationRequest returns the query of the locations matching the export filters.
+func (e *ExportLocationRequest) NewQueryLocationRequest() *QueryLocationRequest {
+	return &QueryLocationRequest{
+		VehicleId: e.VehicleId,
+		Status:    e.Status,
+		From:      e.From,
+		To:        e.To,
+		Flagged:   e.Flagged,
+		Flag:      e.Flag,
+	}
+}
+
+// TimedLocationInApp is a location recorded at a known timestamp,
+// like the ones imported from the history of other providers.
+type TimedLocationInApp struct {
+	Location  *LocationInApp
+	Timestamp time.Time
+}
+
+// ImportLocationRequest is the request structure for importing the history of locations from a file.
+type ImportLocationRequest struct {
+	Format          string `query:"format" validate:"required,oneof=csv gpx" example:"csv"`
+	VehicleId       string `query:"vehicle_id" validate:"omitempty,alphanum,len=7" example:"ABC1234"`
+	Mapping         string `query:"mapping" example:"latitude=lat,longitude=lng,timestamp=time"`
+	TimestampLayout string `query:"timestamp_layout" example:"unixms"`
+	DryRun          bool   `query:"dry_run" example:"true"`
+}
+
+// ImportRejectedRowOut is a row of the imported file that was not saved and the reason.
+type ImportRejectedRowOut struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportReportOut is the report of an import, with the rows read, saved and rejected.
+// Only the first rejected rows are listed, RejectedCount has the total.
+type ImportReportOut struct {
+	DryRun        bool                    `json:"dry_run"`
+	Total         int                     `json:"total"`
+	Accepted      int                     `json:"accepted"`
+	Inserted      int                     `json:"inserted"`
+	RejectedCount int                     `json:"rejected_count"`
+	Rejected      []*ImportRejectedRowOut `json:"rejected"`
+}
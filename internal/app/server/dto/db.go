@@ -14,12 +14,17 @@ type CoordinatesOutDB struct {
 
 // LocationOutDB is the output data for saving a location in the database.
 type LocationOutDB struct {
-	ID        string            `bson:"_id,omitempty"`
-	VehicleId string            `bson:"vehicle_id"`
-	Timestamp time.Time         `bson:"timestamp"`
-	Location  *CoordinatesOutDB `bson:"location"`
-	Speed     int               `bson:"speed"`
-	Status    string            `bson:"status"`
+	ID         string            `bson:"_id,omitempty"`
+	TenantId   string            `bson:"tenant_id"`
+	VehicleId  string            `bson:"vehicle_id"`
+	Timestamp  time.Time         `bson:"timestamp"`
+	Location   *CoordinatesOutDB `bson:"location"`
+	Speed      int               `bson:"speed"`
+	Status     string            `bson:"status"`
+	Attributes map[string]any    `bson:"attributes,omitempty"`
+	Flags      []string          `bson:"flags,omitempty"`
+	// Version is set by the repository, 1 on the insertion and incremented on every change.
+	Version int64 `bson:"version,omitempty"`
 }
 
 // CoordinatesInDB is the input data for retrieving a location from the database.
@@ -30,20 +35,54 @@ type CoordinatesInDB struct {
 
 // LocationInDB is the input data for retrieving a location from the database.
 type LocationInDB struct {
-	ID        bson.ObjectID    `bson:"_id"`
-	VehicleId string           `bson:"vehicle_id"`
-	Timestamp time.Time        `bson:"timestamp"`
-	Location  *CoordinatesInDB `bson:"location"`
-	Speed     int              `bson:"speed"`
-	Status    string           `bson:"status"`
+	ID         bson.ObjectID    `bson:"_id"`
+	VehicleId  string           `bson:"vehicle_id"`
+	Timestamp  time.Time        `bson:"timestamp"`
+	Location   *CoordinatesInDB `bson:"location"`
+	Speed      int              `bson:"speed"`
+	Status     string           `bson:"status"`
+	Attributes map[string]any   `bson:"attributes,omitempty"`
+	Flags      []string         `bson:"flags,omitempty"`
+	DeletedAt  *time.Time       `bson:"deleted_at,omitempty"`
+	Version    int64            `bson:"version"`
+}
+
+// LocationPatchOutDB is the output data for changing some fields of a location in the database,
+// the nil fields are not changed. The attributes are set and removed by their keys.
+type LocationPatchOutDB struct {
+	VehicleId        *string
+	Latitude         *string
+	Longitude        *string
+	Speed            *int
+	Status           *string
+	SetAttributes    map[string]any
+	RemoveAttributes []string
 }
 
 // QueryLocationOutDB is the input data for querying locations from the database.
 type QueryLocationOutDB struct {
-	Limit     int    `bson:"limit"`
-	Page      int    `bson:"page"`
-	VehicleId string `bson:"vehicle_id"`
-	Status    string `bson:"status"`
+	Limit     int       `bson:"limit"`
+	Page      int       `bson:"page"`
+	VehicleId string    `bson:"vehicle_id"`
+	Status    string    `bson:"status"`
+	From      time.Time `bson:"from"`
+	To        time.Time `bson:"to"`
+	Flagged   bool      `bson:"flagged"`
+	Flag      string    `bson:"flag"`
+	// VehicleIds limits the query to the vehicles, when not nil. An empty list matches no location.
+	VehicleIds []string `bson:"vehicle_ids"`
+	// Bounds limits the query to the locations inside the area, when set.
+	Bounds *BoundsOutDB `bson:"bounds"`
+	// IncludeDeleted includes the deleted locations that were not purged yet.
+	IncludeDeleted bool `bson:"include_deleted"`
+}
+
+// BoundsOutDB is an area for querying locations from the database, in decimal degrees.
+type BoundsOutDB struct {
+	MinLatitude  float64 `bson:"min_latitude"`
+	MinLongitude float64 `bson:"min_longitude"`
+	MaxLatitude  float64 `bson:"max_latitude"`
+	MaxLongitude float64 `bson:"max_longitude"`
 }
 
 // QueryLocationInDB is the input data for retrieving locations from the database.
@@ -52,3 +91,167 @@ type QueryLocationInDB struct {
 	Page  int             `bson:"page"`
 	Data  []*LocationInDB `bson:"data"`
 }
+
+// DailyStatsOutDB is the output data for saving the statistics of a vehicle in a day.
+// The distance is in meters and the durations in seconds.
+type DailyStatsOutDB struct {
+	TenantId        string    `bson:"tenant_id"`
+	VehicleId       string    `bson:"vehicle_id"`
+	Day             time.Time `bson:"day"`
+	Points          int       `bson:"points"`
+	Distance        float64   `bson:"distance"`
+	MaxSpeed        int       `bson:"max_speed"`
+	AvgSpeed        float64   `bson:"avg_speed"`
+	MovingDuration  int64     `bson:"moving_duration"`
+	StoppedDuration int64     `bson:"stopped_duration"`
+	OfflineDuration int64     `bson:"offline_duration"`
+	FirstSeen       time.Time `bson:"first_seen"`
+	LastSeen        time.Time `bson:"last_seen"`
+	UpdatedAt       time.Time `bson:"updated_at"`
+}
+
+// DailyStatsInDB is the input data for retrieving the statistics of a vehicle in a day from the database.
+type DailyStatsInDB struct {
+	VehicleId       string    `bson:"vehicle_id"`
+	Day             time.Time `bson:"day"`
+	Points          int       `bson:"points"`
+	Distance        float64   `bson:"distance"`
+	MaxSpeed        int       `bson:"max_speed"`
+	AvgSpeed        float64   `bson:"avg_speed"`
+	MovingDuration  int64     `bson:"moving_duration"`
+	StoppedDuration int64     `bson:"stopped_duration"`
+	OfflineDuration int64     `bson:"offline_duration"`
+	FirstSeen       time.Time `bson:"first_seen"`
+	LastSeen        time.Time `bson:"last_seen"`
+	UpdatedAt       time.Time `bson:"updated_at"`
+}
+
+// QueryDailyStatsOutDB is the input data for querying the daily statistics from the database.
+type QueryDailyStatsOutDB struct {
+	VehicleId string    `bson:"vehicle_id"`
+	From      time.Time `bson:"from"`
+	To        time.Time `bson:"to"`
+	// VehicleIds limits the query to the vehicles, when not nil. An empty list matches no statistics.
+	VehicleIds []string `bson:"vehicle_ids"`
+}
+
+// StatusTransitionOutDB is the output data for saving a status transition of a vehicle in the database.
+type StatusTransitionOutDB struct {
+	TenantId   string    `bson:"tenant_id"`
+	VehicleId  string    `bson:"vehicle_id"`
+	From       string    `bson:"from"`
+	To         string    `bson:"to"`
+	At         time.Time `bson:"at"`
+	Source     string    `bson:"source"`
+	LocationId string    `bson:"location_id"`
+}
+
+// StatusTransitionInDB is the input data for retrieving a status transition of a vehicle from the database.
+type StatusTransitionInDB struct {
+	ID         bson.ObjectID `bson:"_id"`
+	VehicleId  string        `bson:"vehicle_id"`
+	From       string        `bson:"from"`
+	To         string        `bson:"to"`
+	At         time.Time     `bson:"at"`
+	Source     string        `bson:"source"`
+	LocationId string        `bson:"location_id"`
+}
+
+// QueryStatusTransitionOutDB is the input data for querying the status transitions of a vehicle from the database.
+type QueryStatusTransitionOutDB struct {
+	VehicleId string    `bson:"vehicle_id"`
+	From      time.Time `bson:"from"`
+	To        time.Time `bson:"to"`
+}
+
+// APIKeyOutDB is the output data for saving an API key in the database.
+type APIKeyOutDB struct {
+	Tenant    string     `bson:"tenant_id,omitempty"`
+	Name      string     `bson:"name"`
+	Prefix    string     `bson:"prefix"`
+	Hash      string     `bson:"hash"`
+	Scopes    []string   `bson:"scopes"`
+	Vehicles  []string   `bson:"vehicles,omitempty"`
+	Groups    []string   `bson:"groups,omitempty"`
+	CreatedAt time.Time  `bson:"created_at"`
+	ExpiresAt *time.Time `bson:"expires_at,omitempty"`
+	RevokedAt *time.Time `bson:"revoked_at,omitempty"`
+}
+
+// APIKeyInDB is the input data for retrieving an API key from the database.
+type APIKeyInDB struct {
+	ID        bson.ObjectID `bson:"_id"`
+	Tenant    string        `bson:"tenant_id,omitempty"`
+	Name      string        `bson:"name"`
+	Prefix    string        `bson:"prefix"`
+	Hash      string        `bson:"hash"`
+	Scopes    []string      `bson:"scopes"`
+	Vehicles  []string      `bson:"vehicles,omitempty"`
+	Groups    []string      `bson:"groups,omitempty"`
+	CreatedAt time.Time     `bson:"created_at"`
+	ExpiresAt *time.Time    `bson:"expires_at,omitempty"`
+	RevokedAt *time.Time    `bson:"revoked_at,omitempty"`
+}
+
+// AuditActorOutDB is the client that made a change, saved in the audit log.
+type AuditActorOutDB struct {
+	Subject string `bson:"subject"`
+	Name    string `bson:"name"`
+	Method  string `bson:"method"`
+}
+
+// AuditEntryOutDB is the output data for saving a change in the audit log.
+// The documents before and after the change are nil when it created or deleted the resource.
+type AuditEntryOutDB struct {
+	TenantId   string           `bson:"tenant_id"`
+	Action     string           `bson:"action"`
+	Resource   string           `bson:"resource"`
+	ResourceId string           `bson:"resource_id"`
+	Actor      *AuditActorOutDB `bson:"actor,omitempty"`
+	IP         string           `bson:"ip,omitempty"`
+	RequestId  string           `bson:"request_id,omitempty"`
+	At         time.Time        `bson:"at"`
+	Before     map[string]any   `bson:"before,omitempty"`
+	After      map[string]any   `bson:"after,omitempty"`
+}
+
+// AuditActorInDB is the client that made a change, retrieved from the audit log.
+type AuditActorInDB struct {
+	Subject string `bson:"subject"`
+	Name    string `bson:"name"`
+	Method  string `bson:"method"`
+}
+
+// AuditEntryInDB is the input data for retrieving a change from the audit log.
+type AuditEntryInDB struct {
+	ID         bson.ObjectID   `bson:"_id"`
+	Action     string          `bson:"action"`
+	Resource   string          `bson:"resource"`
+	ResourceId string          `bson:"resource_id"`
+	Actor      *AuditActorInDB `bson:"actor,omitempty"`
+	IP         string          `bson:"ip,omitempty"`
+	RequestId  string          `bson:"request_id,omitempty"`
+	At         time.Time       `bson:"at"`
+	Before     map[string]any  `bson:"before,omitempty"`
+	After      map[string]any  `bson:"after,omitempty"`
+}
+
+// QueryAuditOutDB is the input data for querying the audit log, the empty filters match every entry.
+type QueryAuditOutDB struct {
+	Limit      int       `bson:"limit"`
+	Page       int       `bson:"page"`
+	Action     string    `bson:"action"`
+	Resource   string    `bson:"resource"`
+	ResourceId string    `bson:"resource_id"`
+	Actor      string    `bson:"actor"`
+	RequestId  string    `bson:"request_id"`
+	From       time.Time `bson:"from"`
+	To         time.Time `bson:"to"`
+}
+
+// QueryAuditInDB is the input data for retrieving the entries of the audit log from the database.
+type QueryAuditInDB struct {
+	Limit int               `bson:"limit"`
+	Page  int               `bson:"page"`
+	Data  []*AuditEntryInDB `bson:"data"`
+}
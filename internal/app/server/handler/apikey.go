@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// KeysAddOne godoc
+//
+//	@Summary		Issue an API key
+//	@Description	Issue an API key with the scopes read, write, delete or admin, and an optional expiry.
+//	@Description	The key is returned only in this response, the database keeps its hash.
+//	@Description	The key belongs to the tenant of the client, the tenant field is only used without authentication.
+//	@Tags			Keys
+//	@Accept			json
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			request	body		dto.APIKeyInApp			true	"Request of issuing an api key"
+//	@Success		201		{object}	dto.APIKeyIssuedOut		"api key issued"
+//	@Failure		400		{object}	GlobalErrorHandlerResp	"validation error"
+//	@Failure		401		{object}	GlobalErrorHandlerResp	"authentication required"
+//	@Failure		403		{object}	GlobalErrorHandlerResp	"permission denied or tenant not allowed"
+//	@Failure		500		{object}	GlobalErrorHandlerResp	"internal server error"
+//	@Router			/api/v1/keys [post]
+func KeysAddOne(c *fiber.Ctx) error {
+	apiKeyDataIn := new(dto.APIKeyInApp)
+	if err := c.BodyParser(apiKeyDataIn); err != nil {
+		slog.Error("error parsing apiKeyDataIn", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error processing the api key data provided",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := makeValidation(apiKeyDataIn); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error validating the api key data provided",
+			Error:   err.Error(),
+		})
+	}
+
+	apiKeyDataOut, err := usecase.IssueAPIKey(CurrentPrincipal(c), apiKeyDataIn)
+	err = auditNotRecorded(c, err)
+	if errors.Is(err, usecase.ErrPrincipalRequired) {
+		return authenticationRequired(c, err)
+	} else if errors.Is(err, usecase.ErrInvalidAPIKeyExpiry) {
+		return c.Status(fiber.StatusBadRequest).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error validating the api key data provided",
+			Error:   err.Error(),
+		})
+	} else if errors.Is(err, usecase.ErrTenantNotAllowed) {
+		return c.Status(fiber.StatusForbidden).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "permission denied",
+			Error:   err.Error(),
+		})
+	} else if err != nil {
+		slog.Error("error issuing api key", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error issuing the api key",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(apiKeyDataOut)
+}
+
+// KeysGetAll godoc
+//
+//	@Summary		Get all API keys
+//	@Description	Get all the API keys with their scopes, expiry and revocation, without their secrets.
+//	@Tags			Keys
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		200	{object}	dto.APIKeyListOut		"api keys"
+//	@Failure		401	{object}	GlobalErrorHandlerResp	"authentication required"
+//	@Failure		403	{object}	GlobalErrorHandlerResp	"permission denied"
+//	@Failure		500	{object}	GlobalErrorHandlerResp	"internal server error"
+//	@Router			/api/v1/keys [get]
+func KeysGetAll(c *fiber.Ctx) error {
+	apiKeysDataOut, err := usecase.GetAPIKeys(CurrentPrincipal(c))
+	if errors.Is(err, usecase.ErrPrincipalRequired) {
+		return authenticationRequired(c, err)
+	} else if err != nil {
+		slog.Error("error getting api keys", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error getting the api keys",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(apiKeysDataOut)
+}
+
+// KeysRotateOne godoc
+//
+//	@Summary		Rotate an API key
+//	@Description	Replace the secret of an API key, keeping its name, scopes and expiry.
+//	@Description	The previous key stops working and the new one is returned only in this response.
+//	@Tags			Keys
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			id	path		string							true	"id of the api key"
+//	@Success		200	{object}	dto.APIKeyIssuedOut				"api key rotated"
+//	@Failure		401	{object}	GlobalErrorHandlerResp			"authentication required"
+//	@Failure		403	{object}	GlobalErrorHandlerResp			"permission denied"
+//	@Failure		404	{object}	dto.DefaultResponseMessageOut	"api key not found"
+//	@Failure		409	{object}	GlobalErrorHandlerResp			"api key revoked"
+//	@Failure		500	{object}	GlobalErrorHandlerResp			"internal server error"
+//	@Router			/api/v1/keys/{id}/rotate [post]
+func KeysRotateOne(c *fiber.Ctx) error {
+	apiKeyID := c.Params("id")
+
+	apiKeyDataOut, err := usecase.RotateAPIKey(CurrentPrincipal(c), apiKeyID)
+	err = auditNotRecorded(c, err)
+	if errors.Is(err, usecase.ErrPrincipalRequired) {
+		return authenticationRequired(c, err)
+	} else if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.Status(fiber.StatusNotFound).JSON(dto.DefaultResponseMessageOut{
+			Message: fmt.Sprintf("the api key ID %s does not exist", apiKeyID),
+		})
+	} else if errors.Is(err, usecase.ErrAPIKeyRevoked) {
+		return c.Status(fiber.StatusConflict).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: fmt.Sprintf("the api key ID %s can't be rotated", apiKeyID),
+			Error:   err.Error(),
+		})
+	} else if err != nil {
+		slog.Error("error rotating api key", "error", err.Error(), "apiKeyID", apiKeyID)
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: fmt.Sprintf("there is an error rotating the api key by provided id %s", apiKeyID),
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(apiKeyDataOut)
+}
+
+// KeysDeleteOne godoc
+//
+//	@Summary		Revoke an API key
+//	@Description	Revoke an API key, which can't be used nor rotated anymore. The key is kept in the list.
+//	@Tags			Keys
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Param			id	path		string							true	"id of the api key"
+//	@Success		200	{object}	dto.DefaultResponseMessageOut	"api key revoked"
+//	@Failure		401	{object}	GlobalErrorHandlerResp			"authentication required"
+//	@Failure		403	{object}	GlobalErrorHandlerResp			"permission denied"
+//	@Failure		404	{object}	dto.DefaultResponseMessageOut	"api key not found"
+//	@Failure		409	{object}	GlobalErrorHandlerResp			"api key already revoked"
+//	@Failure		500	{object}	GlobalErrorHandlerResp			"internal server error"
+//	@Router			/api/v1/keys/{id} [delete]
+func KeysDeleteOne(c *fiber.Ctx) error {
+	apiKeyID := c.Params("id")
+
+	err := usecase.RevokeAPIKey(CurrentPrincipal(c), apiKeyID)
+	err = auditNotRecorded(c, err)
+	if errors.Is(err, usecase.ErrPrincipalRequired) {
+		return authenticationRequired(c, err)
+	} else if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.Status(fiber.StatusNotFound).JSON(dto.DefaultResponseMessageOut{
+			Message: fmt.Sprintf("the api key ID %s does not exist", apiKeyID),
+		})
+	} else if errors.Is(err, usecase.ErrAPIKeyRevoked) {
+		return c.Status(fiber.StatusConflict).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: fmt.Sprintf("the api key ID %s can't be revoked", apiKeyID),
+			Error:   err.Error(),
+		})
+	} else if err != nil {
+		slog.Error("error revoking api key", "error", err.Error(), "apiKeyID", apiKeyID)
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: fmt.Sprintf("there is an error revoking the api key by provided id %s", apiKeyID),
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(dto.DefaultResponseMessageOut{
+		Message: fmt.Sprintf("the api key ID %s was revoked", apiKeyID),
+	})
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AuditGetAll godoc
+//
+//	@Summary		Get the audit log
+//	@Description	Get the creations, updates and deletions of the locations and the changes of the API keys of the tenant,
+//	@Description	from the newest, with the client that made them, the IP and the ID of the request and the documents
+//	@Description	before and after every change. The X-Request-ID header of the responses is the ID of their requests.
+//	@Tags			Audit
+//	@Produce		json
+//	@Param			q	query	dto.AuditRequest	false	"Query parameters for filtering the audit log"
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		200	{object}	dto.AuditResponseOut	"audit log entries"
+//	@Failure		400	{object}	GlobalErrorHandlerResp	"validation error"
+//	@Failure		401	{object}	GlobalErrorHandlerResp	"authentication required"
+//	@Failure		403	{object}	GlobalErrorHandlerResp	"permission denied"
+//	@Failure		500	{object}	GlobalErrorHandlerResp	"internal server error"
+//	@Router			/api/v1/audit [get]
+func AuditGetAll(c *fiber.Ctx) error {
+	auditRequest := new(dto.AuditRequest)
+	if err := c.QueryParser(auditRequest); err != nil {
+		slog.Error("error parsing query parameters", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error processing the audit request",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := makeValidation(auditRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "query parameters are not valid",
+			Error:   err.Error(),
+		})
+	}
+
+	auditDataOut, err := usecase.GetAuditEntries(CurrentPrincipal(c), auditRequest)
+	if err != nil {
+		slog.Error("error getting audit log", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error getting the audit log",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(auditDataOut)
+}
+
+// auditNotRecorded answers the changes that were saved but not recorded in the audit log as succeeded,
+// with a Warning header, since retrying them would save them again. It returns the other errors.
+func auditNotRecorded(c *fiber.Ctx, err error) error {
+	if !errors.Is(err, usecase.ErrAuditNotRecorded) {
+		return err
+	}
+	c.Set(fiber.HeaderWarning, `199 - "`+usecase.ErrAuditNotRecorded.Error()+`"`)
+	return nil
+}
@@ -1,44 +1,9 @@
 package handler
 
-import "github.com/go-playground/validator/v10"
-
 type (
-	ErrorResponse struct {
-		Error       bool
-		FailedField string
-		Tag         string
-		Value       interface{}
-	}
-
-	XValidator struct {
-		validator *validator.Validate
-	}
-
 	GlobalErrorHandlerResp struct {
 		Success bool   `json:"success"`
 		Message string `json:"message"`
 		Error   string `json:"error"`
 	}
 )
-
-var validate = validator.New()
-
-func (v XValidator) Validate(data interface{}) []ErrorResponse {
-	var validationErrors []ErrorResponse
-
-	errs := validate.Struct(data)
-	if errs != nil {
-		for _, err := range errs.(validator.ValidationErrors) {
-			var elem ErrorResponse
-
-			elem.FailedField = err.Field() // Export struct field name
-			elem.Tag = err.Tag()           // Export struct tag
-			elem.Value = err.Value()       // Export field value
-			elem.Error = true
-
-			validationErrors = append(validationErrors, elem)
-		}
-	}
-
-	return validationErrors
-}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/allansbo/goapi/internal/pkg/export"
+	"github.com/gofiber/fiber/v2"
+)
+
+// LocationsExport godoc
+//
+//	@Summary		Export locations data
+//	@Description	Stream all locations matching the filters, ordered by timestamp, as a CSV or Parquet file
+//	@Tags			Locations
+//	@Param			q	query	dto.ExportLocationRequest	false	"Query parameters for filtering locations"
+//	@Produce		text/csv
+//	@Produce		application/vnd.apache.parquet
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		200	{file}		file					"exported file"
+//	@Failure		400	{object}	GlobalErrorHandlerResp	"validation error"
+//	@Failure		403	{object}	GlobalErrorHandlerResp	"vehicle not allowed"
+//	@Router			/api/v1/locations/export [get]
+func LocationsExport(c *fiber.Ctx) error {
+	exportRequest := new(dto.ExportLocationRequest)
+	if err := c.QueryParser(exportRequest); err != nil {
+		slog.Error("error parsing query parameters", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error processing the export request",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := makeValidation(exportRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "query parameters are not valid",
+			Error:   err.Error(),
+		})
+	}
+
+	if exportRequest.Format == "" {
+		exportRequest.Format = "csv"
+	}
+
+	queryParams := exportRequest.NewQueryLocationRequest()
+	filename := fmt.Sprintf("locations-%s.%s", time.Now().UTC().Format("20060102T150405Z"), exportRequest.Format)
+
+	return streamFile(c, exportRequest.VehicleId, export.ContentType(exportRequest.Format), filename, func(w *bufio.Writer, principal *entity.Principal) error {
+		return usecase.ExportLocations(principal, queryParams, exportRequest.Format, w)
+	})
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// LocationsGetHeatmap godoc
+//
+//	@Summary		Get the density of locations by geohash cell
+//	@Description	Bucket the locations matching the filters in a period by their geohash at the requested precision,
+//	@Description	returning the count and average speed of every cell, from the densest, to draw a heatmap layer.
+//	@Tags			Locations
+//	@Param			q	query	dto.HeatmapRequest	true	"filters, period and precision of the cells"
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		200	{object}	dto.HeatmapResponseOut	"heatmap cells"
+//	@Failure		400	{object}	GlobalErrorHandlerResp	"validation error"
+//	@Failure		500	{object}	GlobalErrorHandlerResp	"internal server error"
+//	@Failure		403	{object}	GlobalErrorHandlerResp	"vehicle not allowed"
+//	@Router			/api/v1/locations/heatmap [get]
+func LocationsGetHeatmap(c *fiber.Ctx) error {
+	heatmapRequest := new(dto.HeatmapRequest)
+	if err := c.QueryParser(heatmapRequest); err != nil {
+		slog.Error("error parsing query parameters", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error processing the heatmap request",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := makeValidation(heatmapRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "heatmap parameters are not valid",
+			Error:   err.Error(),
+		})
+	}
+
+	heatmap, err := usecase.GetHeatmap(CurrentPrincipal(c), heatmapRequest)
+	if errors.Is(err, usecase.ErrVehicleNotAllowed) {
+		return vehicleNotAllowed(c, err)
+	}
+	if err != nil {
+		slog.Error("error getting heatmap", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error getting the heatmap",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(heatmap)
+}
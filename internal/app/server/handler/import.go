@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/ingestion"
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/allansbo/goapi/internal/pkg/importfile"
+	"github.com/gofiber/fiber/v2"
+)
+
+// LocationsImport godoc
+//
+//	@Summary		Import location history
+//	@Description	Import the history of locations from a CSV or GPX file sent as the request body.
+//	@Description	Every row is validated with the same rules of the location data and the valid ones are inserted in bulk.
+//	@Description	The CSV must have a header line, the columns are mapped to the fields with the mapping parameter.
+//	@Description	On a dry run the file is only validated. Large files should be imported with the locationctl tool.
+//	@Description	The rows of the vehicles that the client can't access are rejected.
+//	@Tags			Locations
+//	@Accept			text/csv
+//	@Accept			application/gpx+xml
+//	@Produce		json
+//	@Param			q		query	dto.ImportLocationRequest	true	"Import parameters"
+//	@Param			request	body	string						true	"CSV or GPX file"
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		200	{object}	dto.ImportReportOut		"import report"
+//	@Failure		400	{object}	GlobalErrorHandlerResp	"validation error"
+//	@Failure		429	{object}	GlobalErrorHandlerResp	"rate limit or daily quota exceeded"
+//	@Failure		500	{object}	GlobalErrorHandlerResp	"internal server error"
+//	@Router			/api/v1/locations/import [post]
+func LocationsImport(c *fiber.Ctx) error {
+	importRequest := new(dto.ImportLocationRequest)
+	if err := c.QueryParser(importRequest); err != nil {
+		slog.Error("error parsing query parameters", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error processing the import request",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := makeValidation(importRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "query parameters are not valid",
+			Error:   err.Error(),
+		})
+	}
+
+	mapping, err := importfile.ParseMapping(importRequest.Mapping)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "query parameters are not valid",
+			Error:   err.Error(),
+		})
+	}
+
+	reader, err := importfile.NewReader(importRequest.Format, bytes.NewReader(c.Body()), importfile.Options{
+		VehicleId:       importRequest.VehicleId,
+		Mapping:         mapping,
+		TimestampLayout: importRequest.TimestampLayout,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "the file provided can't be read",
+			Error:   err.Error(),
+		})
+	}
+
+	report, err := ingestion.ImportLocations(CurrentPrincipal(c), reader, importRequest.DryRun)
+	err = auditNotRecorded(c, err)
+	var quotaErr *usecase.QuotaExceededError
+	if errors.As(err, &quotaErr) {
+		return TooManyRequests(c, time.Until(quotaErr.Reset), fmt.Sprintf("%s, %d locations were inserted", err, report.Inserted))
+	}
+	if err != nil {
+		slog.Error("error importing locations", "error", err.Error(), "inserted", report.Inserted)
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error importing the file provided, check the locations already inserted",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(report)
+}
@@ -8,28 +8,18 @@ import (
 
 	"github.com/allansbo/goapi/internal/app/server/dto"
 	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/allansbo/goapi/internal/pkg/etag"
+	"github.com/allansbo/goapi/internal/pkg/jsonpatch"
+	"github.com/allansbo/goapi/internal/pkg/validation"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
 func makeValidation(data any) *fiber.Error {
-	dataValidator := &XValidator{validator: validate}
-
-	if errs := dataValidator.Validate(data); len(errs) > 0 && errs[0].Error {
-		errMsgs := make([]string, 0)
-
-		for _, err := range errs {
-			errMsgs = append(errMsgs, fmt.Sprintf(
-				"[%s]: '%v' | Needs to implement '%s'",
-				err.FailedField,
-				err.Value,
-				err.Tag,
-			))
-		}
-
+	if err := validation.Validate(data); err != nil {
 		return &fiber.Error{
 			Code:    fiber.ErrBadRequest.Code,
-			Message: strings.Join(errMsgs, " and "),
+			Message: err.Error(),
 		}
 	}
 
@@ -43,10 +33,15 @@ func makeValidation(data any) *fiber.Error {
 //	@Tags			Locations
 //	@Accept			json
 //	@Produce		json
-//	@Param			request	body		dto.LocationInApp				true	"Request of creating location object"
-//	@Success		201		{object}	dto.LocationCreatedResponseOut	"document created"
-//	@Failure		400		{object}	GlobalErrorHandlerResp			"validation error"
-//	@Failure		500		{object}	GlobalErrorHandlerResp			"internal server error"
+//	@Param			request	body	dto.LocationInApp	true	"Request of creating location object"
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		201	{object}	dto.LocationCreatedResponseOut	"document created"
+//	@Failure		400	{object}	GlobalErrorHandlerResp			"validation error"
+//	@Failure		422	{object}	GlobalErrorHandlerResp			"location rejected by the anomaly checks"
+//	@Failure		500	{object}	GlobalErrorHandlerResp			"internal server error"
+//	@Failure		403	{object}	GlobalErrorHandlerResp			"vehicle not allowed"
+//	@Failure		429	{object}	GlobalErrorHandlerResp			"rate limit or daily quota exceeded"
 //	@Router			/api/v1/locations [post]
 func LocationsAddOne(c *fiber.Ctx) error {
 	locationDataIn := new(dto.LocationInApp)
@@ -67,7 +62,23 @@ func LocationsAddOne(c *fiber.Ctx) error {
 		})
 	}
 
-	locationDataOut, err := usecase.SaveLocation(locationDataIn)
+	locationDataOut, err := usecase.SaveLocation(CurrentPrincipal(c), locationDataIn)
+	err = auditNotRecorded(c, err)
+	if errors.Is(err, usecase.ErrVehicleNotAllowed) {
+		return vehicleNotAllowed(c, err)
+	}
+	var quotaErr *usecase.QuotaExceededError
+	if errors.As(err, &quotaErr) {
+		return quotaExceeded(c, quotaErr)
+	}
+	var rejectedErr *usecase.LocationRejectedError
+	if errors.As(err, &rejectedErr) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "the location data provided was rejected",
+			Error:   err.Error(),
+		})
+	}
 	if err != nil {
 		slog.Error("error saving location", "error", err.Error())
 		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
@@ -84,17 +95,28 @@ func LocationsAddOne(c *fiber.Ctx) error {
 //
 //	@Summary		Get location data
 //	@Description	Get location data from database based on a document_id
+//	@Description	The deleted locations are found only with include_deleted, for the admin role.
+//	@Description	The ETag header has the version of the location, and 304 is returned when it matches If-None-Match.
 //	@Tags			Locations
-//	@Param			id	path	string	true	"id from document"
+//	@Param			id				path	string	true	"id from document"
+//	@Param			include_deleted	query	bool	false	"include the deleted location"
+//	@Param			If-None-Match	header	string	false	"ETag of the cached version"
 //	@Produce		json
-//	@Success		200	{object}	dto.LocationOutApp				"located document"
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		200	{object}	dto.LocationOutApp	"located document"
+//	@Success		304	"document not modified"
 //	@Success		404	{object}	dto.DefaultResponseMessageOut	"document not found"
 //	@Failure		500	{object}	GlobalErrorHandlerResp			"internal server error"
+//	@Failure		403	{object}	GlobalErrorHandlerResp			"vehicle or deleted locations not allowed"
 //	@Router			/api/v1/locations/{id} [get]
 func LocationsGetOne(c *fiber.Ctx) error {
 	locationID := c.Params("id")
 
-	locationDataOut, err := usecase.GetLocationById(locationID)
+	locationDataOut, err := usecase.GetLocationById(CurrentPrincipal(c), locationID, c.QueryBool("include_deleted"))
+	if errors.Is(err, usecase.ErrVehicleNotAllowed) || errors.Is(err, usecase.ErrDeletedNotAllowed) {
+		return vehicleNotAllowed(c, err)
+	}
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		return c.Status(fiber.StatusNotFound).JSON(dto.DefaultResponseMessageOut{
 			Message: fmt.Sprintf("the location ID %s does not exist", locationID),
@@ -108,6 +130,12 @@ func LocationsGetOne(c *fiber.Ctx) error {
 		})
 	}
 
+	tag := etag.Format(locationDataOut.Version)
+	c.Set(fiber.HeaderETag, tag)
+	if ifNoneMatch := c.Get(fiber.HeaderIfNoneMatch); ifNoneMatch != "" && etag.Match(ifNoneMatch, tag, true) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
 	return c.JSON(locationDataOut)
 }
 
@@ -115,13 +143,17 @@ func LocationsGetOne(c *fiber.Ctx) error {
 //
 //	@Summary		Get all locations data
 //	@Description	Get all locations data from database based on query parameters
+//	@Description	The deleted locations are listed only with include_deleted, for the admin role.
 //	@Tags			Locations
 //	@Produce		json
-//	@Param			q	query		dto.QueryLocationRequest		false	"Query parameters for filtering locations"
+//	@Param			q	query	dto.QueryLocationRequest	false	"Query parameters for filtering locations"
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
 //	@Success		200	{object}	dto.QueryLocationResponse		"located documents"
 //	@Failure		400	{object}	GlobalErrorHandlerResp			"validation error"
 //	@Failure		404	{object}	dto.DefaultResponseMessageOut	"no locations found"
 //	@Failure		500	{object}	GlobalErrorHandlerResp			"internal server error"
+//	@Failure		403	{object}	GlobalErrorHandlerResp			"vehicle or deleted locations not allowed"
 //	@Router			/api/v1/locations [get]
 func LocationsGetAll(c *fiber.Ctx) error {
 	queryParams := new(dto.QueryLocationRequest)
@@ -144,7 +176,10 @@ func LocationsGetAll(c *fiber.Ctx) error {
 		})
 	}
 
-	locationsDataOut, err := usecase.GetAllLocations(queryParams)
+	locationsDataOut, err := usecase.GetAllLocations(CurrentPrincipal(c), queryParams)
+	if errors.Is(err, usecase.ErrVehicleNotAllowed) || errors.Is(err, usecase.ErrDeletedNotAllowed) {
+		return vehicleNotAllowed(c, err)
+	}
 	if err != nil {
 		slog.Error("error getting all locations", "error", err.Error())
 		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
@@ -167,14 +202,21 @@ func LocationsGetAll(c *fiber.Ctx) error {
 //
 //	@Summary		Update location data
 //	@Description	Update location data into database based on a document_id
+//	@Description	A change of the status is recorded in the status transitions of the vehicle.
+//	@Description	With If-Match, the location is updated only on the version of the ETag, and the ETag of the new one is returned.
 //	@Tags			Locations
 //	@Param			id	path	string	true	"id from document"
 //	@Produce		json
-//	@Param			request	body		dto.LocationInApp				true	"Request of updating location object"
-//	@Success		200		{object}	dto.DefaultResponseMessageOut	"updated document"
-//	@Failure		400		{object}	GlobalErrorHandlerResp			"validation error"
-//	@Success		404		{object}	dto.DefaultResponseMessageOut	"document not found"
-//	@Failure		500		{object}	GlobalErrorHandlerResp			"internal server error"
+//	@Param			request		body	dto.LocationInApp	true	"Request of updating location object"
+//	@Param			If-Match	header	string				false	"ETag of the expected version"
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		200	{object}	dto.DefaultResponseMessageOut	"updated document"
+//	@Failure		400	{object}	GlobalErrorHandlerResp			"validation error"
+//	@Success		404	{object}	dto.DefaultResponseMessageOut	"document not found"
+//	@Failure		412	{object}	GlobalErrorHandlerResp			"version mismatch"
+//	@Failure		500	{object}	GlobalErrorHandlerResp			"internal server error"
+//	@Failure		403	{object}	GlobalErrorHandlerResp			"vehicle not allowed"
 //	@Router			/api/v1/locations/{id} [put]
 func LocationsUpdateOne(c *fiber.Ctx) error {
 	locationID := c.Params("id")
@@ -196,7 +238,19 @@ func LocationsUpdateOne(c *fiber.Ctx) error {
 		})
 	}
 
-	locationUpdated, err := usecase.UpdateLocation(locationID, locationDataIn)
+	locationUpdated, err := usecase.UpdateLocation(CurrentPrincipal(c), locationID, locationDataIn, c.Get(fiber.HeaderIfMatch))
+	err = auditNotRecorded(c, err)
+	if errors.Is(err, usecase.ErrVehicleNotAllowed) {
+		return vehicleNotAllowed(c, err)
+	}
+	if errors.Is(err, usecase.ErrVersionMismatch) {
+		return versionMismatch(c, err)
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.Status(fiber.StatusNotFound).JSON(dto.DefaultResponseMessageOut{
+			Message: fmt.Sprintf("the location ID %s does not exist", locationID),
+		})
+	}
 	if err != nil {
 		slog.Error("error updating location", "error", err.Error(), "locationID", locationID, "locationData", locationDataIn)
 		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
@@ -206,33 +260,137 @@ func LocationsUpdateOne(c *fiber.Ctx) error {
 		})
 	}
 
-	if locationUpdated {
-		return c.JSON(dto.DefaultResponseMessageOut{
-			Message: fmt.Sprintf("the location ID %s has been updated", locationID),
+	c.Set(fiber.HeaderETag, etag.Format(locationUpdated.Version))
+	return c.JSON(dto.DefaultResponseMessageOut{
+		Message: fmt.Sprintf("the location ID %s has been updated", locationID),
+	})
+}
+
+// LocationsPatchOne godoc
+//
+//	@Summary		Patch location data
+//	@Description	Change only some fields of a location based on a document_id, returning the patched location.
+//	@Description	The body is a JSON merge patch (RFC 7396), with the application/merge-patch+json or application/json content type,
+//	@Description	or a JSON patch (RFC 6902), with the application/json-patch+json content type, of the fields of the update request.
+//	@Description	The timestamp is kept, the patched location is validated and only the changed fields are saved.
+//	@Description	A change of the status is recorded in the status transitions of the vehicle.
+//	@Description	With If-Match, the location is patched only on the version of the ETag, and the ETag of the new one is returned.
+//	@Tags			Locations
+//	@Accept			application/merge-patch+json
+//	@Accept			application/json-patch+json
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	string	true	"id from document"
+//	@Param			request		body	object	true	"Merge patch of the location fields, or list of JSON patch operations"
+//	@Param			If-Match	header	string	false	"ETag of the expected version"
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		200	{object}	dto.LocationOutApp				"patched document"
+//	@Failure		400	{object}	GlobalErrorHandlerResp			"malformed patch"
+//	@Failure		403	{object}	GlobalErrorHandlerResp			"vehicle not allowed"
+//	@Success		404	{object}	dto.DefaultResponseMessageOut	"document not found"
+//	@Failure		409	{object}	GlobalErrorHandlerResp			"patch can't be applied"
+//	@Failure		412	{object}	GlobalErrorHandlerResp			"version mismatch"
+//	@Failure		422	{object}	GlobalErrorHandlerResp			"patched location not valid"
+//	@Failure		500	{object}	GlobalErrorHandlerResp			"internal server error"
+//	@Router			/api/v1/locations/{id} [patch]
+func LocationsPatchOne(c *fiber.Ctx) error {
+	locationID := c.Params("id")
+
+	var patch jsonpatch.Patch
+	var err error
+	if ContentType(c) == jsonpatch.JSONPatchMediaType {
+		patch, err = jsonpatch.DecodeOperations(c.Body())
+	} else {
+		patch, err = jsonpatch.DecodeMergePatch(c.Body())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error parsing the patch provided",
+			Error:   err.Error(),
 		})
 	}
 
-	return c.Status(fiber.StatusNotFound).JSON(dto.DefaultResponseMessageOut{
-		Message: fmt.Sprintf("the location ID %s does not exist", locationID),
-	})
+	locationPatched, err := usecase.PatchLocation(CurrentPrincipal(c), locationID, patch, c.Get(fiber.HeaderIfMatch))
+	err = auditNotRecorded(c, err)
+	if errors.Is(err, usecase.ErrVehicleNotAllowed) {
+		return vehicleNotAllowed(c, err)
+	}
+	if errors.Is(err, usecase.ErrVersionMismatch) {
+		return versionMismatch(c, err)
+	}
+	if errors.Is(err, usecase.ErrPatchNotApplied) {
+		return c.Status(fiber.StatusConflict).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: fmt.Sprintf("the patch can't be applied to the location ID %s", locationID),
+			Error:   err.Error(),
+		})
+	}
+	if errors.Is(err, usecase.ErrInvalidPatchedLocation) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error validating the patched location",
+			Error:   err.Error(),
+		})
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.Status(fiber.StatusNotFound).JSON(dto.DefaultResponseMessageOut{
+			Message: fmt.Sprintf("the location ID %s does not exist", locationID),
+		})
+	}
+	if err != nil {
+		slog.Error("error patching location", "error", err.Error(), "locationID", locationID)
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error patching the location data provided",
+			Error:   err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderETag, etag.Format(locationPatched.Version))
+	return c.JSON(locationPatched)
+}
+
+// ContentType returns the media type of the request body, in lowercase and without its parameters.
+func ContentType(c *fiber.Ctx) string {
+	return strings.ToLower(strings.TrimSpace(strings.Split(c.Get(fiber.HeaderContentType), ";")[0]))
 }
 
 // LocationsDeleteOne godoc
 //
 //	@Summary		Delete location data
 //	@Description	Delete location data from database based on a document_id
+//	@Description	The location is kept as deleted, and can be restored, until it is purged after the retention period.
+//	@Description	With If-Match, the location is deleted only on the version of the ETag.
 //	@Tags			Locations
-//	@Param			id	path	string	true	"id from document"
+//	@Param			id			path	string	true	"id from document"
+//	@Param			If-Match	header	string	false	"ETag of the expected version"
 //	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
 //	@Success		200	{object}	dto.DefaultResponseMessageOut	"deleted document"
 //	@Success		404	{object}	dto.DefaultResponseMessageOut	"document not found"
+//	@Failure		412	{object}	GlobalErrorHandlerResp			"version mismatch"
 //	@Success		500	{object}	GlobalErrorHandlerResp			"internal server error"
+//	@Failure		403	{object}	GlobalErrorHandlerResp			"vehicle not allowed"
 //	@Router			/api/v1/locations/{id} [delete]
 func LocationsDeleteOne(c *fiber.Ctx) error {
 	locationID := c.Params("id")
 
-	locationDeleted, err := usecase.DeleteLocation(locationID)
-	if err != nil {
+	err := usecase.DeleteLocation(CurrentPrincipal(c), locationID, c.Get(fiber.HeaderIfMatch))
+	err = auditNotRecorded(c, err)
+	if errors.Is(err, usecase.ErrVehicleNotAllowed) {
+		return vehicleNotAllowed(c, err)
+	}
+	if errors.Is(err, usecase.ErrVersionMismatch) {
+		return versionMismatch(c, err)
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.Status(fiber.StatusNotFound).JSON(dto.DefaultResponseMessageOut{
+			Message: fmt.Sprintf("the location ID %s does not exist", locationID),
+		})
+	} else if err != nil {
 		slog.Error("error deleting location", "error", err.Error())
 		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
 			Success: false,
@@ -241,13 +399,63 @@ func LocationsDeleteOne(c *fiber.Ctx) error {
 		})
 	}
 
-	if locationDeleted {
-		return c.JSON(dto.DefaultResponseMessageOut{
-			Message: fmt.Sprintf("the location ID %s has been deleted", locationID),
+	return c.JSON(dto.DefaultResponseMessageOut{
+		Message: fmt.Sprintf("the location ID %s has been deleted", locationID),
+	})
+}
+
+// LocationsRestoreOne godoc
+//
+//	@Summary		Restore location data
+//	@Description	Restore a deleted location that was not purged yet, based on a document_id. It requires the admin role.
+//	@Tags			Locations
+//	@Param			id	path	string	true	"id from document"
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		200	{object}	dto.LocationOutApp				"restored document"
+//	@Failure		403	{object}	GlobalErrorHandlerResp			"vehicle or deleted locations not allowed"
+//	@Failure		404	{object}	dto.DefaultResponseMessageOut	"document not found"
+//	@Failure		409	{object}	GlobalErrorHandlerResp			"document not deleted"
+//	@Failure		500	{object}	GlobalErrorHandlerResp			"internal server error"
+//	@Router			/api/v1/locations/{id}/restore [post]
+func LocationsRestoreOne(c *fiber.Ctx) error {
+	locationID := c.Params("id")
+
+	locationDataOut, err := usecase.RestoreLocation(CurrentPrincipal(c), locationID)
+	err = auditNotRecorded(c, err)
+	if errors.Is(err, usecase.ErrVehicleNotAllowed) || errors.Is(err, usecase.ErrDeletedNotAllowed) {
+		return vehicleNotAllowed(c, err)
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return c.Status(fiber.StatusNotFound).JSON(dto.DefaultResponseMessageOut{
+			Message: fmt.Sprintf("the location ID %s does not exist", locationID),
+		})
+	} else if errors.Is(err, usecase.ErrLocationNotDeleted) {
+		return c.Status(fiber.StatusConflict).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: fmt.Sprintf("the location ID %s can't be restored", locationID),
+			Error:   err.Error(),
+		})
+	} else if err != nil {
+		slog.Error("error restoring location", "error", err.Error(), "locationID", locationID)
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: fmt.Sprintf("there is an error restoring the location data by provided id %s", locationID),
+			Error:   err.Error(),
 		})
 	}
 
-	return c.Status(fiber.StatusNotFound).JSON(dto.DefaultResponseMessageOut{
-		Message: fmt.Sprintf("the location ID %s does not exist", locationID),
+	c.Set(fiber.HeaderETag, etag.Format(locationDataOut.Version))
+	return c.JSON(locationDataOut)
+}
+
+// versionMismatch answers the changes of the locations that are not on the version of the If-Match header,
+// or that were changed by another request during the change.
+func versionMismatch(c *fiber.Ctx, err error) error {
+	return c.Status(fiber.StatusPreconditionFailed).JSON(GlobalErrorHandlerResp{
+		Success: false,
+		Message: "precondition failed",
+		Error:   err.Error(),
 	})
 }
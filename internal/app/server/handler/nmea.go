@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/allansbo/goapi/internal/pkg/nmea"
+	"github.com/gofiber/fiber/v2"
+)
+
+// LocationsAddNMEA godoc
+//
+//	@Summary		Insert location data from NMEA sentences
+//	@Description	Insert the positions of the $GPRMC and $GPGGA sentences forwarded by a vehicle.
+//	@Description	The checksums are verified, the RMC and GGA sentences of the same epoch are merged
+//	@Description	and the status is derived from the speed, so the GGA fixes without the RMC sentence of their epoch are rejected.
+//	@Description	The rejected sentences are reported with their line, starting at 1. The positions over the daily quota of the tenant are rejected.
+//	@Tags			Locations
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body	dto.NMEALocationInApp	true	"NMEA sentences of a vehicle"
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		201	{object}	dto.NMEALocationResponseOut	"documents created"
+//	@Failure		400	{object}	GlobalErrorHandlerResp		"validation error"
+//	@Failure		422	{object}	dto.NMEALocationResponseOut	"no valid positions"
+//	@Failure		500	{object}	GlobalErrorHandlerResp		"internal server error"
+//	@Failure		403	{object}	GlobalErrorHandlerResp		"vehicle not allowed"
+//	@Failure		429	{object}	GlobalErrorHandlerResp		"rate limit or daily quota exceeded"
+//	@Router			/api/v1/locations/nmea [post]
+func LocationsAddNMEA(c *fiber.Ctx) error {
+	nmeaDataIn := new(dto.NMEALocationInApp)
+	if err := c.BodyParser(nmeaDataIn); err != nil {
+		slog.Error("error parsing nmeaDataIn", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error processing the nmea data provided",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := makeValidation(nmeaDataIn); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error validating the nmea data provided",
+			Error:   err.Error(),
+		})
+	}
+
+	principal := CurrentPrincipal(c)
+	if err := usecase.CheckVehicleAccess(principal, nmeaDataIn.VehicleId); err != nil {
+		return vehicleNotAllowed(c, err)
+	}
+
+	fixes, errs := nmea.Decode(nmeaDataIn.Sentences, time.Now())
+
+	response := &dto.NMEALocationResponseOut{DocumentIDs: make([]string, 0, len(fixes))}
+	for _, err := range errs {
+		response.Rejected = append(response.Rejected, newRejectedSentence(err.Sentence, err.Line, err.Err))
+	}
+
+	for _, fix := range fixes {
+		if err := fix.Check(); err != nil {
+			response.Rejected = append(response.Rejected, newRejectedSentence(fix.Sentence, fix.Line, err))
+			continue
+		}
+
+		locationDataIn := newLocationFromFix(nmeaDataIn.VehicleId, fix)
+		if err := makeValidation(locationDataIn); err != nil {
+			response.Rejected = append(response.Rejected, newRejectedSentence(fix.Sentence, fix.Line, err))
+			continue
+		}
+
+		locationDataOut, err := usecase.SaveLocationAt(principal, locationDataIn, fix.Timestamp, entity.SourceNMEA)
+		err = auditNotRecorded(c, err)
+		var quotaErr *usecase.QuotaExceededError
+		if errors.As(err, &quotaErr) && len(response.DocumentIDs) == 0 {
+			return quotaExceeded(c, quotaErr)
+		}
+		var rejectedErr *usecase.LocationRejectedError
+		if errors.As(err, &rejectedErr) || quotaErr != nil {
+			response.Rejected = append(response.Rejected, newRejectedSentence(fix.Sentence, fix.Line, err))
+			continue
+		}
+		if err != nil {
+			slog.Error("error saving nmea location", "error", err.Error(), "vehicleID", nmeaDataIn.VehicleId)
+			return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+				Success: false,
+				Message: "there is an error saving the nmea data provided",
+				Error:   err.Error(),
+			})
+		}
+		response.DocumentIDs = append(response.DocumentIDs, locationDataOut.ID)
+	}
+
+	if len(response.DocumentIDs) == 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(response)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+func newRejectedSentence(sentence string, line int, err error) *dto.NMEARejectedSentenceOut {
+	return &dto.NMEARejectedSentenceOut{Line: line, Sentence: sentence, Error: err.Error()}
+}
+
+// newLocationFromFix maps a checked NMEA fix into the location data, deriving the status from the speed.
+func newLocationFromFix(vehicleID string, fix *nmea.Fix) *dto.LocationInApp {
+	speed := int(math.Round(fix.Speed))
+
+	attributes := map[string]any{"course": fix.Course}
+	if fix.Satellites > 0 {
+		attributes["satellites"] = fix.Satellites
+		attributes["altitude"] = fix.Altitude
+	}
+
+	return &dto.LocationInApp{
+		VehicleId:  vehicleID,
+		Latitude:   strconv.FormatFloat(fix.Latitude, 'f', 6, 64),
+		Longitude:  strconv.FormatFloat(fix.Longitude, 'f', 6, 64),
+		Status:     entity.StatusFromSpeed(speed),
+		Speed:      speed,
+		Attributes: attributes,
+	}
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// VehiclesGetPlayback godoc
+//
+//	@Summary		Replay the movement of a vehicle
+//	@Description	Get the positions of a vehicle at every interval of a period, linearly interpolated between its locations,
+//	@Description	with the heading of the movement. The positions where the vehicle was offline or without locations for longer
+//	@Description	than max_gap seconds are marked as gaps. A period can have at most 10000 positions.
+//	@Tags			Vehicles
+//	@Param			id			path	string	true	"vehicle id"
+//	@Param			from		query	string	true	"start of the period, RFC 3339"
+//	@Param			to			query	string	true	"end of the period, RFC 3339"
+//	@Param			interval	query	int		true	"time step in seconds"
+//	@Param			max_gap		query	int		false	"time in seconds without locations after which the vehicle is in a gap"	default(600)
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		200	{object}	dto.PlaybackResponseOut	"positions of the vehicle"
+//	@Failure		400	{object}	GlobalErrorHandlerResp	"validation error"
+//	@Failure		500	{object}	GlobalErrorHandlerResp	"internal server error"
+//	@Failure		403	{object}	GlobalErrorHandlerResp	"vehicle not allowed"
+//	@Router			/api/v1/vehicles/{id}/playback [get]
+func VehiclesGetPlayback(c *fiber.Ctx) error {
+	playbackRequest := new(dto.PlaybackRequest)
+	if err := c.ParamsParser(playbackRequest); err != nil {
+		slog.Error("error parsing path parameters", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error processing the playback request",
+			Error:   err.Error(),
+		})
+	}
+	if err := c.QueryParser(playbackRequest); err != nil {
+		slog.Error("error parsing query parameters", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error processing the playback request",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := makeValidation(playbackRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "playback parameters are not valid",
+			Error:   err.Error(),
+		})
+	}
+
+	playback, err := usecase.GetPlayback(CurrentPrincipal(c), playbackRequest)
+	if errors.Is(err, usecase.ErrVehicleNotAllowed) {
+		return vehicleNotAllowed(c, err)
+	}
+	if errors.Is(err, usecase.ErrInvalidPlayback) {
+		return c.Status(fiber.StatusBadRequest).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "playback parameters are not valid",
+			Error:   err.Error(),
+		})
+	}
+	if err != nil {
+		slog.Error("error getting playback", "error", err.Error(), "vehicleID", playbackRequest.VehicleId)
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error getting the playback of the vehicle",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(playback)
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// PrincipalLocal is the key of the authenticated *entity.Principal in the locals of the request.
+const PrincipalLocal = "principal"
+
+// RequestIDLocal is the key of the ID of the request in its locals.
+const RequestIDLocal = "requestid"
+
+// CurrentPrincipal returns the client authenticated in the request, with its roles, the anonymous
+// principal when the authentication is disabled, or nil out of the API routes.
+func CurrentPrincipal(c *fiber.Ctx) *entity.Principal {
+	principal, _ := c.Locals(PrincipalLocal).(*entity.Principal)
+	return principal
+}
+
+// vehicleNotAllowed answers the requests for the vehicles that the client is not allowed to access.
+func vehicleNotAllowed(c *fiber.Ctx, err error) error {
+	return c.Status(fiber.StatusForbidden).JSON(GlobalErrorHandlerResp{
+		Success: false,
+		Message: "permission denied",
+		Error:   err.Error(),
+	})
+}
+
+// authenticationRequired answers the requests that reach a handler without a principal.
+func authenticationRequired(c *fiber.Ctx, err error) error {
+	return c.Status(fiber.StatusUnauthorized).JSON(GlobalErrorHandlerResp{
+		Success: false,
+		Message: "authentication required",
+		Error:   err.Error(),
+	})
+}
+
+// TooManyRequests answers the requests over the rate limits or the quotas with the seconds to wait
+// in the Retry-After header.
+func TooManyRequests(c *fiber.Ctx, wait time.Duration, reason string) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	return c.Status(fiber.StatusTooManyRequests).JSON(GlobalErrorHandlerResp{
+		Success: false,
+		Message: "too many requests",
+		Error:   reason,
+	})
+}
+
+// quotaExceeded answers the requests with more locations than the daily quota of the tenant allows.
+func quotaExceeded(c *fiber.Ctx, err *usecase.QuotaExceededError) error {
+	return TooManyRequests(c, time.Until(err.Reset), err.Error())
+}
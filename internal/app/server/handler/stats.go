@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// StatsGetDaily godoc
+//
+//	@Summary		Get the daily statistics of the vehicles
+//	@Description	Get the per-vehicle, per-day statistics computed by the roll-up job, with the point count, distance in meters,
+//	@Description	max and average speed, moving, stopped and offline durations in seconds and the first and last seen times.
+//	@Description	The days are in UTC.
+//	@Tags			Stats
+//	@Param			q	query	dto.DailyStatsRequest	true	"vehicle and period of days"
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		200	{object}	dto.DailyStatsResponseOut	"daily statistics"
+//	@Failure		400	{object}	GlobalErrorHandlerResp		"validation error"
+//	@Failure		500	{object}	GlobalErrorHandlerResp		"internal server error"
+//	@Failure		403	{object}	GlobalErrorHandlerResp		"vehicle not allowed"
+//	@Router			/api/v1/stats/daily [get]
+func StatsGetDaily(c *fiber.Ctx) error {
+	statsRequest := new(dto.DailyStatsRequest)
+	if err := c.QueryParser(statsRequest); err != nil {
+		slog.Error("error parsing query parameters", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error processing the stats request",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := makeValidation(statsRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "stats parameters are not valid",
+			Error:   err.Error(),
+		})
+	}
+
+	stats, err := usecase.GetDailyStats(CurrentPrincipal(c), statsRequest)
+	if errors.Is(err, usecase.ErrVehicleNotAllowed) {
+		return vehicleNotAllowed(c, err)
+	}
+	if err != nil {
+		slog.Error("error getting daily stats", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error getting the daily stats",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(stats)
+}
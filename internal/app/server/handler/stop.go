@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// VehiclesGetStops godoc
+//
+//	@Summary		Get the stops of a vehicle
+//	@Description	Get where and how long a vehicle stopped in a period. A stop is a sequence of locations with the stopped status
+//	@Description	or a low speed within a radius, and the idle duration is the time stopped with the ignition on.
+//	@Description	The durations are in seconds.
+//	@Tags			Vehicles
+//	@Param			id				path	string	true	"vehicle id"
+//	@Param			from			query	string	true	"start of the period, RFC 3339"
+//	@Param			to				query	string	true	"end of the period, RFC 3339"
+//	@Param			radius			query	number	false	"distance in meters that the vehicle can drift while stopped"	default(50)
+//	@Param			min_duration	query	int		false	"minimum duration of a stop in seconds"							default(120)
+//	@Param			max_speed		query	int		false	"speed in km/h up to which the vehicle is considered stopped"	default(5)
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		200	{object}	dto.StopReportOut		"stops of the vehicle"
+//	@Failure		400	{object}	GlobalErrorHandlerResp	"validation error"
+//	@Failure		500	{object}	GlobalErrorHandlerResp	"internal server error"
+//	@Failure		403	{object}	GlobalErrorHandlerResp	"vehicle not allowed"
+//	@Router			/api/v1/vehicles/{id}/stops [get]
+func VehiclesGetStops(c *fiber.Ctx) error {
+	stopRequest := new(dto.StopReportRequest)
+	if err := c.ParamsParser(stopRequest); err != nil {
+		slog.Error("error parsing path parameters", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error processing the stops request",
+			Error:   err.Error(),
+		})
+	}
+	if err := c.QueryParser(stopRequest); err != nil {
+		slog.Error("error parsing query parameters", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error processing the stops request",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := makeValidation(stopRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "stops parameters are not valid",
+			Error:   err.Error(),
+		})
+	}
+
+	if stopRequest.Radius == 0 {
+		stopRequest.Radius = usecase.DefaultStopRadius
+	}
+	if stopRequest.MinDuration == 0 {
+		stopRequest.MinDuration = usecase.DefaultStopMinDuration
+	}
+	if stopRequest.MaxSpeed == 0 {
+		stopRequest.MaxSpeed = usecase.DefaultStopMaxSpeed
+	}
+
+	report, err := usecase.GetStopReport(CurrentPrincipal(c), stopRequest)
+	if errors.Is(err, usecase.ErrVehicleNotAllowed) {
+		return vehicleNotAllowed(c, err)
+	}
+	if err != nil {
+		slog.Error("error getting stops", "error", err.Error(), "vehicleID", stopRequest.VehicleId)
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error getting the stops of the vehicle",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(report)
+}
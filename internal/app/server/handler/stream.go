@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// streamFile answers with a file of the vehicle, or of all the vehicles allowed with an empty ID,
+// written by write as an attachment. The access is checked first, since the HTTP status is sent
+// with the first bytes of the file and can't be changed once it starts. The file is written after
+// the handler returns, so write can't use anything from the fiber context.
+func streamFile(c *fiber.Ctx, vehicleID, contentType, filename string, write func(w *bufio.Writer, principal *entity.Principal) error) error {
+	principal := CurrentPrincipal(c)
+	if err := usecase.CheckVehicleAccess(principal, vehicleID); err != nil {
+		return vehicleNotAllowed(c, err)
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		err := write(w, principal)
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			slog.Error("error streaming file", "error", err.Error(), "filename", filename)
+		}
+	})
+
+	return nil
+}
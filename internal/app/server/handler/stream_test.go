@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestStreamFile(t *testing.T) {
+	app := fiber.New()
+	app.Get("/:id", func(c *fiber.Ctx) error {
+		c.Locals(PrincipalLocal, &entity.Principal{Method: entity.AuthAPIKey, Roles: []string{entity.ScopeRead}, Vehicles: []string{"ABC1234"}})
+		return streamFile(c, c.Params("id"), "text/csv", "track.csv", func(w *bufio.Writer, principal *entity.Principal) error {
+			_, err := w.WriteString("vehicle_id\n" + principal.Vehicles[0] + "\n")
+			return err
+		})
+	})
+
+	tests := []struct {
+		name            string
+		vehicleID       string
+		wantStatus      int
+		wantDisposition string
+		wantBody        string
+	}{
+		{name: "allowed vehicle", vehicleID: "ABC1234", wantStatus: http.StatusOK, wantDisposition: `attachment; filename="track.csv"`, wantBody: "vehicle_id\nABC1234\n"},
+		{name: "other vehicle", vehicleID: "DEF5678", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := app.Test(httptest.NewRequest(http.MethodGet, "/"+tt.vehicleID, nil))
+			if err != nil {
+				t.Fatalf("Test() error = %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", response.StatusCode, tt.wantStatus)
+			}
+			if got := response.Header.Get(fiber.HeaderContentDisposition); got != tt.wantDisposition {
+				t.Errorf("Content-Disposition = %q, want %q", got, tt.wantDisposition)
+			}
+			if tt.wantBody == "" {
+				return
+			}
+			body, err := io.ReadAll(response.Body)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
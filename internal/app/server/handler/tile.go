@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/allansbo/goapi/internal/pkg/mvt"
+	"github.com/gofiber/fiber/v2"
+)
+
+// TilesGetOne godoc
+//
+//	@Summary		Get a vector tile of the locations
+//	@Description	Encode the locations inside a tile of the XYZ scheme as a Mapbox Vector Tile, with a layer named locations.
+//	@Description	In the latest mode only the last position of every vehicle is drawn, in the history mode all the locations
+//	@Description	in the period, which is required and at most 7 days long. Up to the cluster zoom, and past 10000 points deeper,
+//	@Description	the close points are merged into points with the cluster and point_count properties.
+//	@Tags			Tiles
+//	@Param			z				path	int		true	"zoom level"
+//	@Param			x				path	int		true	"tile column"
+//	@Param			y				path	int		true	"tile row"
+//	@Param			vehicle_id		query	string	false	"filter by vehicle id"
+//	@Param			status			query	string	false	"filter by status"	Enums(moving, stopped, offline)
+//	@Param			from			query	string	false	"start of the period, RFC 3339, required by the history mode"
+//	@Param			to				query	string	false	"end of the period, RFC 3339, required by the history mode"
+//	@Param			mode			query	string	false	"positions drawn"									Enums(latest, history)	default(latest)
+//	@Param			cluster_zoom	query	int		false	"deepest zoom level where the points are clustered"	default(12)
+//	@Produce		application/vnd.mapbox-vector-tile
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		200	{file}		file					"vector tile"
+//	@Failure		400	{object}	GlobalErrorHandlerResp	"validation error"
+//	@Failure		500	{object}	GlobalErrorHandlerResp	"internal server error"
+//	@Failure		403	{object}	GlobalErrorHandlerResp	"vehicle not allowed"
+//	@Router			/api/v1/tiles/{z}/{x}/{y}.mvt [get]
+func TilesGetOne(c *fiber.Ctx) error {
+	tileRequest := new(dto.TileRequest)
+	if err := c.ParamsParser(tileRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "tile parameters are not valid",
+			Error:   err.Error(),
+		})
+	}
+	if err := c.QueryParser(tileRequest); err != nil {
+		slog.Error("error parsing query parameters", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error processing the tile request",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := makeValidation(tileRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "tile parameters are not valid",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := (mvt.Tile{Z: tileRequest.Z, X: tileRequest.X, Y: tileRequest.Y}).Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "tile parameters are not valid",
+			Error:   err.Error(),
+		})
+	}
+
+	tile, err := usecase.GetTile(CurrentPrincipal(c), tileRequest)
+	if errors.Is(err, usecase.ErrVehicleNotAllowed) {
+		return vehicleNotAllowed(c, err)
+	}
+	if errors.Is(err, usecase.ErrInvalidTileHistory) {
+		return c.Status(fiber.StatusBadRequest).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "tile parameters are not valid",
+			Error:   err.Error(),
+		})
+	}
+	if err != nil {
+		slog.Error("error getting tile", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error getting the tile",
+			Error:   err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, mvt.ContentType)
+	return c.Status(fiber.StatusOK).Send(tile)
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/allansbo/goapi/internal/pkg/trackfile"
+	"github.com/gofiber/fiber/v2"
+)
+
+// VehiclesGetTrack godoc
+//
+//	@Summary		Export the track of a vehicle
+//	@Description	Stream the locations of a vehicle in a period, ordered by timestamp, as a GPX, KML or GeoJSON file
+//	@Description	with the speed and status as attributes of the points. With simplify, the points not needed to draw the track
+//	@Description	within the tolerance are removed by the Douglas-Peucker algorithm.
+//	@Tags			Vehicles
+//	@Param			id			path	string	true	"vehicle id"
+//	@Param			from		query	string	true	"start of the period, RFC 3339"
+//	@Param			to			query	string	true	"end of the period, RFC 3339"
+//	@Param			format		query	string	false	"file format"	Enums(gpx, kml, geojson)	default(gpx)
+//	@Param			simplify	query	number	false	"tolerance in meters to simplify the track, keeping the status changes and stops"
+//	@Produce		application/gpx+xml
+//	@Produce		application/vnd.google-earth.kml+xml
+//	@Produce		application/geo+json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		200	{file}		file					"track file"
+//	@Failure		400	{object}	GlobalErrorHandlerResp	"validation error"
+//	@Failure		403	{object}	GlobalErrorHandlerResp	"vehicle not allowed"
+//	@Router			/api/v1/vehicles/{id}/track [get]
+func VehiclesGetTrack(c *fiber.Ctx) error {
+	trackRequest := new(dto.TrackRequest)
+	if err := c.ParamsParser(trackRequest); err != nil {
+		slog.Error("error parsing path parameters", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error processing the track request",
+			Error:   err.Error(),
+		})
+	}
+	if err := c.QueryParser(trackRequest); err != nil {
+		slog.Error("error parsing query parameters", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error processing the track request",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := makeValidation(trackRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "track parameters are not valid",
+			Error:   err.Error(),
+		})
+	}
+
+	if trackRequest.Format == "" {
+		trackRequest.Format = "gpx"
+	}
+
+	queryParams := &dto.QueryLocationRequest{
+		VehicleId: trackRequest.VehicleId,
+		From:      trackRequest.From,
+		To:        trackRequest.To,
+		Simplify:  trackRequest.Simplify,
+	}
+	filename := fmt.Sprintf("%s.%s", trackRequest.VehicleId, trackRequest.Format)
+
+	return streamFile(c, trackRequest.VehicleId, trackfile.ContentType(trackRequest.Format), filename, func(w *bufio.Writer, principal *entity.Principal) error {
+		return streamTrack(w, principal, trackRequest.Format, trackRequest.VehicleId, queryParams)
+	})
+}
+
+func streamTrack(w *bufio.Writer, principal *entity.Principal, format, name string, queryParams *dto.QueryLocationRequest) error {
+	trackWriter, err := trackfile.NewWriter(format, w, name)
+	if err != nil {
+		return err
+	}
+
+	err = usecase.StreamLocations(principal, queryParams, func(location *dto.LocationOutApp) error {
+		point, err := newTrackPoint(location)
+		if err != nil {
+			return err
+		}
+		return trackWriter.WritePoint(point)
+	})
+	if err != nil {
+		return err
+	}
+
+	return trackWriter.Close()
+}
+
+func newTrackPoint(location *dto.LocationOutApp) (*trackfile.Point, error) {
+	latitude, err := strconv.ParseFloat(location.Location.Latitude, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid latitude on location %s: %w", location.ID, err)
+	}
+
+	longitude, err := strconv.ParseFloat(location.Location.Longitude, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid longitude on location %s: %w", location.ID, err)
+	}
+
+	return &trackfile.Point{
+		Latitude:  latitude,
+		Longitude: longitude,
+		Timestamp: location.Timestamp,
+		Speed:     location.Speed,
+		Status:    location.Status,
+	}, nil
+}
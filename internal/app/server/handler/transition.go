@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/gofiber/fiber/v2"
+)
+
+// VehiclesGetTransitions godoc
+//
+//	@Summary		Get the status transitions of a vehicle
+//	@Description	Get the changes of the status of a vehicle in a period, with the time, the source that reported them
+//	@Description	and the location that caused them, and the time in seconds spent on every status in the period.
+//	@Tags			Vehicles
+//	@Param			id		path	string	true	"vehicle id"
+//	@Param			from	query	string	true	"start of the period, RFC 3339"
+//	@Param			to		query	string	true	"end of the period, RFC 3339"
+//	@Produce		json
+//	@Security		ApiKeyAuth
+//	@Security		BearerAuth
+//	@Success		200	{object}	dto.StatusTransitionResponseOut	"status transitions of the vehicle"
+//	@Failure		400	{object}	GlobalErrorHandlerResp			"validation error"
+//	@Failure		500	{object}	GlobalErrorHandlerResp			"internal server error"
+//	@Failure		403	{object}	GlobalErrorHandlerResp			"vehicle not allowed"
+//	@Router			/api/v1/vehicles/{id}/transitions [get]
+func VehiclesGetTransitions(c *fiber.Ctx) error {
+	transitionRequest := new(dto.StatusTransitionRequest)
+	if err := c.ParamsParser(transitionRequest); err != nil {
+		slog.Error("error parsing path parameters", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error processing the transitions request",
+			Error:   err.Error(),
+		})
+	}
+	if err := c.QueryParser(transitionRequest); err != nil {
+		slog.Error("error parsing query parameters", "error", err.Error())
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error processing the transitions request",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := makeValidation(transitionRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "transitions parameters are not valid",
+			Error:   err.Error(),
+		})
+	}
+
+	transitions, err := usecase.GetStatusTransitions(CurrentPrincipal(c), transitionRequest)
+	if errors.Is(err, usecase.ErrVehicleNotAllowed) {
+		return vehicleNotAllowed(c, err)
+	}
+	if err != nil {
+		slog.Error("error getting status transitions", "error", err.Error(), "vehicleID", transitionRequest.VehicleId)
+		return c.Status(fiber.StatusInternalServerError).JSON(GlobalErrorHandlerResp{
+			Success: false,
+			Message: "there is an error getting the status transitions of the vehicle",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(transitions)
+}
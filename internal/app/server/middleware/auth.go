@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/allansbo/goapi/internal/app/server/handler"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/allansbo/goapi/internal/pkg/ratelimit"
+	"github.com/gofiber/fiber/v2"
+)
+
+// APIKeyHeader is the header with the API key of the requests.
+const APIKeyHeader = "X-API-Key"
+
+// errMissingCredentials is returned when the request has neither an API key nor a bearer token.
+var errMissingCredentials = errors.New("the " + APIKeyHeader + " header or a bearer token is required")
+
+// adminRoutes are the routes that require the admin role whatever the method.
+var adminRoutes = []string{"/api/v1/keys", "/api/v1/audit"}
+
+// UseAuthMiddleware is a middleware that authenticates the routes of the API group by the API key
+// or by a bearer token, and checks if the client has the role of the request: read for GET,
+// delete for DELETE, write for the other methods and admin for the management of the keys and the audit log.
+// The roles of an API key are its scopes, the ones of a token are mapped from its claims.
+// It returns a 401 error without valid credentials and a 403 error without the role.
+// Every 401 error takes a token of the auth group of the limiter from the IP of the request, which gets
+// a 429 error, without being authenticated, while it has none, so the keys and the tokens can't be guessed.
+func UseAuthMiddleware(api fiber.Router, limiter *ratelimit.Limiter) {
+	api.Use(func(ctx *fiber.Ctx) error {
+		if wait := failedAuthWait(ctx, limiter); wait > 0 {
+			return handler.TooManyRequests(ctx, wait, "too many failed authentications from the client")
+		}
+
+		principal, err := authenticate(ctx)
+		if errors.Is(err, errMissingCredentials) || errors.Is(err, usecase.ErrInvalidAPIKey) ||
+			errors.Is(err, usecase.ErrInvalidToken) || errors.Is(err, usecase.ErrTokenDisabled) {
+			recordFailedAuth(ctx, limiter)
+			return ctx.Status(http.StatusUnauthorized).JSON(handler.GlobalErrorHandlerResp{
+				Success: false,
+				Message: "authentication failed",
+				Error:   err.Error(),
+			})
+		} else if err != nil {
+			slog.Error("error authenticating request", "error", err.Error())
+			return ctx.Status(http.StatusInternalServerError).JSON(handler.GlobalErrorHandlerResp{
+				Success: false,
+				Message: "there is an error authenticating the request",
+				Error:   err.Error(),
+			})
+		}
+
+		role := requiredRole(ctx)
+		if !principal.HasRole(role) {
+			return ctx.Status(http.StatusForbidden).JSON(handler.GlobalErrorHandlerResp{
+				Success: false,
+				Message: "permission denied",
+				Error:   "the client doesn't have the " + role + " role",
+			})
+		}
+
+		ctx.Locals(handler.PrincipalLocal, identify(ctx, principal))
+		return ctx.Next()
+	})
+}
+
+// authenticate returns the principal of the API key or the bearer token of the request.
+func authenticate(ctx *fiber.Ctx) (*entity.Principal, error) {
+	if key := ctx.Get(APIKeyHeader); key != "" {
+		apiKey, err := usecase.AuthenticateAPIKey(key)
+		if err != nil {
+			return nil, err
+		}
+		return apiKey.NewPrincipal(), nil
+	}
+
+	scheme, token, found := strings.Cut(ctx.Get(fiber.HeaderAuthorization), " ")
+	if found && strings.EqualFold(scheme, "Bearer") && strings.TrimSpace(token) != "" {
+		return usecase.AuthenticateToken(strings.TrimSpace(token))
+	}
+
+	return nil, errMissingCredentials
+}
+
+// requiredRole returns the role that the client needs for the request.
+func requiredRole(ctx *fiber.Ctx) string {
+	path := routePath(ctx)
+	for _, route := range adminRoutes {
+		if path == route || strings.HasPrefix(path, route+"/") {
+			return entity.ScopeAdmin
+		}
+	}
+
+	switch ctx.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return entity.ScopeRead
+	case fiber.MethodDelete:
+		return entity.ScopeDelete
+	default:
+		return entity.ScopeWrite
+	}
+}
+
+// routePath returns the path of the request in lower case and without the trailing slash,
+// to be compared with the routes whatever the routing options of the app.
+func routePath(ctx *fiber.Ctx) string {
+	return strings.ToLower(strings.TrimSuffix(ctx.Path(), "/"))
+}
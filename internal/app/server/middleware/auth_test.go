@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRequiredRole(t *testing.T) {
+	app := fiber.New()
+	app.Use(func(ctx *fiber.Ctx) error {
+		return ctx.SendString(requiredRole(ctx))
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{method: http.MethodGet, path: "/api/v1/locations", want: entity.ScopeRead},
+		{method: http.MethodHead, path: "/api/v1/locations", want: entity.ScopeRead},
+		{method: http.MethodPost, path: "/api/v1/locations", want: entity.ScopeWrite},
+		{method: http.MethodPatch, path: "/api/v1/locations/1", want: entity.ScopeWrite},
+		{method: http.MethodDelete, path: "/api/v1/locations/1", want: entity.ScopeDelete},
+		{method: http.MethodGet, path: "/api/v1/keys", want: entity.ScopeAdmin},
+		{method: http.MethodPost, path: "/api/v1/keys/", want: entity.ScopeAdmin},
+		{method: http.MethodDelete, path: "/api/v1/keys/1", want: entity.ScopeAdmin},
+		{method: http.MethodPost, path: "/API/V1/KEYS", want: entity.ScopeAdmin},
+		{method: http.MethodGet, path: "/api/v1/Audit", want: entity.ScopeAdmin},
+		{method: http.MethodGet, path: "/api/v1/keysets", want: entity.ScopeRead},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			response, err := app.Test(httptest.NewRequest(tt.method, tt.path, nil))
+			if err != nil {
+				t.Fatalf("Test() error = %v", err)
+			}
+			body, _ := io.ReadAll(response.Body)
+			if tt.method != http.MethodHead && string(body) != tt.want {
+				t.Errorf("requiredRole() = %q, want %q", body, tt.want)
+			}
+		})
+	}
+}
@@ -4,11 +4,21 @@ import (
 	"net/http"
 
 	"github.com/allansbo/goapi/internal/app/server/handler"
+	"github.com/allansbo/goapi/internal/pkg/jsonpatch"
 	"github.com/gofiber/fiber/v2"
 )
 
+// fileRoutes are the routes that receive files instead of JSON, with the content types they accept.
+var fileRoutes = map[string][]string{
+	"/api/v1/locations/import": {"text/csv", "application/gpx+xml", "application/xml", "text/xml"},
+}
+
+// patchContentTypes are the content types of the patches, accepted by the PATCH routes besides JSON.
+var patchContentTypes = []string{jsonpatch.MergePatchMediaType, jsonpatch.JSONPatchMediaType}
+
 // UseJSONMiddleware is a middleware that checks if the request is a JSON request
 // and returns a 400 error if it is not. It is used to validate the request body.
+// The requests without a body, like the restore of a location, have no content type to check.
 func UseJSONMiddleware(app *fiber.App) {
 
 	// Always that a user send data to the server,
@@ -17,7 +27,7 @@ func UseJSONMiddleware(app *fiber.App) {
 	app.Use(func(ctx *fiber.Ctx) error {
 		switch ctx.Method() {
 		case fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch:
-			if ctx.Is("json") {
+			if len(ctx.Body()) == 0 || ctx.Is("json") || isFileAllowed(ctx) || isPatchAllowed(ctx) {
 				return ctx.Next()
 			}
 
@@ -31,3 +41,25 @@ func UseJSONMiddleware(app *fiber.App) {
 		}
 	})
 }
+
+// isFileAllowed checks if the route receives files of the request content type.
+func isFileAllowed(ctx *fiber.Ctx) bool {
+	return isContentType(ctx, fileRoutes[routePath(ctx)])
+}
+
+// isPatchAllowed checks if the request is a patch of one of the patch content types.
+func isPatchAllowed(ctx *fiber.Ctx) bool {
+	return ctx.Method() == fiber.MethodPatch && isContentType(ctx, patchContentTypes)
+}
+
+// isContentType checks if the request content type, without its parameters, is one of the allowed ones.
+func isContentType(ctx *fiber.Ctx, allowedTypes []string) bool {
+	contentType := handler.ContentType(ctx)
+
+	for _, allowed := range allowedTypes {
+		if contentType == allowed {
+			return true
+		}
+	}
+	return false
+}
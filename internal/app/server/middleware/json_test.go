@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/allansbo/goapi/internal/pkg/jsonpatch"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestJSONMiddleware(t *testing.T) {
+	app := fiber.New()
+	UseJSONMiddleware(app)
+	app.Use(func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{name: "json", method: http.MethodPost, path: "/api/v1/locations", contentType: fiber.MIMEApplicationJSON, body: `{}`, wantStatus: http.StatusNoContent},
+		{name: "json with a charset", method: http.MethodPut, path: "/api/v1/locations/1", contentType: fiber.MIMEApplicationJSONCharsetUTF8, body: `{}`, wantStatus: http.StatusNoContent},
+		{name: "text", method: http.MethodPost, path: "/api/v1/locations", contentType: fiber.MIMETextPlain, body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "body without a content type", method: http.MethodPost, path: "/api/v1/locations", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "restore without a body", method: http.MethodPost, path: "/api/v1/locations/1/restore", wantStatus: http.StatusNoContent},
+		{name: "rotate without a body", method: http.MethodPost, path: "/api/v1/keys/1/rotate", wantStatus: http.StatusNoContent},
+		{name: "import of a csv file", method: http.MethodPost, path: "/api/v1/locations/import", contentType: "text/csv", body: "vehicle_id\n", wantStatus: http.StatusNoContent},
+		{name: "csv file on another route", method: http.MethodPost, path: "/api/v1/locations", contentType: "text/csv", body: "vehicle_id\n", wantStatus: http.StatusBadRequest},
+		{name: "merge patch", method: http.MethodPatch, path: "/api/v1/locations/1", contentType: jsonpatch.MergePatchMediaType, body: `{}`, wantStatus: http.StatusNoContent},
+		{name: "merge patch on a post", method: http.MethodPost, path: "/api/v1/locations", contentType: jsonpatch.MergePatchMediaType, body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "get", method: http.MethodGet, path: "/api/v1/locations", wantStatus: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				request.Header.Set(fiber.HeaderContentType, tt.contentType)
+			}
+
+			response, err := app.Test(request)
+			if err != nil {
+				t.Fatalf("Test() error = %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, response.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
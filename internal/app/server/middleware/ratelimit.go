@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/handler"
+	"github.com/allansbo/goapi/internal/pkg/ratelimit"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Route groups of the rate limits.
+const (
+	RateLimitIngest = "ingest"
+	RateLimitRead   = "read"
+	RateLimitWrite  = "write"
+	RateLimitAdmin  = "admin"
+	// RateLimitAuth limits the failed authentications of every IP, whatever the route.
+	RateLimitAuth = "auth"
+)
+
+// RateLimitGroups are the route groups that can be limited.
+var RateLimitGroups = []string{RateLimitIngest, RateLimitRead, RateLimitWrite, RateLimitAdmin, RateLimitAuth}
+
+// ingestRoutes are the routes that receive locations, limited by the ingest group.
+var ingestRoutes = []string{"/api/v1/locations", "/api/v1/locations/nmea", "/api/v1/locations/import"}
+
+// UseRateLimitMiddleware is a middleware that limits the requests to the routes of the API group of every client
+// with the token bucket of the route group: ingest for the routes that receive locations, admin for the
+// management of the keys, read for the other GET routes and write for the other methods.
+// The clients are identified by their API key or token, or by their IP without authentication,
+// so it must be used after the authentication middleware. It returns a 429 error with the Retry-After header.
+func UseRateLimitMiddleware(api fiber.Router, limiter *ratelimit.Limiter) {
+	api.Use(func(ctx *fiber.Ctx) error {
+		wait, err := limiter.Allow(rateLimitGroup(ctx), rateLimitKey(ctx))
+		if err != nil {
+			// The requests are not rejected when the store is unavailable.
+			slog.Error("error checking rate limit", "error", err.Error())
+			return ctx.Next()
+		}
+		if wait > 0 {
+			return handler.TooManyRequests(ctx, wait, "the rate limit of the client was exceeded")
+		}
+
+		return ctx.Next()
+	})
+}
+
+// rateLimitGroup returns the route group of the request.
+func rateLimitGroup(ctx *fiber.Ctx) string {
+	path := routePath(ctx)
+	for _, route := range adminRoutes {
+		if path == route || strings.HasPrefix(path, route+"/") {
+			return RateLimitAdmin
+		}
+	}
+
+	switch ctx.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return RateLimitRead
+	case fiber.MethodPost:
+		for _, route := range ingestRoutes {
+			if path == route {
+				return RateLimitIngest
+			}
+		}
+	}
+	return RateLimitWrite
+}
+
+// failedAuthWait returns how long the IP of the request must wait before being authenticated again,
+// when its failed authentications emptied its bucket of the auth group.
+func failedAuthWait(ctx *fiber.Ctx, limiter *ratelimit.Limiter) time.Duration {
+	wait, err := limiter.Wait(RateLimitAuth, "ip:"+ctx.IP())
+	if err != nil {
+		slog.Error("error checking rate limit", "error", err.Error())
+		return 0
+	}
+	return wait
+}
+
+// recordFailedAuth takes a token of the bucket of the auth group of the IP of the request.
+func recordFailedAuth(ctx *fiber.Ctx, limiter *ratelimit.Limiter) {
+	if _, err := limiter.Allow(RateLimitAuth, "ip:"+ctx.IP()); err != nil {
+		slog.Error("error checking rate limit", "error", err.Error())
+	}
+}
+
+// rateLimitKey identifies the client of the request by its credentials, or by its IP without authentication.
+func rateLimitKey(ctx *fiber.Ctx) string {
+	if principal := handler.CurrentPrincipal(ctx); !principal.Anonymous() {
+		return principal.Method + ":" + principal.Subject
+	}
+	return "ip:" + ctx.IP()
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/allansbo/goapi/internal/pkg/ratelimit"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRateLimitGroup(t *testing.T) {
+	app := fiber.New()
+	app.Use(func(ctx *fiber.Ctx) error {
+		return ctx.SendString(rateLimitGroup(ctx))
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{method: http.MethodPost, path: "/api/v1/locations", want: RateLimitIngest},
+		{method: http.MethodPost, path: "/api/v1/locations/", want: RateLimitIngest},
+		{method: http.MethodPost, path: "/api/v1/locations/nmea", want: RateLimitIngest},
+		{method: http.MethodPost, path: "/api/v1/locations/import", want: RateLimitIngest},
+		{method: http.MethodPost, path: "/API/V1/Locations", want: RateLimitIngest},
+		{method: http.MethodGet, path: "/api/v1/locations", want: RateLimitRead},
+		{method: http.MethodPut, path: "/api/v1/locations/1", want: RateLimitWrite},
+		{method: http.MethodDelete, path: "/api/v1/locations/1", want: RateLimitWrite},
+		{method: http.MethodGet, path: "/api/v1/keys", want: RateLimitAdmin},
+		{method: http.MethodPost, path: "/api/v1/KEYS/1/rotate", want: RateLimitAdmin},
+		{method: http.MethodGet, path: "/api/v1/audit", want: RateLimitAdmin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			response, err := app.Test(httptest.NewRequest(tt.method, tt.path, nil))
+			if err != nil {
+				t.Fatalf("Test() error = %v", err)
+			}
+			body, _ := io.ReadAll(response.Body)
+			if string(body) != tt.want {
+				t.Errorf("rateLimitGroup() = %q, want %q", body, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailedAuthRateLimit(t *testing.T) {
+	limiter, err := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), []string{"auth=1/m:2"}, RateLimitGroups...)
+	if err != nil {
+		t.Fatalf("NewLimiter() error = %v", err)
+	}
+
+	app := fiber.New()
+	UseAuthMiddleware(app.Group("/api"), limiter)
+
+	request := func(header, value string) *http.Response {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/locations", nil)
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		response, err := app.Test(r)
+		if err != nil {
+			t.Fatalf("Test() error = %v", err)
+		}
+		return response
+	}
+
+	// Every failure takes a token, whatever the credentials.
+	if response := request("", ""); response.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("request without credentials = %d, want %d", response.StatusCode, http.StatusUnauthorized)
+	}
+	if response := request(fiber.HeaderAuthorization, "Bearer guess"); response.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("request with an invalid token = %d, want %d", response.StatusCode, http.StatusUnauthorized)
+	}
+
+	// The IP is rejected before its credentials are checked while its bucket is empty.
+	response := request(APIKeyHeader, "guess")
+	if response.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("request over the limit = %d, want %d", response.StatusCode, http.StatusTooManyRequests)
+	}
+	if response.Header.Get(fiber.HeaderRetryAfter) == "" {
+		t.Errorf("request over the limit has no %s header", fiber.HeaderRetryAfter)
+	}
+}
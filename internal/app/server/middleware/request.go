@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"github.com/allansbo/goapi/internal/app/server/handler"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+)
+
+// UseRequestIDMiddleware is a middleware that identifies every request by the X-Request-ID header,
+// generated when the client doesn't send one, and returns it in the response.
+// The ID is saved in the audit log, so the changes can be matched with the logs of the clients.
+func UseRequestIDMiddleware(app *fiber.App) {
+	app.Use(requestid.New(requestid.Config{ContextKey: handler.RequestIDLocal}))
+}
+
+// UseAnonymousMiddleware is a middleware that sets the anonymous principal in the routes of the API group
+// when the authentication is disabled, so the audit log has the IP and the request ID of the changes.
+func UseAnonymousMiddleware(api fiber.Router) {
+	api.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals(handler.PrincipalLocal, identify(ctx, entity.NewAnonymousPrincipal()))
+		return ctx.Next()
+	})
+}
+
+// identify sets the IP and the ID of the request on its principal.
+func identify(ctx *fiber.Ctx, principal *entity.Principal) *entity.Principal {
+	principal.IP = ctx.IP()
+	principal.RequestID, _ = ctx.Locals(handler.RequestIDLocal).(string)
+	return principal
+}
@@ -7,17 +7,42 @@ import (
 	fiberSwagger "github.com/swaggo/fiber-swagger"
 )
 
+// APIPrefix is the prefix of the API routes, which require credentials when the authentication is enabled.
+const APIPrefix = "/api"
+
 // MakeRoutes is a function that makes the routes for the application.
 // It is used to define the routes for the application.
 func MakeRoutes(app *fiber.App) {
 	app.Get("/docs/*", fiberSwagger.WrapHandler)
 
-	api := app.Group("/api")
+	api := app.Group(APIPrefix)
 	v1 := api.Group("/v1")
 
 	v1.Post("/locations", handler.LocationsAddOne)
+	v1.Post("/locations/nmea", handler.LocationsAddNMEA)
+	v1.Get("/locations/export", handler.LocationsExport)
+	v1.Get("/locations/heatmap", handler.LocationsGetHeatmap)
+	v1.Post("/locations/import", handler.LocationsImport)
 	v1.Get("/locations/:id", handler.LocationsGetOne)
 	v1.Get("/locations", handler.LocationsGetAll)
 	v1.Put("/locations/:id", handler.LocationsUpdateOne)
+	v1.Patch("/locations/:id", handler.LocationsPatchOne)
 	v1.Delete("/locations/:id", handler.LocationsDeleteOne)
+	v1.Post("/locations/:id/restore", handler.LocationsRestoreOne)
+
+	v1.Get("/vehicles/:id/track", handler.VehiclesGetTrack)
+	v1.Get("/vehicles/:id/stops", handler.VehiclesGetStops)
+	v1.Get("/vehicles/:id/playback", handler.VehiclesGetPlayback)
+	v1.Get("/vehicles/:id/transitions", handler.VehiclesGetTransitions)
+
+	v1.Get("/stats/daily", handler.StatsGetDaily)
+
+	v1.Get("/tiles/:z/:x/:y.mvt", handler.TilesGetOne)
+
+	v1.Post("/keys", handler.KeysAddOne)
+	v1.Get("/keys", handler.KeysGetAll)
+	v1.Post("/keys/:id/rotate", handler.KeysRotateOne)
+	v1.Delete("/keys/:id", handler.KeysDeleteOne)
+
+	v1.Get("/audit", handler.AuditGetAll)
 }
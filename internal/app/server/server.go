@@ -6,19 +6,27 @@ import (
 
 	"github.com/allansbo/goapi/internal/app/server/middleware"
 	"github.com/allansbo/goapi/internal/app/server/router"
+	"github.com/allansbo/goapi/internal/pkg/ratelimit"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/healthcheck"
 )
 
 type AppServer struct {
-	FiberApp *fiber.App
-	appPort  string
+	FiberApp    *fiber.App
+	appPort     string
+	authEnabled bool
+	limiter     *ratelimit.Limiter
 }
 
-func NewAppServer(appPort string) *AppServer {
+// NewAppServer creates the server. When authEnabled is true, the API routes require an API key.
+// The API routes are limited by the limiter, when it has any limit. The routes are case sensitive,
+// so a path can't reach a route in another case than the one that the middlewares check.
+func NewAppServer(appPort string, authEnabled bool, limiter *ratelimit.Limiter) *AppServer {
 	return &AppServer{
-		FiberApp: fiber.New(),
-		appPort:  appPort,
+		FiberApp:    fiber.New(fiber.Config{CaseSensitive: true}),
+		appPort:     appPort,
+		authEnabled: authEnabled,
+		limiter:     limiter,
 	}
 }
 
@@ -26,9 +34,7 @@ func NewAppServer(appPort string) *AppServer {
 // It is responsible for setting up the server and starting the application
 // by using the Fiber framework.
 func (s *AppServer) Start() {
-	s.FiberApp.Use(healthcheck.New())
-	middleware.UseJSONMiddleware(s.FiberApp)
-	router.MakeRoutes(s.FiberApp)
+	s.setup()
 
 	slog.Info("Server running", "Port", s.appPort)
 	if err := s.FiberApp.Listen(fmt.Sprintf(":%s", s.appPort)); err != nil {
@@ -36,3 +42,22 @@ func (s *AppServer) Start() {
 	}
 
 }
+
+// setup registers the middlewares and the routes. The authentication and the rate limits are mounted
+// on the group of the API routes, so they apply to every route of the group.
+func (s *AppServer) setup() {
+	s.FiberApp.Use(healthcheck.New())
+	middleware.UseRequestIDMiddleware(s.FiberApp)
+
+	api := s.FiberApp.Group(router.APIPrefix)
+	if s.authEnabled {
+		middleware.UseAuthMiddleware(api, s.limiter)
+	} else {
+		middleware.UseAnonymousMiddleware(api)
+	}
+	if s.limiter.Enabled() {
+		middleware.UseRateLimitMiddleware(api, s.limiter)
+	}
+	middleware.UseJSONMiddleware(s.FiberApp)
+	router.MakeRoutes(s.FiberApp)
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/app/server/middleware"
+	"github.com/allansbo/goapi/internal/domain/usecase"
+	"github.com/allansbo/goapi/internal/pkg/ratelimit"
+	"github.com/allansbo/goapi/internal/provider/db"
+)
+
+// activeLocationRepository is a repository with a single location that isn't deleted.
+type activeLocationRepository struct {
+	db.Repository
+}
+
+func (r activeLocationRepository) Tenant(string) db.Repository {
+	return r
+}
+
+func (r activeLocationRepository) GetOne(id string, _ bool) (*dto.LocationInDB, error) {
+	return &dto.LocationInDB{VehicleId: "ABC1234"}, nil
+}
+
+func TestAPIRoutesRequireCredentials(t *testing.T) {
+	s := NewAppServer("0", true, nil)
+	s.setup()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{name: "create a key", method: http.MethodPost, path: "/api/v1/keys", wantStatus: http.StatusUnauthorized},
+		{name: "create a key with a trailing slash", method: http.MethodPost, path: "/api/v1/keys/", wantStatus: http.StatusUnauthorized},
+		{name: "list the locations", method: http.MethodGet, path: "/api/v1/locations", wantStatus: http.StatusUnauthorized},
+		{name: "unknown api route", method: http.MethodGet, path: "/api/v2/anything", wantStatus: http.StatusUnauthorized},
+		{name: "create a key in upper case", method: http.MethodPost, path: "/API/v1/keys", wantStatus: http.StatusNotFound},
+		{name: "create a key in mixed case", method: http.MethodPost, path: "/Api/V1/Keys", wantStatus: http.StatusNotFound},
+		{name: "route in another case under the api group", method: http.MethodGet, path: "/api/v1/KEYS", wantStatus: http.StatusUnauthorized},
+		{name: "health check", method: http.MethodGet, path: "/livez", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{}`))
+			request.Header.Set("Content-Type", "application/json")
+
+			response, err := s.FiberApp.Test(request)
+			if err != nil {
+				t.Fatalf("Test() error = %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, response.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAPIRoutesAreRateLimited(t *testing.T) {
+	limiter, err := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), []string{"read=1/m:1"}, middleware.RateLimitGroups...)
+	if err != nil {
+		t.Fatalf("NewLimiter() error = %v", err)
+	}
+	s := NewAppServer("0", false, limiter)
+	s.setup()
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{name: "first request", path: "/api/v2/anything", wantStatus: http.StatusNotFound},
+		{name: "request over the limit", path: "/api/v2/anything", wantStatus: http.StatusTooManyRequests},
+		{name: "route in another case over the limit", path: "/api/v2/ANYTHING", wantStatus: http.StatusTooManyRequests},
+		{name: "health check out of the api group", path: "/livez", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := s.FiberApp.Test(httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if err != nil {
+				t.Fatalf("Test() error = %v", err)
+			}
+			if response.StatusCode != tt.wantStatus {
+				t.Errorf("GET %s = %d, want %d", tt.path, response.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRestoreLocationWithoutBody(t *testing.T) {
+	usecase.LoadLocationUseCase(activeLocationRepository{})
+	t.Cleanup(func() { usecase.LoadLocationUseCase(nil) })
+
+	s := NewAppServer("0", false, nil)
+	s.setup()
+
+	// The request has no body, so it has no content type either, and reaches the handler.
+	response, err := s.FiberApp.Test(httptest.NewRequest(http.MethodPost, "/api/v1/locations/665f1c2b8e4a3b2d1c0f9e8d/restore", nil))
+	if err != nil {
+		t.Fatalf("Test() error = %v", err)
+	}
+	if response.StatusCode != http.StatusConflict {
+		t.Errorf("POST restore without a body = %d, want %d", response.StatusCode, http.StatusConflict)
+	}
+}
@@ -2,7 +2,9 @@ package config
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/allansbo/goapi/internal/pkg/validation"
 	"github.com/spf13/viper"
 )
 
@@ -15,6 +17,127 @@ type EnvConfig struct {
 	DBHost       string `mapstructure:"DB_HOST"`
 	DBPort       string `mapstructure:"DB_PORT"`
 	AppPort      string `mapstructure:"APP_PORT"`
+
+	// MQTT ingestion gateway, disabled when MQTT_BROKER is empty.
+	MQTTBroker   string   `mapstructure:"MQTT_BROKER"`
+	MQTTClientID string   `mapstructure:"MQTT_CLIENT_ID"`
+	MQTTUser     string   `mapstructure:"MQTT_USER"`
+	MQTTPass     string   `mapstructure:"MQTT_PASS"`
+	MQTTTopics   []string `mapstructure:"MQTT_TOPICS"`
+	MQTTQoS      byte     `mapstructure:"MQTT_QOS"`
+
+	// TCP listeners for the tracker protocols, in the format <protocol>:<port>.
+	TCPListeners []string `mapstructure:"TCP_LISTENERS"`
+	// Mapping of the device identifiers to vehicle IDs, in the format <device id>=<vehicle id>.
+	DeviceVehicles []string `mapstructure:"DEVICE_VEHICLES"`
+	// Accept the devices not mapped, with the last 7 characters of their identifier as the vehicle ID.
+	DeviceVehicleFallback bool `mapstructure:"DEVICE_VEHICLE_FALLBACK"`
+
+	// Anomaly checks of the ingested locations: the maximum plausible speed in km/h
+	// and the flags that reject the location instead of saving it flagged.
+	AnomalyMaxSpeed float64  `mapstructure:"ANOMALY_MAX_SPEED"`
+	AnomalyReject   []string `mapstructure:"ANOMALY_REJECT"`
+
+	// Daily statistics roll-up: the collection of the summaries and how often they are recomputed, 0 disables the job.
+	DBStatsCollection   string        `mapstructure:"DB_STATS_COLLECTION"`
+	StatsRollupInterval time.Duration `mapstructure:"STATS_ROLLUP_INTERVAL"`
+
+	// Collection of the log of the status transitions of the vehicles.
+	DBTransitionsCollection string `mapstructure:"DB_TRANSITIONS_COLLECTION"`
+
+	// Soft delete: how long the deleted locations are kept before being purged and how often the purge runs, 0 disables the job.
+	DeletedRetention time.Duration `mapstructure:"DELETED_RETENTION"`
+	PurgeInterval    time.Duration `mapstructure:"PURGE_INTERVAL"`
+
+	// Append-only collection of the audit log of the changes of the locations and the API keys.
+	DBAuditCollection string `mapstructure:"DB_AUDIT_COLLECTION"`
+
+	// Authentication of the API routes by the API keys saved in the keys collection, enabled by default.
+	// When disabled, every client is an anonymous admin.
+	AuthEnabled         bool   `mapstructure:"AUTH_ENABLED"`
+	DBAPIKeysCollection string `mapstructure:"DB_API_KEYS_COLLECTION"`
+
+	// Bearer tokens of an identity provider, disabled when JWT_JWKS is empty: the path or URL of the public keys,
+	// the expected iss and aud claims, the claim with the roles and their mapping, in the format <claim value>=<role>
+	// where the values not mapped grant no role,
+	// and the claims with the vehicles and the fleet groups that the client can access.
+	JWTJWKS          string        `mapstructure:"JWT_JWKS"`
+	JWTIssuer        string        `mapstructure:"JWT_ISSUER"`
+	JWTAudience      string        `mapstructure:"JWT_AUDIENCE"`
+	JWTRolesClaim    string        `mapstructure:"JWT_ROLES_CLAIM"`
+	JWTVehiclesClaim string        `mapstructure:"JWT_VEHICLES_CLAIM"`
+	JWTGroupsClaim   string        `mapstructure:"JWT_GROUPS_CLAIM"`
+	JWTRoleMap       []string      `mapstructure:"JWT_ROLE_MAP"`
+	JWTJWKSRefresh   time.Duration `mapstructure:"JWT_JWKS_REFRESH"`
+	JWTLeeway        time.Duration `mapstructure:"JWT_LEEWAY"`
+
+	// Tenants of the fleet operators: the tenant of the credentials without one and of the data saved before
+	// the tenants, whether every other tenant has its own database, the collection where these tenants are registered,
+	// the claim of the tokens with the tenant
+	// and the tenant of the vehicles of the MQTT gateway and the TCP listeners, in the format <vehicle id>=<tenant>.
+	DefaultTenant   string   `mapstructure:"DEFAULT_TENANT"`
+	TenantDatabases bool     `mapstructure:"TENANT_DATABASES"`
+	DBTenants       string   `mapstructure:"DB_TENANTS_COLLECTION"`
+	JWTTenantClaim  string   `mapstructure:"JWT_TENANT_CLAIM"`
+	VehicleTenants  []string `mapstructure:"VEHICLE_TENANTS"`
+
+	// Rate limits of the route groups, of the failed authentications and of the devices, in the format <group>=<requests>/<s|m|h>[:<burst>],
+	// and the daily quota of locations of every tenant, 0 for no quota, and of specific tenants, in the format <tenant>=<locations>.
+	RateLimits       []string `mapstructure:"RATE_LIMITS"`
+	TenantDailyQuota int64    `mapstructure:"TENANT_DAILY_QUOTA"`
+	TenantQuotas     []string `mapstructure:"TENANT_QUOTAS"`
+
+	// Fleet groups that the clients can be allowed to access, in the format <group>=<vehicle id>|<vehicle id>.
+	FleetGroups []string `mapstructure:"FLEET_GROUPS"`
+
+	// Offline reverse geocoding: the gazetteer file, disabled when empty,
+	// and the distance in meters beyond which a location has no place.
+	GeocoderFile        string  `mapstructure:"GEOCODER_FILE"`
+	GeocoderMaxDistance float64 `mapstructure:"GEOCODER_MAX_DISTANCE"`
+}
+
+// setDefaults is a function that sets the default values for the optional configuration.
+// Registering the keys also allows viper to read them from the environment variables.
+func setDefaults() {
+	viper.SetDefault("MQTT_BROKER", "")
+	viper.SetDefault("MQTT_CLIENT_ID", "goapi-ingestion")
+	viper.SetDefault("MQTT_USER", "")
+	viper.SetDefault("MQTT_PASS", "")
+	viper.SetDefault("MQTT_TOPICS", "fleet/+/location")
+	viper.SetDefault("MQTT_QOS", 1)
+	viper.SetDefault("TCP_LISTENERS", "")
+	viper.SetDefault("DEVICE_VEHICLES", "")
+	viper.SetDefault("DEVICE_VEHICLE_FALLBACK", false)
+	viper.SetDefault("ANOMALY_MAX_SPEED", 300)
+	viper.SetDefault("ANOMALY_REJECT", "")
+	viper.SetDefault("DB_STATS_COLLECTION", "daily_stats")
+	viper.SetDefault("STATS_ROLLUP_INTERVAL", "1h")
+	viper.SetDefault("DB_TRANSITIONS_COLLECTION", "status_transitions")
+	viper.SetDefault("DELETED_RETENTION", "720h")
+	viper.SetDefault("PURGE_INTERVAL", "1h")
+	viper.SetDefault("DB_AUDIT_COLLECTION", "audit_log")
+	viper.SetDefault("AUTH_ENABLED", true)
+	viper.SetDefault("DB_API_KEYS_COLLECTION", "api_keys")
+	viper.SetDefault("JWT_JWKS", "")
+	viper.SetDefault("JWT_ISSUER", "")
+	viper.SetDefault("JWT_AUDIENCE", "")
+	viper.SetDefault("JWT_ROLES_CLAIM", "roles")
+	viper.SetDefault("JWT_VEHICLES_CLAIM", "vehicles")
+	viper.SetDefault("JWT_GROUPS_CLAIM", "groups")
+	viper.SetDefault("JWT_ROLE_MAP", "")
+	viper.SetDefault("JWT_JWKS_REFRESH", "1h")
+	viper.SetDefault("JWT_LEEWAY", "30s")
+	viper.SetDefault("DEFAULT_TENANT", "default")
+	viper.SetDefault("TENANT_DATABASES", false)
+	viper.SetDefault("DB_TENANTS_COLLECTION", "tenants")
+	viper.SetDefault("JWT_TENANT_CLAIM", "tenant")
+	viper.SetDefault("VEHICLE_TENANTS", "")
+	viper.SetDefault("RATE_LIMITS", "")
+	viper.SetDefault("TENANT_DAILY_QUOTA", 0)
+	viper.SetDefault("TENANT_QUOTAS", "")
+	viper.SetDefault("FLEET_GROUPS", "")
+	viper.SetDefault("GEOCODER_FILE", "")
+	viper.SetDefault("GEOCODER_MAX_DISTANCE", 10000)
 }
 
 // isValidConfig is a function that checks if the configuration is valid.
@@ -42,6 +165,7 @@ func (e *EnvConfig) isValidConfig() error {
 func LoadEnvConfig() (*EnvConfig, error) {
 	viper.SetConfigFile(".env")
 	viper.AutomaticEnv()
+	setDefaults()
 
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, err
@@ -56,5 +180,45 @@ func LoadEnvConfig() (*EnvConfig, error) {
 		return nil, err
 	}
 
+	if config.MQTTQoS > 2 {
+		return nil, fmt.Errorf("MQTT_QOS must be 0, 1 or 2")
+	}
+
+	if config.AnomalyMaxSpeed <= 0 {
+		return nil, fmt.Errorf("ANOMALY_MAX_SPEED must be greater than 0")
+	}
+
+	if config.StatsRollupInterval < 0 {
+		return nil, fmt.Errorf("STATS_ROLLUP_INTERVAL must not be negative")
+	}
+
+	if config.DeletedRetention < 0 {
+		return nil, fmt.Errorf("DELETED_RETENTION must not be negative")
+	}
+
+	if config.PurgeInterval < 0 {
+		return nil, fmt.Errorf("PURGE_INTERVAL must not be negative")
+	}
+
+	if config.JWTJWKSRefresh <= 0 {
+		return nil, fmt.Errorf("JWT_JWKS_REFRESH must be greater than 0")
+	}
+
+	if config.JWTLeeway < 0 {
+		return nil, fmt.Errorf("JWT_LEEWAY must not be negative")
+	}
+
+	if !validation.IsTenant(config.DefaultTenant) {
+		return nil, fmt.Errorf("DEFAULT_TENANT must have up to 32 lowercase letters, digits, hyphens or underscores")
+	}
+
+	if config.TenantDailyQuota < 0 {
+		return nil, fmt.Errorf("TENANT_DAILY_QUOTA must not be negative")
+	}
+
+	if config.GeocoderMaxDistance <= 0 {
+		return nil, fmt.Errorf("GEOCODER_MAX_DISTANCE must be greater than 0")
+	}
+
 	return &config, nil
 }
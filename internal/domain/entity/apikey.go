@@ -0,0 +1,151 @@
+package entity
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+)
+
+// Scopes of the API keys. The admin scope grants all the others and the management of the keys.
+const (
+	ScopeRead   = "read"
+	ScopeWrite  = "write"
+	ScopeDelete = "delete"
+	ScopeAdmin  = "admin"
+)
+
+// apiKeyPrefix starts every API key, so they can be recognized in configurations and secret scanners.
+const apiKeyPrefix = "gk_"
+
+// APIKey is the entity that represents an API key. Only the hash of the secret is stored,
+// the key is known only when it is issued or rotated.
+type APIKey struct {
+	ID        string     `bson:"_id,omitempty" json:"id"`
+	Name      string     `bson:"name" json:"name"`
+	Prefix    string     `bson:"prefix" json:"prefix"`
+	Hash      string     `bson:"hash" json:"-"`
+	Tenant    string     `bson:"tenant_id,omitempty" json:"tenant,omitempty"`
+	Scopes    []string   `bson:"scopes" json:"scopes"`
+	Vehicles  []string   `bson:"vehicles,omitempty" json:"vehicles,omitempty"`
+	Groups    []string   `bson:"groups,omitempty" json:"groups,omitempty"`
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+	ExpiresAt *time.Time `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	RevokedAt *time.Time `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+// NewAPIKeyInApp is a function that creates a new API key with a random secret.
+// It returns the key, in the format gk_<prefix>.<secret>, which must be shown to the user only once.
+func NewAPIKeyInApp(in *dto.APIKeyInApp) (*APIKey, string, error) {
+	apiKey := &APIKey{
+		Name:      in.Name,
+		Tenant:    in.Tenant,
+		Scopes:    in.Scopes,
+		Vehicles:  in.Vehicles,
+		Groups:    in.Groups,
+		CreatedAt: time.Now(),
+		ExpiresAt: in.ExpiresAt,
+	}
+
+	key, err := apiKey.Rotate()
+	if err != nil {
+		return nil, "", err
+	}
+	return apiKey, key, nil
+}
+
+// Rotate is a function that replaces the secret of the key, returning the new key.
+func (k *APIKey) Rotate() (string, error) {
+	prefix := make([]byte, 6)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", fmt.Errorf("error generating api key: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("error generating api key: %w", err)
+	}
+
+	k.Prefix = hex.EncodeToString(prefix)
+	key := apiKeyPrefix + k.Prefix + "." + base64.RawURLEncoding.EncodeToString(secret)
+	k.Hash = HashAPIKey(key)
+	return key, nil
+}
+
+// HashAPIKey is a function that returns the hash of a key as it is stored.
+// The keys have 256 random bits, so a fast hash is enough to protect them.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseAPIKeyPrefix is a function that returns the prefix of a key, used to find it in the database.
+func ParseAPIKeyPrefix(key string) (string, bool) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return "", false
+	}
+	prefix, _, found := strings.Cut(strings.TrimPrefix(key, apiKeyPrefix), ".")
+	return prefix, found && prefix != ""
+}
+
+// Active is a function that reports if the key is not revoked nor expired at the time.
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// NewAPIKeyInDB is a function that creates an API key in the application.
+// The data is coming from the database.
+func NewAPIKeyInDB(apiKey *dto.APIKeyInDB) *APIKey {
+	return &APIKey{
+		ID:        apiKey.ID.Hex(),
+		Name:      apiKey.Name,
+		Prefix:    apiKey.Prefix,
+		Hash:      apiKey.Hash,
+		Tenant:    apiKey.Tenant,
+		Scopes:    apiKey.Scopes,
+		Vehicles:  apiKey.Vehicles,
+		Groups:    apiKey.Groups,
+		CreatedAt: apiKey.CreatedAt,
+		ExpiresAt: apiKey.ExpiresAt,
+		RevokedAt: apiKey.RevokedAt,
+	}
+}
+
+// NewAPIKeyOutDB is a function that exports the API key to the database format.
+func (k *APIKey) NewAPIKeyOutDB() *dto.APIKeyOutDB {
+	return &dto.APIKeyOutDB{
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Hash:      k.Hash,
+		Tenant:    k.Tenant,
+		Scopes:    k.Scopes,
+		Vehicles:  k.Vehicles,
+		Groups:    k.Groups,
+		CreatedAt: k.CreatedAt,
+		ExpiresAt: k.ExpiresAt,
+		RevokedAt: k.RevokedAt,
+	}
+}
+
+// NewAPIKeyOutApp is a function that exports the API key to the application format, without the hash.
+func (k *APIKey) NewAPIKeyOutApp() *dto.APIKeyOutApp {
+	return &dto.APIKeyOutApp{
+		ID:        k.ID,
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Tenant:    k.Tenant,
+		Scopes:    k.Scopes,
+		Vehicles:  k.Vehicles,
+		Groups:    k.Groups,
+		CreatedAt: k.CreatedAt,
+		ExpiresAt: k.ExpiresAt,
+		RevokedAt: k.RevokedAt,
+	}
+}
@@ -0,0 +1,147 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+)
+
+// Actions of the audit log.
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditRotate  = "rotate"
+	AuditRevoke  = "revoke"
+)
+
+// Resources of the audit log.
+const (
+	ResourceLocation = "location"
+	ResourceAPIKey   = "api_key"
+)
+
+// AuditActor is the client that made a change.
+type AuditActor struct {
+	Subject string `json:"subject"`
+	Name    string `json:"name"`
+	Method  string `json:"method"`
+}
+
+// AuditEntry is the entity that represents a change of a resource in the audit log.
+// Before and After are the documents of the resource as returned by the API, nil when it didn't exist.
+type AuditEntry struct {
+	ID         string         `json:"id"`
+	Action     string         `json:"action"`
+	Resource   string         `json:"resource"`
+	ResourceId string         `json:"resource_id"`
+	Actor      *AuditActor    `json:"actor,omitempty"`
+	IP         string         `json:"ip,omitempty"`
+	RequestId  string         `json:"request_id,omitempty"`
+	At         time.Time      `json:"at"`
+	Before     map[string]any `json:"before,omitempty"`
+	After      map[string]any `json:"after,omitempty"`
+}
+
+// NewAuditEntry is a function that creates the entry of a change made by the principal, nil when it was made
+// without a client, like on locationctl. The documents before and after the change are converted to their JSON
+// fields, and an error is returned when they can't be.
+func NewAuditEntry(principal *Principal, action, resource, resourceID string, before, after any) (*AuditEntry, error) {
+	entry := &AuditEntry{
+		Action:     action,
+		Resource:   resource,
+		ResourceId: resourceID,
+		At:         time.Now(),
+	}
+	if principal != nil {
+		entry.Actor = &AuditActor{Subject: principal.Subject, Name: principal.Name, Method: principal.Method}
+		entry.IP = principal.IP
+		entry.RequestId = principal.RequestID
+	}
+
+	var err error
+	if entry.Before, err = auditDocument(before); err != nil {
+		return nil, err
+	}
+	if entry.After, err = auditDocument(after); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// auditDocument returns the JSON fields of a document, so the audit log keeps it as the clients see it.
+func auditDocument(document any) (map[string]any, error) {
+	if document == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// NewAuditEntryInDB is a function that creates an entry of the audit log in the application.
+// The data is coming from the database.
+func NewAuditEntryInDB(entry *dto.AuditEntryInDB) *AuditEntry {
+	auditEntry := &AuditEntry{
+		ID:         entry.ID.Hex(),
+		Action:     entry.Action,
+		Resource:   entry.Resource,
+		ResourceId: entry.ResourceId,
+		IP:         entry.IP,
+		RequestId:  entry.RequestId,
+		At:         entry.At,
+		Before:     entry.Before,
+		After:      entry.After,
+	}
+	if entry.Actor != nil {
+		auditEntry.Actor = &AuditActor{Subject: entry.Actor.Subject, Name: entry.Actor.Name, Method: entry.Actor.Method}
+	}
+	return auditEntry
+}
+
+// NewAuditEntryOutDB is a function that exports the entry to the database format.
+func (a *AuditEntry) NewAuditEntryOutDB() *dto.AuditEntryOutDB {
+	entry := &dto.AuditEntryOutDB{
+		Action:     a.Action,
+		Resource:   a.Resource,
+		ResourceId: a.ResourceId,
+		IP:         a.IP,
+		RequestId:  a.RequestId,
+		At:         a.At,
+		Before:     a.Before,
+		After:      a.After,
+	}
+	if a.Actor != nil {
+		entry.Actor = &dto.AuditActorOutDB{Subject: a.Actor.Subject, Name: a.Actor.Name, Method: a.Actor.Method}
+	}
+	return entry
+}
+
+// NewAuditEntryOutApp is a function that exports the entry to the application format.
+func (a *AuditEntry) NewAuditEntryOutApp() *dto.AuditEntryOutApp {
+	entry := &dto.AuditEntryOutApp{
+		ID:         a.ID,
+		Action:     a.Action,
+		Resource:   a.Resource,
+		ResourceId: a.ResourceId,
+		IP:         a.IP,
+		RequestId:  a.RequestId,
+		At:         a.At,
+		Before:     a.Before,
+		After:      a.After,
+	}
+	if a.Actor != nil {
+		entry.Actor = &dto.AuditActorOutApp{Subject: a.Actor.Subject, Name: a.Actor.Name, Method: a.Actor.Method}
+	}
+	return entry
+}
@@ -1,9 +1,14 @@
 package entity
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/pkg/geo"
 )
 
 // Coordinates is the entity that represents the coordinates of a location.
@@ -12,24 +17,78 @@ type Coordinates struct {
 	Longitude string `bson:"longitude" json:"longitude"`
 }
 
+// Point is a function that parses the coordinates into decimal degrees.
+func (c *Coordinates) Point() (geo.Point, error) {
+	latitude, err := strconv.ParseFloat(c.Latitude, 64)
+	if err != nil {
+		return geo.Point{}, fmt.Errorf("invalid latitude %q: %w", c.Latitude, err)
+	}
+
+	longitude, err := strconv.ParseFloat(c.Longitude, 64)
+	if err != nil {
+		return geo.Point{}, fmt.Errorf("invalid longitude %q: %w", c.Longitude, err)
+	}
+
+	return geo.Point{Latitude: latitude, Longitude: longitude}, nil
+}
+
 // Location is the entity that represents the location of a vehicle.
 type Location struct {
-	ID        string       `bson:"_id,omitempty" json:"id"`
-	VehicleId string       `bson:"vehicle_id" json:"vehicle_id"`
-	Timestamp time.Time    `bson:"timestamp" json:"timestamp"`
-	Location  *Coordinates `bson:"location" json:"location"`
-	Speed     int          `bson:"speed" json:"speed"`
-	Status    string       `bson:"status" json:"status"`
+	ID         string         `bson:"_id,omitempty" json:"id"`
+	VehicleId  string         `bson:"vehicle_id" json:"vehicle_id"`
+	Timestamp  time.Time      `bson:"timestamp" json:"timestamp"`
+	Location   *Coordinates   `bson:"location" json:"location"`
+	Speed      int            `bson:"speed" json:"speed"`
+	Status     string         `bson:"status" json:"status"`
+	Attributes map[string]any `bson:"attributes,omitempty" json:"attributes,omitempty"`
+	Flags      []string       `bson:"flags,omitempty" json:"flags,omitempty"`
+	DeletedAt  *time.Time     `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	Version    int64          `bson:"version" json:"version"`
+}
+
+// StatusFromSpeed is a function that derives the status of a vehicle
+// that is reporting its position from the speed in km/h.
+func StatusFromSpeed(speed int) string {
+	if speed > 0 {
+		return StatusMoving
+	}
+	return StatusStopped
+}
+
+// Ignition is a function that returns if the engine was on, from the ignition attribute
+// reported by the device. The known result is false when the device doesn't report it.
+func (l *Location) Ignition() (on bool, known bool) {
+	switch value := l.Attributes["ignition"].(type) {
+	case bool:
+		return value, true
+	case int:
+		return value != 0, true
+	case int32:
+		return value != 0, true
+	case int64:
+		return value != 0, true
+	case float64:
+		return value != 0, true
+	default:
+		return false, false
+	}
 }
 
 // NewLocationInApp is a function that creates a new location in the application.
 // The user input was validated by the *dto.LocationInApp struct.
 func NewLocationInApp(location *dto.LocationInApp) *Location {
+	return NewLocationInAppAt(location, time.Now())
+}
+
+// NewLocationInAppAt is a function that creates a new location in the application
+// recorded at the provided timestamp, as reported by devices that send their own fix time.
+func NewLocationInAppAt(location *dto.LocationInApp, timestamp time.Time) *Location {
 	return &Location{
-		VehicleId: location.VehicleId,
-		Timestamp: time.Now(),
-		Speed:     location.Speed,
-		Status:    location.Status,
+		VehicleId:  location.VehicleId,
+		Timestamp:  timestamp,
+		Speed:      location.Speed,
+		Status:     location.Status,
+		Attributes: location.Attributes,
 		Location: &Coordinates{
 			Latitude:  location.Latitude,
 			Longitude: location.Longitude,
@@ -41,11 +100,15 @@ func NewLocationInApp(location *dto.LocationInApp) *Location {
 // The data is coming from the database.
 func NewLocationInDB(location *dto.LocationInDB) *Location {
 	return &Location{
-		ID:        location.ID.Hex(),
-		VehicleId: location.VehicleId,
-		Timestamp: location.Timestamp,
-		Speed:     location.Speed,
-		Status:    location.Status,
+		ID:         location.ID.Hex(),
+		VehicleId:  location.VehicleId,
+		Timestamp:  location.Timestamp,
+		Speed:      location.Speed,
+		Status:     location.Status,
+		Attributes: location.Attributes,
+		Flags:      location.Flags,
+		DeletedAt:  location.DeletedAt,
+		Version:    location.Version,
 		Location: &Coordinates{
 			Latitude:  location.Location.Latitude,
 			Longitude: location.Location.Longitude,
@@ -56,10 +119,12 @@ func NewLocationInDB(location *dto.LocationInDB) *Location {
 // NewLocationOutDB is a function that exports the location to the database format.
 func (l *Location) NewLocationOutDB() *dto.LocationOutDB {
 	return &dto.LocationOutDB{
-		VehicleId: l.VehicleId,
-		Timestamp: l.Timestamp,
-		Speed:     l.Speed,
-		Status:    l.Status,
+		VehicleId:  l.VehicleId,
+		Timestamp:  l.Timestamp,
+		Speed:      l.Speed,
+		Status:     l.Status,
+		Attributes: l.Attributes,
+		Flags:      l.Flags,
 		Location: &dto.CoordinatesOutDB{
 			Latitude:  l.Location.Latitude,
 			Longitude: l.Location.Longitude,
@@ -71,11 +136,15 @@ func (l *Location) NewLocationOutDB() *dto.LocationOutDB {
 // to the format that will response a request user.
 func (l *Location) NewLocationOutApp() *dto.LocationOutApp {
 	return &dto.LocationOutApp{
-		ID:        l.ID,
-		VehicleId: l.VehicleId,
-		Timestamp: l.Timestamp,
-		Speed:     l.Speed,
-		Status:    l.Status,
+		ID:         l.ID,
+		VehicleId:  l.VehicleId,
+		Timestamp:  l.Timestamp,
+		Speed:      l.Speed,
+		Status:     l.Status,
+		Attributes: l.Attributes,
+		Flags:      l.Flags,
+		DeletedAt:  l.DeletedAt,
+		Version:    l.Version,
 		Location: &dto.CoordinatesOutApp{
 			Latitude:  l.Location.Latitude,
 			Longitude: l.Location.Longitude,
@@ -83,21 +152,105 @@ func (l *Location) NewLocationOutApp() *dto.LocationOutApp {
 	}
 }
 
+// NewPatchableLocationInApp is a function that exports the fields of the location that can be patched
+// to the format of the update request, so a patch changes the same fields a client sends on an update.
+func (l *Location) NewPatchableLocationInApp() *dto.LocationInApp {
+	return &dto.LocationInApp{
+		VehicleId:  l.VehicleId,
+		Latitude:   l.Location.Latitude,
+		Longitude:  l.Location.Longitude,
+		Status:     l.Status,
+		Speed:      l.Speed,
+		Attributes: l.Attributes,
+	}
+}
+
+// NewLocationPatchOutDB is a function that exports the fields of the patched location that differ
+// from the location to the database format. It returns nil when no field changed.
+// The attributes are compared by their JSON values, so the numbers read from the database
+// match the ones decoded from a patch.
+func (l *Location) NewLocationPatchOutDB(patched *Location) *dto.LocationPatchOutDB {
+	patch := &dto.LocationPatchOutDB{}
+	changed := false
+
+	if patched.VehicleId != l.VehicleId {
+		patch.VehicleId, changed = &patched.VehicleId, true
+	}
+	if patched.Location.Latitude != l.Location.Latitude {
+		patch.Latitude, changed = &patched.Location.Latitude, true
+	}
+	if patched.Location.Longitude != l.Location.Longitude {
+		patch.Longitude, changed = &patched.Location.Longitude, true
+	}
+	if patched.Speed != l.Speed {
+		patch.Speed, changed = &patched.Speed, true
+	}
+	if patched.Status != l.Status {
+		patch.Status, changed = &patched.Status, true
+	}
+
+	for key, value := range patched.Attributes {
+		current, ok := l.Attributes[key]
+		if ok && sameJSON(current, value) {
+			continue
+		}
+		if patch.SetAttributes == nil {
+			patch.SetAttributes = make(map[string]any)
+		}
+		patch.SetAttributes[key], changed = value, true
+	}
+	for key := range l.Attributes {
+		if _, ok := patched.Attributes[key]; !ok {
+			patch.RemoveAttributes, changed = append(patch.RemoveAttributes, key), true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+	return patch
+}
+
+// sameJSON reports if the values have the same JSON encoding.
+func sameJSON(a, b any) bool {
+	dataA, errA := json.Marshal(a)
+	dataB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(dataA, dataB)
+}
+
 // QueryLocationRequest is the entity that represents a request to query locations.
 type QueryLocationRequest struct {
-	Limit     int    `bson:"limit" json:"limit"`
-	Page      int    `bson:"page" json:"page"`
-	VehicleId string `bson:"vehicle_id" json:"vehicle_id"`
-	Status    string `bson:"status" json:"status"`
+	Limit     int       `bson:"limit" json:"limit"`
+	Page      int       `bson:"page" json:"page"`
+	VehicleId string    `bson:"vehicle_id" json:"vehicle_id"`
+	Status    string    `bson:"status" json:"status"`
+	From      time.Time `bson:"from" json:"from"`
+	To        time.Time `bson:"to" json:"to"`
+	Simplify  float64   `bson:"simplify" json:"simplify"`
+	Flagged   bool      `bson:"flagged" json:"flagged"`
+	Flag      string    `bson:"flag" json:"flag"`
+
+	IncludeDeleted bool `bson:"include_deleted" json:"include_deleted"`
 }
 
-// NewQueryLocationRequest is a function that creates a new query location request
+// NewQueryLocationRequest is a function that creates a new query location request.
+// The period was validated as RFC 3339 by the *dto.QueryLocationRequest struct, empty values mean no limit.
 func NewQueryLocationRequest(query *dto.QueryLocationRequest) *QueryLocationRequest {
+	from, _ := time.Parse(time.RFC3339, query.From)
+	to, _ := time.Parse(time.RFC3339, query.To)
+
 	return &QueryLocationRequest{
 		Limit:     query.Limit,
 		Page:      query.Page,
 		VehicleId: query.VehicleId,
 		Status:    query.Status,
+		From:      from,
+		To:        to,
+		Simplify:  query.Simplify,
+		Flagged:   query.Flagged,
+		Flag:      query.Flag,
+
+		IncludeDeleted: query.IncludeDeleted,
 	}
 }
 
@@ -108,6 +261,12 @@ func (q *QueryLocationRequest) NewQueryLocationOutDB() *dto.QueryLocationOutDB {
 		Page:      q.Page,
 		VehicleId: q.VehicleId,
 		Status:    q.Status,
+		From:      q.From,
+		To:        q.To,
+		Flagged:   q.Flagged,
+		Flag:      q.Flag,
+
+		IncludeDeleted: q.IncludeDeleted,
 	}
 }
 
@@ -0,0 +1,92 @@
+package entity
+
+// Authentication methods of the principals.
+const (
+	AuthAPIKey = "api_key"
+	AuthJWT    = "jwt"
+	// AuthInternal is the method of the principals of the ingestion transports, trusted by the application.
+	AuthInternal = "internal"
+	// AuthNone is the method of the clients of the API when the authentication is disabled.
+	AuthNone = "none"
+)
+
+// Principal is the entity that represents the client authenticated in a request,
+// by an API key or a token, with the roles it was granted. The roles are the API key scopes.
+// The vehicles and the fleet groups, when any, are the only ones the client can access.
+// The tenant is the fleet operator whose data the client accesses, the default tenant when empty.
+// The IP and the request ID identify the request in the audit log.
+type Principal struct {
+	Subject   string   `json:"subject"`
+	Name      string   `json:"name"`
+	Method    string   `json:"method"`
+	Tenant    string   `json:"tenant,omitempty"`
+	Roles     []string `json:"roles"`
+	Vehicles  []string `json:"vehicles,omitempty"`
+	Groups    []string `json:"groups,omitempty"`
+	IP        string   `json:"-"`
+	RequestID string   `json:"-"`
+}
+
+// HasRole is a function that reports if the principal has the role. The admin role grants all the others.
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role || r == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Restricted is a function that reports if the principal can only access its vehicles and fleet groups.
+// The admins and the clients without vehicles nor groups access all the vehicles.
+func (p *Principal) Restricted() bool {
+	return !p.HasRole(ScopeAdmin) && (len(p.Vehicles) > 0 || len(p.Groups) > 0)
+}
+
+// Anonymous is a function that reports if the principal is not identified, like the clients of the API
+// when the authentication is disabled. A nil principal is anonymous.
+func (p *Principal) Anonymous() bool {
+	return p == nil || p.Method == AuthNone
+}
+
+// Internal is a function that reports if the principal is trusted by the application, like the ingestion
+// transports and locationctl, and not a client of the API.
+func (p *Principal) Internal() bool {
+	return p != nil && p.Method == AuthInternal
+}
+
+// NewPrincipal is a function that creates the principal of the requests authenticated by the API key.
+func (k *APIKey) NewPrincipal() *Principal {
+	return &Principal{
+		Subject:  k.ID,
+		Name:     k.Name,
+		Method:   AuthAPIKey,
+		Tenant:   k.Tenant,
+		Roles:    k.Scopes,
+		Vehicles: k.Vehicles,
+		Groups:   k.Groups,
+	}
+}
+
+// NewInternalPrincipal is a function that creates the principal of the data received by an ingestion transport,
+// like mqtt, for a tenant, the default one when empty. It accesses all the vehicles of the tenant.
+func NewInternalPrincipal(name, tenant string) *Principal {
+	return &Principal{
+		Subject: name,
+		Name:    name,
+		Method:  AuthInternal,
+		Tenant:  tenant,
+		Roles:   []string{ScopeAdmin},
+	}
+}
+
+// NewAnonymousPrincipal is a function that creates the principal of the requests when the authentication
+// is disabled. It accesses all the vehicles of the default tenant.
+func NewAnonymousPrincipal() *Principal {
+	return &Principal{
+		Subject: "anonymous",
+		Name:    "anonymous",
+		Method:  AuthNone,
+		Roles:   []string{ScopeAdmin},
+	}
+}
@@ -0,0 +1,78 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+)
+
+// DailyStats is the entity that represents the statistics of a vehicle in a day.
+// The distance is in meters, the speeds in km/h and the durations in seconds.
+type DailyStats struct {
+	VehicleId       string    `bson:"vehicle_id" json:"vehicle_id"`
+	Day             time.Time `bson:"day" json:"day"`
+	Points          int       `bson:"points" json:"points"`
+	Distance        float64   `bson:"distance" json:"distance"`
+	MaxSpeed        int       `bson:"max_speed" json:"max_speed"`
+	AvgSpeed        float64   `bson:"avg_speed" json:"avg_speed"`
+	MovingDuration  int64     `bson:"moving_duration" json:"moving_duration"`
+	StoppedDuration int64     `bson:"stopped_duration" json:"stopped_duration"`
+	OfflineDuration int64     `bson:"offline_duration" json:"offline_duration"`
+	FirstSeen       time.Time `bson:"first_seen" json:"first_seen"`
+	LastSeen        time.Time `bson:"last_seen" json:"last_seen"`
+	UpdatedAt       time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// NewDailyStatsInDB is a function that creates the daily statistics in the application.
+// The data is coming from the database.
+func NewDailyStatsInDB(stats *dto.DailyStatsInDB) *DailyStats {
+	return &DailyStats{
+		VehicleId:       stats.VehicleId,
+		Day:             stats.Day,
+		Points:          stats.Points,
+		Distance:        stats.Distance,
+		MaxSpeed:        stats.MaxSpeed,
+		AvgSpeed:        stats.AvgSpeed,
+		MovingDuration:  stats.MovingDuration,
+		StoppedDuration: stats.StoppedDuration,
+		OfflineDuration: stats.OfflineDuration,
+		FirstSeen:       stats.FirstSeen,
+		LastSeen:        stats.LastSeen,
+		UpdatedAt:       stats.UpdatedAt,
+	}
+}
+
+// NewDailyStatsOutDB is a function that exports the daily statistics to the database format.
+func (s *DailyStats) NewDailyStatsOutDB() *dto.DailyStatsOutDB {
+	return &dto.DailyStatsOutDB{
+		VehicleId:       s.VehicleId,
+		Day:             s.Day,
+		Points:          s.Points,
+		Distance:        s.Distance,
+		MaxSpeed:        s.MaxSpeed,
+		AvgSpeed:        s.AvgSpeed,
+		MovingDuration:  s.MovingDuration,
+		StoppedDuration: s.StoppedDuration,
+		OfflineDuration: s.OfflineDuration,
+		FirstSeen:       s.FirstSeen,
+		LastSeen:        s.LastSeen,
+		UpdatedAt:       s.UpdatedAt,
+	}
+}
+
+// NewDailyStatsOutApp is a function that exports the daily statistics to the application format.
+func (s *DailyStats) NewDailyStatsOutApp() *dto.DailyStatsOutApp {
+	return &dto.DailyStatsOutApp{
+		VehicleId:       s.VehicleId,
+		Day:             s.Day.UTC().Format(time.DateOnly),
+		Points:          s.Points,
+		Distance:        s.Distance,
+		MaxSpeed:        s.MaxSpeed,
+		AvgSpeed:        s.AvgSpeed,
+		MovingDuration:  s.MovingDuration,
+		StoppedDuration: s.StoppedDuration,
+		OfflineDuration: s.OfflineDuration,
+		FirstSeen:       s.FirstSeen,
+		LastSeen:        s.LastSeen,
+	}
+}
@@ -0,0 +1,101 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+)
+
+// Statuses of a vehicle.
+const (
+	StatusMoving  = "moving"
+	StatusStopped = "stopped"
+	StatusOffline = "offline"
+)
+
+// Sources of the status transitions, the TCP ones are followed by the protocol, like tcp:gt06.
+const (
+	SourceAPI    = "api"
+	SourceNMEA   = "nmea"
+	SourceMQTT   = "mqtt"
+	SourceTCP    = "tcp"
+	SourceUpdate = "update"
+)
+
+// isStatus reports if the status is one of the statuses of a vehicle.
+func isStatus(status string) bool {
+	return status == StatusMoving || status == StatusStopped || status == StatusOffline
+}
+
+// StatusTransition is the entity that represents a change of the status of a vehicle.
+type StatusTransition struct {
+	ID         string    `bson:"_id,omitempty" json:"id"`
+	VehicleId  string    `bson:"vehicle_id" json:"vehicle_id"`
+	From       string    `bson:"from" json:"from"`
+	To         string    `bson:"to" json:"to"`
+	At         time.Time `bson:"at" json:"at"`
+	Source     string    `bson:"source" json:"source"`
+	LocationId string    `bson:"location_id" json:"location_id"`
+}
+
+// NewStatusTransition is a function that creates the transition of a vehicle between two statuses.
+// A vehicle can go from any status to any other one, since the devices report the status they observe.
+// It returns nil when the status didn't change, and an error when a status is unknown, like on old locations.
+func NewStatusTransition(vehicleID, from, to string, at time.Time, source, locationID string) (*StatusTransition, error) {
+	if from == to {
+		return nil, nil
+	}
+	for _, status := range []string{from, to} {
+		if !isStatus(status) {
+			return nil, fmt.Errorf("unknown status %q", status)
+		}
+	}
+
+	return &StatusTransition{
+		VehicleId:  vehicleID,
+		From:       from,
+		To:         to,
+		At:         at,
+		Source:     source,
+		LocationId: locationID,
+	}, nil
+}
+
+// NewStatusTransitionInDB is a function that creates a transition in the application.
+// The data is coming from the database.
+func NewStatusTransitionInDB(transition *dto.StatusTransitionInDB) *StatusTransition {
+	return &StatusTransition{
+		ID:         transition.ID.Hex(),
+		VehicleId:  transition.VehicleId,
+		From:       transition.From,
+		To:         transition.To,
+		At:         transition.At,
+		Source:     transition.Source,
+		LocationId: transition.LocationId,
+	}
+}
+
+// NewStatusTransitionOutDB is a function that exports the transition to the database format.
+func (t *StatusTransition) NewStatusTransitionOutDB() *dto.StatusTransitionOutDB {
+	return &dto.StatusTransitionOutDB{
+		VehicleId:  t.VehicleId,
+		From:       t.From,
+		To:         t.To,
+		At:         t.At,
+		Source:     t.Source,
+		LocationId: t.LocationId,
+	}
+}
+
+// NewStatusTransitionOutApp is a function that exports the transition to the application format.
+func (t *StatusTransition) NewStatusTransitionOutApp() *dto.StatusTransitionOutApp {
+	return &dto.StatusTransitionOutApp{
+		ID:         t.ID,
+		From:       t.From,
+		To:         t.To,
+		At:         t.At,
+		Source:     t.Source,
+		LocationId: t.LocationId,
+	}
+}
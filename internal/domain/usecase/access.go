@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+)
+
+// ErrVehicleNotAllowed is returned when the client requests or changes a vehicle it is not allowed to access.
+var ErrVehicleNotAllowed = errors.New("the client is not allowed to access the vehicle")
+
+type fleetUseCase struct {
+	groups map[string][]string
+}
+
+var f = fleetUseCase{groups: make(map[string][]string)}
+
+// LoadFleetGroups sets the fleet groups that the clients can be allowed to access,
+// from the entries in the format <group>=<vehicle id>|<vehicle id>, like acme=ABC1234|DEF5678.
+func LoadFleetGroups(entries []string) error {
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		group, vehicles, found := strings.Cut(entry, "=")
+		group = strings.TrimSpace(group)
+		if !found || group == "" || strings.TrimSpace(vehicles) == "" {
+			return fmt.Errorf("invalid fleet group %q, expected <group>=<vehicle id>|<vehicle id>", entry)
+		}
+
+		for _, vehicleID := range strings.Split(vehicles, "|") {
+			if vehicleID = strings.TrimSpace(vehicleID); vehicleID != "" {
+				f.groups[group] = append(f.groups[group], vehicleID)
+			}
+		}
+	}
+	return nil
+}
+
+// allowedVehicles returns the vehicles that the principal can access, with the ones of its fleet groups.
+// It returns nil when the principal is not restricted, like the admins, the clients without vehicles
+// nor groups and the internal transports. A missing principal and a restricted principal whose groups
+// don't exist get an empty list, so they can't access any vehicle.
+func allowedVehicles(principal *entity.Principal) []string {
+	if principal == nil {
+		return []string{}
+	}
+	if !principal.Restricted() {
+		return nil
+	}
+
+	vehicles := make([]string, 0, len(principal.Vehicles))
+	vehicles = append(vehicles, principal.Vehicles...)
+	for _, group := range principal.Groups {
+		vehicles = append(vehicles, f.groups[group]...)
+	}
+	return vehicles
+}
+
+// CheckVehicleAccess returns ErrVehicleNotAllowed when the principal can't access the vehicle,
+// and for any vehicle when there is no principal.
+// An empty vehicle ID is allowed, since the queries without a vehicle are restricted to the allowed ones.
+// It is used by the transports that must check the access before streaming their response.
+func CheckVehicleAccess(principal *entity.Principal, vehicleID string) error {
+	if principal == nil {
+		return ErrVehicleNotAllowed
+	}
+
+	vehicles := allowedVehicles(principal)
+	if vehicles == nil || vehicleID == "" || slices.Contains(vehicles, vehicleID) {
+		return nil
+	}
+	return ErrVehicleNotAllowed
+}
+
+// restrictQuery limits a query to the vehicles that the principal can access.
+// It returns ErrVehicleNotAllowed when the query is for a vehicle the principal can't access,
+// and ErrDeletedNotAllowed when the query includes the deleted locations without the admin role.
+func restrictQuery(principal *entity.Principal, query *dto.QueryLocationOutDB) error {
+	if query.IncludeDeleted {
+		if err := checkDeletedAccess(principal); err != nil {
+			return err
+		}
+	}
+	if err := CheckVehicleAccess(principal, query.VehicleId); err != nil {
+		return err
+	}
+
+	if query.VehicleId == "" {
+		query.VehicleIds = allowedVehicles(principal)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+)
+
+func TestCheckVehicleAccess(t *testing.T) {
+	f.groups = map[string][]string{"acme": {"GHI9012"}}
+	t.Cleanup(func() { f.groups = make(map[string][]string) })
+
+	restricted := &entity.Principal{Method: entity.AuthAPIKey, Roles: []string{entity.ScopeRead}, Vehicles: []string{"ABC1234"}, Groups: []string{"acme"}}
+	unknownGroup := &entity.Principal{Method: entity.AuthAPIKey, Roles: []string{entity.ScopeRead}, Groups: []string{"missing"}}
+	unrestricted := &entity.Principal{Method: entity.AuthAPIKey, Roles: []string{entity.ScopeRead}}
+
+	tests := []struct {
+		name      string
+		principal *entity.Principal
+		vehicleID string
+		wantErr   error
+	}{
+		{name: "own vehicle", principal: restricted, vehicleID: "ABC1234"},
+		{name: "vehicle of a group", principal: restricted, vehicleID: "GHI9012"},
+		{name: "other vehicle", principal: restricted, vehicleID: "DEF5678", wantErr: ErrVehicleNotAllowed},
+		{name: "query without a vehicle", principal: restricted},
+		{name: "unknown group", principal: unknownGroup, vehicleID: "GHI9012", wantErr: ErrVehicleNotAllowed},
+		{name: "unrestricted client", principal: unrestricted, vehicleID: "DEF5678"},
+		{name: "admin", principal: entity.NewAnonymousPrincipal(), vehicleID: "DEF5678"},
+		{name: "internal transport", principal: entity.NewInternalPrincipal("mqtt", ""), vehicleID: "DEF5678"},
+		{name: "missing principal", vehicleID: "DEF5678", wantErr: ErrVehicleNotAllowed},
+		{name: "missing principal without a vehicle", wantErr: ErrVehicleNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckVehicleAccess(tt.principal, tt.vehicleID); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckVehicleAccess() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAllowedVehicles(t *testing.T) {
+	f.groups = map[string][]string{"acme": {"GHI9012"}}
+	t.Cleanup(func() { f.groups = make(map[string][]string) })
+
+	if got := allowedVehicles(nil); got == nil || len(got) != 0 {
+		t.Errorf("allowedVehicles(nil) = %#v, want an empty list", got)
+	}
+	if got := allowedVehicles(entity.NewInternalPrincipal("mqtt", "")); got != nil {
+		t.Errorf("allowedVehicles(internal) = %v, want nil", got)
+	}
+
+	principal := &entity.Principal{Roles: []string{entity.ScopeRead}, Vehicles: []string{"ABC1234"}, Groups: []string{"acme"}}
+	if got, want := allowedVehicles(principal), []string{"ABC1234", "GHI9012"}; !slices.Equal(got, want) {
+		t.Errorf("allowedVehicles() = %v, want %v", got, want)
+	}
+}
+
+func TestRestrictQuery(t *testing.T) {
+	principal := &entity.Principal{Roles: []string{entity.ScopeRead}, Vehicles: []string{"ABC1234"}}
+
+	query := &dto.QueryLocationOutDB{}
+	if err := restrictQuery(principal, query); err != nil {
+		t.Fatalf("restrictQuery() error = %v", err)
+	}
+	if !slices.Equal(query.VehicleIds, []string{"ABC1234"}) {
+		t.Errorf("VehicleIds = %v, want [ABC1234]", query.VehicleIds)
+	}
+
+	if err := restrictQuery(nil, &dto.QueryLocationOutDB{}); !errors.Is(err, ErrVehicleNotAllowed) {
+		t.Errorf("restrictQuery(nil) error = %v, want %v", err, ErrVehicleNotAllowed)
+	}
+	if err := restrictQuery(principal, &dto.QueryLocationOutDB{IncludeDeleted: true}); !errors.Is(err, ErrDeletedNotAllowed) {
+		t.Errorf("restrictQuery() with the deleted locations error = %v, want %v", err, ErrDeletedNotAllowed)
+	}
+}
@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/pkg/geo"
+)
+
+// Anomaly flags set by the default location checks.
+const (
+	FlagNullIsland      = "null_island"
+	FlagImpossibleSpeed = "impossible_speed"
+	FlagDuplicate       = "duplicate"
+)
+
+// teleportMinDistance is the distance in meters under which a jump is attributed to the GPS precision.
+const teleportMinDistance = 100
+
+// LocationCheck is a stage of the validation of the locations saved by SaveLocation.
+// It compares a location with the previous one of the same vehicle, which is nil for
+// the first location, and returns the anomaly flag found or an empty string.
+type LocationCheck func(location, previous *entity.Location) string
+
+// LocationRejectedError is returned when a location has anomalies configured to be rejected.
+type LocationRejectedError struct {
+	Flags []string
+}
+
+func (e *LocationRejectedError) Error() string {
+	return fmt.Sprintf("location rejected by the anomaly checks: %s", strings.Join(e.Flags, ", "))
+}
+
+type anomalyUseCase struct {
+	checks []LocationCheck
+	reject map[string]bool
+}
+
+var a anomalyUseCase
+
+// LoadAnomalyUseCase sets the checks applied to the locations saved by SaveLocation.
+// The locations with any of the reject flags are not saved, the other anomalies are saved with their flags.
+func LoadAnomalyUseCase(checks []LocationCheck, reject []string) {
+	a.checks = checks
+	a.reject = make(map[string]bool, len(reject))
+	for _, flag := range reject {
+		a.reject[strings.TrimSpace(flag)] = true
+	}
+}
+
+// DefaultLocationChecks returns the checks for (0,0) coordinates, jumps that imply
+// a speed above maxSpeed in km/h and fixes repeated with the same timestamp.
+func DefaultLocationChecks(maxSpeed float64) []LocationCheck {
+	return []LocationCheck{
+		NullIslandCheck,
+		ImpossibleSpeedCheck(maxSpeed),
+		DuplicateCheck,
+	}
+}
+
+// NullIslandCheck flags the (0,0) coordinates sent by receivers without a fix.
+func NullIslandCheck(location, _ *entity.Location) string {
+	point, err := location.Location.Point()
+	if err == nil && point.Latitude == 0 && point.Longitude == 0 {
+		return FlagNullIsland
+	}
+	return ""
+}
+
+// ImpossibleSpeedCheck flags the locations that are too far from the previous one
+// to be reached at maxSpeed in km/h, like a truck that appears 300 km away for one sample.
+func ImpossibleSpeedCheck(maxSpeed float64) LocationCheck {
+	return func(location, previous *entity.Location) string {
+		if previous == nil {
+			return ""
+		}
+
+		point, err := location.Location.Point()
+		if err != nil {
+			return ""
+		}
+		previousPoint, err := previous.Location.Point()
+		if err != nil {
+			return ""
+		}
+
+		distance := geo.Distance(previousPoint, point)
+		if distance < teleportMinDistance {
+			return ""
+		}
+
+		hours := location.Timestamp.Sub(previous.Timestamp).Hours()
+		if hours <= 0 || (distance/1000)/hours > maxSpeed {
+			return FlagImpossibleSpeed
+		}
+		return ""
+	}
+}
+
+// DuplicateCheck flags the stale fixes sent again with the same timestamp and coordinates of the previous one.
+func DuplicateCheck(location, previous *entity.Location) string {
+	if previous == nil || !location.Timestamp.Equal(previous.Timestamp) {
+		return ""
+	}
+
+	if location.Location.Latitude == previous.Location.Latitude && location.Location.Longitude == previous.Location.Longitude {
+		return FlagDuplicate
+	}
+	return ""
+}
+
+// checkAnomalies applies the checks to a location, comparing it with the previous location of the vehicle,
+// setting its flags. It returns a LocationRejectedError when any flag is configured to be rejected.
+func checkAnomalies(location, previous *entity.Location) error {
+	var rejected []string
+	for _, check := range a.checks {
+		flag := check(location, previous)
+		if flag == "" {
+			continue
+		}
+
+		location.Flags = append(location.Flags, flag)
+		if a.reject[flag] {
+			rejected = append(rejected, flag)
+		}
+	}
+
+	if len(rejected) > 0 {
+		return &LocationRejectedError{Flags: rejected}
+	}
+	return nil
+}
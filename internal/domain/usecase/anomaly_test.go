@@ -0,0 +1,208 @@
+package usecase
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+)
+
+// locationAt returns a location of the vehicle ABC1234 at the coordinates, the seconds after noon of 2025-01-01.
+func locationAt(latitude, longitude string, seconds int) *entity.Location {
+	return &entity.Location{
+		VehicleId: "ABC1234",
+		Timestamp: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC).Add(time.Duration(seconds) * time.Second),
+		Location:  &entity.Coordinates{Latitude: latitude, Longitude: longitude},
+		Status:    entity.StatusMoving,
+	}
+}
+
+// useAnomalyChecks sets the checks of the use cases for the duration of the test.
+func useAnomalyChecks(t *testing.T, checks []LocationCheck, reject ...string) {
+	previous := a
+	LoadAnomalyUseCase(checks, reject)
+	t.Cleanup(func() { a = previous })
+}
+
+func TestNullIslandCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		location *entity.Location
+		want     string
+	}{
+		{name: "null island", location: locationAt("0", "0", 0), want: FlagNullIsland},
+		{name: "null island with decimals", location: locationAt("0.000000", "-0.000000", 0), want: FlagNullIsland},
+		{name: "on the equator", location: locationAt("0", "0.0001", 0)},
+		{name: "on the prime meridian", location: locationAt("51.4778", "0", 0)},
+		{name: "invalid coordinates", location: locationAt("north", "0", 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NullIslandCheck(tt.location, nil); got != tt.want {
+				t.Errorf("NullIslandCheck() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImpossibleSpeedCheck(t *testing.T) {
+	// 0.1 degree of latitude is ~11.12 km, reached at 200 km/h in ~200.15 seconds.
+	previous := locationAt("10", "20", 0)
+	check := ImpossibleSpeedCheck(200)
+
+	tests := []struct {
+		name     string
+		location *entity.Location
+		previous *entity.Location
+		want     string
+	}{
+		{name: "first location", location: locationAt("10.1", "20", 1)},
+		{name: "just under the maximum speed", location: locationAt("10.1", "20", 201), previous: previous},
+		{name: "just over the maximum speed", location: locationAt("10.1", "20", 199), previous: previous, want: FlagImpossibleSpeed},
+		{name: "jump with the same timestamp", location: locationAt("10.1", "20", 0), previous: previous, want: FlagImpossibleSpeed},
+		{name: "jump back in time", location: locationAt("10.1", "20", -60), previous: previous, want: FlagImpossibleSpeed},
+		{name: "drift with the same timestamp", location: locationAt("10.0005", "20", 0), previous: previous},
+		{name: "invalid coordinates", location: locationAt("north", "20", 1), previous: previous},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := check(tt.location, tt.previous); got != tt.want {
+				t.Errorf("ImpossibleSpeedCheck() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuplicateCheck(t *testing.T) {
+	previous := locationAt("10", "20", 0)
+
+	tests := []struct {
+		name     string
+		location *entity.Location
+		previous *entity.Location
+		want     string
+	}{
+		{name: "first location", location: locationAt("10", "20", 0)},
+		{name: "same timestamp and coordinates", location: locationAt("10", "20", 0), previous: previous, want: FlagDuplicate},
+		{name: "same timestamp on other coordinates", location: locationAt("10.0001", "20", 0), previous: previous},
+		{name: "same coordinates later", location: locationAt("10", "20", 1), previous: previous},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DuplicateCheck(tt.location, tt.previous); got != tt.want {
+				t.Errorf("DuplicateCheck() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckAnomalies(t *testing.T) {
+	tests := []struct {
+		name         string
+		reject       []string
+		location     *entity.Location
+		previous     *entity.Location
+		wantFlags    []string
+		wantRejected []string
+	}{
+		{name: "valid location", location: locationAt("10", "20", 60), previous: locationAt("10", "20", 0)},
+		{name: "flagged location", location: locationAt("0", "0", 0), wantFlags: []string{FlagNullIsland}},
+		{
+			name:         "rejected location",
+			reject:       []string{FlagNullIsland},
+			location:     locationAt("0", "0", 0),
+			wantFlags:    []string{FlagNullIsland},
+			wantRejected: []string{FlagNullIsland},
+		},
+		{
+			name:      "flag not rejected",
+			reject:    []string{FlagImpossibleSpeed},
+			location:  locationAt("10", "20", 0),
+			previous:  locationAt("10", "20", 0),
+			wantFlags: []string{FlagDuplicate},
+		},
+		{
+			name:         "some flags rejected",
+			reject:       []string{FlagImpossibleSpeed},
+			location:     locationAt("0", "0", 0),
+			previous:     locationAt("10", "20", 0),
+			wantFlags:    []string{FlagNullIsland, FlagImpossibleSpeed},
+			wantRejected: []string{FlagImpossibleSpeed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useAnomalyChecks(t, DefaultLocationChecks(200), tt.reject...)
+
+			err := checkAnomalies(tt.location, tt.previous)
+			if !slices.Equal(tt.location.Flags, tt.wantFlags) {
+				t.Errorf("checkAnomalies() flags = %v, want %v", tt.location.Flags, tt.wantFlags)
+			}
+
+			var rejected *LocationRejectedError
+			if !errors.As(err, &rejected) {
+				if tt.wantRejected != nil {
+					t.Errorf("checkAnomalies() error = %v, want the rejected flags %v", err, tt.wantRejected)
+				}
+				return
+			}
+			if !slices.Equal(rejected.Flags, tt.wantRejected) {
+				t.Errorf("checkAnomalies() rejected = %v, want %v", rejected.Flags, tt.wantRejected)
+			}
+		})
+	}
+}
+
+func TestSaveLocationAnomalies(t *testing.T) {
+	principal := entity.NewInternalPrincipal("mqtt", "")
+	nullIsland := &dto.LocationInApp{VehicleId: "ABC1234", Latitude: "0", Longitude: "0", Status: entity.StatusMoving}
+	at := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("flagged", func(t *testing.T) {
+		repository := newFakeRepository()
+		useFakeRepository(t, repository)
+		useAnomalyChecks(t, DefaultLocationChecks(200))
+
+		out, err := SaveLocationAt(principal, nullIsland, at, entity.SourceAPI)
+		if err != nil {
+			t.Fatalf("SaveLocationAt() error = %v", err)
+		}
+		if !slices.Equal(out.Flags, []string{FlagNullIsland}) {
+			t.Errorf("SaveLocationAt() flags = %v, want %v", out.Flags, []string{FlagNullIsland})
+		}
+		if len(repository.store.locations) != 1 {
+			t.Errorf("SaveLocationAt() saved %d locations, want 1", len(repository.store.locations))
+		}
+
+		// The same fix sent again is saved as a duplicate.
+		out, err = SaveLocationAt(principal, nullIsland, at, entity.SourceAPI)
+		if err != nil {
+			t.Fatalf("SaveLocationAt() error = %v", err)
+		}
+		if !slices.Equal(out.Flags, []string{FlagNullIsland, FlagDuplicate}) {
+			t.Errorf("SaveLocationAt() flags = %v, want %v", out.Flags, []string{FlagNullIsland, FlagDuplicate})
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		repository := newFakeRepository()
+		useFakeRepository(t, repository)
+		useAnomalyChecks(t, DefaultLocationChecks(200), FlagNullIsland)
+
+		_, err := SaveLocationAt(principal, nullIsland, at, entity.SourceAPI)
+		var rejected *LocationRejectedError
+		if !errors.As(err, &rejected) {
+			t.Fatalf("SaveLocationAt() error = %v, want a *LocationRejectedError", err)
+		}
+		if len(repository.store.locations) != 0 {
+			t.Errorf("SaveLocationAt() saved %d locations, want 0", len(repository.store.locations))
+		}
+	})
+}
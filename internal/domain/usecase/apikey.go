@@ -0,0 +1,255 @@
+package usecase
+
+import (
+	"crypto/subtle"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/provider/db"
+)
+
+// apiKeyCacheTTL is how long an authenticated key is trusted without reading it again from the database.
+// It bounds the time a key revoked by another instance of the API keeps working.
+const apiKeyCacheTTL = 30 * time.Second
+
+var (
+	// ErrInvalidAPIKey is returned when a key doesn't exist, is revoked or is expired.
+	ErrInvalidAPIKey = errors.New("the api key is invalid, revoked or expired")
+	// ErrAPIKeyRevoked is returned when rotating or revoking a key that is already revoked.
+	ErrAPIKeyRevoked = errors.New("the api key is revoked")
+	// ErrInvalidAPIKeyExpiry is returned when issuing a key that expires in the past.
+	ErrInvalidAPIKeyExpiry = errors.New("the api key must expire in the future")
+	// ErrTenantNotAllowed is returned when a client issues a key of another tenant.
+	ErrTenantNotAllowed = errors.New("the api key must belong to the tenant of the client")
+	// ErrPrincipalRequired is returned when the keys are managed without a principal.
+	ErrPrincipalRequired = errors.New("the api keys can only be managed by an identified client")
+)
+
+type cachedAPIKey struct {
+	apiKey   *entity.APIKey
+	cachedAt time.Time
+}
+
+type apiKeyUseCase struct {
+	mu    sync.Mutex
+	cache map[string]cachedAPIKey
+}
+
+var k = apiKeyUseCase{cache: make(map[string]cachedAPIKey)}
+
+// IssueAPIKey creates an API key with the scopes. The key is returned only here, the database keeps its hash.
+// The changes of the keys are recorded in the audit log of their tenant, without their secrets.
+// The key belongs to the tenant of the principal, and ErrTenantNotAllowed is returned when another tenant is requested.
+// An internal principal, like the one of locationctl, issues the key of the requested tenant, the default one when empty.
+// It returns ErrPrincipalRequired without a principal.
+func IssueAPIKey(principal *entity.Principal, in *dto.APIKeyInApp) (*dto.APIKeyIssuedOut, error) {
+	if principal == nil {
+		return nil, ErrPrincipalRequired
+	}
+	if in.ExpiresAt != nil && !in.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidAPIKeyExpiry
+	}
+
+	if !principal.Internal() && in.Tenant != "" && in.Tenant != principal.Tenant {
+		return nil, ErrTenantNotAllowed
+	}
+
+	repository := tenantRepository(principal)
+	if principal.Internal() {
+		repository = l.repository.Tenant(in.Tenant)
+	}
+
+	apiKey, key, err := entity.NewAPIKeyInApp(in)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeyOutDB := apiKey.NewAPIKeyOutDB()
+	apiKey.ID, err = repository.InsertAPIKey(apiKeyOutDB)
+	if err != nil {
+		return nil, err
+	}
+	apiKey.Tenant = apiKeyOutDB.Tenant
+
+	apiKeyDataOut := apiKey.NewAPIKeyOutApp()
+	err = recordAudit(repository, principal, entity.AuditCreate, entity.ResourceAPIKey, auditChange{id: apiKey.ID, after: apiKeyDataOut})
+
+	return &dto.APIKeyIssuedOut{APIKeyOutApp: apiKeyDataOut, Key: key}, err
+}
+
+// GetAPIKeys returns the API keys of the tenant of the principal, without their secrets.
+// An internal principal gets the keys of all the tenants.
+func GetAPIKeys(principal *entity.Principal) (*dto.APIKeyListOut, error) {
+	repository, err := apiKeysRepository(principal)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeysInDB, err := repository.GetAPIKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.APIKeyListOut{Data: make([]*dto.APIKeyOutApp, 0, len(apiKeysInDB))}
+	for _, apiKeyInDB := range apiKeysInDB {
+		out.Data = append(out.Data, entity.NewAPIKeyInDB(apiKeyInDB).NewAPIKeyOutApp())
+	}
+	out.Success = len(out.Data) != 0
+	return out, nil
+}
+
+// RotateAPIKey replaces the secret of an API key, keeping its name, scopes and expiry.
+// The previous key stops working immediately. It returns ErrAPIKeyRevoked for revoked keys.
+// The keys of other tenants than the one of the principal are not found.
+func RotateAPIKey(principal *entity.Principal, id string) (*dto.APIKeyIssuedOut, error) {
+	repository, err := apiKeysRepository(principal)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeyInDB, err := repository.GetAPIKey(id)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey := entity.NewAPIKeyInDB(apiKeyInDB)
+	if apiKey.RevokedAt != nil {
+		return nil, ErrAPIKeyRevoked
+	}
+	before := apiKey.NewAPIKeyOutApp()
+
+	key, err := apiKey.Rotate()
+	if err != nil {
+		return nil, err
+	}
+
+	rotated, err := repository.RotateAPIKey(id, apiKey.Prefix, apiKey.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, ErrAPIKeyRevoked
+	}
+	k.forget(id)
+
+	apiKeyDataOut := apiKey.NewAPIKeyOutApp()
+	err = recordAudit(l.repository.Tenant(apiKey.Tenant), principal, entity.AuditRotate, entity.ResourceAPIKey, auditChange{id: id, before: before, after: apiKeyDataOut})
+
+	return &dto.APIKeyIssuedOut{APIKeyOutApp: apiKeyDataOut, Key: key}, err
+}
+
+// RevokeAPIKey revokes an API key, which can't be used nor rotated anymore.
+// It returns ErrAPIKeyRevoked when the key was already revoked.
+// The keys of other tenants than the one of the principal are not found.
+func RevokeAPIKey(principal *entity.Principal, id string) error {
+	repository, err := apiKeysRepository(principal)
+	if err != nil {
+		return err
+	}
+
+	apiKeyInDB, err := repository.GetAPIKey(id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	revoked, err := repository.RevokeAPIKey(id, now)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyRevoked
+	}
+	k.forget(id)
+
+	apiKey := entity.NewAPIKeyInDB(apiKeyInDB)
+	before := apiKey.NewAPIKeyOutApp()
+	apiKey.RevokedAt = &now
+	err = recordAudit(l.repository.Tenant(apiKey.Tenant), principal, entity.AuditRevoke, entity.ResourceAPIKey, auditChange{id: id, before: before, after: apiKey.NewAPIKeyOutApp()})
+
+	return err
+}
+
+// AuthenticateAPIKey returns the API key that matches the key sent by a client, of any tenant.
+// It returns ErrInvalidAPIKey when the key doesn't exist, is revoked or is expired.
+func AuthenticateAPIKey(key string) (*entity.APIKey, error) {
+	now := time.Now()
+	hash := entity.HashAPIKey(key)
+
+	if apiKey := k.get(hash, now); apiKey != nil {
+		if !apiKey.Active(now) {
+			return nil, ErrInvalidAPIKey
+		}
+		return apiKey, nil
+	}
+
+	prefix, ok := entity.ParseAPIKeyPrefix(key)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKeyInDB, err := l.repository.GetAPIKeyByPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if apiKeyInDB == nil || subtle.ConstantTimeCompare([]byte(apiKeyInDB.Hash), []byte(hash)) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey := entity.NewAPIKeyInDB(apiKeyInDB)
+	if !apiKey.Active(now) {
+		return nil, ErrInvalidAPIKey
+	}
+	k.set(hash, apiKey, now)
+
+	return apiKey, nil
+}
+
+// apiKeysRepository returns the repository of the keys managed by the principal, the ones of its tenant.
+// An internal principal manages the keys of all the tenants. It returns ErrPrincipalRequired without a principal.
+func apiKeysRepository(principal *entity.Principal) (db.Repository, error) {
+	if principal == nil {
+		return nil, ErrPrincipalRequired
+	}
+	if principal.Internal() {
+		return l.repository, nil
+	}
+	return tenantRepository(principal), nil
+}
+
+func (u *apiKeyUseCase) get(hash string, now time.Time) *entity.APIKey {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	cached, ok := u.cache[hash]
+	if !ok {
+		return nil
+	}
+	if now.Sub(cached.cachedAt) > apiKeyCacheTTL {
+		delete(u.cache, hash)
+		return nil
+	}
+	return cached.apiKey
+}
+
+func (u *apiKeyUseCase) set(hash string, apiKey *entity.APIKey, now time.Time) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.cache[hash] = cachedAPIKey{apiKey: apiKey, cachedAt: now}
+}
+
+// forget removes a key from the cache after it is rotated or revoked.
+func (u *apiKeyUseCase) forget(id string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for hash, cached := range u.cache {
+		if cached.apiKey.ID == id {
+			delete(u.cache, hash)
+		}
+	}
+}
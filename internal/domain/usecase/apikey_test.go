@@ -0,0 +1,290 @@
+package usecase
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/provider/db"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// fakeRepository keeps the API keys, the locations, the transitions and the audit entries of the tenants in memory.
+// The repository of all the tenants is the one not returned by Tenant.
+type fakeRepository struct {
+	db.Repository
+	tenant string
+	scoped bool
+	store  *fakeStore
+}
+
+type fakeStore struct {
+	apiKeys     []*dto.APIKeyInDB
+	locations   []*dto.LocationInDB
+	transitions []*dto.StatusTransitionOutDB
+	dailyStats  []*dto.DailyStatsOutDB
+	insertErr   error
+	audit       map[string][]*dto.AuditEntryOutDB
+	auditErr    error
+}
+
+func newFakeRepository(apiKeys ...*dto.APIKeyInDB) *fakeRepository {
+	return &fakeRepository{store: &fakeStore{apiKeys: apiKeys, audit: make(map[string][]*dto.AuditEntryOutDB)}}
+}
+
+// useFakeRepository sets the repository of the use cases for the duration of the test.
+func useFakeRepository(t *testing.T, repository *fakeRepository) {
+	previous := l.repository
+	l.repository = repository
+	t.Cleanup(func() { l.repository = previous })
+}
+
+func (r *fakeRepository) Tenant(id string) db.Repository {
+	return &fakeRepository{tenant: id, scoped: true, store: r.store}
+}
+
+func (r *fakeRepository) InsertAPIKey(apiKey *dto.APIKeyOutDB) (string, error) {
+	apiKey.Tenant = r.tenant
+	id := bson.NewObjectID()
+	r.store.apiKeys = append(r.store.apiKeys, &dto.APIKeyInDB{ID: id, Tenant: r.tenant, Name: apiKey.Name, Scopes: apiKey.Scopes})
+	return id.Hex(), nil
+}
+
+func (r *fakeRepository) GetAPIKeys() ([]*dto.APIKeyInDB, error) {
+	var apiKeys []*dto.APIKeyInDB
+	for _, apiKey := range r.store.apiKeys {
+		if !r.scoped || apiKey.Tenant == r.tenant {
+			apiKeys = append(apiKeys, apiKey)
+		}
+	}
+	return apiKeys, nil
+}
+
+// GetOne returns a copy of the location, as it is decoded from the database.
+func (r *fakeRepository) GetOne(id string, includeDeleted bool) (*dto.LocationInDB, error) {
+	location, err := r.storedLocation(id, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	found := *location
+	return &found, nil
+}
+
+func (r *fakeRepository) storedLocation(id string, includeDeleted bool) (*dto.LocationInDB, error) {
+	for _, location := range r.store.locations {
+		if location.ID.Hex() == id && (includeDeleted || location.DeletedAt == nil) {
+			return location, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *fakeRepository) GetPrevious(vehicleID string, timestamp time.Time) (*dto.LocationInDB, error) {
+	var previous *dto.LocationInDB
+	for _, location := range r.store.locations {
+		if location.VehicleId != vehicleID || location.Timestamp.After(timestamp) || location.DeletedAt != nil {
+			continue
+		}
+		if previous == nil || !location.Timestamp.Before(previous.Timestamp) {
+			previous = location
+		}
+	}
+	return previous, nil
+}
+
+// Stream calls fn with the locations of the vehicle in the period, ordered by timestamp.
+func (r *fakeRepository) Stream(query *dto.QueryLocationOutDB, fn func(location *dto.LocationInDB) error) error {
+	locations := slices.Clone(r.store.locations)
+	slices.SortStableFunc(locations, func(a, b *dto.LocationInDB) int { return a.Timestamp.Compare(b.Timestamp) })
+
+	for _, location := range locations {
+		if query.VehicleId != "" && location.VehicleId != query.VehicleId ||
+			!query.From.IsZero() && location.Timestamp.Before(query.From) ||
+			!query.To.IsZero() && location.Timestamp.After(query.To) ||
+			location.DeletedAt != nil && !query.IncludeDeleted {
+			continue
+		}
+		if err := fn(location); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepository) InsertOne(location *dto.LocationOutDB) (string, error) {
+	ids, err := r.InsertMany([]*dto.LocationOutDB{location})
+	if err != nil {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+func (r *fakeRepository) InsertMany(locations []*dto.LocationOutDB) ([]string, error) {
+	if r.store.insertErr != nil {
+		return nil, r.store.insertErr
+	}
+
+	ids := make([]string, 0, len(locations))
+	for _, location := range locations {
+		location.Version = 1
+		id := bson.NewObjectID()
+		r.store.locations = append(r.store.locations, &dto.LocationInDB{
+			ID:         id,
+			VehicleId:  location.VehicleId,
+			Timestamp:  location.Timestamp,
+			Location:   &dto.CoordinatesInDB{Latitude: location.Location.Latitude, Longitude: location.Location.Longitude},
+			Speed:      location.Speed,
+			Status:     location.Status,
+			Attributes: location.Attributes,
+			Flags:      location.Flags,
+			Version:    location.Version,
+		})
+		ids = append(ids, id.Hex())
+	}
+	return ids, nil
+}
+
+func (r *fakeRepository) UpdateOne(id string, version int64, location *dto.LocationOutDB) (bool, error) {
+	current, err := r.storedLocation(id, false)
+	if err != nil || current.Version != version {
+		return false, nil
+	}
+
+	*current = dto.LocationInDB{
+		ID:         current.ID,
+		VehicleId:  location.VehicleId,
+		Timestamp:  location.Timestamp,
+		Location:   &dto.CoordinatesInDB{Latitude: location.Location.Latitude, Longitude: location.Location.Longitude},
+		Speed:      location.Speed,
+		Status:     location.Status,
+		Attributes: location.Attributes,
+		Flags:      location.Flags,
+		Version:    version + 1,
+	}
+	return true, nil
+}
+
+func (r *fakeRepository) PatchOne(id string, version int64, patch *dto.LocationPatchOutDB) (bool, error) {
+	current, err := r.storedLocation(id, false)
+	if err != nil || current.Version != version {
+		return false, nil
+	}
+
+	if patch.VehicleId != nil {
+		current.VehicleId = *patch.VehicleId
+	}
+	if patch.Status != nil {
+		current.Status = *patch.Status
+	}
+	if patch.Speed != nil {
+		current.Speed = *patch.Speed
+	}
+	current.Version++
+	return true, nil
+}
+
+func (r *fakeRepository) InsertTransition(transition *dto.StatusTransitionOutDB) (string, error) {
+	r.store.transitions = append(r.store.transitions, transition)
+	return bson.NewObjectID().Hex(), nil
+}
+
+func (r *fakeRepository) SaveDailyStats(stats []*dto.DailyStatsOutDB) error {
+	r.store.dailyStats = append(r.store.dailyStats, stats...)
+	return nil
+}
+
+func (r *fakeRepository) InsertAuditEntries(entries []*dto.AuditEntryOutDB) error {
+	if r.store.auditErr != nil {
+		return r.store.auditErr
+	}
+	r.store.audit[r.tenant] = append(r.store.audit[r.tenant], entries...)
+	return nil
+}
+
+func TestAPIKeysRequirePrincipal(t *testing.T) {
+	useFakeRepository(t, newFakeRepository())
+
+	if _, err := IssueAPIKey(nil, &dto.APIKeyInApp{Name: "fleet", Scopes: []string{entity.ScopeRead}}); !errors.Is(err, ErrPrincipalRequired) {
+		t.Errorf("IssueAPIKey() error = %v, want %v", err, ErrPrincipalRequired)
+	}
+	if _, err := GetAPIKeys(nil); !errors.Is(err, ErrPrincipalRequired) {
+		t.Errorf("GetAPIKeys() error = %v, want %v", err, ErrPrincipalRequired)
+	}
+	if _, err := RotateAPIKey(nil, bson.NewObjectID().Hex()); !errors.Is(err, ErrPrincipalRequired) {
+		t.Errorf("RotateAPIKey() error = %v, want %v", err, ErrPrincipalRequired)
+	}
+	if err := RevokeAPIKey(nil, bson.NewObjectID().Hex()); !errors.Is(err, ErrPrincipalRequired) {
+		t.Errorf("RevokeAPIKey() error = %v, want %v", err, ErrPrincipalRequired)
+	}
+}
+
+func TestIssueAPIKeyTenant(t *testing.T) {
+	client := &entity.Principal{Subject: "key", Method: entity.AuthAPIKey, Tenant: "acme", Roles: []string{entity.ScopeAdmin}}
+
+	tests := []struct {
+		name       string
+		principal  *entity.Principal
+		tenant     string
+		wantTenant string
+		wantErr    error
+	}{
+		{name: "client of its tenant", principal: client, wantTenant: "acme"},
+		{name: "client requesting its tenant", principal: client, tenant: "acme", wantTenant: "acme"},
+		{name: "client requesting another tenant", principal: client, tenant: "globex", wantErr: ErrTenantNotAllowed},
+		{name: "anonymous client", principal: entity.NewAnonymousPrincipal(), wantTenant: ""},
+		{name: "anonymous client requesting another tenant", principal: entity.NewAnonymousPrincipal(), tenant: "globex", wantErr: ErrTenantNotAllowed},
+		{name: "locationctl", principal: entity.NewInternalPrincipal("locationctl", "globex"), tenant: "globex", wantTenant: "globex"},
+		{name: "locationctl on the default tenant", principal: entity.NewInternalPrincipal("locationctl", ""), wantTenant: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFakeRepository(t, newFakeRepository())
+
+			out, err := IssueAPIKey(tt.principal, &dto.APIKeyInApp{Name: "fleet", Tenant: tt.tenant, Scopes: []string{entity.ScopeRead}})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("IssueAPIKey() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if out.Tenant != tt.wantTenant {
+				t.Errorf("IssueAPIKey() tenant = %q, want %q", out.Tenant, tt.wantTenant)
+			}
+		})
+	}
+}
+
+func TestGetAPIKeysTenant(t *testing.T) {
+	useFakeRepository(t, newFakeRepository(
+		&dto.APIKeyInDB{ID: bson.NewObjectID(), Name: "default"},
+		&dto.APIKeyInDB{ID: bson.NewObjectID(), Tenant: "acme", Name: "acme"},
+		&dto.APIKeyInDB{ID: bson.NewObjectID(), Tenant: "globex", Name: "globex"},
+	))
+
+	tests := []struct {
+		name      string
+		principal *entity.Principal
+		want      int
+	}{
+		{name: "client", principal: &entity.Principal{Method: entity.AuthAPIKey, Tenant: "acme", Roles: []string{entity.ScopeAdmin}}, want: 1},
+		{name: "anonymous client", principal: entity.NewAnonymousPrincipal(), want: 1},
+		{name: "locationctl", principal: entity.NewInternalPrincipal("locationctl", ""), want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := GetAPIKeys(tt.principal)
+			if err != nil {
+				t.Fatalf("GetAPIKeys() error = %v", err)
+			}
+			if len(out.Data) != tt.want {
+				t.Errorf("GetAPIKeys() = %d keys, want %d", len(out.Data), tt.want)
+			}
+		})
+	}
+}
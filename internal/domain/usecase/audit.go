@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/provider/db"
+)
+
+// ErrAuditNotRecorded is returned, with the result of the change, when a change was saved but its entries
+// were not recorded in the audit log. The change is not undone, so the callers must not retry it.
+var ErrAuditNotRecorded = errors.New("the change was saved but not recorded in the audit log")
+
+// auditChange is a change of a resource to record in the audit log, with its documents before and after it.
+type auditChange struct {
+	id     string
+	before any
+	after  any
+}
+
+// recordAudit appends the changes of the resource made by the principal to the audit log of the repository's tenant.
+// The changes are already saved, so it returns ErrAuditNotRecorded, with the cause, when any entry is not recorded.
+func recordAudit(repository db.Repository, principal *entity.Principal, action, resource string, changes ...auditChange) error {
+	var errs []error
+	entries := make([]*dto.AuditEntryOutDB, 0, len(changes))
+	for _, change := range changes {
+		entry, err := entity.NewAuditEntry(principal, action, resource, change.id, change.before, change.after)
+		if err != nil {
+			slog.Error("error recording audit entry", "error", err.Error(), "action", action, "resource", resource, "resourceID", change.id)
+			errs = append(errs, err)
+			continue
+		}
+		entries = append(entries, entry.NewAuditEntryOutDB())
+	}
+
+	if len(entries) != 0 {
+		if err := repository.InsertAuditEntries(entries); err != nil {
+			slog.Error("error recording audit entries", "error", err.Error(), "action", action, "resource", resource, "count", len(entries))
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("%w: %w", ErrAuditNotRecorded, errors.Join(errs...))
+	}
+	return nil
+}
+
+// GetAuditEntries returns the entries of the audit log of the tenant of the principal matching the query, from the newest.
+func GetAuditEntries(principal *entity.Principal, request *dto.AuditRequest) (*dto.AuditResponseOut, error) {
+	from, _ := time.Parse(time.RFC3339, request.From)
+	to, _ := time.Parse(time.RFC3339, request.To)
+
+	entriesInDB, err := tenantRepository(principal).GetAuditEntries(&dto.QueryAuditOutDB{
+		Limit:      request.Limit,
+		Page:       request.Page,
+		Action:     request.Action,
+		Resource:   request.Resource,
+		ResourceId: request.ResourceId,
+		Actor:      request.Actor,
+		RequestId:  request.RequestId,
+		From:       from,
+		To:         to,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.AuditResponseOut{
+		Data: make([]*dto.AuditEntryOutApp, 0, len(entriesInDB.Data)),
+		Pagination: &dto.PaginationInfoResponse{
+			Page:  entriesInDB.Page,
+			Limit: entriesInDB.Limit,
+		},
+	}
+	for _, entryInDB := range entriesInDB.Data {
+		response.Data = append(response.Data, entity.NewAuditEntryInDB(entryInDB).NewAuditEntryOutApp())
+	}
+	response.Success = len(response.Data) != 0
+	return response, nil
+}
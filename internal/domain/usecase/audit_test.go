@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+)
+
+func TestRecordAudit(t *testing.T) {
+	repository := newFakeRepository()
+	principal := entity.NewInternalPrincipal("mqtt", "")
+
+	err := recordAudit(repository.Tenant("acme"), principal, entity.AuditCreate, entity.ResourceLocation,
+		auditChange{id: "1", after: map[string]any{"speed": 10}},
+		auditChange{id: "2", after: map[string]any{"speed": 20}},
+	)
+	if err != nil {
+		t.Fatalf("recordAudit() error = %v", err)
+	}
+	if entries := repository.store.audit["acme"]; len(entries) != 2 {
+		t.Errorf("recordAudit() recorded %d entries in the tenant, want 2", len(entries))
+	}
+}
+
+func TestRecordAuditFailure(t *testing.T) {
+	repository := newFakeRepository()
+	repository.store.auditErr = errors.New("connection lost")
+
+	err := recordAudit(repository, entity.NewInternalPrincipal("mqtt", ""), entity.AuditCreate, entity.ResourceLocation, auditChange{id: "1"})
+	if !errors.Is(err, ErrAuditNotRecorded) || !errors.Is(err, repository.store.auditErr) {
+		t.Errorf("recordAudit() error = %v, want %v with its cause", err, ErrAuditNotRecorded)
+	}
+
+	// A document that can't be encoded is reported too.
+	repository.store.auditErr = nil
+	err = recordAudit(repository, entity.NewInternalPrincipal("mqtt", ""), entity.AuditCreate, entity.ResourceLocation, auditChange{id: "1", after: func() {}})
+	if !errors.Is(err, ErrAuditNotRecorded) {
+		t.Errorf("recordAudit() with an invalid document error = %v, want %v", err, ErrAuditNotRecorded)
+	}
+}
+
+func TestIssueAPIKeyAuditFailure(t *testing.T) {
+	repository := newFakeRepository()
+	repository.store.auditErr = errors.New("connection lost")
+	useFakeRepository(t, repository)
+
+	// The key is saved, so it is returned with the failure, since it can't be retrieved later.
+	out, err := IssueAPIKey(entity.NewInternalPrincipal("locationctl", ""), &dto.APIKeyInApp{Name: "fleet", Scopes: []string{entity.ScopeRead}})
+	if !errors.Is(err, ErrAuditNotRecorded) {
+		t.Fatalf("IssueAPIKey() error = %v, want %v", err, ErrAuditNotRecorded)
+	}
+	if out == nil || out.Key == "" {
+		t.Errorf("IssueAPIKey() = %+v, want the issued key", out)
+	}
+	if len(repository.store.apiKeys) != 1 {
+		t.Errorf("IssueAPIKey() saved %d keys, want 1", len(repository.store.apiKeys))
+	}
+}
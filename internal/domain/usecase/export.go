@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/pkg/export"
+)
+
+// ExportLocations writes all locations matching the query parameters to w in the format, csv or parquet.
+// The locations are streamed from the database, so the export doesn't hold them in memory.
+// The locations are limited to the vehicles that the principal can access, all of them when it is nil.
+func ExportLocations(principal *entity.Principal, queryParams *dto.QueryLocationRequest, format string, w io.Writer) error {
+	exportWriter, err := export.NewWriter(format, w)
+	if err != nil {
+		return err
+	}
+
+	err = StreamLocations(principal, queryParams, func(location *dto.LocationOutApp) error {
+		row, err := newExportRow(location)
+		if err != nil {
+			return err
+		}
+		return exportWriter.WriteRow(row)
+	})
+	if err != nil {
+		return err
+	}
+
+	return exportWriter.Close()
+}
+
+func newExportRow(location *dto.LocationOutApp) (*export.Row, error) {
+	latitude, err := strconv.ParseFloat(location.Location.Latitude, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid latitude on location %s: %w", location.ID, err)
+	}
+
+	longitude, err := strconv.ParseFloat(location.Location.Longitude, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid longitude on location %s: %w", location.ID, err)
+	}
+
+	attributes, err := export.AttributesJSON(location.Attributes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attributes on location %s: %w", location.ID, err)
+	}
+
+	return &export.Row{
+		ID:         location.ID,
+		VehicleId:  location.VehicleId,
+		Timestamp:  location.Timestamp,
+		Latitude:   latitude,
+		Longitude:  longitude,
+		Speed:      int64(location.Speed),
+		Status:     location.Status,
+		Attributes: attributes,
+	}, nil
+}
@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"math"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/pkg/gazetteer"
+)
+
+type geocoderUseCase struct {
+	gazetteer   *gazetteer.Gazetteer
+	maxDistance float64
+}
+
+var g geocoderUseCase
+
+// LoadGeocoderUseCase sets the gazetteer used to enrich the locations and stops with the nearest place.
+// The places farther than maxDistance in meters are ignored. Without a gazetteer, the outputs have no place.
+func LoadGeocoderUseCase(places *gazetteer.Gazetteer, maxDistance float64) {
+	g.gazetteer = places
+	g.maxDistance = maxDistance
+}
+
+// ReverseGeocode returns the nearest place of the coordinates, or nil when there is none close enough.
+func ReverseGeocode(latitude, longitude string) *dto.PlaceOutApp {
+	if g.gazetteer == nil {
+		return nil
+	}
+
+	coordinates := &entity.Coordinates{Latitude: latitude, Longitude: longitude}
+	point, err := coordinates.Point()
+	if err != nil {
+		return nil
+	}
+
+	place, distance := g.gazetteer.Nearest(point)
+	if place == nil || distance > g.maxDistance {
+		return nil
+	}
+
+	return &dto.PlaceOutApp{
+		Name:     place.Label(),
+		Country:  place.Country,
+		Distance: math.Round(distance*10) / 10,
+	}
+}
+
+// geocodeLocations sets the place of the locations.
+func geocodeLocations(locations ...*dto.LocationOutApp) {
+	if g.gazetteer == nil {
+		return
+	}
+
+	for _, location := range locations {
+		location.Place = ReverseGeocode(location.Location.Latitude, location.Location.Longitude)
+	}
+}
@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"sort"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/pkg/geohash"
+)
+
+// DefaultHeatmapLimit is the number of cells returned when the request has no limit.
+const DefaultHeatmapLimit = 1000
+
+type heatmapCell struct {
+	count    int
+	speedSum int
+}
+
+// GetHeatmap buckets the locations matching the filters by the geohash of the requested precision,
+// returning the densest cells with their count and average speed. The locations are streamed,
+// so only the cells are held in memory. The locations are limited to the vehicles that the principal can access.
+func GetHeatmap(principal *entity.Principal, request *dto.HeatmapRequest) (*dto.HeatmapResponseOut, error) {
+	qLocationOutDB := entity.NewQueryLocationRequest(request.NewQueryLocationRequest()).NewQueryLocationOutDB()
+	if err := restrictQuery(principal, qLocationOutDB); err != nil {
+		return nil, err
+	}
+
+	cells := make(map[string]*heatmapCell)
+	err := tenantRepository(principal).Stream(qLocationOutDB, func(locationInDB *dto.LocationInDB) error {
+		location := entity.NewLocationInDB(locationInDB)
+		point, err := location.Location.Point()
+		if err != nil {
+			return err
+		}
+
+		hash := geohash.Encode(point.Latitude, point.Longitude, request.Precision)
+		cell, ok := cells[hash]
+		if !ok {
+			cell = new(heatmapCell)
+			cells[hash] = cell
+		}
+		cell.count++
+		cell.speedSum += location.Speed
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.HeatmapResponseOut{
+		Precision:  request.Precision,
+		TotalCells: len(cells),
+		Cells:      make([]*dto.HeatmapCellOut, 0, len(cells)),
+	}
+	for hash, cell := range cells {
+		latitude, longitude := geohash.Decode(hash).Center()
+		response.Cells = append(response.Cells, &dto.HeatmapCellOut{
+			Geohash:   hash,
+			Latitude:  latitude,
+			Longitude: longitude,
+			Count:     cell.count,
+			AvgSpeed:  float64(cell.speedSum) / float64(cell.count),
+		})
+	}
+
+	sort.Slice(response.Cells, func(i, j int) bool {
+		if response.Cells[i].Count != response.Cells[j].Count {
+			return response.Cells[i].Count > response.Cells[j].Count
+		}
+		return response.Cells[i].Geohash < response.Cells[j].Geohash
+	})
+
+	limit := request.Limit
+	if limit == 0 {
+		limit = DefaultHeatmapLimit
+	}
+	if len(response.Cells) > limit {
+		response.Cells = response.Cells[:limit]
+	}
+
+	return response, nil
+}
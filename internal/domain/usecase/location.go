@@ -1,11 +1,34 @@
 package usecase
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/allansbo/goapi/internal/app/server/dto"
 	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/pkg/etag"
+	"github.com/allansbo/goapi/internal/pkg/jsonpatch"
+	"github.com/allansbo/goapi/internal/pkg/validation"
 	"github.com/allansbo/goapi/internal/provider/db"
 )
 
+var (
+	// ErrDeletedNotAllowed is returned when a client without the admin role accesses the deleted locations.
+	ErrDeletedNotAllowed = errors.New("only the admin role can access the deleted locations")
+	// ErrLocationNotDeleted is returned when restoring a location that is not deleted.
+	ErrLocationNotDeleted = errors.New("the location is not deleted")
+	// ErrVersionMismatch is returned when changing a location that is not on the expected version.
+	ErrVersionMismatch = errors.New("the location was changed, its version doesn't match the expected one")
+	// ErrPatchNotApplied is returned when a patch can't be applied to a location, like a JSON patch test that fails.
+	ErrPatchNotApplied = errors.New("the patch can't be applied to the location")
+	// ErrInvalidPatchedLocation is returned when the location changed by a patch is not valid.
+	ErrInvalidPatchedLocation = errors.New("the patched location is not valid")
+)
+
 type locationUseCase struct {
 	repository db.Repository
 }
@@ -19,69 +42,463 @@ func LoadLocationUseCase(repository db.Repository) {
 // SaveLocation saves a new location in the database and returns the saved location.
 // It takes a pointer to dto.LocationInApp as input, which contains the validated location data.
 // It returns a pointer to dto.LocationOutApp and an error if any occurs.
-func SaveLocation(locationDataIn *dto.LocationInApp) (*dto.LocationOutApp, error) {
-	locationEntity := entity.NewLocationInApp(locationDataIn)
+func SaveLocation(principal *entity.Principal, locationDataIn *dto.LocationInApp) (*dto.LocationOutApp, error) {
+	return SaveLocationAt(principal, locationDataIn, time.Now(), entity.SourceAPI)
+}
+
+// SaveLocationAt saves a new location in the database recorded at the provided timestamp.
+// It is used by the ingestion transports, where the devices report the time of the fix.
+// The location goes through the anomaly checks first and a *LocationRejectedError is returned
+// when it has anomalies configured to be rejected, and a *QuotaExceededError when the tenant
+// has saved its daily quota of locations. When the status of the vehicle changes,
+// the transition is recorded with the source, like api or mqtt.
+// The principal is the client of the request or the internal principal of the transport,
+// and ErrVehicleNotAllowed is returned when it can't access the vehicle.
+// The location is saved on the tenant of the principal, and its creation is recorded in the audit log.
+func SaveLocationAt(principal *entity.Principal, locationDataIn *dto.LocationInApp, timestamp time.Time, source string) (*dto.LocationOutApp, error) {
+	if err := CheckVehicleAccess(principal, locationDataIn.VehicleId); err != nil {
+		return nil, err
+	}
+	locationEntity := entity.NewLocationInAppAt(locationDataIn, timestamp)
+	repository := tenantRepository(principal)
+
+	previous, err := getPreviousLocation(repository, locationEntity)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkAnomalies(locationEntity, previous); err != nil {
+		return nil, err
+	}
+	refundQuota, err := consumeQuota(principal, 1)
+	if err != nil {
+		return nil, err
+	}
 	locationOutDB := locationEntity.NewLocationOutDB()
 
-	var err error
-	locationEntity.ID, err = l.repository.InsertOne(locationOutDB)
+	locationEntity.ID, err = repository.InsertOne(locationOutDB)
+	if err != nil {
+		refundQuota(1)
+		return nil, err
+	}
+	locationEntity.Version = locationOutDB.Version
+
+	recordTransition(repository, previous, locationEntity, source)
+
+	locationDataOut := locationEntity.NewLocationOutApp()
+	err = recordAudit(repository, principal, entity.AuditCreate, entity.ResourceLocation, auditChange{id: locationEntity.ID, after: locationDataOut})
+
+	return locationDataOut, err
+}
+
+// getPreviousLocation returns the latest location of the vehicle until the timestamp of the location, if any.
+func getPreviousLocation(repository db.Repository, location *entity.Location) (*entity.Location, error) {
+	previousInDB, err := repository.GetPrevious(location.VehicleId, location.Timestamp)
+	if err != nil || previousInDB == nil {
+		return nil, err
+	}
+	return entity.NewLocationInDB(previousInDB), nil
+}
+
+// SaveLocationsAt saves a batch of locations recorded at known timestamps in a single bulk operation,
+// like the history imported from other providers. It returns the IDs of the saved locations.
+// The anomaly checks are not applied and the status transitions are not recorded,
+// since the imported rows are not in the order they were recorded.
+// The locations are saved on the tenant of the principal, and ErrVehicleNotAllowed is returned,
+// saving none of them, when it can't access any of their vehicles, and a *QuotaExceededError
+// when they would go over the daily quota of the tenant. The locations not saved by a failed insert
+// are given back to the quota. The creation of every saved location is recorded in the audit log.
+func SaveLocationsAt(principal *entity.Principal, locationsDataIn []*dto.TimedLocationInApp) ([]string, error) {
+	locationEntities := make([]*entity.Location, 0, len(locationsDataIn))
+	locationsOutDB := make([]*dto.LocationOutDB, 0, len(locationsDataIn))
+	for _, locationDataIn := range locationsDataIn {
+		if err := CheckVehicleAccess(principal, locationDataIn.Location.VehicleId); err != nil {
+			return nil, err
+		}
+		locationEntity := entity.NewLocationInAppAt(locationDataIn.Location, locationDataIn.Timestamp)
+		locationEntities = append(locationEntities, locationEntity)
+		locationsOutDB = append(locationsOutDB, locationEntity.NewLocationOutDB())
+	}
+
+	refundQuota, err := consumeQuota(principal, len(locationsOutDB))
 	if err != nil {
 		return nil, err
 	}
+	repository := tenantRepository(principal)
+
+	ids, err := repository.InsertMany(locationsOutDB)
+	if err != nil {
+		refundQuota(len(locationsOutDB) - len(ids))
+		return ids, err
+	}
 
-	return locationEntity.NewLocationOutApp(), nil
+	changes := make([]auditChange, 0, len(ids))
+	for i, id := range ids {
+		locationEntities[i].ID = id
+		locationEntities[i].Version = locationsOutDB[i].Version
+		changes = append(changes, auditChange{id: id, after: locationEntities[i].NewLocationOutApp()})
+	}
+	err = recordAudit(repository, principal, entity.AuditCreate, entity.ResourceLocation, changes...)
+
+	return ids, err
 }
 
 // GetLocationById retrieves a location by its ID from the database.
 // It takes a string ID as input and returns a pointer to dto.LocationOutApp and an error if any occurs.
-func GetLocationById(id string) (*dto.LocationOutApp, error) {
-	locationInDB, err := l.repository.GetOne(id)
+// ErrVehicleNotAllowed is returned when the principal can't access the vehicle of the location.
+// The deleted locations are found only with includeDeleted, and ErrDeletedNotAllowed is returned
+// when the principal doesn't have the admin role.
+func GetLocationById(principal *entity.Principal, id string, includeDeleted bool) (*dto.LocationOutApp, error) {
+	if includeDeleted {
+		if err := checkDeletedAccess(principal); err != nil {
+			return nil, err
+		}
+	}
+
+	locationInDB, err := tenantRepository(principal).GetOne(id, includeDeleted)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := CheckVehicleAccess(principal, locationInDB.VehicleId); err != nil {
+		return nil, err
+	}
+
 	locationEntity := entity.NewLocationInDB(locationInDB)
 
-	return locationEntity.NewLocationOutApp(), nil
+	locationDataOut := locationEntity.NewLocationOutApp()
+	geocodeLocations(locationDataOut)
+
+	return locationDataOut, nil
 }
 
 // GetAllLocations retrieves all locations from the database based on the provided query parameters.
-func GetAllLocations(queryParams *dto.QueryLocationRequest) (*dto.QueryLocationResponse, error) {
+// The locations are limited to the vehicles that the principal can access.
+func GetAllLocations(principal *entity.Principal, queryParams *dto.QueryLocationRequest) (*dto.QueryLocationResponse, error) {
 	qLocationEntity := entity.NewQueryLocationRequest(queryParams)
 	qLocationOutDB := qLocationEntity.NewQueryLocationOutDB()
+	if err := restrictQuery(principal, qLocationOutDB); err != nil {
+		return nil, err
+	}
 
-	locationsInDB, err := l.repository.GetAll(qLocationOutDB)
+	locationsInDB, err := tenantRepository(principal).GetAll(qLocationOutDB)
 	if err != nil {
 		return nil, err
 	}
 
 	qLocationEntityOutApp := entity.NewQueryLocationResponse(locationsInDB)
 
-	return qLocationEntityOutApp.NewQueryLocationOutApp(), nil
+	if qLocationEntity.Simplify > 0 {
+		if qLocationEntityOutApp.Data, err = simplifyByVehicle(qLocationEntityOutApp.Data, qLocationEntity.Simplify); err != nil {
+			return nil, err
+		}
+	}
+
+	qLocationsOutApp := qLocationEntityOutApp.NewQueryLocationOutApp()
+	geocodeLocations(qLocationsOutApp.Data...)
+
+	return qLocationsOutApp, nil
+}
+
+// StreamLocations iterates over all locations matching the query parameters, ordered by timestamp,
+// calling fn for each one without loading the whole result in memory. The pagination is ignored.
+// When the query has a simplify tolerance, the locations are expected to be of a single vehicle
+// and only the ones needed to draw its track within the tolerance are returned.
+// The locations are limited to the vehicles that the principal can access.
+func StreamLocations(principal *entity.Principal, queryParams *dto.QueryLocationRequest, fn func(location *dto.LocationOutApp) error) error {
+	qLocationEntity := entity.NewQueryLocationRequest(queryParams)
+	qLocationOutDB := qLocationEntity.NewQueryLocationOutDB()
+	if err := restrictQuery(principal, qLocationOutDB); err != nil {
+		return err
+	}
+	repository := tenantRepository(principal)
+
+	if qLocationEntity.Simplify <= 0 {
+		return repository.Stream(qLocationOutDB, func(locationInDB *dto.LocationInDB) error {
+			return fn(entity.NewLocationInDB(locationInDB).NewLocationOutApp())
+		})
+	}
+
+	simplifier := &trackSimplifier{
+		tolerance: qLocationEntity.Simplify,
+		emit: func(location *entity.Location) error {
+			return fn(location.NewLocationOutApp())
+		},
+	}
+
+	err := repository.Stream(qLocationOutDB, func(locationInDB *dto.LocationInDB) error {
+		return simplifier.add(entity.NewLocationInDB(locationInDB))
+	})
+	if err != nil {
+		return err
+	}
+
+	return simplifier.flush()
 }
 
 // UpdateLocation updates an existing location in the database.
 // It takes a string ID and a pointer to dto.LocationInApp as input,
 // which contains the validated location data that will be updated.
-// It returns a boolean indicating success and an error if any occurs.
-func UpdateLocation(id string, locationDataIn *dto.LocationInApp) (bool, error) {
+// It returns the updated location, on its next version, and an error if any occurs.
+// The location is replaced, so the attributes and the flags left out of the request are removed.
+// When the status is changed, the transition is recorded with the update source, unless the vehicle is changed,
+// and the location before and after the update is recorded in the audit log.
+// ErrVehicleNotAllowed is returned when the principal can't access the current or the new vehicle.
+// The ifMatch entity tags, when not empty, must match the current version of the location,
+// and ErrVersionMismatch is returned when they don't or when it is changed during the update.
+func UpdateLocation(principal *entity.Principal, id string, locationDataIn *dto.LocationInApp, ifMatch string) (*dto.LocationOutApp, error) {
 	locationEntity := entity.NewLocationInApp(locationDataIn)
 	locationOutDB := locationEntity.NewLocationOutDB()
+	repository := tenantRepository(principal)
+
+	currentInDB, err := repository.GetOne(id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CheckVehicleAccess(principal, currentInDB.VehicleId); err != nil {
+		return nil, err
+	}
+	if err := CheckVehicleAccess(principal, locationEntity.VehicleId); err != nil {
+		return nil, err
+	}
+	if err := checkVersion(currentInDB, ifMatch); err != nil {
+		return nil, err
+	}
+
+	res, err := repository.UpdateOne(id, currentInDB.Version, locationOutDB)
+	if err != nil {
+		return nil, err
+	}
+	if !res {
+		return nil, ErrVersionMismatch
+	}
+
+	locationEntity.ID = id
+	recordUpdateTransition(repository, currentInDB, locationEntity)
+
+	locationEntity.Version = currentInDB.Version + 1
+	locationDataOut := locationEntity.NewLocationOutApp()
+	err = recordAudit(repository, principal, entity.AuditUpdate, entity.ResourceLocation, auditChange{
+		id:     id,
+		before: entity.NewLocationInDB(currentInDB).NewLocationOutApp(),
+		after:  locationDataOut,
+	})
+
+	return locationDataOut, err
+}
+
+// PatchLocation changes some fields of an existing location in the database and returns it.
+// The patch applies to the fields of the update request, the timestamp is kept,
+// and ErrPatchNotApplied is returned when it can't be applied and ErrInvalidPatchedLocation
+// when the patched location is not valid. Only the changed fields are saved, on the next version
+// of the location, and the location is returned as it is when none changed.
+// As on UpdateLocation, a change of the status is recorded with the update source,
+// the change is recorded in the audit log, ErrVehicleNotAllowed is returned when the principal
+// can't access the current or the new vehicle, and ErrVersionMismatch when the ifMatch
+// entity tags don't match the current version or it is changed during the patch.
+func PatchLocation(principal *entity.Principal, id string, patch jsonpatch.Patch, ifMatch string) (*dto.LocationOutApp, error) {
+	repository := tenantRepository(principal)
+
+	currentInDB, err := repository.GetOne(id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CheckVehicleAccess(principal, currentInDB.VehicleId); err != nil {
+		return nil, err
+	}
+	if err := checkVersion(currentInDB, ifMatch); err != nil {
+		return nil, err
+	}
+
+	currentEntity := entity.NewLocationInDB(currentInDB)
+	locationDataIn, err := applyPatch(currentEntity.NewPatchableLocationInApp(), patch)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckVehicleAccess(principal, locationDataIn.VehicleId); err != nil {
+		return nil, err
+	}
+
+	locationEntity := entity.NewLocationInAppAt(locationDataIn, currentEntity.Timestamp)
+	locationEntity.ID = id
+	locationEntity.Flags = currentEntity.Flags
+
+	patchOutDB := currentEntity.NewLocationPatchOutDB(locationEntity)
+	if patchOutDB == nil {
+		return currentEntity.NewLocationOutApp(), nil
+	}
 
-	res, err := l.repository.UpdateOne(id, locationOutDB)
+	res, err := repository.PatchOne(id, currentInDB.Version, patchOutDB)
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+	if !res {
+		return nil, ErrVersionMismatch
+	}
+
+	recordUpdateTransition(repository, currentInDB, locationEntity)
+
+	locationEntity.Version = currentInDB.Version + 1
+	locationDataOut := locationEntity.NewLocationOutApp()
+	err = recordAudit(repository, principal, entity.AuditUpdate, entity.ResourceLocation, auditChange{
+		id:     id,
+		before: currentEntity.NewLocationOutApp(),
+		after:  locationDataOut,
+	})
+
+	return locationDataOut, err
+}
+
+// applyPatch returns the location data changed by the patch, decoded as an update request and validated.
+// The attribute keys are checked too, since they are saved one by one as the paths of their fields.
+func applyPatch(locationDataIn *dto.LocationInApp, patch jsonpatch.Patch) (*dto.LocationInApp, error) {
+	data, err := json.Marshal(locationDataIn)
+	if err != nil {
+		return nil, err
+	}
+
+	var document any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	if document, err = patch.Apply(document); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPatchNotApplied, err)
+	}
+	if data, err = json.Marshal(document); err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	patched := new(dto.LocationInApp)
+	if err := decoder.Decode(patched); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatchedLocation, err)
+	}
+	if err := validation.Validate(patched); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatchedLocation, err)
+	}
+	for key := range patched.Attributes {
+		if key == "" || strings.Contains(key, ".") || strings.HasPrefix(key, "$") {
+			return nil, fmt.Errorf("%w: the attribute %q is empty, has dots or starts with $", ErrInvalidPatchedLocation, key)
+		}
 	}
 
-	return res, nil
+	return patched, nil
+}
+
+// checkVersion returns ErrVersionMismatch when the ifMatch entity tags, if any, don't match the version of the location.
+func checkVersion(locationInDB *dto.LocationInDB, ifMatch string) error {
+	if ifMatch != "" && !etag.Match(ifMatch, etag.Format(locationInDB.Version), false) {
+		return ErrVersionMismatch
+	}
+	return nil
 }
 
 // DeleteLocation deletes a location by its ID from the database.
-// It takes a string ID as input and returns a boolean indicating success and an error if any occurs.
-func DeleteLocation(id string) (bool, error) {
-	res, err := l.repository.DeleteOne(id)
+// It takes a string ID as input and returns an error if any occurs.
+// ErrVehicleNotAllowed is returned when the principal can't access the vehicle of the location.
+// The ifMatch entity tags, when not empty, must match the current version of the location,
+// and ErrVersionMismatch is returned when they don't or when it is changed during the deletion.
+// The location is only marked as deleted, so it can be restored until it is purged,
+// and it is recorded in the audit log.
+func DeleteLocation(principal *entity.Principal, id string, ifMatch string) error {
+	repository := tenantRepository(principal)
+
+	currentInDB, err := repository.GetOne(id, false)
+	if err != nil {
+		return err
+	}
+	if err := CheckVehicleAccess(principal, currentInDB.VehicleId); err != nil {
+		return err
+	}
+	if err := checkVersion(currentInDB, ifMatch); err != nil {
+		return err
+	}
+
+	res, err := repository.DeleteOne(id, currentInDB.Version, time.Now())
+	if err != nil {
+		return err
+	}
+	if !res {
+		return ErrVersionMismatch
+	}
+
+	err = recordAudit(repository, principal, entity.AuditDelete, entity.ResourceLocation, auditChange{
+		id:     id,
+		before: entity.NewLocationInDB(currentInDB).NewLocationOutApp(),
+	})
+	return err
+}
+
+// RestoreLocation removes the deletion mark of a location that was not purged yet and returns it.
+// ErrDeletedNotAllowed is returned when the principal doesn't have the admin role,
+// ErrVehicleNotAllowed when it can't access the vehicle of the location and ErrLocationNotDeleted
+// when the location is not deleted. The restoration is recorded in the audit log.
+func RestoreLocation(principal *entity.Principal, id string) (*dto.LocationOutApp, error) {
+	if err := checkDeletedAccess(principal); err != nil {
+		return nil, err
+	}
+	repository := tenantRepository(principal)
+
+	currentInDB, err := repository.GetOne(id, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckVehicleAccess(principal, currentInDB.VehicleId); err != nil {
+		return nil, err
+	}
+	if currentInDB.DeletedAt == nil {
+		return nil, ErrLocationNotDeleted
+	}
+
+	res, err := repository.RestoreOne(id)
+	if err != nil {
+		return nil, err
+	}
+	if !res {
+		return nil, ErrLocationNotDeleted
+	}
+
+	locationEntity := entity.NewLocationInDB(currentInDB)
+	before := locationEntity.NewLocationOutApp()
+	locationEntity.DeletedAt = nil
+	locationEntity.Version++
+	locationDataOut := locationEntity.NewLocationOutApp()
+	err = recordAudit(repository, principal, entity.AuditRestore, entity.ResourceLocation, auditChange{id: id, before: before, after: locationDataOut})
+
+	return locationDataOut, err
+}
+
+// PurgeDeletedLocations permanently removes the locations of every tenant deleted for longer than the retention
+// and returns how many were removed. A failure on a tenant doesn't stop the others, and the errors of all of them are returned.
+func PurgeDeletedLocations(retention time.Duration) (int64, error) {
+	tenants, err := l.repository.Tenants()
 	if err != nil {
-		return false, err
+		return 0, err
+	}
+
+	before := time.Now().Add(-retention)
+
+	var purged int64
+	var errs []error
+	for _, tenant := range tenants {
+		count, err := l.repository.Tenant(tenant).PurgeDeleted(before)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("tenant %s: %w", tenant, err))
+		}
+		purged += count
+	}
+	return purged, errors.Join(errs...)
+}
+
+// checkDeletedAccess returns ErrDeletedNotAllowed when the principal can't access the deleted locations,
+// which requires the admin role, and without a principal.
+func checkDeletedAccess(principal *entity.Principal) error {
+	if principal == nil || !principal.HasRole(entity.ScopeAdmin) {
+		return ErrDeletedNotAllowed
 	}
-	return res, nil
+	return nil
 }
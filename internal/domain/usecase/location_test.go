@@ -0,0 +1,134 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/pkg/jsonpatch"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestCheckDeletedAccess(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *entity.Principal
+		wantErr   error
+	}{
+		{name: "admin", principal: &entity.Principal{Method: entity.AuthAPIKey, Roles: []string{entity.ScopeAdmin}}},
+		{name: "anonymous client", principal: entity.NewAnonymousPrincipal()},
+		{name: "locationctl", principal: entity.NewInternalPrincipal("locationctl", "")},
+		{name: "client without the admin role", principal: &entity.Principal{Method: entity.AuthAPIKey, Roles: []string{entity.ScopeRead, entity.ScopeDelete}}, wantErr: ErrDeletedNotAllowed},
+		{name: "missing principal", wantErr: ErrDeletedNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkDeletedAccess(tt.principal); !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkDeletedAccess() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeletedLocationsRequirePrincipal(t *testing.T) {
+	// The access is checked before the location is read.
+	if _, err := GetLocationById(nil, "665f1c2b8e4a3b2d1c0f9e8d", true); !errors.Is(err, ErrDeletedNotAllowed) {
+		t.Errorf("GetLocationById() error = %v, want %v", err, ErrDeletedNotAllowed)
+	}
+	if _, err := RestoreLocation(nil, "665f1c2b8e4a3b2d1c0f9e8d"); !errors.Is(err, ErrDeletedNotAllowed) {
+		t.Errorf("RestoreLocation() error = %v, want %v", err, ErrDeletedNotAllowed)
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	locationInDB := &dto.LocationInDB{Version: 3}
+
+	tests := []struct {
+		ifMatch string
+		wantErr error
+	}{
+		{ifMatch: ""},
+		{ifMatch: `"3"`},
+		{ifMatch: `*`},
+		{ifMatch: `"2", "3"`},
+		{ifMatch: `"2"`, wantErr: ErrVersionMismatch},
+		{ifMatch: `W/"3"`, wantErr: ErrVersionMismatch},
+	}
+
+	for _, tt := range tests {
+		if err := checkVersion(locationInDB, tt.ifMatch); !errors.Is(err, tt.wantErr) {
+			t.Errorf("checkVersion(%q) error = %v, want %v", tt.ifMatch, err, tt.wantErr)
+		}
+	}
+}
+
+func TestUpdateLocationTransition(t *testing.T) {
+	principal := entity.NewInternalPrincipal("locationctl", "")
+
+	tests := []struct {
+		name      string
+		vehicleID string
+		status    string
+		want      []string
+	}{
+		{name: "status changed", vehicleID: "ABC1234", status: entity.StatusStopped, want: []string{entity.StatusMoving, entity.StatusStopped}},
+		{name: "status kept", vehicleID: "ABC1234", status: entity.StatusMoving},
+		{name: "vehicle changed", vehicleID: "DEF5678", status: entity.StatusStopped},
+	}
+
+	update := map[string]func(id, vehicleID, status string) error{
+		"put": func(id, vehicleID, status string) error {
+			_, err := UpdateLocation(principal, id, &dto.LocationInApp{VehicleId: vehicleID, Latitude: "10", Longitude: "20", Status: status}, "")
+			return err
+		},
+		"patch": func(id, vehicleID, status string) error {
+			patch, err := jsonpatch.DecodeMergePatch([]byte(fmt.Sprintf(`{"vehicle_id": %q, "status": %q}`, vehicleID, status)))
+			if err != nil {
+				return err
+			}
+			_, err = PatchLocation(principal, id, patch, "")
+			return err
+		},
+	}
+
+	for method, apply := range update {
+		for _, tt := range tests {
+			t.Run(method+" "+tt.name, func(t *testing.T) {
+				id := bson.NewObjectID()
+				repository := newFakeRepository()
+				repository.store.locations = append(repository.store.locations, &dto.LocationInDB{
+					ID:        id,
+					VehicleId: "ABC1234",
+					Timestamp: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+					Location:  &dto.CoordinatesInDB{Latitude: "10", Longitude: "20"},
+					Status:    entity.StatusMoving,
+					Version:   1,
+				})
+				useFakeRepository(t, repository)
+
+				if err := apply(id.Hex(), tt.vehicleID, tt.status); err != nil {
+					t.Fatalf("%s error = %v", method, err)
+				}
+
+				transitions := repository.store.transitions
+				if tt.want == nil {
+					if len(transitions) != 0 {
+						t.Errorf("transitions = %d, want 0", len(transitions))
+					}
+					return
+				}
+				if len(transitions) != 1 {
+					t.Fatalf("transitions = %d, want 1", len(transitions))
+				}
+				transition := transitions[0]
+				if transition.From != tt.want[0] || transition.To != tt.want[1] || transition.Source != entity.SourceUpdate || transition.VehicleId != tt.vehicleID {
+					t.Errorf("transition = %+v, want from %s to %s by %s", transition, tt.want[0], tt.want[1], entity.SourceUpdate)
+				}
+			})
+		}
+	}
+}
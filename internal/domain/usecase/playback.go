@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/pkg/geo"
+)
+
+// DefaultPlaybackMaxGap is the time in seconds without locations after which the vehicle is in a gap,
+// when the request doesn't set it.
+const DefaultPlaybackMaxGap = 600
+
+// MaxPlaybackFrames is the maximum number of positions of a playback.
+const MaxPlaybackFrames = 10000
+
+// headingMinDistance is the distance in meters under which the heading is kept from the previous movement,
+// since the bearing of the GPS noise of a stopped vehicle is meaningless.
+const headingMinDistance = 1
+
+// ErrInvalidPlayback is returned when the period of a playback is empty or has too many positions.
+var ErrInvalidPlayback = fmt.Errorf("the playback period must end after it starts and have at most %d positions", MaxPlaybackFrames)
+
+type playbackPoint struct {
+	location *entity.Location
+	point    geo.Point
+}
+
+// playback resamples the locations of a vehicle, ordered by timestamp, at a fixed time step.
+type playback struct {
+	next     time.Time
+	to       time.Time
+	interval time.Duration
+	maxGap   time.Duration
+	previous *playbackPoint
+	heading  float64
+	frames   []*dto.PlaybackFrameOut
+}
+
+// add emits the frames until the location, interpolating them from the previous one.
+func (p *playback) add(location *entity.Location) error {
+	point, err := location.Location.Point()
+	if err != nil {
+		return err
+	}
+	current := &playbackPoint{location: location, point: point}
+
+	if p.previous != nil && geo.Distance(p.previous.point, point) >= headingMinDistance {
+		p.heading = math.Round(geo.Bearing(p.previous.point, point)*10) / 10
+	}
+
+	for !p.next.After(p.to) && p.next.Before(location.Timestamp) {
+		p.frames = append(p.frames, p.frame(p.next, current))
+		p.next = p.next.Add(p.interval)
+	}
+
+	p.previous = current
+	return nil
+}
+
+// flush emits the frames after the last location.
+func (p *playback) flush() {
+	for !p.next.After(p.to) {
+		p.frames = append(p.frames, p.frame(p.next, nil))
+		p.next = p.next.Add(p.interval)
+	}
+}
+
+// frame returns the position at the timestamp, between the previous location and the following one, if any.
+func (p *playback) frame(timestamp time.Time, following *playbackPoint) *dto.PlaybackFrameOut {
+	frame := &dto.PlaybackFrameOut{Timestamp: timestamp, Heading: p.heading}
+	if p.previous == nil {
+		frame.Gap = true
+		return frame
+	}
+
+	previous := p.previous.location
+	frame.Location = &dto.CoordinatesOutApp{Latitude: previous.Location.Latitude, Longitude: previous.Location.Longitude}
+	frame.Speed = previous.Speed
+	frame.Status = previous.Status
+	frame.Interpolated = !timestamp.Equal(previous.Timestamp)
+
+	if previous.Status == "offline" {
+		frame.Gap = true
+		return frame
+	}
+
+	if !frame.Interpolated {
+		return frame
+	}
+
+	if following == nil {
+		frame.Gap = timestamp.Sub(previous.Timestamp) > p.maxGap
+		return frame
+	}
+
+	span := following.location.Timestamp.Sub(previous.Timestamp)
+	if span > p.maxGap {
+		frame.Gap = true
+		return frame
+	}
+
+	fraction := float64(timestamp.Sub(previous.Timestamp)) / float64(span)
+	frame.Location = &dto.CoordinatesOutApp{
+		Latitude:  formatCoordinate(p.previous.point.Latitude + (following.point.Latitude-p.previous.point.Latitude)*fraction),
+		Longitude: formatCoordinate(p.previous.point.Longitude + (following.point.Longitude-p.previous.point.Longitude)*fraction),
+	}
+	frame.Speed = int(math.Round(float64(previous.Speed) + float64(following.location.Speed-previous.Speed)*fraction))
+	return frame
+}
+
+func formatCoordinate(value float64) string {
+	return strconv.FormatFloat(value, 'f', 6, 64)
+}
+
+// GetPlayback returns the positions of a vehicle at every interval of the period, interpolated between its locations,
+// with the heading of the movement. The frames where the vehicle was offline or without locations for longer
+// than the max gap are marked as gaps. It returns ErrInvalidPlayback when the period is not valid.
+func GetPlayback(principal *entity.Principal, request *dto.PlaybackRequest) (*dto.PlaybackResponseOut, error) {
+	if err := CheckVehicleAccess(principal, request.VehicleId); err != nil {
+		return nil, err
+	}
+
+	queryParams := &dto.QueryLocationRequest{
+		VehicleId: request.VehicleId,
+		From:      request.From,
+		To:        request.To,
+	}
+	qLocationEntity := entity.NewQueryLocationRequest(queryParams)
+
+	interval := time.Duration(request.Interval) * time.Second
+	period := qLocationEntity.To.Sub(qLocationEntity.From)
+	if period < 0 || period/interval >= MaxPlaybackFrames {
+		return nil, ErrInvalidPlayback
+	}
+
+	maxGap := request.MaxGap
+	if maxGap == 0 {
+		maxGap = DefaultPlaybackMaxGap
+	}
+
+	p := &playback{
+		next:     qLocationEntity.From,
+		to:       qLocationEntity.To,
+		interval: interval,
+		maxGap:   time.Duration(maxGap) * time.Second,
+		frames:   make([]*dto.PlaybackFrameOut, 0, period/interval+1),
+	}
+
+	// The location before the period starts the interpolation of its first frames.
+	repository := tenantRepository(principal)
+	previousInDB, err := repository.GetPrevious(request.VehicleId, qLocationEntity.From)
+	if err != nil {
+		return nil, err
+	}
+	if previousInDB != nil {
+		if err := p.add(entity.NewLocationInDB(previousInDB)); err != nil {
+			return nil, err
+		}
+	}
+
+	err = repository.Stream(qLocationEntity.NewQueryLocationOutDB(), func(locationInDB *dto.LocationInDB) error {
+		if previousInDB != nil && locationInDB.ID == previousInDB.ID {
+			return nil
+		}
+		return p.add(entity.NewLocationInDB(locationInDB))
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.flush()
+
+	return &dto.PlaybackResponseOut{
+		VehicleId: request.VehicleId,
+		From:      qLocationEntity.From,
+		To:        qLocationEntity.To,
+		Interval:  request.Interval,
+		Frames:    p.frames,
+	}, nil
+}
@@ -0,0 +1,271 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// playbackSample is a location of the playback tests, the seconds after noon.
+type playbackSample struct {
+	seconds   int
+	latitude  string
+	longitude string
+	status    string
+	speed     int
+}
+
+// wantFrame is a frame expected by the tests, the seconds after noon, without a location when the latitude is empty.
+type wantFrame struct {
+	seconds      int
+	latitude     string
+	longitude    string
+	speed        int
+	heading      float64
+	interpolated bool
+	gap          bool
+}
+
+var playbackNoon = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func playbackLocation(sample playbackSample) *entity.Location {
+	return &entity.Location{
+		VehicleId: "ABC1234",
+		Timestamp: playbackNoon.Add(time.Duration(sample.seconds) * time.Second),
+		Location:  &entity.Coordinates{Latitude: sample.latitude, Longitude: sample.longitude},
+		Speed:     sample.speed,
+		Status:    sample.status,
+	}
+}
+
+func checkFrames(t *testing.T, frames []*dto.PlaybackFrameOut, want []wantFrame) {
+	t.Helper()
+	if len(frames) != len(want) {
+		t.Fatalf("frames = %d, want %d", len(frames), len(want))
+	}
+	for i, frame := range frames {
+		got := wantFrame{
+			seconds:      int(frame.Timestamp.Sub(playbackNoon).Seconds()),
+			speed:        frame.Speed,
+			heading:      frame.Heading,
+			interpolated: frame.Interpolated,
+			gap:          frame.Gap,
+		}
+		if frame.Location != nil {
+			got.latitude, got.longitude = frame.Location.Latitude, frame.Location.Longitude
+		}
+		if got != want[i] {
+			t.Errorf("frame %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestPlayback(t *testing.T) {
+	const m, s, o = entity.StatusMoving, entity.StatusStopped, entity.StatusOffline
+
+	tests := []struct {
+		name    string
+		maxGap  int
+		samples []playbackSample
+		want    []wantFrame
+	}{
+		{
+			name: "interpolation between two points",
+			samples: []playbackSample{
+				{seconds: 0, latitude: "10", longitude: "20", status: m, speed: 20},
+				{seconds: 40, latitude: "10", longitude: "20.004", status: m, speed: 60},
+			},
+			want: []wantFrame{
+				{seconds: 0, latitude: "10", longitude: "20", speed: 20, heading: 90},
+				{seconds: 10, latitude: "10.000000", longitude: "20.001000", speed: 30, heading: 90, interpolated: true},
+				{seconds: 20, latitude: "10.000000", longitude: "20.002000", speed: 40, heading: 90, interpolated: true},
+				{seconds: 30, latitude: "10.000000", longitude: "20.003000", speed: 50, heading: 90, interpolated: true},
+				{seconds: 40, latitude: "10", longitude: "20.004", speed: 60, heading: 90},
+				{seconds: 50, latitude: "10", longitude: "20.004", speed: 60, heading: 90, interpolated: true},
+				{seconds: 60, latitude: "10", longitude: "20.004", speed: 60, heading: 90, interpolated: true},
+			},
+		},
+		{
+			name: "heading kept under the minimum distance",
+			samples: []playbackSample{
+				{seconds: 0, latitude: "10", longitude: "20", status: m, speed: 40},
+				{seconds: 20, latitude: "10", longitude: "20.002", status: s},
+				{seconds: 40, latitude: "10.000005", longitude: "20.002", status: s},
+			},
+			want: []wantFrame{
+				{seconds: 0, latitude: "10", longitude: "20", speed: 40, heading: 90},
+				{seconds: 10, latitude: "10.000000", longitude: "20.001000", speed: 20, heading: 90, interpolated: true},
+				{seconds: 20, latitude: "10", longitude: "20.002", heading: 90},
+				{seconds: 30, latitude: "10.000003", longitude: "20.002000", heading: 90, interpolated: true},
+				{seconds: 40, latitude: "10.000005", longitude: "20.002", heading: 90},
+				{seconds: 50, latitude: "10.000005", longitude: "20.002", heading: 90, interpolated: true},
+				{seconds: 60, latitude: "10.000005", longitude: "20.002", heading: 90, interpolated: true},
+			},
+		},
+		{
+			name:   "span over the max gap",
+			maxGap: 20,
+			samples: []playbackSample{
+				{seconds: 0, latitude: "10", longitude: "20", status: m, speed: 40},
+				{seconds: 30, latitude: "10.003", longitude: "20", status: m, speed: 40},
+			},
+			want: []wantFrame{
+				{seconds: 0, latitude: "10", longitude: "20", speed: 40},
+				{seconds: 10, latitude: "10", longitude: "20", speed: 40, interpolated: true, gap: true},
+				{seconds: 20, latitude: "10", longitude: "20", speed: 40, interpolated: true, gap: true},
+				{seconds: 30, latitude: "10.003", longitude: "20", speed: 40},
+				{seconds: 40, latitude: "10.003", longitude: "20", speed: 40, interpolated: true},
+				{seconds: 50, latitude: "10.003", longitude: "20", speed: 40, interpolated: true},
+				{seconds: 60, latitude: "10.003", longitude: "20", speed: 40, interpolated: true, gap: true},
+			},
+		},
+		{
+			name: "offline frames",
+			samples: []playbackSample{
+				{seconds: 0, latitude: "10", longitude: "20", status: o},
+				{seconds: 30, latitude: "10", longitude: "20", status: s},
+			},
+			want: []wantFrame{
+				{seconds: 0, latitude: "10", longitude: "20", gap: true},
+				{seconds: 10, latitude: "10", longitude: "20", interpolated: true, gap: true},
+				{seconds: 20, latitude: "10", longitude: "20", interpolated: true, gap: true},
+				{seconds: 30, latitude: "10", longitude: "20"},
+				{seconds: 40, latitude: "10", longitude: "20", interpolated: true},
+				{seconds: 50, latitude: "10", longitude: "20", interpolated: true},
+				{seconds: 60, latitude: "10", longitude: "20", interpolated: true},
+			},
+		},
+		{
+			name: "frames before the first location",
+			samples: []playbackSample{
+				{seconds: 30, latitude: "10", longitude: "20", status: s},
+			},
+			want: []wantFrame{
+				{seconds: 0, gap: true},
+				{seconds: 10, gap: true},
+				{seconds: 20, gap: true},
+				{seconds: 30, latitude: "10", longitude: "20"},
+				{seconds: 40, latitude: "10", longitude: "20", interpolated: true},
+				{seconds: 50, latitude: "10", longitude: "20", interpolated: true},
+				{seconds: 60, latitude: "10", longitude: "20", interpolated: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxGap := tt.maxGap
+			if maxGap == 0 {
+				maxGap = DefaultPlaybackMaxGap
+			}
+			p := &playback{
+				next:     playbackNoon,
+				to:       playbackNoon.Add(time.Minute),
+				interval: 10 * time.Second,
+				maxGap:   time.Duration(maxGap) * time.Second,
+			}
+			for _, sample := range tt.samples {
+				if err := p.add(playbackLocation(sample)); err != nil {
+					t.Fatalf("add() error = %v", err)
+				}
+			}
+			p.flush()
+
+			checkFrames(t, p.frames, tt.want)
+		})
+	}
+}
+
+func TestGetPlaybackPreviousLocation(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []playbackSample
+		want    []wantFrame
+	}{
+		{
+			name: "location before the period",
+			samples: []playbackSample{
+				{seconds: -30, latitude: "10", longitude: "20", status: entity.StatusMoving, speed: 40},
+				{seconds: 20, latitude: "10", longitude: "20.005", status: entity.StatusMoving, speed: 40},
+			},
+			want: []wantFrame{
+				{seconds: 0, latitude: "10.000000", longitude: "20.003000", speed: 40, heading: 90, interpolated: true},
+				{seconds: 10, latitude: "10.000000", longitude: "20.004000", speed: 40, heading: 90, interpolated: true},
+				{seconds: 20, latitude: "10", longitude: "20.005", speed: 40, heading: 90},
+			},
+		},
+		{
+			// The location at the start is both the previous one and the first of the period.
+			name: "location at the start of the period",
+			samples: []playbackSample{
+				{seconds: 0, latitude: "10", longitude: "20", status: entity.StatusMoving, speed: 40},
+				{seconds: 20, latitude: "10", longitude: "20.002", status: entity.StatusMoving, speed: 40},
+			},
+			want: []wantFrame{
+				{seconds: 0, latitude: "10", longitude: "20", speed: 40, heading: 90},
+				{seconds: 10, latitude: "10.000000", longitude: "20.001000", speed: 40, heading: 90, interpolated: true},
+				{seconds: 20, latitude: "10", longitude: "20.002", speed: 40, heading: 90},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := newFakeRepository()
+			for _, sample := range tt.samples {
+				location := playbackLocation(sample)
+				repository.store.locations = append(repository.store.locations, &dto.LocationInDB{
+					ID:        bson.NewObjectID(),
+					VehicleId: location.VehicleId,
+					Timestamp: location.Timestamp,
+					Location:  &dto.CoordinatesInDB{Latitude: location.Location.Latitude, Longitude: location.Location.Longitude},
+					Speed:     location.Speed,
+					Status:    location.Status,
+				})
+			}
+			useFakeRepository(t, repository)
+
+			out, err := GetPlayback(entity.NewInternalPrincipal("locationctl", ""), &dto.PlaybackRequest{
+				VehicleId: "ABC1234",
+				From:      playbackNoon.Format(time.RFC3339),
+				To:        playbackNoon.Add(20 * time.Second).Format(time.RFC3339),
+				Interval:  10,
+			})
+			if err != nil {
+				t.Fatalf("GetPlayback() error = %v", err)
+			}
+			checkFrames(t, out.Frames, tt.want)
+		})
+	}
+}
+
+func TestGetPlaybackInvalidPeriod(t *testing.T) {
+	useFakeRepository(t, newFakeRepository())
+
+	tests := []struct {
+		name     string
+		to       time.Duration
+		interval int
+	}{
+		{name: "period ending before it starts", to: -time.Second, interval: 10},
+		{name: "too many positions", to: MaxPlaybackFrames * time.Second, interval: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := GetPlayback(entity.NewInternalPrincipal("locationctl", ""), &dto.PlaybackRequest{
+				VehicleId: "ABC1234",
+				From:      playbackNoon.Format(time.RFC3339),
+				To:        playbackNoon.Add(tt.to).Format(time.RFC3339),
+				Interval:  tt.interval,
+			})
+			if !errors.Is(err, ErrInvalidPlayback) {
+				t.Errorf("GetPlayback() error = %v, want %v", err, ErrInvalidPlayback)
+			}
+		})
+	}
+}
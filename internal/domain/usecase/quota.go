@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/pkg/ratelimit"
+)
+
+// QuotaExceededError is returned when the locations would go over the daily quota of the tenant.
+// The quota is reset at the start of the next UTC day.
+type QuotaExceededError struct {
+	Tenant string
+	Quota  int64
+	Reset  time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("the daily quota of %d locations of the tenant %s was exceeded", e.Quota, e.Tenant)
+}
+
+type quotaUseCase struct {
+	store         ratelimit.Store
+	defaultTenant string
+	daily         int64
+	tenants       map[string]int64
+}
+
+var q quotaUseCase
+
+// LoadQuotaUseCase sets the daily quota of locations saved by every tenant, 0 for no quota, and the quotas
+// of specific tenants, from entries in the format <tenant>=<locations>. The counters are kept by the store.
+func LoadQuotaUseCase(store ratelimit.Store, defaultTenant string, daily int64, tenantQuotas []string) error {
+	q.store = store
+	q.defaultTenant = defaultTenant
+	q.daily = daily
+	q.tenants = make(map[string]int64, len(tenantQuotas))
+
+	for _, entry := range tenantQuotas {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		tenant, value, found := strings.Cut(entry, "=")
+		quota, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if !found || tenant == "" || err != nil || quota < 0 {
+			return fmt.Errorf("invalid tenant quota %q, expected <tenant>=<locations>", entry)
+		}
+		q.tenants[strings.TrimSpace(tenant)] = quota
+	}
+	return nil
+}
+
+// consumeQuota counts n locations on the daily quota of the tenant of the principal before they are saved,
+// so concurrent requests can't go over it together. It returns the function that gives back the locations
+// that were not saved, and a *QuotaExceededError, counting nothing, when they would go over the quota.
+func consumeQuota(principal *entity.Principal, n int) (func(unsaved int), error) {
+	noRefund := func(int) {}
+	if q.store == nil || n == 0 {
+		return noRefund, nil
+	}
+
+	tenant := q.defaultTenant
+	if principal != nil && principal.Tenant != "" {
+		tenant = principal.Tenant
+	}
+
+	quota, ok := q.tenants[tenant]
+	if !ok {
+		quota = q.daily
+	}
+	if quota == 0 {
+		return noRefund, nil
+	}
+
+	now := time.Now()
+	day := Day(now)
+	reset := day.Add(24 * time.Hour)
+	key := "quota:" + tenant + ":" + day.Format(time.DateOnly)
+
+	_, consumed, err := q.store.Consume(key, int64(n), quota, reset, now)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, &QuotaExceededError{Tenant: tenant, Quota: quota, Reset: reset}
+	}
+
+	refund := func(unsaved int) {
+		if unsaved <= 0 {
+			return
+		}
+		if err := q.store.Release(key, int64(unsaved), time.Now()); err != nil {
+			slog.Error("error refunding quota", "error", err.Error(), "tenant", tenant, "locations", unsaved)
+		}
+	}
+	return refund, nil
+}
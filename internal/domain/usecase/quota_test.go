@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/pkg/ratelimit"
+)
+
+// useQuota sets the daily quota of the tenants for the duration of the test.
+func useQuota(t *testing.T, daily int64) {
+	previous := q
+	if err := LoadQuotaUseCase(ratelimit.NewMemoryStore(), "default", daily, nil); err != nil {
+		t.Fatalf("LoadQuotaUseCase() error = %v", err)
+	}
+	t.Cleanup(func() { q = previous })
+}
+
+func TestSaveLocationQuotaRefund(t *testing.T) {
+	repository := newFakeRepository()
+	useFakeRepository(t, repository)
+	useQuota(t, 1)
+
+	principal := entity.NewInternalPrincipal("mqtt", "")
+	locationDataIn := &dto.LocationInApp{VehicleId: "ABC1234", Latitude: "10", Longitude: "20", Status: entity.StatusMoving}
+	at := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	repository.store.insertErr = errors.New("connection lost")
+	if _, err := SaveLocationAt(principal, locationDataIn, at, entity.SourceMQTT); !errors.Is(err, repository.store.insertErr) {
+		t.Fatalf("SaveLocationAt() error = %v, want %v", err, repository.store.insertErr)
+	}
+
+	// The failed insert gave its location back, so the quota still has it.
+	repository.store.insertErr = nil
+	if _, err := SaveLocationAt(principal, locationDataIn, at, entity.SourceMQTT); err != nil {
+		t.Fatalf("SaveLocationAt() after the failure error = %v", err)
+	}
+
+	var quotaErr *QuotaExceededError
+	if _, err := SaveLocationAt(principal, locationDataIn, at.Add(time.Second), entity.SourceMQTT); !errors.As(err, &quotaErr) {
+		t.Errorf("SaveLocationAt() over the quota error = %v, want a *QuotaExceededError", err)
+	}
+}
+
+func TestSaveLocationsQuotaRefund(t *testing.T) {
+	repository := newFakeRepository()
+	useFakeRepository(t, repository)
+	useQuota(t, 2)
+
+	principal := entity.NewInternalPrincipal("locationctl", "")
+	batch := make([]*dto.TimedLocationInApp, 2)
+	for i := range batch {
+		batch[i] = &dto.TimedLocationInApp{
+			Location:  &dto.LocationInApp{VehicleId: "ABC1234", Latitude: "10", Longitude: "20", Status: entity.StatusMoving},
+			Timestamp: time.Date(2025, 1, 1, 12, 0, i, 0, time.UTC),
+		}
+	}
+
+	repository.store.insertErr = errors.New("connection lost")
+	if _, err := SaveLocationsAt(principal, batch); !errors.Is(err, repository.store.insertErr) {
+		t.Fatalf("SaveLocationsAt() error = %v, want %v", err, repository.store.insertErr)
+	}
+
+	repository.store.insertErr = nil
+	ids, err := SaveLocationsAt(principal, batch)
+	if err != nil {
+		t.Fatalf("SaveLocationsAt() after the failure error = %v", err)
+	}
+	if len(ids) != 2 {
+		t.Errorf("SaveLocationsAt() saved %d locations, want 2", len(ids))
+	}
+
+	var quotaErr *QuotaExceededError
+	if _, err := SaveLocationsAt(principal, batch[:1]); !errors.As(err, &quotaErr) {
+		t.Errorf("SaveLocationsAt() over the quota error = %v, want a *QuotaExceededError", err)
+	}
+}
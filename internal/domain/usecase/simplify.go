@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"sort"
+
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/pkg/geo"
+)
+
+// simplifyChunkSize is the number of locations simplified at a time when a track is streamed.
+const simplifyChunkSize = 10000
+
+// simplifyLocations returns the locations needed to draw the track of a vehicle within the tolerance in meters,
+// by the Douglas-Peucker algorithm. The locations must be ordered by timestamp.
+// The first and last locations of every run of the same status, which mark the status changes and
+// where the stops begin and end, and the moving locations without speed are always kept.
+func simplifyLocations(locations []*entity.Location, tolerance float64) ([]*entity.Location, error) {
+	points := make([]geo.Point, len(locations))
+	keep := make([]bool, len(locations))
+
+	for i, location := range locations {
+		var err error
+		if points[i], err = location.Location.Point(); err != nil {
+			return nil, err
+		}
+
+		statusChanged := (i > 0 && locations[i-1].Status != location.Status) ||
+			(i < len(locations)-1 && locations[i+1].Status != location.Status)
+		keep[i] = statusChanged || (location.Status == "moving" && location.Speed == 0)
+	}
+
+	indexes := geo.Simplify(points, tolerance, keep)
+
+	simplified := make([]*entity.Location, 0, len(indexes))
+	for _, i := range indexes {
+		simplified = append(simplified, locations[i])
+	}
+	return simplified, nil
+}
+
+// trackSimplifier simplifies a streamed track in chunks, so it is never held entirely in memory.
+// The last location of a chunk is always kept and starts the next one, so the chunks join seamlessly.
+type trackSimplifier struct {
+	tolerance float64
+	chunk     []*entity.Location
+	emit      func(location *entity.Location) error
+}
+
+func (t *trackSimplifier) add(location *entity.Location) error {
+	t.chunk = append(t.chunk, location)
+	if len(t.chunk) < simplifyChunkSize {
+		return nil
+	}
+
+	simplified, err := simplifyLocations(t.chunk, t.tolerance)
+	if err != nil {
+		return err
+	}
+	for _, s := range simplified[:len(simplified)-1] {
+		if err := t.emit(s); err != nil {
+			return err
+		}
+	}
+
+	last := t.chunk[len(t.chunk)-1]
+	t.chunk = append(t.chunk[:0], last)
+	return nil
+}
+
+func (t *trackSimplifier) flush() error {
+	simplified, err := simplifyLocations(t.chunk, t.tolerance)
+	if err != nil {
+		return err
+	}
+	for _, s := range simplified {
+		if err := t.emit(s); err != nil {
+			return err
+		}
+	}
+
+	t.chunk = t.chunk[:0]
+	return nil
+}
+
+// simplifyByVehicle simplifies the track of every vehicle in a list of locations,
+// keeping the remaining locations in their original order.
+func simplifyByVehicle(locations []*entity.Location, tolerance float64) ([]*entity.Location, error) {
+	tracks := make(map[string][]*entity.Location)
+	for _, location := range locations {
+		tracks[location.VehicleId] = append(tracks[location.VehicleId], location)
+	}
+
+	kept := make(map[*entity.Location]bool, len(locations))
+	for _, track := range tracks {
+		sort.SliceStable(track, func(i, j int) bool {
+			return track[i].Timestamp.Before(track[j].Timestamp)
+		})
+
+		simplified, err := simplifyLocations(track, tolerance)
+		if err != nil {
+			return nil, err
+		}
+		for _, location := range simplified {
+			kept[location] = true
+		}
+	}
+
+	simplified := make([]*entity.Location, 0, len(kept))
+	for _, location := range locations {
+		if kept[location] {
+			simplified = append(simplified, location)
+		}
+	}
+	return simplified, nil
+}
@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/allansbo/goapi/internal/domain/entity"
+)
+
+// straightTrack returns a track to the east, a location every ~11 m, with the statuses given.
+func straightTrack(vehicleID string, statuses ...string) []*entity.Location {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	track := make([]*entity.Location, len(statuses))
+	for i, status := range statuses {
+		speed := 0
+		if status == entity.StatusMoving {
+			speed = 40
+		}
+		track[i] = &entity.Location{
+			ID:        vehicleID + "-" + strconv.Itoa(i),
+			VehicleId: vehicleID,
+			Timestamp: start.Add(time.Duration(i) * time.Second),
+			Location:  &entity.Coordinates{Latitude: "0", Longitude: strconv.FormatFloat(float64(i)*0.0001, 'f', -1, 64)},
+			Speed:     speed,
+			Status:    status,
+		}
+	}
+	return track
+}
+
+func locationIDs(locations []*entity.Location) []string {
+	ids := make([]string, len(locations))
+	for i, location := range locations {
+		ids[i] = location.ID
+	}
+	return ids
+}
+
+func TestSimplifyLocations(t *testing.T) {
+	const m, s = entity.StatusMoving, entity.StatusStopped
+
+	tests := []struct {
+		name  string
+		track []*entity.Location
+		want  []string
+	}{
+		{
+			name:  "straight moving track",
+			track: straightTrack("ABC1234", m, m, m, m, m),
+			want:  []string{"ABC1234-0", "ABC1234-4"},
+		},
+		{
+			name:  "stop in the middle",
+			track: straightTrack("ABC1234", m, m, m, s, s, s, m, m, m),
+			want:  []string{"ABC1234-0", "ABC1234-2", "ABC1234-3", "ABC1234-5", "ABC1234-6", "ABC1234-8"},
+		},
+		{
+			name: "moving without speed",
+			track: func() []*entity.Location {
+				track := straightTrack("ABC1234", m, m, m, m, m)
+				track[2].Speed = 0
+				return track
+			}(),
+			want: []string{"ABC1234-0", "ABC1234-2", "ABC1234-4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			simplified, err := simplifyLocations(tt.track, 5)
+			if err != nil {
+				t.Fatalf("simplifyLocations() error = %v", err)
+			}
+			if got := locationIDs(simplified); !slices.Equal(got, tt.want) {
+				t.Errorf("simplifyLocations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimplifyLocationsInvalidCoordinates(t *testing.T) {
+	track := straightTrack("ABC1234", entity.StatusMoving, entity.StatusMoving, entity.StatusMoving)
+	track[1].Location.Latitude = "north"
+
+	if _, err := simplifyLocations(track, 5); err == nil {
+		t.Error("simplifyLocations() error = nil, want an error")
+	}
+}
+
+func TestTrackSimplifierChunks(t *testing.T) {
+	statuses := make([]string, 2*simplifyChunkSize+10)
+	for i := range statuses {
+		statuses[i] = entity.StatusMoving
+	}
+	track := straightTrack("ABC1234", statuses...)
+
+	var emitted []*entity.Location
+	simplifier := &trackSimplifier{tolerance: 5, emit: func(location *entity.Location) error {
+		emitted = append(emitted, location)
+		return nil
+	}}
+	for _, location := range track {
+		if err := simplifier.add(location); err != nil {
+			t.Fatalf("add() error = %v", err)
+		}
+	}
+	if err := simplifier.flush(); err != nil {
+		t.Fatalf("flush() error = %v", err)
+	}
+
+	// The straight track keeps its ends and the locations joining the chunks, each one emitted once.
+	want := []string{"ABC1234-0", "ABC1234-9999", "ABC1234-19998", "ABC1234-20009"}
+	if got := locationIDs(emitted); !slices.Equal(got, want) {
+		t.Errorf("emitted = %v, want %v", got, want)
+	}
+}
+
+func TestSimplifyByVehicle(t *testing.T) {
+	const m = entity.StatusMoving
+	first, second := straightTrack("ABC1234", m, m, m, m), straightTrack("XYZ9876", m, m, m)
+
+	// The locations of both vehicles interleaved and out of order.
+	locations := []*entity.Location{second[2], first[1], first[3], second[0], first[0], second[1], first[2]}
+
+	simplified, err := simplifyByVehicle(locations, 5)
+	if err != nil {
+		t.Fatalf("simplifyByVehicle() error = %v", err)
+	}
+
+	want := []string{"XYZ9876-2", "ABC1234-3", "XYZ9876-0", "ABC1234-0"}
+	if got := locationIDs(simplified); !slices.Equal(got, want) {
+		t.Errorf("simplifyByVehicle() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,140 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/pkg/geo"
+	"github.com/allansbo/goapi/internal/provider/db"
+)
+
+// Day returns the start of the UTC day of the time, which identifies the daily statistics.
+func Day(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// RollUpDailyStats computes the statistics of every vehicle of every tenant in the UTC day of the time and saves them,
+// replacing the ones computed before. The repositories that implement db.DailyStatsAggregator
+// compute them on the database, the others from the streamed locations, with the same rules.
+// A failure on a tenant doesn't stop the others, and the errors of all of them are returned.
+func RollUpDailyStats(day time.Time) error {
+	day = Day(day)
+
+	tenants, err := l.repository.Tenants()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, tenant := range tenants {
+		if err := rollUpTenantDailyStats(l.repository.Tenant(tenant), day); err != nil {
+			errs = append(errs, fmt.Errorf("tenant %s: %w", tenant, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func rollUpTenantDailyStats(repository db.Repository, day time.Time) error {
+	if aggregator, ok := repository.(db.DailyStatsAggregator); ok {
+		return aggregator.AggregateDailyStats(day)
+	}
+
+	query := &dto.QueryLocationOutDB{From: day, To: day.Add(24*time.Hour - time.Nanosecond)}
+
+	accumulators := make(map[string]*dailyStatsAccumulator)
+	err := repository.Stream(query, func(locationInDB *dto.LocationInDB) error {
+		location := entity.NewLocationInDB(locationInDB)
+
+		accumulator, ok := accumulators[location.VehicleId]
+		if !ok {
+			accumulator = &dailyStatsAccumulator{stats: &entity.DailyStats{VehicleId: location.VehicleId, Day: day}}
+			accumulators[location.VehicleId] = accumulator
+		}
+		return accumulator.add(location)
+	})
+	if err != nil {
+		return err
+	}
+
+	stats := make([]*dto.DailyStatsOutDB, 0, len(accumulators))
+	for _, accumulator := range accumulators {
+		stats = append(stats, accumulator.result().NewDailyStatsOutDB())
+	}
+	return repository.SaveDailyStats(stats)
+}
+
+// dailyStatsAccumulator computes the statistics of a vehicle from its locations ordered by timestamp.
+// The time between a location and the next one is counted on the status of the first,
+// and the distance is the sum of the distances between the consecutive locations.
+// It is given the locations of one day, so nothing is counted from the last one to midnight.
+type dailyStatsAccumulator struct {
+	stats     *entity.DailyStats
+	previous  *entity.Location
+	lastPoint geo.Point
+	speedSum  int
+	durations map[string]time.Duration
+}
+
+func (a *dailyStatsAccumulator) add(location *entity.Location) error {
+	point, err := location.Location.Point()
+	if err != nil {
+		return err
+	}
+
+	if a.previous == nil {
+		a.stats.FirstSeen = location.Timestamp
+		a.stats.MaxSpeed = location.Speed
+		a.durations = make(map[string]time.Duration)
+	} else {
+		a.durations[a.previous.Status] += location.Timestamp.Sub(a.previous.Timestamp)
+		a.stats.Distance += geo.Distance(a.lastPoint, point)
+	}
+
+	a.stats.Points++
+	a.stats.LastSeen = location.Timestamp
+	a.stats.MaxSpeed = max(a.stats.MaxSpeed, location.Speed)
+	a.speedSum += location.Speed
+	a.previous = location
+	a.lastPoint = point
+	return nil
+}
+
+func (a *dailyStatsAccumulator) result() *entity.DailyStats {
+	a.stats.AvgSpeed = float64(a.speedSum) / float64(a.stats.Points)
+	a.stats.MovingDuration = int64(a.durations["moving"].Seconds())
+	a.stats.StoppedDuration = int64(a.durations["stopped"].Seconds())
+	a.stats.OfflineDuration = int64(a.durations["offline"].Seconds())
+	a.stats.UpdatedAt = time.Now()
+	return a.stats
+}
+
+// GetDailyStats retrieves the statistics saved by the roll-up for the days in the period.
+// The period was validated as dates by the *dto.DailyStatsRequest struct.
+// The statistics are limited to the vehicles that the principal can access.
+func GetDailyStats(principal *entity.Principal, request *dto.DailyStatsRequest) (*dto.DailyStatsResponseOut, error) {
+	from, _ := time.Parse(time.DateOnly, request.From)
+	to, _ := time.Parse(time.DateOnly, request.To)
+
+	if err := CheckVehicleAccess(principal, request.VehicleId); err != nil {
+		return nil, err
+	}
+
+	statsInDB, err := tenantRepository(principal).GetDailyStats(&dto.QueryDailyStatsOutDB{
+		VehicleId:  request.VehicleId,
+		From:       from,
+		To:         to,
+		VehicleIds: allowedVehicles(principal),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.DailyStatsResponseOut{Success: true, Data: make([]*dto.DailyStatsOutApp, 0, len(statsInDB))}
+	for _, s := range statsInDB {
+		response.Data = append(response.Data, entity.NewDailyStatsInDB(s).NewDailyStatsOutApp())
+	}
+	return response, nil
+}
@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/pkg/geo"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// latitudeDegree is the distance in meters of a degree of latitude.
+const latitudeDegree = math.Pi / 180 * geo.EarthRadius
+
+func TestDay(t *testing.T) {
+	want := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, at := range []time.Time{
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 1, 23, 59, 59, 999999999, time.UTC),
+		time.Date(2025, 1, 1, 21, 0, 0, 0, time.FixedZone("BRT", -3*60*60)).Add(-time.Nanosecond),
+	} {
+		if got := Day(at); !got.Equal(want) {
+			t.Errorf("Day(%v) = %v, want %v", at, got, want)
+		}
+	}
+}
+
+func TestDailyStatsAccumulator(t *testing.T) {
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	locations := []struct {
+		minutes  int
+		latitude string
+		status   string
+		speed    int
+	}{
+		{minutes: 0, latitude: "10", status: entity.StatusMoving, speed: 40},
+		{minutes: 10, latitude: "10.01", status: entity.StatusMoving, speed: 60},
+		{minutes: 20, latitude: "10.01", status: entity.StatusStopped},
+		{minutes: 50, latitude: "10.01", status: entity.StatusOffline},
+		{minutes: 60, latitude: "10.02", status: entity.StatusMoving, speed: 20},
+	}
+
+	accumulator := &dailyStatsAccumulator{stats: &entity.DailyStats{VehicleId: "ABC1234", Day: day}}
+	for _, location := range locations {
+		err := accumulator.add(&entity.Location{
+			VehicleId: "ABC1234",
+			Timestamp: day.Add(time.Duration(location.minutes) * time.Minute),
+			Location:  &entity.Coordinates{Latitude: location.latitude, Longitude: "20"},
+			Speed:     location.speed,
+			Status:    location.status,
+		})
+		if err != nil {
+			t.Fatalf("add() error = %v", err)
+		}
+	}
+	stats := accumulator.result()
+
+	// The time of every location is counted on its status until the next one, and none for the last.
+	if stats.MovingDuration != 1200 || stats.StoppedDuration != 1800 || stats.OfflineDuration != 600 {
+		t.Errorf("durations = %d, %d, %d, want 1200, 1800, 600", stats.MovingDuration, stats.StoppedDuration, stats.OfflineDuration)
+	}
+	if want := 0.02 * latitudeDegree; math.Abs(stats.Distance-want) > 0.01 {
+		t.Errorf("Distance = %f, want %f", stats.Distance, want)
+	}
+	if stats.AvgSpeed != 24 || stats.MaxSpeed != 60 {
+		t.Errorf("speeds = %v, %d, want 24, 60", stats.AvgSpeed, stats.MaxSpeed)
+	}
+	if stats.Points != 5 {
+		t.Errorf("Points = %d, want 5", stats.Points)
+	}
+	if !stats.FirstSeen.Equal(day) || !stats.LastSeen.Equal(day.Add(time.Hour)) {
+		t.Errorf("seen = %v, %v, want %v, %v", stats.FirstSeen, stats.LastSeen, day, day.Add(time.Hour))
+	}
+}
+
+func TestRollUpTenantDailyStatsAcrossMidnight(t *testing.T) {
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	next := day.Add(24 * time.Hour)
+
+	repository := newFakeRepository()
+	for _, location := range []struct {
+		at       time.Time
+		latitude string
+		status   string
+		speed    int
+	}{
+		{at: next.Add(-20 * time.Minute), latitude: "10", status: entity.StatusMoving, speed: 50},
+		{at: next.Add(-10 * time.Minute), latitude: "10", status: entity.StatusStopped},
+		{at: next.Add(10 * time.Minute), latitude: "10.01", status: entity.StatusMoving, speed: 30},
+		{at: next.Add(30 * time.Minute), latitude: "10.02", status: entity.StatusStopped},
+	} {
+		repository.store.locations = append(repository.store.locations, &dto.LocationInDB{
+			ID:        bson.NewObjectID(),
+			VehicleId: "ABC1234",
+			Timestamp: location.at,
+			Location:  &dto.CoordinatesInDB{Latitude: location.latitude, Longitude: "20"},
+			Speed:     location.speed,
+			Status:    location.status,
+		})
+	}
+
+	// The time and the distance between the last location of a day and the first of the next are not counted.
+	tests := []struct {
+		day          time.Time
+		wantPoints   int
+		wantMoving   int64
+		wantStopped  int64
+		wantDistance float64
+		wantFirst    time.Time
+		wantLast     time.Time
+	}{
+		{day: day, wantPoints: 2, wantMoving: 600, wantFirst: next.Add(-20 * time.Minute), wantLast: next.Add(-10 * time.Minute)},
+		{day: next, wantPoints: 2, wantMoving: 1200, wantDistance: 0.01 * latitudeDegree, wantFirst: next.Add(10 * time.Minute), wantLast: next.Add(30 * time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.day.Format(time.DateOnly), func(t *testing.T) {
+			repository.store.dailyStats = nil
+			if err := rollUpTenantDailyStats(repository, tt.day); err != nil {
+				t.Fatalf("rollUpTenantDailyStats() error = %v", err)
+			}
+			if len(repository.store.dailyStats) != 1 {
+				t.Fatalf("rollUpTenantDailyStats() saved %d stats, want 1", len(repository.store.dailyStats))
+			}
+
+			stats := repository.store.dailyStats[0]
+			if !stats.Day.Equal(tt.day) || stats.Points != tt.wantPoints {
+				t.Errorf("day and points = %v, %d, want %v, %d", stats.Day, stats.Points, tt.day, tt.wantPoints)
+			}
+			if stats.MovingDuration != tt.wantMoving || stats.StoppedDuration != tt.wantStopped {
+				t.Errorf("durations = %d, %d, want %d, %d", stats.MovingDuration, stats.StoppedDuration, tt.wantMoving, tt.wantStopped)
+			}
+			if math.Abs(stats.Distance-tt.wantDistance) > 0.01 {
+				t.Errorf("Distance = %f, want %f", stats.Distance, tt.wantDistance)
+			}
+			if !stats.FirstSeen.Equal(tt.wantFirst) || !stats.LastSeen.Equal(tt.wantLast) {
+				t.Errorf("seen = %v, %v, want %v, %v", stats.FirstSeen, stats.LastSeen, tt.wantFirst, tt.wantLast)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/pkg/geo"
+)
+
+// Default parameters of the stop detection.
+const (
+	DefaultStopRadius      = 50
+	DefaultStopMinDuration = 120
+	DefaultStopMaxSpeed    = 5
+)
+
+// stop is a stop being detected, with the sum of the coordinates to place it on their centroid.
+type stop struct {
+	anchor    geo.Point
+	sum       geo.Point
+	count     int
+	startedAt time.Time
+	endedAt   time.Time
+	idle      time.Duration
+	last      *entity.Location
+}
+
+func (s *stop) add(location *entity.Location, point geo.Point) {
+	s.extend(location.Timestamp)
+	s.sum.Latitude += point.Latitude
+	s.sum.Longitude += point.Longitude
+	s.count++
+	s.last = location
+}
+
+// extend moves the end of the stop to the timestamp, counting the time since
+// the previous location as idle when the engine was on.
+func (s *stop) extend(timestamp time.Time) {
+	if on, _ := s.last.Ignition(); on {
+		s.idle += timestamp.Sub(s.last.Timestamp)
+	}
+	s.endedAt = timestamp
+}
+
+func (s *stop) newStopOut() *dto.StopOut {
+	count := float64(s.count)
+	location := &dto.CoordinatesOutApp{
+		Latitude:  strconv.FormatFloat(s.sum.Latitude/count, 'f', 6, 64),
+		Longitude: strconv.FormatFloat(s.sum.Longitude/count, 'f', 6, 64),
+	}
+
+	return &dto.StopOut{
+		StartedAt:    s.startedAt,
+		EndedAt:      s.endedAt,
+		Location:     location,
+		Place:        ReverseGeocode(location.Latitude, location.Longitude),
+		Duration:     int64(s.endedAt.Sub(s.startedAt).Seconds()),
+		IdleDuration: int64(s.idle.Seconds()),
+		Locations:    s.count,
+	}
+}
+
+// stopDetector finds the stops in the locations of a vehicle ordered by timestamp. A stop is a sequence of
+// locations with the stopped status or a speed up to maxSpeed, within the radius of the first one.
+// It ends on the first location that leaves, which is when the vehicle was last seen there,
+// and is emitted if it lasted at least minDuration. The offline locations end a stop without extending it.
+type stopDetector struct {
+	radius      float64
+	minDuration time.Duration
+	maxSpeed    int
+	current     *stop
+	emit        func(s *dto.StopOut)
+}
+
+func (d *stopDetector) add(location *entity.Location) error {
+	if location.Status == "offline" {
+		d.close()
+		return nil
+	}
+
+	point, err := location.Location.Point()
+	if err != nil {
+		return err
+	}
+
+	stationary := location.Status == "stopped" || location.Speed <= d.maxSpeed
+	if d.current != nil {
+		if stationary && geo.Distance(d.current.anchor, point) <= d.radius {
+			d.current.add(location, point)
+			return nil
+		}
+
+		d.current.extend(location.Timestamp)
+		d.close()
+	}
+
+	if stationary {
+		d.current = &stop{anchor: point, startedAt: location.Timestamp, endedAt: location.Timestamp, last: location}
+		d.current.add(location, point)
+	}
+	return nil
+}
+
+// close ends the current stop, emitting it if it lasted at least the minimum duration.
+func (d *stopDetector) close() {
+	if d.current == nil {
+		return
+	}
+
+	if d.current.endedAt.Sub(d.current.startedAt) >= d.minDuration {
+		d.emit(d.current.newStopOut())
+	}
+	d.current = nil
+}
+
+// GetStopReport returns the stops of a vehicle in a period, with the time stopped and idle in each one and in total.
+// The locations are streamed, so long periods are not held in memory.
+func GetStopReport(principal *entity.Principal, request *dto.StopReportRequest) (*dto.StopReportOut, error) {
+	if err := CheckVehicleAccess(principal, request.VehicleId); err != nil {
+		return nil, err
+	}
+
+	queryParams := &dto.QueryLocationRequest{
+		VehicleId: request.VehicleId,
+		From:      request.From,
+		To:        request.To,
+	}
+	qLocationEntity := entity.NewQueryLocationRequest(queryParams)
+
+	report := &dto.StopReportOut{
+		VehicleId: request.VehicleId,
+		From:      qLocationEntity.From,
+		To:        qLocationEntity.To,
+		Stops:     make([]*dto.StopOut, 0),
+	}
+
+	detector := &stopDetector{
+		radius:      request.Radius,
+		minDuration: time.Duration(request.MinDuration) * time.Second,
+		maxSpeed:    request.MaxSpeed,
+		emit: func(s *dto.StopOut) {
+			report.Stops = append(report.Stops, s)
+			report.TotalDuration += s.Duration
+			report.TotalIdleDuration += s.IdleDuration
+		},
+	}
+
+	err := tenantRepository(principal).Stream(qLocationEntity.NewQueryLocationOutDB(), func(locationInDB *dto.LocationInDB) error {
+		return detector.add(entity.NewLocationInDB(locationInDB))
+	})
+	if err != nil {
+		return nil, err
+	}
+	detector.close()
+
+	report.Count = len(report.Stops)
+	return report, nil
+}
@@ -0,0 +1,212 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+)
+
+// stopSample is a location of the stop tests, the seconds after noon on the longitude 20.
+type stopSample struct {
+	seconds  int
+	latitude string
+	status   string
+	speed    int
+	ignition any
+}
+
+// wantStop is a stop expected by the tests, with the seconds after noon of its start and end.
+type wantStop struct {
+	start, end int
+	idle       int64
+	locations  int
+}
+
+func stopTrack(samples []stopSample) []*entity.Location {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	track := make([]*entity.Location, len(samples))
+	for i, sample := range samples {
+		track[i] = &entity.Location{
+			VehicleId: "ABC1234",
+			Timestamp: start.Add(time.Duration(sample.seconds) * time.Second),
+			Location:  &entity.Coordinates{Latitude: sample.latitude, Longitude: "20"},
+			Speed:     sample.speed,
+			Status:    sample.status,
+		}
+		if sample.ignition != nil {
+			track[i].Attributes = map[string]any{"ignition": sample.ignition}
+		}
+	}
+	return track
+}
+
+func TestStopDetector(t *testing.T) {
+	const m, s, o = entity.StatusMoving, entity.StatusStopped, entity.StatusOffline
+
+	// 0.00044 degree of latitude is ~48.9 m and 0.00045 is ~50.0 m, around the radius of 50 m.
+	tests := []struct {
+		name    string
+		samples []stopSample
+		want    []wantStop
+	}{
+		{
+			name: "stop within the radius",
+			samples: []stopSample{
+				{seconds: 0, latitude: "10", status: s},
+				{seconds: 60, latitude: "10.0002", status: s},
+				{seconds: 180, latitude: "10.00044", status: s},
+				{seconds: 240, latitude: "10.01", status: m, speed: 40},
+			},
+			want: []wantStop{{start: 0, end: 240, locations: 3}},
+		},
+		{
+			name: "drift out of the radius",
+			samples: []stopSample{
+				{seconds: 0, latitude: "10", status: s},
+				{seconds: 60, latitude: "10.00045", status: s},
+				{seconds: 300, latitude: "10.00045", status: s},
+				{seconds: 360, latitude: "10.01", status: m, speed: 40},
+			},
+			want: []wantStop{{start: 60, end: 360, locations: 2}},
+		},
+		{
+			name: "slow moving locations",
+			samples: []stopSample{
+				{seconds: 0, latitude: "10", status: m, speed: 5},
+				{seconds: 150, latitude: "10.0001", status: m, speed: 3},
+				{seconds: 200, latitude: "10.0002", status: m, speed: 6},
+			},
+			want: []wantStop{{start: 0, end: 200, locations: 2}},
+		},
+		{
+			name: "offline location ending a stop",
+			samples: []stopSample{
+				{seconds: 0, latitude: "10", status: s},
+				{seconds: 180, latitude: "10", status: s},
+				{seconds: 600, latitude: "10", status: o},
+				{seconds: 660, latitude: "10", status: s},
+			},
+			want: []wantStop{{start: 0, end: 180, locations: 2}},
+		},
+		{
+			name: "stop shorter than the minimum duration",
+			samples: []stopSample{
+				{seconds: 0, latitude: "10", status: s},
+				{seconds: 60, latitude: "10", status: s},
+				{seconds: 100, latitude: "10.01", status: m, speed: 40},
+			},
+		},
+		{
+			name: "stop of the minimum duration",
+			samples: []stopSample{
+				{seconds: 0, latitude: "10", status: s},
+				{seconds: 120, latitude: "10.01", status: m, speed: 40},
+			},
+			want: []wantStop{{start: 0, end: 120, locations: 1}},
+		},
+		{
+			name: "idle with the ignition on",
+			samples: []stopSample{
+				{seconds: 0, latitude: "10", status: s, ignition: true},
+				{seconds: 60, latitude: "10", status: s, ignition: 1},
+				{seconds: 120, latitude: "10", status: s, ignition: false},
+				{seconds: 300, latitude: "10", status: s, ignition: true},
+				{seconds: 360, latitude: "10.01", status: m, speed: 40, ignition: true},
+			},
+			want: []wantStop{{start: 0, end: 360, idle: 180, locations: 4}},
+		},
+		{
+			name: "idle without the ignition",
+			samples: []stopSample{
+				{seconds: 0, latitude: "10", status: s},
+				{seconds: 300, latitude: "10", status: s},
+			},
+			want: []wantStop{{start: 0, end: 300, locations: 2}},
+		},
+		{
+			name: "stop open at the end of the period",
+			samples: []stopSample{
+				{seconds: 0, latitude: "10.01", status: m, speed: 40},
+				{seconds: 60, latitude: "10", status: s},
+				{seconds: 600, latitude: "10", status: s},
+			},
+			want: []wantStop{{start: 60, end: 600, locations: 2}},
+		},
+		{
+			name: "two stops",
+			samples: []stopSample{
+				{seconds: 0, latitude: "10", status: s},
+				{seconds: 300, latitude: "10.01", status: m, speed: 40},
+				{seconds: 600, latitude: "10.02", status: s},
+				{seconds: 900, latitude: "10.02", status: s},
+			},
+			want: []wantStop{{start: 0, end: 300, locations: 1}, {start: 600, end: 900, locations: 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stops []*dto.StopOut
+			detector := &stopDetector{
+				radius:      DefaultStopRadius,
+				minDuration: DefaultStopMinDuration * time.Second,
+				maxSpeed:    DefaultStopMaxSpeed,
+				emit:        func(s *dto.StopOut) { stops = append(stops, s) },
+			}
+			for _, location := range stopTrack(tt.samples) {
+				if err := detector.add(location); err != nil {
+					t.Fatalf("add() error = %v", err)
+				}
+			}
+			detector.close()
+
+			if len(stops) != len(tt.want) {
+				t.Fatalf("stops = %d, want %d", len(stops), len(tt.want))
+			}
+			noon := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+			for i, want := range tt.want {
+				got := stops[i]
+				if start := int(got.StartedAt.Sub(noon).Seconds()); start != want.start {
+					t.Errorf("stop %d started at %d s, want %d s", i, start, want.start)
+				}
+				if end := int(got.EndedAt.Sub(noon).Seconds()); end != want.end {
+					t.Errorf("stop %d ended at %d s, want %d s", i, end, want.end)
+				}
+				if got.Duration != int64(want.end-want.start) {
+					t.Errorf("stop %d duration = %d, want %d", i, got.Duration, want.end-want.start)
+				}
+				if got.IdleDuration != want.idle {
+					t.Errorf("stop %d idle duration = %d, want %d", i, got.IdleDuration, want.idle)
+				}
+				if got.Locations != want.locations {
+					t.Errorf("stop %d locations = %d, want %d", i, got.Locations, want.locations)
+				}
+			}
+		})
+	}
+}
+
+func TestStopDetectorCentroid(t *testing.T) {
+	var stops []*dto.StopOut
+	detector := &stopDetector{radius: DefaultStopRadius, emit: func(s *dto.StopOut) { stops = append(stops, s) }}
+
+	for _, location := range stopTrack([]stopSample{
+		{seconds: 0, latitude: "10", status: entity.StatusStopped},
+		{seconds: 60, latitude: "10.0002", status: entity.StatusStopped},
+		{seconds: 120, latitude: "10.0004", status: entity.StatusStopped},
+	}) {
+		if err := detector.add(location); err != nil {
+			t.Fatalf("add() error = %v", err)
+		}
+	}
+	detector.close()
+
+	if len(stops) != 1 {
+		t.Fatalf("stops = %d, want 1", len(stops))
+	}
+	if location := stops[0].Location; location.Latitude != "10.000200" || location.Longitude != "20.000000" {
+		t.Errorf("location = %s, %s, want 10.000200, 20.000000", location.Latitude, location.Longitude)
+	}
+}
@@ -0,0 +1,15 @@
+package usecase
+
+import (
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/provider/db"
+)
+
+// tenantRepository returns the repository limited to the tenant of the principal,
+// the default tenant when it is nil, so a client never reads nor changes the data of another tenant.
+func tenantRepository(principal *entity.Principal) db.Repository {
+	if principal == nil {
+		return l.repository.Tenant("")
+	}
+	return l.repository.Tenant(principal.Tenant)
+}
@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/pkg/mvt"
+)
+
+// DefaultClusterZoom is the deepest zoom level where the points are clustered when the request doesn't set it.
+const DefaultClusterZoom = 12
+
+// clusterCellSize is the size in tile units of the cells where the points are clustered,
+// which is 40 pixels on a tile drawn with 256 pixels.
+const clusterCellSize = mvt.Extent * 40 / 256
+
+// tileLayerName is the name of the layer of the locations in the tiles.
+const tileLayerName = "locations"
+
+// MaxTileHistoryDays is the longest period of the history mode, in days.
+const MaxTileHistoryDays = 7
+
+// MaxTilePoints is the number of locations drawn as single points in a tile past the cluster zoom.
+// The following locations are clustered, so a tile never grows past this number of points and the clusters.
+const MaxTilePoints = 10000
+
+// ErrInvalidTileHistory is returned when the history mode has no period or a period too long.
+var ErrInvalidTileHistory = fmt.Errorf("the history mode requires a period, from and to, of at most %d days", MaxTileHistoryDays)
+
+type tileCluster struct {
+	location *entity.Location
+	sumX     int
+	sumY     int
+	count    int
+}
+
+// GetTile encodes the locations inside a tile as a Mapbox Vector Tile with a single layer of points.
+// In the latest mode, the default, only the last position of every vehicle is drawn, in the history mode
+// all the locations in the period. Up to the cluster zoom, the points close to each other are merged
+// into a cluster point with the cluster and point_count properties, as the points past MaxTilePoints deeper.
+// The tile must exist at its zoom level. Only the vehicles that the principal can access are drawn.
+// It returns ErrInvalidTileHistory when the period of the history mode is missing or too long.
+func GetTile(principal *entity.Principal, request *dto.TileRequest) ([]byte, error) {
+	tile := mvt.Tile{Z: request.Z, X: request.X, Y: request.Y}
+
+	qLocationEntity := entity.NewQueryLocationRequest(request.NewQueryLocationRequest())
+	if request.Mode == "history" && !validTileHistory(qLocationEntity.From, qLocationEntity.To) {
+		return nil, ErrInvalidTileHistory
+	}
+
+	qLocationOutDB := qLocationEntity.NewQueryLocationOutDB()
+	if err := restrictQuery(principal, qLocationOutDB); err != nil {
+		return nil, err
+	}
+	bounds := new(dto.BoundsOutDB)
+	bounds.MinLatitude, bounds.MinLongitude, bounds.MaxLatitude, bounds.MaxLongitude = tile.Bounds()
+	qLocationOutDB.Bounds = bounds
+
+	clusterZoom := DefaultClusterZoom
+	if request.ClusterZoom != nil {
+		clusterZoom = *request.ClusterZoom
+	}
+	clustered := request.Z <= clusterZoom
+
+	layer := mvt.NewLayer(tileLayerName)
+	clusters := make(map[[2]int]*tileCluster)
+
+	add := func(location *entity.Location) error {
+		point, err := location.Location.Point()
+		if err != nil {
+			return err
+		}
+
+		x, y := tile.Project(point.Latitude, point.Longitude)
+		if x < 0 || x >= mvt.Extent || y < 0 || y >= mvt.Extent {
+			return nil
+		}
+
+		if !clustered && layer.Len() < MaxTilePoints {
+			layer.AddPoint(x, y, tileProperties(location))
+			return nil
+		}
+
+		cell := [2]int{x / clusterCellSize, y / clusterCellSize}
+		cluster, ok := clusters[cell]
+		if !ok {
+			cluster = &tileCluster{location: location}
+			clusters[cell] = cluster
+		}
+		cluster.sumX += x
+		cluster.sumY += y
+		cluster.count++
+		return nil
+	}
+
+	if request.Mode == "history" {
+		err := tenantRepository(principal).Stream(qLocationOutDB, func(locationInDB *dto.LocationInDB) error {
+			return add(entity.NewLocationInDB(locationInDB))
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		locationsInDB, err := tenantRepository(principal).GetLatest(qLocationOutDB)
+		if err != nil {
+			return nil, err
+		}
+		for _, locationInDB := range locationsInDB {
+			if err := add(entity.NewLocationInDB(locationInDB)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, cluster := range clusters {
+		x, y := cluster.sumX/cluster.count, cluster.sumY/cluster.count
+		if cluster.count == 1 {
+			layer.AddPoint(x, y, tileProperties(cluster.location))
+			continue
+		}
+		layer.AddPoint(x, y, map[string]any{"cluster": true, "point_count": cluster.count})
+	}
+
+	return mvt.Encode(layer), nil
+}
+
+// validTileHistory reports if the period of the history mode is set, ends after it starts and is not too long.
+func validTileHistory(from, to time.Time) bool {
+	return !from.IsZero() && !to.IsZero() && !to.Before(from) && to.Sub(from) <= MaxTileHistoryDays*24*time.Hour
+}
+
+// tileProperties returns the properties of the point of a location in the tiles.
+func tileProperties(location *entity.Location) map[string]any {
+	return map[string]any{
+		"id":         location.ID,
+		"vehicle_id": location.VehicleId,
+		"status":     location.Status,
+		"speed":      location.Speed,
+		"timestamp":  location.Timestamp.UTC().Format(time.RFC3339),
+	}
+}
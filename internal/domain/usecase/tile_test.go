@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+)
+
+func TestValidTileHistory(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     bool
+	}{
+		{name: "one day", from: from, to: from.AddDate(0, 0, 1), want: true},
+		{name: "longest period", from: from, to: from.AddDate(0, 0, MaxTileHistoryDays), want: true},
+		{name: "period too long", from: from, to: from.AddDate(0, 0, MaxTileHistoryDays).Add(time.Second)},
+		{name: "period ending before it starts", from: from, to: from.Add(-time.Second)},
+		{name: "missing from", to: from},
+		{name: "missing to", from: from},
+		{name: "missing period"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validTileHistory(tt.from, tt.to); got != tt.want {
+				t.Errorf("validTileHistory() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetTileHistoryRequiresPeriod(t *testing.T) {
+	// The period is checked before the locations are read, so the whole history is never streamed.
+	for _, request := range []*dto.TileRequest{
+		{Mode: "history"},
+		{Mode: "history", From: "2025-01-01T00:00:00Z"},
+		{Mode: "history", From: "2025-01-01T00:00:00Z", To: "2025-02-01T00:00:00Z"},
+	} {
+		if _, err := GetTile(nil, request); !errors.Is(err, ErrInvalidTileHistory) {
+			t.Errorf("GetTile(%+v) error = %v, want %v", request, err, ErrInvalidTileHistory)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/pkg/jwt"
+	"github.com/allansbo/goapi/internal/pkg/validation"
+)
+
+var (
+	// ErrInvalidToken is returned, wrapped with the reason, when a bearer token can't be trusted.
+	ErrInvalidToken = jwt.ErrInvalidToken
+	// ErrTokenDisabled is returned when a bearer token is sent but no JWKS is configured.
+	ErrTokenDisabled = errors.New("bearer tokens are not enabled")
+)
+
+type tokenUseCase struct {
+	verifier      *jwt.Verifier
+	rolesClaim    string
+	vehiclesClaim string
+	groupsClaim   string
+	tenantClaim   string
+	roles         map[string]string
+}
+
+var j tokenUseCase
+
+// tokenRoles are the roles that the claim values can be mapped to, the same of the API key scopes.
+var tokenRoles = []string{entity.ScopeRead, entity.ScopeWrite, entity.ScopeDelete, entity.ScopeAdmin}
+
+// LoadTokenUseCase sets the verifier of the bearer tokens issued by the identity provider.
+// The roles of the clients are read from the rolesClaim of the tokens and translated by the roleMap entries,
+// in the format <claim value>=<role>, like fleet-manager=write. The values not mapped are dropped,
+// so the roles of the identity provider for other applications never grant a role of the API.
+// The vehicles and the fleet groups that the clients can access are read from the vehiclesClaim and the groupsClaim,
+// and their tenant from the tenantClaim.
+func LoadTokenUseCase(verifier *jwt.Verifier, rolesClaim, vehiclesClaim, groupsClaim, tenantClaim string, roleMap []string) error {
+	j.verifier = verifier
+	j.rolesClaim = rolesClaim
+	j.vehiclesClaim = vehiclesClaim
+	j.groupsClaim = groupsClaim
+	j.tenantClaim = tenantClaim
+	j.roles = make(map[string]string, len(roleMap))
+
+	for _, entry := range roleMap {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		value, role, found := strings.Cut(entry, "=")
+		value, role = strings.TrimSpace(value), strings.TrimSpace(role)
+		if !found || value == "" || role == "" {
+			return fmt.Errorf("invalid role mapping %q, expected <claim value>=<role>", entry)
+		}
+		if !slices.Contains(tokenRoles, role) {
+			return fmt.Errorf("invalid role mapping %q, the roles are %v", entry, tokenRoles)
+		}
+		j.roles[value] = role
+	}
+	return nil
+}
+
+// AuthenticateToken verifies a bearer token and returns its principal, with the roles mapped from its claims
+// and the tenant, vehicles and fleet groups it can access. The tokens without the tenant claim belong to the default tenant.
+// It returns an error wrapping ErrInvalidToken when the token can't be trusted
+// and ErrTokenDisabled when the tokens are not configured.
+func AuthenticateToken(token string) (*entity.Principal, error) {
+	if j.verifier == nil {
+		return nil, ErrTokenDisabled
+	}
+
+	claims, err := j.verifier.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant := ""
+	tenants := claims.Strings(j.tenantClaim)
+	if len(tenants) > 1 || (len(tenants) == 1 && !validation.IsTenant(tenants[0])) {
+		return nil, fmt.Errorf("%w: invalid %s claim", ErrInvalidToken, j.tenantClaim)
+	}
+	if len(tenants) == 1 {
+		tenant = tenants[0]
+	}
+
+	return &entity.Principal{
+		Subject:  claims.Subject(),
+		Name:     tokenName(claims),
+		Method:   entity.AuthJWT,
+		Tenant:   tenant,
+		Roles:    mapRoles(claims.Strings(j.rolesClaim)),
+		Vehicles: claims.Strings(j.vehiclesClaim),
+		Groups:   claims.Strings(j.groupsClaim),
+	}, nil
+}
+
+// mapRoles translates the values of the roles claim by the role mapping, dropping the values not mapped.
+func mapRoles(values []string) []string {
+	roles := make([]string, 0, len(values))
+	for _, value := range values {
+		if role, ok := j.roles[value]; ok && !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// tokenName returns the name of the client of a token, from the usual claims of the identity providers.
+func tokenName(claims jwt.Claims) string {
+	for _, claim := range []string{"preferred_username", "name", "email"} {
+		if name, ok := claims[claim].(string); ok && name != "" {
+			return name
+		}
+	}
+	return claims.Subject()
+}
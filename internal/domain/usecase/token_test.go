@@ -0,0 +1,69 @@
+package usecase
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestLoadTokenUseCaseRoleMap(t *testing.T) {
+	t.Cleanup(func() { j = tokenUseCase{} })
+
+	tests := []struct {
+		name    string
+		roleMap []string
+		wantErr bool
+	}{
+		{name: "mapping", roleMap: []string{"fleet-manager=write", " fleet-admin = admin ", ""}},
+		{name: "identity mapping", roleMap: []string{"read=read", "write=write", "delete=delete", "admin=admin"}},
+		{name: "missing role", roleMap: []string{"fleet-manager="}, wantErr: true},
+		{name: "missing value", roleMap: []string{"=write"}, wantErr: true},
+		{name: "missing separator", roleMap: []string{"write"}, wantErr: true},
+		{name: "unknown role", roleMap: []string{"fleet-manager=superuser"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := LoadTokenUseCase(nil, "roles", "vehicles", "groups", "tenant", tt.roleMap)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadTokenUseCase() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMapRoles(t *testing.T) {
+	t.Cleanup(func() { j = tokenUseCase{} })
+	if err := LoadTokenUseCase(nil, "roles", "vehicles", "groups", "tenant", []string{"fleet-manager=write", "fleet-viewer=read", "viewer=read"}); err != nil {
+		t.Fatalf("LoadTokenUseCase() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		values []string
+		want   []string
+	}{
+		{name: "mapped roles", values: []string{"fleet-manager", "fleet-viewer"}, want: []string{"write", "read"}},
+		{name: "unmapped roles dropped", values: []string{"admin", "offline_access", "fleet-viewer"}, want: []string{"read"}},
+		{name: "api roles not mapped", values: []string{"read", "delete", "admin"}, want: []string{}},
+		{name: "duplicated roles", values: []string{"fleet-viewer", "viewer"}, want: []string{"read"}},
+		{name: "no roles", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mapRoles(tt.values); !slices.Equal(got, tt.want) {
+				t.Errorf("mapRoles(%v) = %v, want %v", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticateTokenDisabled(t *testing.T) {
+	t.Cleanup(func() { j = tokenUseCase{} })
+	j = tokenUseCase{}
+
+	if _, err := AuthenticateToken("a.b.c"); !errors.Is(err, ErrTokenDisabled) {
+		t.Errorf("AuthenticateToken() error = %v, want %v", err, ErrTokenDisabled)
+	}
+}
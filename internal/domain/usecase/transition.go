@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/allansbo/goapi/internal/app/server/dto"
+	"github.com/allansbo/goapi/internal/domain/entity"
+	"github.com/allansbo/goapi/internal/provider/db"
+)
+
+// recordTransition saves the transition of the vehicle from the status of its previous location to the
+// status of the saved one, if it changed. The location is already saved, so a failure is only logged.
+func recordTransition(repository db.Repository, previous, location *entity.Location, source string) {
+	if previous == nil {
+		return
+	}
+	saveTransition(repository, location.VehicleId, previous.Status, location.Status, location.Timestamp, source, location.ID)
+}
+
+// recordUpdateTransition saves the transition of the vehicle from the status of a location before
+// an update to the status after it, if it changed. Nothing is saved when the location is moved to
+// another vehicle, since the status before it was not a status of that vehicle.
+func recordUpdateTransition(repository db.Repository, before *dto.LocationInDB, after *entity.Location) {
+	if before.VehicleId != after.VehicleId {
+		return
+	}
+	saveTransition(repository, after.VehicleId, before.Status, after.Status, after.Timestamp, entity.SourceUpdate, after.ID)
+}
+
+func saveTransition(repository db.Repository, vehicleID, from, to string, at time.Time, source, locationID string) {
+	transition, err := entity.NewStatusTransition(vehicleID, from, to, at, source, locationID)
+	if err == nil && transition != nil {
+		_, err = repository.InsertTransition(transition.NewStatusTransitionOutDB())
+	}
+	if err != nil {
+		slog.Error("error recording status transition", "error", err.Error(), "vehicleID", vehicleID, "locationID", locationID)
+	}
+}
+
+// GetStatusTransitions returns the status transitions of a vehicle in a period and the time spent on every status.
+// The status at the start of the period is the one of the last transition before it, and the time before
+// the first transition of a vehicle without any previous one is not counted.
+func GetStatusTransitions(principal *entity.Principal, request *dto.StatusTransitionRequest) (*dto.StatusTransitionResponseOut, error) {
+	if err := CheckVehicleAccess(principal, request.VehicleId); err != nil {
+		return nil, err
+	}
+
+	from, _ := time.Parse(time.RFC3339, request.From)
+	to, _ := time.Parse(time.RFC3339, request.To)
+
+	repository := tenantRepository(principal)
+
+	transitionsInDB, err := repository.GetTransitions(&dto.QueryStatusTransitionOutDB{
+		VehicleId: request.VehicleId,
+		From:      from,
+		To:        to,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lastInDB, err := repository.GetLastTransition(request.VehicleId, from)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.StatusTransitionResponseOut{
+		VehicleId:   request.VehicleId,
+		From:        from,
+		To:          to,
+		Durations:   map[string]int64{entity.StatusMoving: 0, entity.StatusStopped: 0, entity.StatusOffline: 0},
+		Transitions: make([]*dto.StatusTransitionOutApp, 0, len(transitionsInDB)),
+	}
+
+	status, since := "", from
+	if lastInDB != nil {
+		status = lastInDB.To
+	}
+
+	durations := make(map[string]time.Duration)
+	for _, transitionInDB := range transitionsInDB {
+		transition := entity.NewStatusTransitionInDB(transitionInDB)
+		if status == "" {
+			status = transition.From
+			since = transition.At
+		}
+		durations[status] += transition.At.Sub(since)
+		status, since = transition.To, transition.At
+
+		response.Transitions = append(response.Transitions, transition.NewStatusTransitionOutApp())
+	}
+
+	if status != "" {
+		end := to
+		if now := time.Now(); now.Before(end) {
+			end = now
+		}
+		if end.After(since) {
+			durations[status] += end.Sub(since)
+		}
+	}
+
+	for s, d := range durations {
+		response.Durations[s] = int64(d.Seconds())
+	}
+	return response, nil
+}
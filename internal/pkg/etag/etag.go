@@ -0,0 +1,36 @@
+package etag
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Any is the If-Match and If-None-Match value that matches any version of an existing document.
+const Any = "*"
+
+// Format returns the strong entity tag of a version of a document.
+func Format(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// Match reports if the tag is in the comma-separated list of an If-Match or If-None-Match header,
+// or if the list is "*". The weak tags, prefixed by W/, only match with the weak comparison
+// of If-None-Match, the If-Match header uses the strong one.
+func Match(header, tag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == Any {
+			return true
+		}
+		if opaque, ok := strings.CutPrefix(candidate, "W/"); ok {
+			if !weak {
+				continue
+			}
+			candidate = opaque
+		}
+		if candidate == tag {
+			return true
+		}
+	}
+	return false
+}
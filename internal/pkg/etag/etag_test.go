@@ -0,0 +1,47 @@
+package etag
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	for version, want := range map[int64]string{0: `"0"`, 1: `"1"`, 42: `"42"`, -1: `"-1"`} {
+		if got := Format(version); got != want {
+			t.Errorf("Format(%d) = %s, want %s", version, got, want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tag := Format(3)
+
+	tests := []struct {
+		name   string
+		header string
+		weak   bool
+		want   bool
+	}{
+		{name: "same tag", header: `"3"`, want: true},
+		{name: "other tag", header: `"4"`},
+		{name: "tag in a list", header: `"1", "3"`, want: true},
+		{name: "tag in a list without spaces", header: `"1","3","5"`, want: true},
+		{name: "tag not in a list", header: `"1", "2"`},
+		{name: "any version", header: `*`, want: true},
+		{name: "any version with spaces", header: ` * `, want: true},
+		{name: "empty header", header: ``},
+		{name: "unquoted tag", header: `3`},
+		{name: "other tag with the same prefix", header: `"33"`},
+		{name: "weak tag with the strong comparison", header: `W/"3"`},
+		{name: "weak tag with the weak comparison", header: `W/"3"`, weak: true, want: true},
+		{name: "weak tag in a list with the strong comparison", header: `W/"3", "3"`, want: true},
+		{name: "other weak tag with the weak comparison", header: `W/"4"`, weak: true},
+		{name: "strong tag with the weak comparison", header: `"3"`, weak: true, want: true},
+		{name: "lower case weak prefix", header: `w/"3"`, weak: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Match(tt.header, tag, tt.weak); got != tt.want {
+				t.Errorf("Match(%s, %s, %v) = %v, want %v", tt.header, tag, tt.weak, got, tt.want)
+			}
+		})
+	}
+}
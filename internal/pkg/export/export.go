@@ -0,0 +1,131 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRowGroupSize is the number of rows buffered in memory before a row group is flushed.
+const parquetRowGroupSize = 50_000
+
+// Row is a location exported to a file.
+type Row struct {
+	ID         string    `parquet:"id"`
+	VehicleId  string    `parquet:"vehicle_id,dict"`
+	Timestamp  time.Time `parquet:"timestamp,timestamp(millisecond)"`
+	Latitude   float64   `parquet:"latitude"`
+	Longitude  float64   `parquet:"longitude"`
+	Speed      int64     `parquet:"speed"`
+	Status     string    `parquet:"status,dict"`
+	Attributes string    `parquet:"attributes,optional"`
+}
+
+// Writer writes the exported locations row by row, so millions of rows can be streamed.
+type Writer interface {
+	// WriteRow writes the next row of the file.
+	WriteRow(row *Row) error
+	// Close writes the end of the file and flushes it. It doesn't close the underlying writer.
+	Close() error
+}
+
+type format struct {
+	contentType string
+	newWriter   func(w io.Writer) (Writer, error)
+}
+
+var formats = map[string]format{
+	"csv":     {contentType: "text/csv", newWriter: newCSVWriter},
+	"parquet": {contentType: "application/vnd.apache.parquet", newWriter: newParquetWriter},
+}
+
+// ContentType returns the media type of a format, like text/csv for csv.
+func ContentType(name string) string {
+	return formats[name].contentType
+}
+
+// NewWriter creates a Writer of the format, csv or parquet.
+func NewWriter(name string, w io.Writer) (Writer, error) {
+	f, ok := formats[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported export format %q", name)
+	}
+	return f.newWriter(w)
+}
+
+// AttributesJSON encodes the attributes of a location as they are exported, empty when there is none.
+func AttributesJSON(attributes map[string]any) (string, error) {
+	if len(attributes) == 0 {
+		return "", nil
+	}
+
+	attributesJSON, err := json.Marshal(attributes)
+	if err != nil {
+		return "", err
+	}
+	return string(attributesJSON), nil
+}
+
+// csvWriter writes the rows with a header line, the attributes are encoded as JSON.
+type csvWriter struct {
+	buffer *bufio.Writer
+	w      *csv.Writer
+}
+
+var csvHeader = []string{"id", "vehicle_id", "timestamp", "latitude", "longitude", "speed", "status", "attributes"}
+
+func newCSVWriter(w io.Writer) (Writer, error) {
+	buffer := bufio.NewWriter(w)
+	c := &csvWriter{buffer: buffer, w: csv.NewWriter(buffer)}
+	return c, c.w.Write(csvHeader)
+}
+
+func (c *csvWriter) WriteRow(row *Row) error {
+	return c.w.Write([]string{
+		row.ID,
+		row.VehicleId,
+		row.Timestamp.UTC().Format(time.RFC3339Nano),
+		strconv.FormatFloat(row.Latitude, 'f', -1, 64),
+		strconv.FormatFloat(row.Longitude, 'f', -1, 64),
+		strconv.FormatInt(row.Speed, 10),
+		row.Status,
+		row.Attributes,
+	})
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		return err
+	}
+	return c.buffer.Flush()
+}
+
+// parquetWriter writes the rows with snappy compression, flushing a row group every parquetRowGroupSize rows.
+type parquetWriter struct {
+	w *parquet.GenericWriter[Row]
+}
+
+func newParquetWriter(w io.Writer) (Writer, error) {
+	return &parquetWriter{
+		w: parquet.NewGenericWriter[Row](w,
+			parquet.Compression(&parquet.Snappy),
+			parquet.MaxRowsPerRowGroup(parquetRowGroupSize),
+		),
+	}, nil
+}
+
+func (p *parquetWriter) WriteRow(row *Row) error {
+	_, err := p.w.Write([]Row{*row})
+	return err
+}
+
+func (p *parquetWriter) Close() error {
+	return p.w.Close()
+}
@@ -0,0 +1,230 @@
+package gazetteer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/allansbo/goapi/internal/pkg/geo"
+)
+
+// Place is a named place of the gazetteer.
+type Place struct {
+	Name      string
+	Locality  string
+	Country   string
+	Latitude  float64
+	Longitude float64
+}
+
+// Label returns the name of the place followed by its locality, like "Av. Paulista, São Paulo",
+// or by its country when it has no locality.
+func (p *Place) Label() string {
+	switch {
+	case p.Locality != "":
+		return p.Name + ", " + p.Locality
+	case p.Country != "":
+		return p.Name + ", " + p.Country
+	default:
+		return p.Name
+	}
+}
+
+// Gazetteer finds the nearest place of a coordinate in memory, without any external service.
+// The places are indexed by a k-d tree of their positions on the unit sphere,
+// where the straight distance grows with the great-circle distance.
+type Gazetteer struct {
+	nodes []node
+}
+
+type node struct {
+	place  *Place
+	vector [3]float64
+}
+
+// New indexes the places.
+func New(places []*Place) *Gazetteer {
+	g := &Gazetteer{nodes: make([]node, len(places))}
+	for i, place := range places {
+		g.nodes[i] = node{place: place, vector: unitVector(place.Latitude, place.Longitude)}
+	}
+	g.build(g.nodes, 0)
+	return g
+}
+
+// Len returns the number of places indexed.
+func (g *Gazetteer) Len() int {
+	return len(g.nodes)
+}
+
+// Nearest returns the nearest place of the point and its distance in meters, or nil when the gazetteer is empty.
+func (g *Gazetteer) Nearest(point geo.Point) (*Place, float64) {
+	if len(g.nodes) == 0 {
+		return nil, 0
+	}
+
+	target := unitVector(point.Latitude, point.Longitude)
+	best, bestDistance := -1, math.Inf(1)
+	g.search(0, len(g.nodes), 0, target, &best, &bestDistance)
+
+	place := g.nodes[best].place
+	return place, geo.Distance(point, geo.Point{Latitude: place.Latitude, Longitude: place.Longitude})
+}
+
+// build sorts the nodes as an implicit k-d tree, where the root of every range is its median.
+func (g *Gazetteer) build(nodes []node, axis int) {
+	if len(nodes) < 2 {
+		return
+	}
+
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].vector[axis] < nodes[j].vector[axis] })
+	middle := len(nodes) / 2
+	g.build(nodes[:middle], (axis+1)%3)
+	g.build(nodes[middle+1:], (axis+1)%3)
+}
+
+func (g *Gazetteer) search(start, end, axis int, target [3]float64, best *int, bestDistance *float64) {
+	if start >= end {
+		return
+	}
+
+	middle := start + (end-start)/2
+	if d := squaredDistance(g.nodes[middle].vector, target); d < *bestDistance {
+		*best, *bestDistance = middle, d
+	}
+
+	delta := target[axis] - g.nodes[middle].vector[axis]
+	near, far := [2]int{start, middle}, [2]int{middle + 1, end}
+	if delta > 0 {
+		near, far = far, near
+	}
+
+	g.search(near[0], near[1], (axis+1)%3, target, best, bestDistance)
+	if delta*delta < *bestDistance {
+		g.search(far[0], far[1], (axis+1)%3, target, best, bestDistance)
+	}
+}
+
+func unitVector(latitude, longitude float64) [3]float64 {
+	lat, lon := latitude*math.Pi/180, longitude*math.Pi/180
+	return [3]float64{math.Cos(lat) * math.Cos(lon), math.Cos(lat) * math.Sin(lon), math.Sin(lat)}
+}
+
+func squaredDistance(a, b [3]float64) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dx*dx + dy*dy + dz*dz
+}
+
+// LoadFile reads the places of a file and indexes them. The .csv files must have a header
+// with the name, latitude and longitude columns, and optionally locality and country,
+// like the streets extracted from OpenStreetMap. The other files are read as GeoNames dumps,
+// like cities500.txt, tab separated without a header.
+func LoadFile(path string) (*Gazetteer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var places []*Place
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		places, err = readCSV(file)
+	} else {
+		places, err = readGeoNames(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading gazetteer %s: %w", path, err)
+	}
+
+	return New(places), nil
+}
+
+// readGeoNames reads the GeoNames dump format, where the name is the second column,
+// the latitude and longitude the fifth and sixth, and the country code the ninth.
+func readGeoNames(r io.Reader) ([]*Place, error) {
+	places := make([]*Place, 0)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		columns := strings.Split(scanner.Text(), "\t")
+		if len(columns) < 9 {
+			return nil, fmt.Errorf("line %d: expected at least 9 columns, found %d", line, len(columns))
+		}
+
+		place, err := newPlace(columns[1], columns[4], columns[5])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		place.Country = columns[8]
+		places = append(places, place)
+	}
+
+	return places, scanner.Err()
+}
+
+func readCSV(r io.Reader) ([]*Place, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"name", "latitude", "longitude"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("the %s column is required", required)
+		}
+	}
+
+	column := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	places := make([]*Place, 0)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return places, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		place, err := newPlace(column(record, "name"), column(record, "latitude"), column(record, "longitude"))
+		if err != nil {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		place.Locality = column(record, "locality")
+		place.Country = column(record, "country")
+		places = append(places, place)
+	}
+}
+
+func newPlace(name, latitude, longitude string) (*Place, error) {
+	lat, err := strconv.ParseFloat(latitude, 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return nil, fmt.Errorf("invalid latitude %q", latitude)
+	}
+	lon, err := strconv.ParseFloat(longitude, 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return nil, fmt.Errorf("invalid longitude %q", longitude)
+	}
+	return &Place{Name: name, Latitude: lat, Longitude: lon}, nil
+}
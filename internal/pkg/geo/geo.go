@@ -0,0 +1,119 @@
+package geo
+
+import (
+	"math"
+)
+
+// EarthRadius is the mean radius of the Earth in meters.
+const EarthRadius = 6371008.8
+
+// Point is a coordinate in decimal degrees.
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func degrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
+
+// Distance returns the great-circle distance in meters between two points, by the haversine formula.
+func Distance(a, b Point) float64 {
+	lat1, lat2 := radians(a.Latitude), radians(b.Latitude)
+	dLat := lat2 - lat1
+	dLon := radians(b.Longitude - a.Longitude)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Bearing returns the initial bearing in degrees, from 0 to 360 clockwise from the north, to go from a to b.
+func Bearing(a, b Point) float64 {
+	lat1, lat2 := radians(a.Latitude), radians(b.Latitude)
+	dLon := radians(b.Longitude - a.Longitude)
+
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	return math.Mod(degrees(math.Atan2(y, x))+360, 360)
+}
+
+// Simplify reduces a line to the points needed to keep it within the tolerance in meters,
+// by the Douglas-Peucker algorithm. The points flagged in keep, and the first and last ones,
+// are never removed: the line is simplified between them. It returns the indexes of the kept points, in order.
+func Simplify(points []Point, tolerance float64, keep []bool) []int {
+	if len(points) < 3 || tolerance <= 0 {
+		indexes := make([]int, len(points))
+		for i := range points {
+			indexes[i] = i
+		}
+		return indexes
+	}
+
+	kept := make([]bool, len(points))
+	kept[0], kept[len(points)-1] = true, true
+
+	start := 0
+	for i := 1; i < len(points); i++ {
+		if i == len(points)-1 || (keep != nil && keep[i]) {
+			kept[i] = true
+			douglasPeucker(points, start, i, tolerance, kept)
+			start = i
+		}
+	}
+
+	indexes := make([]int, 0)
+	for i, k := range kept {
+		if k {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// douglasPeucker flags the points between first and last that are farther than the tolerance
+// from the segment, recursively, using an explicit stack to support long lines.
+func douglasPeucker(points []Point, first, last int, tolerance float64, kept []bool) {
+	stack := [][2]int{{first, last}}
+
+	for len(stack) > 0 {
+		segment := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		first, last := segment[0], segment[1]
+
+		farthest, maxDistance := -1, tolerance
+		for i := first + 1; i < last; i++ {
+			if d := segmentDistance(points[i], points[first], points[last]); d > maxDistance {
+				farthest, maxDistance = i, d
+			}
+		}
+
+		if farthest >= 0 {
+			kept[farthest] = true
+			stack = append(stack, [2]int{first, farthest}, [2]int{farthest, last})
+		}
+	}
+}
+
+// segmentDistance returns the distance in meters from p to the segment ab, on a local
+// equirectangular projection, which is accurate for the short segments of a track.
+func segmentDistance(p, a, b Point) float64 {
+	cosLat := math.Cos(radians(a.Latitude))
+	project := func(q Point) (float64, float64) {
+		return radians(q.Longitude-a.Longitude) * cosLat * EarthRadius, radians(q.Latitude-a.Latitude) * EarthRadius
+	}
+
+	px, py := project(p)
+	bx, by := project(b)
+
+	lengthSquared := bx*bx + by*by
+	if lengthSquared == 0 {
+		return math.Hypot(px, py)
+	}
+
+	t := math.Max(0, math.Min(1, (px*bx+py*by)/lengthSquared))
+	return math.Hypot(px-t*bx, py-t*by)
+}
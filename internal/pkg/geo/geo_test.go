@@ -0,0 +1,132 @@
+package geo
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Point
+		want float64
+	}{
+		{name: "same point", a: Point{Latitude: -23.55, Longitude: -46.63}, b: Point{Latitude: -23.55, Longitude: -46.63}, want: 0},
+		{name: "one degree of latitude", a: Point{Latitude: 0, Longitude: 0}, b: Point{Latitude: 1, Longitude: 0}, want: 111195},
+		{name: "one degree of longitude on the equator", a: Point{Latitude: 0, Longitude: 0}, b: Point{Latitude: 0, Longitude: 1}, want: 111195},
+		{name: "Sao Paulo to Rio de Janeiro", a: Point{Latitude: -23.55052, Longitude: -46.633308}, b: Point{Latitude: -22.906847, Longitude: -43.172896}, want: 360750},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Distance(tt.a, tt.b); math.Abs(got-tt.want) > 1 {
+				t.Errorf("Distance() = %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBearing(t *testing.T) {
+	origin := Point{Latitude: 0, Longitude: 0}
+	tests := []struct {
+		to   Point
+		want float64
+	}{
+		{to: Point{Latitude: 1, Longitude: 0}, want: 0},
+		{to: Point{Latitude: 0, Longitude: 1}, want: 90},
+		{to: Point{Latitude: -1, Longitude: 0}, want: 180},
+		{to: Point{Latitude: 0, Longitude: -1}, want: 270},
+	}
+
+	for _, tt := range tests {
+		if got := Bearing(origin, tt.to); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Bearing(%v) = %f, want %f", tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestSegmentDistance(t *testing.T) {
+	a, b := Point{Latitude: 0, Longitude: 0}, Point{Latitude: 0, Longitude: 0.001}
+
+	tests := []struct {
+		name string
+		p    Point
+		want float64
+	}{
+		{name: "on the segment", p: Point{Latitude: 0, Longitude: 0.0005}, want: 0},
+		{name: "beside the segment", p: Point{Latitude: 0.0001, Longitude: 0.0005}, want: 11.12},
+		{name: "beyond the end", p: Point{Latitude: 0, Longitude: 0.002}, want: 111.19},
+		{name: "before the start", p: Point{Latitude: 0.0001, Longitude: 0}, want: 11.12},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := segmentDistance(tt.p, a, b); math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("segmentDistance() = %f, want %f", got, tt.want)
+			}
+		})
+	}
+
+	if got := segmentDistance(Point{Latitude: 0.0001}, a, a); math.Abs(got-11.12) > 0.01 {
+		t.Errorf("segmentDistance() to a point = %f, want 11.12", got)
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	// A line to the east every ~11 m, with a detour of ~22 m to the north at the index 3.
+	line := []Point{
+		{Latitude: 0, Longitude: 0},
+		{Latitude: 0.00001, Longitude: 0.0001},
+		{Latitude: 0, Longitude: 0.0002},
+		{Latitude: 0.0002, Longitude: 0.0003},
+		{Latitude: 0, Longitude: 0.0004},
+		{Latitude: -0.00001, Longitude: 0.0005},
+		{Latitude: 0, Longitude: 0.0006},
+	}
+
+	tests := []struct {
+		name      string
+		points    []Point
+		tolerance float64
+		keep      []bool
+		want      []int
+	}{
+		{name: "detour kept", points: line, tolerance: 5, want: []int{0, 2, 3, 4, 6}},
+		{name: "detour within the tolerance", points: line, tolerance: 50, want: []int{0, 6}},
+		{name: "small deviations kept by a low tolerance", points: line, tolerance: 0.5, want: []int{0, 1, 2, 3, 4, 5, 6}},
+		{name: "flagged point kept", points: line, tolerance: 50, keep: []bool{false, true, false, false, false, false, false}, want: []int{0, 1, 6}},
+		{name: "no tolerance", points: line, tolerance: 0, want: []int{0, 1, 2, 3, 4, 5, 6}},
+		{name: "two points", points: line[:2], tolerance: 50, want: []int{0, 1}},
+		{name: "no points", points: nil, tolerance: 50, want: []int{}},
+		{
+			name:      "straight line",
+			points:    []Point{{Longitude: 0}, {Longitude: 0.001}, {Longitude: 0.002}, {Longitude: 0.003}},
+			tolerance: 1,
+			want:      []int{0, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Simplify(tt.points, tt.tolerance, tt.keep); !slices.Equal(got, tt.want) {
+				t.Errorf("Simplify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimplifyLongLine(t *testing.T) {
+	// A circle of ~1 km of radius with 100k points, ~6 cm apart: the chords kept within 1 m
+	// of the arc are ~90 m long, so ~70 of the points are kept along the circle.
+	points := make([]Point, 100000)
+	for i := range points {
+		angle := 2 * math.Pi * float64(i) / float64(len(points))
+		points[i] = Point{Latitude: 0.009 * math.Sin(angle), Longitude: 0.009 * math.Cos(angle)}
+	}
+
+	got := Simplify(points, 1, nil)
+	if len(got) < 64 || len(got) > 256 || got[0] != 0 || got[len(got)-1] != len(points)-1 {
+		t.Errorf("Simplify() kept %d points, from %d to %d", len(got), got[0], got[len(got)-1])
+	}
+}
@@ -0,0 +1,87 @@
+package geohash
+
+import (
+	"strings"
+)
+
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Box is the area of a geohash cell, in decimal degrees.
+type Box struct {
+	MinLatitude  float64
+	MaxLatitude  float64
+	MinLongitude float64
+	MaxLongitude float64
+}
+
+// Center returns the center of the cell.
+func (b Box) Center() (latitude, longitude float64) {
+	return (b.MinLatitude + b.MaxLatitude) / 2, (b.MinLongitude + b.MaxLongitude) / 2
+}
+
+// Encode returns the geohash of the coordinates with the number of characters of the precision.
+func Encode(latitude, longitude float64, precision int) string {
+	latRange := [2]float64{-90, 90}
+	lonRange := [2]float64{-180, 180}
+
+	hash := new(strings.Builder)
+	hash.Grow(precision)
+
+	bit, char, even := 0, 0, true
+	for hash.Len() < precision {
+		if even {
+			char = char<<1 | bisect(&lonRange, longitude)
+		} else {
+			char = char<<1 | bisect(&latRange, latitude)
+		}
+		even = !even
+
+		if bit++; bit == 5 {
+			hash.WriteByte(base32[char])
+			bit, char = 0, 0
+		}
+	}
+
+	return hash.String()
+}
+
+// Decode returns the cell of a geohash. The characters out of the geohash alphabet are ignored.
+func Decode(hash string) Box {
+	latRange := [2]float64{-90, 90}
+	lonRange := [2]float64{-180, 180}
+
+	even := true
+	for _, c := range hash {
+		char := strings.IndexRune(base32, c)
+		if char < 0 {
+			continue
+		}
+
+		for mask := 16; mask > 0; mask >>= 1 {
+			r := &latRange
+			if even {
+				r = &lonRange
+			}
+			middle := (r[0] + r[1]) / 2
+			if char&mask != 0 {
+				r[0] = middle
+			} else {
+				r[1] = middle
+			}
+			even = !even
+		}
+	}
+
+	return Box{MinLatitude: latRange[0], MaxLatitude: latRange[1], MinLongitude: lonRange[0], MaxLongitude: lonRange[1]}
+}
+
+// bisect halves the range keeping the side of the value, returning 1 for the upper half.
+func bisect(r *[2]float64, value float64) int {
+	middle := (r[0] + r[1]) / 2
+	if value >= middle {
+		r[0] = middle
+		return 1
+	}
+	r[1] = middle
+	return 0
+}
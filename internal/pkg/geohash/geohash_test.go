@@ -0,0 +1,72 @@
+package geohash
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		latitude  float64
+		longitude float64
+		precision int
+		want      string
+	}{
+		{latitude: 57.64911, longitude: 10.40744, precision: 11, want: "u4pruydqqvj"},
+		{latitude: 42.605, longitude: -5.603, precision: 5, want: "ezs42"},
+		{latitude: -23.55052, longitude: -46.633308, precision: 7, want: "6gyf4bf"},
+		{latitude: 0, longitude: 0, precision: 1, want: "s"},
+		{latitude: -90, longitude: -180, precision: 3, want: "000"},
+		{latitude: 90, longitude: 180, precision: 3, want: "zzz"},
+		{latitude: 57.64911, longitude: 10.40744, precision: 0, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := Encode(tt.latitude, tt.longitude, tt.precision); got != tt.want {
+			t.Errorf("Encode(%v, %v, %d) = %q, want %q", tt.latitude, tt.longitude, tt.precision, got, tt.want)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		hash string
+		want Box
+	}{
+		{hash: "ezs42", want: Box{MinLatitude: 42.583007812, MaxLatitude: 42.626953125, MinLongitude: -5.625, MaxLongitude: -5.581054687}},
+		{hash: "s", want: Box{MinLatitude: 0, MaxLatitude: 45, MinLongitude: 0, MaxLongitude: 45}},
+		{hash: "", want: Box{MinLatitude: -90, MaxLatitude: 90, MinLongitude: -180, MaxLongitude: 180}},
+		{hash: "s-a", want: Box{MinLatitude: 0, MaxLatitude: 45, MinLongitude: 0, MaxLongitude: 45}},
+	}
+
+	for _, tt := range tests {
+		got := Decode(tt.hash)
+		if !closeTo(got.MinLatitude, tt.want.MinLatitude) || !closeTo(got.MaxLatitude, tt.want.MaxLatitude) ||
+			!closeTo(got.MinLongitude, tt.want.MinLongitude) || !closeTo(got.MaxLongitude, tt.want.MaxLongitude) {
+			t.Errorf("Decode(%q) = %+v, want %+v", tt.hash, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	// The coordinates are inside the cell of their geohash at every precision.
+	coordinates := [][2]float64{{-23.55052, -46.633308}, {40.689247, -74.044502}, {-33.856784, 151.215297}, {0, 0}}
+
+	for _, c := range coordinates {
+		for precision := 1; precision <= 12; precision++ {
+			box := Decode(Encode(c[0], c[1], precision))
+			if c[0] < box.MinLatitude || c[0] > box.MaxLatitude || c[1] < box.MinLongitude || c[1] > box.MaxLongitude {
+				t.Errorf("%v is out of the cell %+v of precision %d", c, box, precision)
+			}
+
+			latitude, longitude := box.Center()
+			if got := Encode(latitude, longitude, precision); got != Encode(c[0], c[1], precision) {
+				t.Errorf("the center of the cell of %v has the geohash %q at precision %d", c, got, precision)
+			}
+		}
+	}
+}
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) < 1e-8
+}
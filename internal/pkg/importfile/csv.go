@@ -0,0 +1,109 @@
+package importfile
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// csvReader reads a CSV file with a header line, finding the columns of the fields by the mapping.
+type csvReader struct {
+	r       *csv.Reader
+	opts    Options
+	columns map[string]int
+}
+
+func newCSVReader(r io.Reader, opts Options) (*csvReader, error) {
+	c := &csvReader{r: csv.NewReader(r), opts: opts, columns: make(map[string]int)}
+	c.r.FieldsPerRecord = -1
+	c.r.TrimLeadingSpace = true
+
+	header, err := c.r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading the csv header: %w", err)
+	}
+
+	positions := make(map[string]int, len(header))
+	for i, column := range header {
+		positions[strings.TrimSpace(column)] = i
+	}
+
+	for _, field := range Fields {
+		column := field
+		if mapped, ok := opts.Mapping[field]; ok {
+			column = mapped
+		}
+		if i, ok := positions[column]; ok {
+			c.columns[field] = i
+		}
+	}
+
+	required := []string{"latitude", "longitude", "timestamp"}
+	if opts.VehicleId == "" {
+		required = append(required, "vehicle_id")
+	}
+	for _, field := range required {
+		if _, ok := c.columns[field]; !ok {
+			return nil, fmt.Errorf("the csv has no column for the field %s, map it with %s=<column>", field, field)
+		}
+	}
+
+	return c, nil
+}
+
+func (c *csvReader) Next() (*Record, error) {
+	row, err := c.r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &RowError{Line: parseErr.Line, Err: parseErr.Err}
+		}
+		return nil, err
+	}
+	line, _ := c.r.FieldPos(0)
+
+	record := &Record{
+		Line:      line,
+		VehicleId: c.value(row, "vehicle_id"),
+		Latitude:  c.value(row, "latitude"),
+		Longitude: c.value(row, "longitude"),
+		Status:    c.value(row, "status"),
+	}
+	if record.VehicleId == "" {
+		record.VehicleId = c.opts.VehicleId
+	}
+
+	if speed := c.value(row, "speed"); speed != "" {
+		value, err := strconv.ParseFloat(speed, 64)
+		if err != nil {
+			return nil, &RowError{Line: line, Err: fmt.Errorf("invalid speed %q", speed)}
+		}
+		record.Speed = int(math.Round(value))
+	}
+
+	timestamp := c.value(row, "timestamp")
+	if timestamp == "" {
+		return nil, &RowError{Line: line, Err: errMissingTimestamp}
+	}
+	if record.Timestamp, err = parseTimestamp(timestamp, c.opts.TimestampLayout); err != nil {
+		return nil, &RowError{Line: line, Err: fmt.Errorf("invalid timestamp %q: %w", timestamp, err)}
+	}
+
+	return record, nil
+}
+
+func (c *csvReader) value(row []string, field string) string {
+	i, ok := c.columns[field]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
@@ -0,0 +1,116 @@
+package importfile
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gpxPoint is a track point. The speed is the GPX 1.0 element, in m/s,
+// or the extension written by the track export, in km/h, along with the status.
+// The extensions are read only in the namespace of the export, trackfile.GPXNamespace,
+// so the extensions of other applications with the same names are ignored.
+type gpxPoint struct {
+	Latitude   string `xml:"lat,attr"`
+	Longitude  string `xml:"lon,attr"`
+	Time       string `xml:"time"`
+	Speed      string `xml:"speed"`
+	Extensions struct {
+		Speed  string `xml:"https://github.com/allansbo/goapi/gpx/1 speed"`
+		Status string `xml:"https://github.com/allansbo/goapi/gpx/1 status"`
+	} `xml:"extensions"`
+}
+
+// gpxReader reads the points of the tracks of a GPX file, decoding one point at a time.
+// The name of the track is used as the vehicle when the options have none.
+type gpxReader struct {
+	decoder   *xml.Decoder
+	opts      Options
+	trackName string
+}
+
+func newGPXReader(r io.Reader, opts Options) *gpxReader {
+	return &gpxReader{decoder: xml.NewDecoder(r), opts: opts}
+}
+
+func (g *gpxReader) Next() (*Record, error) {
+	for {
+		token, err := g.decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		} else if err != nil {
+			line, _ := g.decoder.InputPos()
+			return nil, fmt.Errorf("error reading the gpx at line %d: %w", line, err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "trk":
+			g.trackName = ""
+		case "name":
+			var name string
+			if err := g.decoder.DecodeElement(&name, &start); err != nil {
+				return nil, err
+			}
+			if g.trackName == "" {
+				g.trackName = strings.TrimSpace(name)
+			}
+		case "trkpt":
+			line, _ := g.decoder.InputPos()
+			point := new(gpxPoint)
+			if err := g.decoder.DecodeElement(point, &start); err != nil {
+				return nil, fmt.Errorf("error reading the gpx at line %d: %w", line, err)
+			}
+			return g.record(line, point)
+		}
+	}
+}
+
+func (g *gpxReader) record(line int, point *gpxPoint) (*Record, error) {
+	record := &Record{
+		Line:      line,
+		VehicleId: g.opts.VehicleId,
+		Latitude:  strings.TrimSpace(point.Latitude),
+		Longitude: strings.TrimSpace(point.Longitude),
+		Status:    strings.TrimSpace(point.Extensions.Status),
+	}
+	if record.VehicleId == "" {
+		record.VehicleId = g.trackName
+	}
+
+	switch {
+	case point.Extensions.Speed != "":
+		speed, err := strconv.ParseFloat(strings.TrimSpace(point.Extensions.Speed), 64)
+		if err != nil {
+			return nil, &RowError{Line: line, Err: fmt.Errorf("invalid speed %q", point.Extensions.Speed)}
+		}
+		record.Speed = int(math.Round(speed))
+	case point.Speed != "":
+		speed, err := strconv.ParseFloat(strings.TrimSpace(point.Speed), 64)
+		if err != nil {
+			return nil, &RowError{Line: line, Err: fmt.Errorf("invalid speed %q", point.Speed)}
+		}
+		record.Speed = int(math.Round(speed * 3.6))
+	}
+
+	if point.Time == "" {
+		return nil, &RowError{Line: line, Err: errMissingTimestamp}
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, strings.TrimSpace(point.Time))
+	if err != nil {
+		return nil, &RowError{Line: line, Err: fmt.Errorf("invalid timestamp %q", point.Time)}
+	}
+	record.Timestamp = timestamp
+
+	return record, nil
+}
@@ -0,0 +1,116 @@
+package importfile
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/allansbo/goapi/internal/pkg/trackfile"
+)
+
+func readAll(t *testing.T, format string, r io.Reader, opts Options) ([]*Record, []error) {
+	t.Helper()
+
+	reader, err := NewReader(format, r, opts)
+	if err != nil {
+		t.Fatalf("NewReader(%q) error = %v", format, err)
+	}
+
+	var records []*Record
+	var errs []error
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return records, errs
+		}
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			errs = append(errs, err)
+			continue
+		} else if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		records = append(records, record)
+	}
+}
+
+func TestGPXReadsTrackExport(t *testing.T) {
+	timestamp := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	buf := new(bytes.Buffer)
+
+	w, err := trackfile.NewWriter("gpx", buf, "ABC1234")
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if err := w.WritePoint(&trackfile.Point{Latitude: -23.55052, Longitude: -46.633308, Timestamp: timestamp, Speed: 80, Status: "moving"}); err != nil {
+		t.Fatalf("WritePoint() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	records, errs := readAll(t, "gpx", buf, Options{})
+	if len(errs) != 0 || len(records) != 1 {
+		t.Fatalf("records = %d, errors = %v, want 1 record", len(records), errs)
+	}
+
+	record := records[0]
+	if record.VehicleId != "ABC1234" || record.Latitude != "-23.55052" || record.Longitude != "-46.633308" {
+		t.Errorf("record = %+v, want the exported point of ABC1234", record)
+	}
+	if record.Speed != 80 || record.Status != "moving" || !record.Timestamp.Equal(timestamp) {
+		t.Errorf("record = %+v, want the speed and status of the export", record)
+	}
+}
+
+func TestGPXRecords(t *testing.T) {
+	gpx := `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" xmlns="http://www.topografix.com/GPX/1/1" xmlns:other="https://example.com/gpx">
+<trk><name>XYZ9876</name><trkseg>
+<trkpt lat="-23.5" lon="-46.6"><time>2025-01-01T12:00:00Z</time><speed>10</speed></trkpt>
+<trkpt lat="-23.6" lon="-46.7"><time>2025-01-01T12:01:00Z</time><extensions><other:speed>99</other:speed><other:status>parked</other:status></extensions></trkpt>
+<trkpt lat="-23.7" lon="-46.8"><time>yesterday</time></trkpt>
+<trkpt lat="-23.8" lon="-46.9"></trkpt>
+<trkpt lat="-23.9" lon="-47.0"><time>2025-01-01T12:03:00Z</time><speed>fast</speed></trkpt>
+</trkseg></trk>
+</gpx>`
+
+	records, errs := readAll(t, "gpx", strings.NewReader(gpx), Options{VehicleId: "ABC1234"})
+
+	if len(records) != 2 {
+		t.Fatalf("records = %d, want 2", len(records))
+	}
+	if records[0].VehicleId != "ABC1234" || records[0].Speed != 36 {
+		t.Errorf("record = %+v, want the vehicle of the options and the GPX 1.0 speed in km/h", records[0])
+	}
+	if records[1].Speed != 0 || records[1].Status != "" {
+		t.Errorf("record = %+v, want the extensions of another namespace ignored", records[1])
+	}
+
+	if len(errs) != 3 {
+		t.Fatalf("errors = %v, want 3", errs)
+	}
+	if !errors.Is(errs[1], errMissingTimestamp) {
+		t.Errorf("error = %v, want %v", errs[1], errMissingTimestamp)
+	}
+	var rowErr *RowError
+	if errors.As(errs[0], &rowErr); rowErr.Line != 6 {
+		t.Errorf("error line = %d, want 6", rowErr.Line)
+	}
+}
+
+func TestGPXMalformed(t *testing.T) {
+	reader, err := NewReader("gpx", strings.NewReader(`<gpx><trk><trkseg><trkpt lat="1"`), Options{})
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+
+	_, err = reader.Next()
+	var rowErr *RowError
+	if err == nil || errors.Is(err, io.EOF) || errors.As(err, &rowErr) {
+		t.Errorf("Next() error = %v, want a file error", err)
+	}
+}
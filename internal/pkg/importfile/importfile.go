@@ -0,0 +1,119 @@
+package importfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fields are the location fields that can be read from a file.
+var Fields = []string{"vehicle_id", "latitude", "longitude", "status", "speed", "timestamp"}
+
+// Record is a location read from a file. The status is empty when the file doesn't have it.
+type Record struct {
+	Line      int
+	VehicleId string
+	Latitude  string
+	Longitude string
+	Status    string
+	Speed     int
+	Timestamp time.Time
+}
+
+// RowError is returned by Reader.Next when a row can't be read. The next rows can still be read.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err.Error())
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Reader reads the locations of a file one at a time.
+type Reader interface {
+	// Next returns the next record, a *RowError for a row that can't be read, or io.EOF at the end of the file.
+	Next() (*Record, error)
+}
+
+// Options configures how the files are read.
+type Options struct {
+	// VehicleId is used for the rows without a vehicle, like the GPX tracks.
+	VehicleId string
+	// Mapping maps the location fields to the CSV columns, like latitude=lat.
+	// The fields not mapped are read from the columns with the same name.
+	Mapping map[string]string
+	// TimestampLayout is the time layout of the CSV timestamps, RFC 3339 by default.
+	// The layouts unix and unixms read the seconds or milliseconds since the epoch.
+	TimestampLayout string
+}
+
+// ParseMapping parses a column mapping in the format field=column,field=column.
+func ParseMapping(value string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	if strings.TrimSpace(value) == "" {
+		return mapping, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		field, column, found := strings.Cut(entry, "=")
+		field, column = strings.TrimSpace(field), strings.TrimSpace(column)
+		if !found || field == "" || column == "" {
+			return nil, fmt.Errorf("invalid column mapping %q, expected <field>=<column>", entry)
+		}
+		if !isField(field) {
+			return nil, fmt.Errorf("invalid column mapping %q, the fields are %v", entry, Fields)
+		}
+		mapping[field] = column
+	}
+
+	return mapping, nil
+}
+
+// NewReader creates a Reader of the format, csv or gpx.
+func NewReader(format string, r io.Reader, opts Options) (Reader, error) {
+	switch format {
+	case "csv":
+		return newCSVReader(r, opts)
+	case "gpx":
+		return newGPXReader(r, opts), nil
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+func isField(name string) bool {
+	for _, field := range Fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTimestamp(value, layout string) (time.Time, error) {
+	switch layout {
+	case "", time.RFC3339:
+		return time.Parse(time.RFC3339, value)
+	case "unix", "unixms":
+		epoch, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid epoch %q", value)
+		}
+		if layout == "unix" {
+			return time.Unix(epoch, 0).UTC(), nil
+		}
+		return time.UnixMilli(epoch).UTC(), nil
+	default:
+		return time.Parse(layout, value)
+	}
+}
+
+var errMissingTimestamp = errors.New("the timestamp is required")
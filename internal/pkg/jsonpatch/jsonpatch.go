@@ -0,0 +1,343 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Media types of the patches.
+const (
+	MergePatchMediaType = "application/merge-patch+json"
+	JSONPatchMediaType  = "application/json-patch+json"
+)
+
+var (
+	ErrInvalidPatch = errors.New("invalid patch")
+	ErrPathNotFound = errors.New("path not found")
+	ErrTestFailed   = errors.New("test failed")
+)
+
+// Patch is a change of a JSON document, decoded as the values of encoding/json: map[string]any, []any,
+// string, float64, bool or nil. The document can be changed in place.
+type Patch interface {
+	Apply(document any) (any, error)
+}
+
+// MergePatch is a JSON merge patch, RFC 7396: the members of the objects replace the ones of the document,
+// recursively, and the null members remove them.
+type MergePatch struct {
+	Value any
+}
+
+// DecodeMergePatch decodes a JSON merge patch.
+func DecodeMergePatch(data []byte) (*MergePatch, error) {
+	patch := new(MergePatch)
+	if err := json.Unmarshal(data, &patch.Value); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return patch, nil
+}
+
+// Apply returns the document merged with the patch.
+func (p *MergePatch) Apply(document any) (any, error) {
+	return merge(document, p.Value), nil
+}
+
+func merge(target, patch any) any {
+	members, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	object, ok := target.(map[string]any)
+	if !ok {
+		object = make(map[string]any)
+	}
+	for name, value := range members {
+		if value == nil {
+			delete(object, name)
+		} else {
+			object[name] = merge(object[name], value)
+		}
+	}
+	return object
+}
+
+// Operation is an operation of a JSON patch: add, remove, replace, move, copy or test.
+// Path and From are JSON pointers, RFC 6901.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Operations is a JSON patch, RFC 6902: a list of operations applied in order,
+// where the failure of any of them fails the whole patch.
+type Operations []Operation
+
+// DecodeOperations decodes a JSON patch, checking the operations and their members.
+func DecodeOperations(data []byte) (Operations, error) {
+	var operations Operations
+	if err := json.Unmarshal(data, &operations); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	for i, operation := range operations {
+		switch operation.Op {
+		case "add", "replace", "test":
+			if operation.Value == nil {
+				return nil, fmt.Errorf("%w: operation %d (%s) has no value", ErrInvalidPatch, i, operation.Op)
+			}
+		case "move", "copy":
+			if _, err := parsePointer(operation.From); err != nil {
+				return nil, fmt.Errorf("%w: operation %d (%s): %v", ErrInvalidPatch, i, operation.Op, err)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("%w: operation %d has the unknown op %q", ErrInvalidPatch, i, operation.Op)
+		}
+		if _, err := parsePointer(operation.Path); err != nil {
+			return nil, fmt.Errorf("%w: operation %d (%s): %v", ErrInvalidPatch, i, operation.Op, err)
+		}
+	}
+	return operations, nil
+}
+
+// Apply returns the document changed by the operations.
+func (o Operations) Apply(document any) (any, error) {
+	for i, operation := range o {
+		var err error
+		if document, err = operation.apply(document); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, operation.Op, operation.Path, err)
+		}
+	}
+	return document, nil
+}
+
+func (o Operation) apply(document any) (any, error) {
+	path, err := parsePointer(o.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch o.Op {
+	case "add":
+		value, err := o.value()
+		if err != nil {
+			return nil, err
+		}
+		return add(document, path, value)
+	case "remove":
+		return remove(document, path)
+	case "replace":
+		value, err := o.value()
+		if err != nil {
+			return nil, err
+		}
+		// The whole document is replaced by the value, it can't be removed.
+		if len(path) == 0 {
+			return value, nil
+		}
+		if document, err = remove(document, path); err != nil {
+			return nil, err
+		}
+		return add(document, path, value)
+	case "move", "copy":
+		from, err := parsePointer(o.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(document, from)
+		if err != nil {
+			return nil, err
+		}
+		if o.Op == "move" {
+			if len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+				return nil, fmt.Errorf("%w: a value can't be moved into itself", ErrInvalidPatch)
+			}
+			if document, err = remove(document, from); err != nil {
+				return nil, err
+			}
+		} else if value, err = clone(value); err != nil {
+			return nil, err
+		}
+		return add(document, path, value)
+	case "test":
+		value, err := o.value()
+		if err != nil {
+			return nil, err
+		}
+		current, err := get(document, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, ErrTestFailed
+		}
+		return document, nil
+	}
+	return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, o.Op)
+}
+
+func (o Operation) value() (any, error) {
+	var value any
+	if err := json.Unmarshal(o.Value, &value); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return value, nil
+}
+
+// parsePointer splits a JSON pointer into its reference tokens, none for the whole document.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: the pointer %q must start with /", ErrInvalidPatch, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// get returns the value of the document at the path.
+func get(document any, path []string) (any, error) {
+	for _, token := range path {
+		switch container := document.(type) {
+		case map[string]any:
+			value, ok := container[token]
+			if !ok {
+				return nil, ErrPathNotFound
+			}
+			document = value
+		case []any:
+			index, err := arrayIndex(token, len(container)-1)
+			if err != nil {
+				return nil, err
+			}
+			document = container[index]
+		default:
+			return nil, ErrPathNotFound
+		}
+	}
+	return document, nil
+}
+
+// add returns the document with the value added at the path, replacing the member of an object
+// and inserting the element of an array, where the - token appends it.
+func add(document any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(document, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	token := path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]any:
+		container[token] = value
+		return document, nil
+	case []any:
+		index := len(container)
+		if token != "-" {
+			if index, err = arrayIndex(token, len(container)); err != nil {
+				return nil, err
+			}
+		}
+		container = append(container[:index], append([]any{value}, container[index:]...)...)
+		return replaceParent(document, path[:len(path)-1], container)
+	}
+	return nil, ErrPathNotFound
+}
+
+// remove returns the document without the value at the path, which must exist.
+func remove(document any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: the whole document can't be removed", ErrInvalidPatch)
+	}
+
+	parent, err := get(document, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	token := path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]any:
+		if _, ok := container[token]; !ok {
+			return nil, ErrPathNotFound
+		}
+		delete(container, token)
+		return document, nil
+	case []any:
+		index, err := arrayIndex(token, len(container)-1)
+		if err != nil {
+			return nil, err
+		}
+		container = append(container[:index:index], container[index+1:]...)
+		return replaceParent(document, path[:len(path)-1], container)
+	}
+	return nil, ErrPathNotFound
+}
+
+// replaceParent sets the array changed by an operation back on its parent, since its length changed.
+func replaceParent(document any, path []string, array []any) (any, error) {
+	if len(path) == 0 {
+		return array, nil
+	}
+
+	parent, err := get(document, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	token := path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]any:
+		container[token] = array
+	case []any:
+		index, err := arrayIndex(token, len(container)-1)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = array
+	}
+	return document, nil
+}
+
+// arrayIndex parses the token of an array element, from 0 to max, without leading zeros.
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, ErrPathNotFound
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > max {
+		return 0, ErrPathNotFound
+	}
+	return index, nil
+}
+
+// clone returns a deep copy of a value, so a copied value is not shared with its source.
+func clone(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var copied any
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
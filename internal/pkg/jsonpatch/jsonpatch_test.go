@@ -0,0 +1,372 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// decode decodes a JSON document of the tests.
+func decode(t *testing.T, data string) any {
+	t.Helper()
+	var value any
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		t.Fatalf("json.Unmarshal(%s) error = %v", data, err)
+	}
+	return value
+}
+
+// TestOperationsRFC6902 applies the examples of the appendix A of RFC 6902.
+func TestOperationsRFC6902(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		patch    string
+		want     string
+		wantErr  error
+	}{
+		{
+			name:     "A.1 adding an object member",
+			document: `{"foo": "bar"}`,
+			patch:    `[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			want:     `{"baz": "qux", "foo": "bar"}`,
+		},
+		{
+			name:     "A.2 adding an array element",
+			document: `{"foo": ["bar", "baz"]}`,
+			patch:    `[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+			want:     `{"foo": ["bar", "qux", "baz"]}`,
+		},
+		{
+			name:     "A.3 removing an object member",
+			document: `{"baz": "qux", "foo": "bar"}`,
+			patch:    `[{"op": "remove", "path": "/baz"}]`,
+			want:     `{"foo": "bar"}`,
+		},
+		{
+			name:     "A.4 removing an array element",
+			document: `{"foo": ["bar", "qux", "baz"]}`,
+			patch:    `[{"op": "remove", "path": "/foo/1"}]`,
+			want:     `{"foo": ["bar", "baz"]}`,
+		},
+		{
+			name:     "A.5 replacing a value",
+			document: `{"baz": "qux", "foo": "bar"}`,
+			patch:    `[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+			want:     `{"baz": "boo", "foo": "bar"}`,
+		},
+		{
+			name:     "A.6 moving a value",
+			document: `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			patch:    `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			want:     `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		},
+		{
+			name:     "A.7 moving an array element",
+			document: `{"foo": ["all", "grass", "cows", "eat"]}`,
+			patch:    `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			want:     `{"foo": ["all", "cows", "eat", "grass"]}`,
+		},
+		{
+			name:     "A.8 testing a value: success",
+			document: `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			patch: `[
+				{"op": "test", "path": "/baz", "value": "qux"},
+				{"op": "test", "path": "/foo/1", "value": 2}
+			]`,
+			want: `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+		},
+		{
+			name:     "A.9 testing a value: error",
+			document: `{"baz": "qux"}`,
+			patch:    `[{"op": "test", "path": "/baz", "value": "bar"}]`,
+			wantErr:  ErrTestFailed,
+		},
+		{
+			name:     "A.10 adding a nested member object",
+			document: `{"foo": "bar"}`,
+			patch:    `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`,
+			want:     `{"foo": "bar", "child": {"grandchild": {}}}`,
+		},
+		{
+			name:     "A.11 ignoring unrecognized elements",
+			document: `{"foo": "bar"}`,
+			patch:    `[{"op": "add", "path": "/baz", "value": "qux", "xyz": 123}]`,
+			want:     `{"foo": "bar", "baz": "qux"}`,
+		},
+		{
+			name:     "A.12 adding to a nonexistent target",
+			document: `{"foo": "bar"}`,
+			patch:    `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+			wantErr:  ErrPathNotFound,
+		},
+		{
+			name:     "A.14 ~ escape ordering",
+			document: `{"/": 9, "~1": 10}`,
+			patch:    `[{"op": "test", "path": "/~01", "value": 10}]`,
+			want:     `{"/": 9, "~1": 10}`,
+		},
+		{
+			name:     "A.15 comparing strings and numbers",
+			document: `{"/": 9, "~1": 10}`,
+			patch:    `[{"op": "test", "path": "/~01", "value": "10"}]`,
+			wantErr:  ErrTestFailed,
+		},
+		{
+			name:     "A.16 adding an array value",
+			document: `{"foo": ["bar"]}`,
+			patch:    `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+			want:     `{"foo": ["bar", ["abc", "def"]]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operations, err := DecodeOperations([]byte(tt.patch))
+			if err != nil {
+				t.Fatalf("DecodeOperations() error = %v", err)
+			}
+
+			got, err := operations.Apply(decode(t, tt.document))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Apply() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if want := decode(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("Apply() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestOperations(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		patch    string
+		want     string
+		wantErr  error
+	}{
+		{
+			name:     "add a null value",
+			document: `{"foo": "bar"}`,
+			patch:    `[{"op": "add", "path": "/baz", "value": null}]`,
+			want:     `{"foo": "bar", "baz": null}`,
+		},
+		{
+			name:     "add at the end of an array by its index",
+			document: `{"foo": ["bar"]}`,
+			patch:    `[{"op": "add", "path": "/foo/1", "value": "baz"}]`,
+			want:     `{"foo": ["bar", "baz"]}`,
+		},
+		{
+			name:     "add past the end of an array",
+			document: `{"foo": ["bar"]}`,
+			patch:    `[{"op": "add", "path": "/foo/2", "value": "baz"}]`,
+			wantErr:  ErrPathNotFound,
+		},
+		{
+			name:     "add in a nested array",
+			document: `{"foo": [["a", "c"]]}`,
+			patch:    `[{"op": "add", "path": "/foo/0/1", "value": "b"}]`,
+			want:     `{"foo": [["a", "b", "c"]]}`,
+		},
+		{
+			name:     "replace the whole document",
+			document: `{"foo": "bar"}`,
+			patch:    `[{"op": "replace", "path": "", "value": {"baz": "qux"}}]`,
+			want:     `{"baz": "qux"}`,
+		},
+		{
+			name:     "replace a missing member",
+			document: `{"foo": "bar"}`,
+			patch:    `[{"op": "replace", "path": "/baz", "value": "qux"}]`,
+			wantErr:  ErrPathNotFound,
+		},
+		{
+			name:     "remove the whole document",
+			document: `{"foo": "bar"}`,
+			patch:    `[{"op": "remove", "path": ""}]`,
+			wantErr:  ErrInvalidPatch,
+		},
+		{
+			name:     "remove an array element with a leading zero",
+			document: `{"foo": ["bar", "baz"]}`,
+			patch:    `[{"op": "remove", "path": "/foo/01"}]`,
+			wantErr:  ErrPathNotFound,
+		},
+		{
+			name:     "copy a value that is not shared",
+			document: `{"foo": {"bar": 1}}`,
+			patch: `[
+				{"op": "copy", "from": "/foo", "path": "/baz"},
+				{"op": "replace", "path": "/baz/bar", "value": 2}
+			]`,
+			want: `{"foo": {"bar": 1}, "baz": {"bar": 2}}`,
+		},
+		{
+			name:     "move a value into itself",
+			document: `{"foo": {"bar": 1}}`,
+			patch:    `[{"op": "move", "from": "/foo", "path": "/foo/bar/baz"}]`,
+			wantErr:  ErrInvalidPatch,
+		},
+		{
+			name:     "test a missing member",
+			document: `{"foo": "bar"}`,
+			patch:    `[{"op": "test", "path": "/baz", "value": "bar"}]`,
+			wantErr:  ErrPathNotFound,
+		},
+		{
+			name:     "failing operation after a successful one",
+			document: `{"foo": "bar"}`,
+			patch: `[
+				{"op": "add", "path": "/baz", "value": "qux"},
+				{"op": "test", "path": "/foo", "value": "baz"}
+			]`,
+			wantErr: ErrTestFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operations, err := DecodeOperations([]byte(tt.patch))
+			if err != nil {
+				t.Fatalf("DecodeOperations() error = %v", err)
+			}
+
+			got, err := operations.Apply(decode(t, tt.document))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Apply() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if want := decode(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("Apply() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeOperationsInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+	}{
+		{name: "not an array", patch: `{"op": "add", "path": "/baz", "value": "qux"}`},
+		{name: "invalid json", patch: `[{"op": "add"`},
+		{name: "unknown op", patch: `[{"op": "merge", "path": "/baz"}]`},
+		{name: "missing op", patch: `[{"path": "/baz"}]`},
+		{name: "add without a value", patch: `[{"op": "add", "path": "/baz"}]`},
+		{name: "replace without a value", patch: `[{"op": "replace", "path": "/baz"}]`},
+		{name: "test without a value", patch: `[{"op": "test", "path": "/baz"}]`},
+		{name: "path without a leading slash", patch: `[{"op": "remove", "path": "baz"}]`},
+		{name: "move with an invalid from", patch: `[{"op": "move", "from": "baz", "path": "/qux"}]`},
+		{name: "copy with an invalid from", patch: `[{"op": "copy", "from": "baz", "path": "/qux"}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeOperations([]byte(tt.patch)); !errors.Is(err, ErrInvalidPatch) {
+				t.Errorf("DecodeOperations() error = %v, want %v", err, ErrInvalidPatch)
+			}
+		})
+	}
+}
+
+func TestDuplicateOpRFC6902(t *testing.T) {
+	// A.13: an operation with a duplicated op member is invalid. It is decoded with the last one,
+	// remove, which fails on the missing member, so it is never applied as an add.
+	operations, err := DecodeOperations([]byte(`[{"op": "add", "path": "/baz", "value": "qux", "op": "remove"}]`))
+	if err != nil {
+		return
+	}
+	if _, err := operations.Apply(decode(t, `{"foo": "bar"}`)); err == nil {
+		t.Errorf("Apply() error = nil, want an error")
+	}
+}
+
+func TestParsePointer(t *testing.T) {
+	tests := []struct {
+		pointer string
+		want    []string
+		wantErr bool
+	}{
+		{pointer: "", want: nil},
+		{pointer: "/", want: []string{""}},
+		{pointer: "/foo", want: []string{"foo"}},
+		{pointer: "/foo/0", want: []string{"foo", "0"}},
+		{pointer: "/a~1b", want: []string{"a/b"}},
+		{pointer: "/m~0n", want: []string{"m~n"}},
+		{pointer: "/~01", want: []string{"~1"}},
+		{pointer: "foo", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pointer, func(t *testing.T) {
+			got, err := parsePointer(tt.pointer)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePointer() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePointer() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMergePatchRFC7396 applies the examples of the appendix A of RFC 7396, and the one of its section 3.
+func TestMergePatchRFC7396(t *testing.T) {
+	tests := []struct {
+		original string
+		patch    string
+		want     string
+	}{
+		{original: `{"a": "b"}`, patch: `{"a": "c"}`, want: `{"a": "c"}`},
+		{original: `{"a": "b"}`, patch: `{"b": "c"}`, want: `{"a": "b", "b": "c"}`},
+		{original: `{"a": "b"}`, patch: `{"a": null}`, want: `{}`},
+		{original: `{"a": "b", "b": "c"}`, patch: `{"a": null}`, want: `{"b": "c"}`},
+		{original: `{"a": ["b"]}`, patch: `{"a": "c"}`, want: `{"a": "c"}`},
+		{original: `{"a": "c"}`, patch: `{"a": ["b"]}`, want: `{"a": ["b"]}`},
+		{original: `{"a": {"b": "c"}}`, patch: `{"a": {"b": "d", "c": null}}`, want: `{"a": {"b": "d"}}`},
+		{original: `{"a": [{"b": "c"}]}`, patch: `{"a": [1]}`, want: `{"a": [1]}`},
+		{original: `["a", "b"]`, patch: `["c", "d"]`, want: `["c", "d"]`},
+		{original: `{"a": "b"}`, patch: `["c"]`, want: `["c"]`},
+		{original: `{"a": "foo"}`, patch: `null`, want: `null`},
+		{original: `{"a": "foo"}`, patch: `"bar"`, want: `"bar"`},
+		{original: `{"e": null}`, patch: `{"a": 1}`, want: `{"e": null, "a": 1}`},
+		{original: `[1, 2]`, patch: `{"a": "b", "c": null}`, want: `{"a": "b"}`},
+		{original: `{}`, patch: `{"a": {"bb": {"ccc": null}}}`, want: `{"a": {"bb": {}}}`},
+		{
+			original: `{"title": "Goodbye!", "author": {"givenName": "John", "familyName": "Doe"}, "tags": ["example", "sample"], "content": "This will be unchanged"}`,
+			patch:    `{"title": "Hello!", "phoneNumber": "+01-123-456-7890", "author": {"familyName": null}, "tags": ["example"]}`,
+			want:     `{"title": "Hello!", "author": {"givenName": "John"}, "tags": ["example"], "content": "This will be unchanged", "phoneNumber": "+01-123-456-7890"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.original+" "+tt.patch, func(t *testing.T) {
+			patch, err := DecodeMergePatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatalf("DecodeMergePatch() error = %v", err)
+			}
+
+			got, err := patch.Apply(decode(t, tt.original))
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if want := decode(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("Apply() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeMergePatchInvalid(t *testing.T) {
+	if _, err := DecodeMergePatch([]byte(`{"a":`)); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("DecodeMergePatch() error = %v, want %v", err, ErrInvalidPatch)
+	}
+}
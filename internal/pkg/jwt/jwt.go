@@ -0,0 +1,297 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minRefreshInterval is the minimum time between two fetches of the keys caused by unknown key IDs,
+// so tokens with random key IDs can't flood the identity provider.
+const minRefreshInterval = time.Minute
+
+// ErrInvalidToken is returned, wrapped with the reason, for the tokens that can't be trusted.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Config is the configuration of a Verifier.
+type Config struct {
+	// JWKS is the path or the http(s) URL of the JWKS document with the public keys.
+	JWKS string
+	// Issuer and Audience must match the iss and aud claims, they are not checked when empty.
+	Issuer   string
+	Audience string
+	// Refresh is how long the keys are cached before being fetched again.
+	Refresh time.Duration
+	// Leeway is the clock skew tolerated on the exp and nbf claims.
+	Leeway time.Duration
+}
+
+// Verifier verifies the RS256 and ES256 JSON Web Tokens signed by the keys of a JWKS document.
+// The keys are cached and fetched again when the cache expires or a token has an unknown key ID,
+// like after a rotation of the keys by the identity provider. The keys are fetched without holding
+// the lock, by a single request at a time, so the tokens with known keys are verified meanwhile.
+type Verifier struct {
+	cfg       Config
+	mu        sync.Mutex
+	keys      *KeySet
+	fetchedAt time.Time
+	// refreshing is closed when the fetch in progress ends, it is nil without a fetch.
+	refreshing chan struct{}
+}
+
+// NewVerifier creates a verifier, loading the keys.
+func NewVerifier(cfg Config) (*Verifier, error) {
+	keys, err := loadKeySet(cfg.JWKS)
+	if err != nil {
+		return nil, fmt.Errorf("error loading jwks: %w", err)
+	}
+	return &Verifier{cfg: cfg, keys: keys, fetchedAt: time.Now()}, nil
+}
+
+// Len returns the number of cached keys.
+func (v *Verifier) Len() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.keys.Len()
+}
+
+// Claims are the claims of a verified token.
+type Claims map[string]any
+
+// Subject returns the sub claim.
+func (c Claims) Subject() string {
+	subject, _ := c["sub"].(string)
+	return subject
+}
+
+// Strings returns the values of a claim that is a string, a space separated string like the scope claim,
+// or an array of strings. A dot separated name reads a nested claim, like realm_access.roles.
+func (c Claims) Strings(name string) []string {
+	var value any = map[string]any(c)
+	for _, part := range strings.Split(name, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[part]
+	}
+
+	switch value := value.(type) {
+	case string:
+		return strings.Fields(value)
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// time returns a NumericDate claim.
+func (c Claims) time(name string) (time.Time, bool) {
+	value, ok := c[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(value), 0), true
+}
+
+// audience returns the aud claim, which is a string or an array of strings.
+func (c Claims) audience() []string {
+	if audience, ok := c["aud"].(string); ok {
+		return []string{audience}
+	}
+	return c.Strings("aud")
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify checks the signature of a compact serialized token and its exp, nbf, iss and aud claims,
+// returning its claims. The exp claim is required. The errors wrap ErrInvalidToken.
+func (v *Verifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+
+	// The algorithm is checked before looking for the key, so tokens like the alg none ones don't fetch the keys.
+	if h.Alg != "RS256" && h.Alg != "ES256" {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, h.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+
+	key, err := v.key(h.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := verifySignature(h.Alg, key, digest[:], signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+
+	if err := v.checkClaims(claims, time.Now()); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// key returns the key of the key ID, fetching the keys again when they expired or don't have it.
+// The expired keys are still used by the other tokens while they are fetched, the tokens with
+// an unknown key ID wait for the fetch in progress. The fetches caused by unknown key IDs are
+// limited to one per minRefreshInterval.
+func (v *Verifier) key(kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	key, ok := v.keys.key(kid)
+	since := time.Since(v.fetchedAt)
+	stale := (ok && since > v.cfg.Refresh) || (!ok && since > minRefreshInterval)
+
+	fetch := stale && v.refreshing == nil
+	if fetch {
+		v.refreshing = make(chan struct{})
+		v.fetchedAt = time.Now()
+	}
+	done := v.refreshing
+	v.mu.Unlock()
+
+	switch {
+	case fetch:
+		v.refresh(done)
+	case !ok && done != nil:
+		<-done
+	default:
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+		}
+		return key, nil
+	}
+
+	v.mu.Lock()
+	key, ok = v.keys.key(kid)
+	v.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+	}
+	return key, nil
+}
+
+// refresh fetches the keys, keeping the cached ones when it fails, and closes done when it ends.
+func (v *Verifier) refresh(done chan struct{}) {
+	keys, err := loadKeySet(v.cfg.JWKS)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if err != nil {
+		slog.Error("error refreshing jwks", "error", err.Error())
+	} else {
+		v.keys = keys
+	}
+	v.refreshing = nil
+	close(done)
+}
+
+func verifySignature(alg string, key crypto.PublicKey, digest, signature []byte) error {
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: the key doesn't match the %s algorithm", ErrInvalidToken, alg)
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest, signature); err != nil {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+	case "ES256":
+		ecdsaKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: the key doesn't match the %s algorithm", ErrInvalidToken, alg)
+		}
+		// The JWS signature is the concatenation of r and s, not the ASN.1 encoding.
+		if len(signature) != 64 {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecdsaKey, digest, r, s) {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, alg)
+	}
+	return nil
+}
+
+func (v *Verifier) checkClaims(claims Claims, now time.Time) error {
+	expiresAt, ok := claims.time("exp")
+	if !ok {
+		return fmt.Errorf("%w: missing exp claim", ErrInvalidToken)
+	}
+	if !now.Before(expiresAt.Add(v.cfg.Leeway)) {
+		return fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+
+	if notBefore, ok := claims.time("nbf"); ok && now.Add(v.cfg.Leeway).Before(notBefore) {
+		return fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	}
+
+	if v.cfg.Issuer != "" {
+		if issuer, _ := claims["iss"].(string); issuer != v.cfg.Issuer {
+			return fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+		}
+	}
+
+	if v.cfg.Audience != "" && !contains(claims.audience(), v.cfg.Audience) {
+		return fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,455 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://id.example.com"
+	testAudience = "goapi"
+)
+
+var (
+	testKeysOnce sync.Once
+	testRSAKey   *rsa.PrivateKey
+	testECKey    *ecdsa.PrivateKey
+)
+
+// testKeys generates the keys of the tests once, the RSA ones being slow to generate.
+func testKeys(t *testing.T) (*rsa.PrivateKey, *ecdsa.PrivateKey) {
+	t.Helper()
+	testKeysOnce.Do(func() {
+		var err error
+		if testRSAKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			t.Fatalf("rsa.GenerateKey() error = %v", err)
+		}
+		if testECKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+			t.Fatalf("ecdsa.GenerateKey() error = %v", err)
+		}
+	})
+	return testRSAKey, testECKey
+}
+
+func encodeInt(n *big.Int, size int) string {
+	return base64.RawURLEncoding.EncodeToString(n.FillBytes(make([]byte, size)))
+}
+
+// jwksDocument returns a JWKS document with the public keys by their key ID.
+func jwksDocument(t *testing.T, keys map[string]crypto.PublicKey) []byte {
+	t.Helper()
+
+	document := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	for kid, key := range keys {
+		switch key := key.(type) {
+		case *rsa.PublicKey:
+			document.Keys = append(document.Keys, jwk{
+				Kty: "RSA", Kid: kid, Use: "sig", Alg: "RS256",
+				N: encodeInt(key.N, key.Size()), E: encodeInt(big.NewInt(int64(key.E)), 3),
+			})
+		case *ecdsa.PublicKey:
+			document.Keys = append(document.Keys, jwk{
+				Kty: "EC", Kid: kid, Use: "sig", Alg: "ES256", Crv: "P-256",
+				X: encodeInt(key.X, 32), Y: encodeInt(key.Y, 32),
+			})
+		}
+	}
+
+	data, err := json.Marshal(document)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	return data
+}
+
+// writeJWKS writes a JWKS document to a file, returning its path.
+func writeJWKS(t *testing.T, keys map[string]crypto.PublicKey) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksDocument(t, keys), 0o600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	return path
+}
+
+// sign returns a compact serialized token of the claims, signed with the key by the algorithm of the header.
+// The key is a private RSA or EC key, a []byte secret for HS256, or nil for the none algorithm.
+func sign(t *testing.T, alg, kid string, key any, claims map[string]any) string {
+	t.Helper()
+
+	segment := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("json.Marshal() error = %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	signingInput := segment(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + segment(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			t.Fatalf("rsa.SignPKCS1v15() error = %v", err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatalf("ecdsa.Sign() error = %v", err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims() map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"sub": "user-1",
+		"iss": testIssuer,
+		"aud": []string{"other", testAudience},
+		"exp": now.Add(time.Hour).Unix(),
+		"nbf": now.Add(-time.Minute).Unix(),
+	}
+}
+
+func newTestVerifier(t *testing.T) *Verifier {
+	t.Helper()
+	rsaKey, ecKey := testKeys(t)
+
+	verifier, err := NewVerifier(Config{
+		JWKS:     writeJWKS(t, map[string]crypto.PublicKey{"rsa-1": &rsaKey.PublicKey, "ec-1": &ecKey.PublicKey}),
+		Issuer:   testIssuer,
+		Audience: testAudience,
+		Refresh:  time.Hour,
+		Leeway:   30 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+	return verifier
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, ecKey := testKeys(t)
+	verifier := newTestVerifier(t)
+
+	with := func(name string, value any) map[string]any {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+	rsaPublicKey, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("x509.MarshalPKIXPublicKey() error = %v", err)
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "RS256", token: sign(t, "RS256", "rsa-1", rsaKey, validClaims())},
+		{name: "ES256", token: sign(t, "ES256", "ec-1", ecKey, validClaims())},
+		{name: "single audience", token: sign(t, "RS256", "rsa-1", rsaKey, with("aud", testAudience))},
+		{name: "expired within the leeway", token: sign(t, "RS256", "rsa-1", rsaKey, with("exp", now.Add(-10*time.Second).Unix()))},
+		{name: "expired", token: sign(t, "RS256", "rsa-1", rsaKey, with("exp", now.Add(-time.Minute).Unix())), wantErr: true},
+		{name: "missing exp", token: sign(t, "RS256", "rsa-1", rsaKey, with("exp", nil)), wantErr: true},
+		{name: "not valid yet", token: sign(t, "ES256", "ec-1", ecKey, with("nbf", now.Add(time.Minute).Unix())), wantErr: true},
+		{name: "wrong audience", token: sign(t, "RS256", "rsa-1", rsaKey, with("aud", "other")), wantErr: true},
+		{name: "missing audience", token: sign(t, "RS256", "rsa-1", rsaKey, with("aud", nil)), wantErr: true},
+		{name: "wrong issuer", token: sign(t, "ES256", "ec-1", ecKey, with("iss", "https://evil.example.com")), wantErr: true},
+		{name: "alg none", token: sign(t, "none", "rsa-1", nil, validClaims()), wantErr: true},
+		{name: "alg none without key ID", token: sign(t, "none", "", nil, validClaims()), wantErr: true},
+		{name: "HS256 with the RSA public key as secret", token: sign(t, "HS256", "rsa-1", rsaPublicKey, validClaims()), wantErr: true},
+		{name: "RS256 header on an EC key", token: sign(t, "RS256", "ec-1", rsaKey, validClaims()), wantErr: true},
+		{name: "ES256 signed by another key", token: sign(t, "ES256", "ec-1", mustECKey(t), validClaims()), wantErr: true},
+		{name: "unknown key", token: sign(t, "RS256", "rsa-2", rsaKey, validClaims()), wantErr: true},
+		{name: "no key ID with several keys", token: sign(t, "RS256", "", rsaKey, validClaims()), wantErr: true},
+		{name: "malformed", token: "not-a-token", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Errorf("Verify() error = %v, want %v", err, ErrInvalidToken)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if claims.Subject() != "user-1" {
+				t.Errorf("Subject() = %q, want user-1", claims.Subject())
+			}
+		})
+	}
+}
+
+func mustECKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey() error = %v", err)
+	}
+	return key
+}
+
+func TestVerifyWithoutKeyID(t *testing.T) {
+	_, ecKey := testKeys(t)
+	verifier, err := NewVerifier(Config{JWKS: writeJWKS(t, map[string]crypto.PublicKey{"ec-1": &ecKey.PublicKey}), Refresh: time.Hour})
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+
+	// A set with a single key verifies the tokens without a key ID.
+	if _, err := verifier.Verify(sign(t, "ES256", "", ecKey, validClaims())); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
+
+func TestClaimsStrings(t *testing.T) {
+	claims := Claims{
+		"scope":        "read write",
+		"roles":        []any{"admin", 1, "read"},
+		"realm_access": map[string]any{"roles": []any{"fleet-manager"}},
+		"number":       1.0,
+	}
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "scope", want: []string{"read", "write"}},
+		{name: "roles", want: []string{"admin", "read"}},
+		{name: "realm_access.roles", want: []string{"fleet-manager"}},
+		{name: "number"},
+		{name: "missing"},
+		{name: "scope.nested"},
+	}
+
+	for _, tt := range tests {
+		got := claims.Strings(tt.name)
+		if len(got) != len(tt.want) {
+			t.Errorf("Strings(%q) = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Strings(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	}
+}
+
+// jwksServer serves a JWKS document, counting the fetches and holding them until release is closed.
+type jwksServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	document []byte
+	fetches  atomic.Int32
+	release  chan struct{}
+}
+
+func newJWKSServer(t *testing.T, document []byte) *jwksServer {
+	t.Helper()
+	s := &jwksServer{document: document}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		s.mu.Lock()
+		release, document := s.release, s.document
+		s.mu.Unlock()
+		if release != nil {
+			<-release
+		}
+		_, _ = w.Write(document)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) set(document []byte, release chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.document, s.release = document, release
+}
+
+func TestVerifierRefreshOnUnknownKey(t *testing.T) {
+	rsaKey, ecKey := testKeys(t)
+	server := newJWKSServer(t, jwksDocument(t, map[string]crypto.PublicKey{"rsa-1": &rsaKey.PublicKey}))
+
+	verifier, err := NewVerifier(Config{JWKS: server.URL, Refresh: time.Hour})
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+
+	// The key is rotated by the identity provider.
+	server.set(jwksDocument(t, map[string]crypto.PublicKey{"rsa-1": &rsaKey.PublicKey, "ec-1": &ecKey.PublicKey}), nil)
+	token := sign(t, "ES256", "ec-1", ecKey, validClaims())
+
+	// The unknown keys don't fetch the keys again before minRefreshInterval.
+	if _, err := verifier.Verify(token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Verify() error = %v, want %v", err, ErrInvalidToken)
+	}
+	if got := server.fetches.Load(); got != 1 {
+		t.Fatalf("fetches = %d, want 1", got)
+	}
+
+	verifier.fetchedAt = time.Now().Add(-2 * minRefreshInterval)
+	if _, err := verifier.Verify(token); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if got := server.fetches.Load(); got != 2 {
+		t.Errorf("fetches = %d, want 2", got)
+	}
+
+	// Random key IDs don't fetch the keys again once they were just fetched.
+	for i := 0; i < 10; i++ {
+		_, _ = verifier.Verify(sign(t, "ES256", "random-"+string(rune('a'+i)), ecKey, validClaims()))
+	}
+	if got := server.fetches.Load(); got != 2 {
+		t.Errorf("fetches = %d, want 2", got)
+	}
+}
+
+func TestVerifierRefreshWithoutLock(t *testing.T) {
+	rsaKey, ecKey := testKeys(t)
+	server := newJWKSServer(t, jwksDocument(t, map[string]crypto.PublicKey{"rsa-1": &rsaKey.PublicKey}))
+
+	verifier, err := NewVerifier(Config{JWKS: server.URL, Refresh: time.Hour})
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+
+	// The next fetch hangs until released, with the rotated keys.
+	release := make(chan struct{})
+	server.set(jwksDocument(t, map[string]crypto.PublicKey{"rsa-1": &rsaKey.PublicKey, "ec-1": &ecKey.PublicKey}), release)
+	verifier.fetchedAt = time.Now().Add(-2 * time.Hour)
+
+	unknown := sign(t, "ES256", "ec-1", ecKey, validClaims())
+	results := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		go func() {
+			_, err := verifier.Verify(unknown)
+			results <- err
+		}()
+	}
+
+	// The tokens of the cached keys are verified while the keys are fetched.
+	deadline := time.Now().Add(5 * time.Second)
+	for server.fetches.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	known := make(chan error, 1)
+	go func() {
+		_, err := verifier.Verify(sign(t, "RS256", "rsa-1", rsaKey, validClaims()))
+		known <- err
+	}()
+	select {
+	case err := <-known:
+		if err != nil {
+			t.Errorf("Verify() of a cached key error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Verify() of a cached key blocked by the fetch of the keys")
+	}
+
+	close(release)
+	for i := 0; i < 5; i++ {
+		if err := <-results; err != nil {
+			t.Errorf("Verify() of the rotated key error = %v", err)
+		}
+	}
+	if got := server.fetches.Load(); got != 2 {
+		t.Errorf("fetches = %d, want 2, the concurrent fetches must be deduplicated", got)
+	}
+}
+
+func TestVerifierKeepsKeysWhenRefreshFails(t *testing.T) {
+	rsaKey, _ := testKeys(t)
+	server := newJWKSServer(t, jwksDocument(t, map[string]crypto.PublicKey{"rsa-1": &rsaKey.PublicKey}))
+
+	verifier, err := NewVerifier(Config{JWKS: server.URL, Refresh: time.Hour})
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+
+	server.set([]byte("not a jwks"), nil)
+	verifier.fetchedAt = time.Now().Add(-2 * time.Hour)
+
+	if _, err := verifier.Verify(sign(t, "RS256", "rsa-1", rsaKey, validClaims())); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if verifier.Len() != 1 {
+		t.Errorf("Len() = %d, want the cached key", verifier.Len())
+	}
+}
+
+func TestParseKeySet(t *testing.T) {
+	smallRSA, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		document string
+		wantLen  int
+		wantErr  bool
+	}{
+		{name: "keys", document: string(jwksDocument(t, map[string]crypto.PublicKey{"ec-1": &mustECKey(t).PublicKey})), wantLen: 1},
+		{name: "small rsa key", document: string(jwksDocument(t, map[string]crypto.PublicKey{"rsa-1": &smallRSA.PublicKey})), wantErr: true},
+		{name: "encryption keys only", document: `{"keys":[{"kty":"RSA","use":"enc","kid":"x"}]}`, wantErr: true},
+		{name: "unsupported key types only", document: `{"keys":[{"kty":"oct","kid":"x","k":"c2VjcmV0"}]}`, wantErr: true},
+		{name: "point not on the curve", document: `{"keys":[{"kty":"EC","crv":"P-256","kid":"x","x":"AQ","y":"AQ"}]}`, wantErr: true},
+		{name: "unsupported curve", document: `{"keys":[{"kty":"EC","crv":"P-384","kid":"x","x":"AQ","y":"AQ"}]}`, wantErr: true},
+		{name: "invalid json", document: `{"keys":`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := ParseKeySet([]byte(tt.document))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKeySet() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && set.Len() != tt.wantLen {
+				t.Errorf("Len() = %d, want %d", set.Len(), tt.wantLen)
+			}
+		})
+	}
+}
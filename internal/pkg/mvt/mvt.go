@@ -0,0 +1,196 @@
+package mvt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Extent is the size of the tiles in their own coordinates, the default of the specification.
+const Extent = 4096
+
+// ContentType is the media type of the encoded tiles.
+const ContentType = "application/vnd.mapbox-vector-tile"
+
+// MaxZoom is the deepest zoom level accepted.
+const MaxZoom = 22
+
+// Tile is a tile of the web mercator grid (the XYZ scheme of the slippy maps).
+type Tile struct {
+	Z int
+	X int
+	Y int
+}
+
+// Validate checks if the tile exists at its zoom level.
+func (t Tile) Validate() error {
+	if t.Z < 0 || t.Z > MaxZoom {
+		return fmt.Errorf("the zoom must be between 0 and %d", MaxZoom)
+	}
+	if n := 1 << t.Z; t.X < 0 || t.X >= n || t.Y < 0 || t.Y >= n {
+		return fmt.Errorf("the tile %d/%d/%d doesn't exist, x and y must be between 0 and %d", t.Z, t.X, t.Y, n-1)
+	}
+	return nil
+}
+
+// Bounds returns the area covered by the tile, in decimal degrees.
+func (t Tile) Bounds() (minLatitude, minLongitude, maxLatitude, maxLongitude float64) {
+	n := float64(int(1) << t.Z)
+	tileLatitude := func(y int) float64 {
+		return math.Atan(math.Sinh(math.Pi*(1-2*float64(y)/n))) * 180 / math.Pi
+	}
+
+	minLongitude = float64(t.X)/n*360 - 180
+	maxLongitude = float64(t.X+1)/n*360 - 180
+	return tileLatitude(t.Y + 1), minLongitude, tileLatitude(t.Y), maxLongitude
+}
+
+// Project returns the position of the coordinates in the tile, from 0 to Extent on both axes
+// when they are inside it, with the origin at the top left corner.
+func (t Tile) Project(latitude, longitude float64) (x, y int) {
+	n := float64(int(1) << t.Z)
+	latitude = math.Max(-85.05112878, math.Min(85.05112878, latitude))
+	sinLatitude := math.Sin(latitude * math.Pi / 180)
+
+	// The latitudes past the limit of the projection are kept on the first and last rows of tiles.
+	worldX := (longitude + 180) / 360 * n
+	worldY := (0.5 - math.Log((1+sinLatitude)/(1-sinLatitude))/(4*math.Pi)) * n
+	worldY = math.Max(0, math.Min(n-1e-9, worldY))
+	return int(math.Floor((worldX - float64(t.X)) * Extent)), int(math.Floor((worldY - float64(t.Y)) * Extent))
+}
+
+// Layer is a layer of point features of a tile. The keys and values of the properties
+// are shared by the features, as required by the specification.
+type Layer struct {
+	name     string
+	features [][]byte
+	keys     []string
+	keyIndex map[string]int
+	values   [][]byte
+	valIndex map[string]int
+}
+
+// NewLayer creates a new empty Layer.
+func NewLayer(name string) *Layer {
+	return &Layer{name: name, keyIndex: make(map[string]int), valIndex: make(map[string]int)}
+}
+
+// AddPoint adds a point feature at the position in the tile. The property values can be
+// strings, booleans, integers or floats, the others are encoded as strings.
+func (l *Layer) AddPoint(x, y int, properties map[string]any) {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tags := make([]uint64, 0, 2*len(names))
+	for _, name := range names {
+		tags = append(tags, uint64(l.key(name)), uint64(l.value(properties[name])))
+	}
+
+	// A single MoveTo command with the zigzag encoded position.
+	geometry := []uint64{1&0x7 | 1<<3, zigzag(int64(x)), zigzag(int64(y))}
+
+	var feature []byte
+	feature = appendPacked(feature, 2, tags)
+	feature = appendVarintField(feature, 3, 1)
+	feature = appendPacked(feature, 4, geometry)
+	l.features = append(l.features, feature)
+}
+
+// Len returns the number of features of the layer.
+func (l *Layer) Len() int {
+	return len(l.features)
+}
+
+func (l *Layer) key(name string) int {
+	if i, ok := l.keyIndex[name]; ok {
+		return i
+	}
+	l.keys = append(l.keys, name)
+	l.keyIndex[name] = len(l.keys) - 1
+	return len(l.keys) - 1
+}
+
+func (l *Layer) value(value any) int {
+	encoded := encodeValue(value)
+	if i, ok := l.valIndex[string(encoded)]; ok {
+		return i
+	}
+	l.values = append(l.values, encoded)
+	l.valIndex[string(encoded)] = len(l.values) - 1
+	return len(l.values) - 1
+}
+
+func (l *Layer) encode() []byte {
+	var layer []byte
+	layer = appendVarintField(layer, 15, 2)
+	layer = appendBytesField(layer, 1, []byte(l.name))
+	for _, feature := range l.features {
+		layer = appendBytesField(layer, 2, feature)
+	}
+	for _, key := range l.keys {
+		layer = appendBytesField(layer, 3, []byte(key))
+	}
+	for _, value := range l.values {
+		layer = appendBytesField(layer, 4, value)
+	}
+	return appendVarintField(layer, 5, Extent)
+}
+
+// Encode encodes the layers as a vector tile, following the version 2.1 of the Mapbox Vector Tile specification.
+func Encode(layers ...*Layer) []byte {
+	var tile []byte
+	for _, layer := range layers {
+		tile = appendBytesField(tile, 3, layer.encode())
+	}
+	return tile
+}
+
+func encodeValue(value any) []byte {
+	switch v := value.(type) {
+	case string:
+		return appendBytesField(nil, 1, []byte(v))
+	case float64:
+		return binary.LittleEndian.AppendUint64(appendTag(nil, 3, 1), math.Float64bits(v))
+	case int:
+		return appendVarintField(nil, 6, zigzag(int64(v)))
+	case int64:
+		return appendVarintField(nil, 6, zigzag(v))
+	case bool:
+		if v {
+			return appendVarintField(nil, 7, 1)
+		}
+		return appendVarintField(nil, 7, 0)
+	default:
+		return appendBytesField(nil, 1, []byte(fmt.Sprint(v)))
+	}
+}
+
+func zigzag(n int64) uint64 {
+	return uint64((n << 1) ^ (n >> 63))
+}
+
+// appendTag appends the key of a protobuf field with the wire type.
+func appendTag(b []byte, field int, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wireType))
+}
+
+func appendVarintField(b []byte, field int, value uint64) []byte {
+	return binary.AppendUvarint(appendTag(b, field, 0), value)
+}
+
+func appendBytesField(b []byte, field int, value []byte) []byte {
+	b = binary.AppendUvarint(appendTag(b, field, 2), uint64(len(value)))
+	return append(b, value...)
+}
+
+func appendPacked(b []byte, field int, values []uint64) []byte {
+	var packed []byte
+	for _, value := range values {
+		packed = binary.AppendUvarint(packed, value)
+	}
+	return appendBytesField(b, field, packed)
+}
@@ -0,0 +1,151 @@
+package mvt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestTileValidate(t *testing.T) {
+	tests := []struct {
+		tile    Tile
+		wantErr bool
+	}{
+		{tile: Tile{Z: 0, X: 0, Y: 0}},
+		{tile: Tile{Z: 2, X: 3, Y: 3}},
+		{tile: Tile{Z: MaxZoom, X: 1<<MaxZoom - 1, Y: 0}},
+		{tile: Tile{Z: 0, X: 1, Y: 0}, wantErr: true},
+		{tile: Tile{Z: 2, X: 0, Y: 4}, wantErr: true},
+		{tile: Tile{Z: 2, X: -1, Y: 0}, wantErr: true},
+		{tile: Tile{Z: -1}, wantErr: true},
+		{tile: Tile{Z: MaxZoom + 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.tile.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, want error %v", tt.tile, err, tt.wantErr)
+		}
+	}
+}
+
+func TestTileBounds(t *testing.T) {
+	const maxLatitude = 85.0511287798
+
+	tests := []struct {
+		tile Tile
+		want [4]float64
+	}{
+		{tile: Tile{Z: 0, X: 0, Y: 0}, want: [4]float64{-maxLatitude, -180, maxLatitude, 180}},
+		{tile: Tile{Z: 1, X: 1, Y: 0}, want: [4]float64{0, 0, maxLatitude, 180}},
+		{tile: Tile{Z: 1, X: 0, Y: 1}, want: [4]float64{-maxLatitude, -180, 0, 0}},
+		{tile: Tile{Z: 2, X: 1, Y: 2}, want: [4]float64{-66.5132604431, -90, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		minLatitude, minLongitude, maxLat, maxLongitude := tt.tile.Bounds()
+		got := [4]float64{minLatitude, minLongitude, maxLat, maxLongitude}
+		for i := range got {
+			if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+				t.Errorf("Bounds(%+v) = %v, want %v", tt.tile, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestTileProject(t *testing.T) {
+	tests := []struct {
+		name      string
+		tile      Tile
+		latitude  float64
+		longitude float64
+		wantX     int
+		wantY     int
+	}{
+		{name: "center of the world", tile: Tile{Z: 0}, latitude: 0, longitude: 0, wantX: 2048, wantY: 2048},
+		{name: "top left corner", tile: Tile{Z: 0}, latitude: 85.0511287798, longitude: -180, wantX: 0, wantY: 0},
+		{name: "north pole", tile: Tile{Z: 0}, latitude: 90, longitude: -180, wantX: 0, wantY: 0},
+		{name: "south pole", tile: Tile{Z: 1, X: 0, Y: 1}, latitude: -90, longitude: -180, wantX: 0, wantY: Extent - 1},
+		{name: "origin of a tile", tile: Tile{Z: 1, X: 1, Y: 1}, latitude: 0, longitude: 0, wantX: 0, wantY: 0},
+		{name: "outside of the tile", tile: Tile{Z: 1, X: 1, Y: 1}, latitude: 10, longitude: -10, wantX: -228, wantY: -229},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if x, y := tt.tile.Project(tt.latitude, tt.longitude); x != tt.wantX || y != tt.wantY {
+				t.Errorf("Project() = %d, %d, want %d, %d", x, y, tt.wantX, tt.wantY)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	layer := NewLayer("l")
+	layer.AddPoint(1, 2, map[string]any{"a": "b"})
+
+	// tile { layer (3) { version (15) 2, name (1) "l", feature (2) { tags (2) [0 0], type (3) POINT,
+	// geometry (4) [MoveTo(1) 1 2] }, key (3) "a", value (4) { string (1) "b" }, extent (5) 4096 } }
+	want := "1a1d" + "7802" + "0a016c" + "120b" + "12020000" + "1801" + "2203090204" + "1a0161" + "22030a0162" + "288020"
+
+	if got := hex.EncodeToString(Encode(layer)); got != want {
+		t.Errorf("Encode() = %s, want %s", got, want)
+	}
+	if layer.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", layer.Len())
+	}
+	if len(Encode()) != 0 {
+		t.Errorf("Encode() without layers = %x, want an empty tile", Encode())
+	}
+}
+
+func TestLayerSharesKeysAndValues(t *testing.T) {
+	layer := NewLayer("locations")
+	layer.AddPoint(0, 0, map[string]any{"status": "moving", "speed": 80})
+	layer.AddPoint(10, 10, map[string]any{"status": "moving", "speed": 80, "cluster": true})
+
+	if strings.Join(layer.keys, ",") != "speed,status,cluster" {
+		t.Errorf("keys = %v, want speed, status and cluster once each, sorted by feature", layer.keys)
+	}
+	if len(layer.values) != 3 {
+		t.Errorf("values = %d, want 3", len(layer.values))
+	}
+	// The tags of the second feature point to the same key and value indexes as the first one.
+	if want := []byte{0x12, 0x06, 0x02, 0x02, 0x00, 0x00, 0x01, 0x01}; !bytes.HasPrefix(layer.features[1], want) {
+		t.Errorf("feature = %x, want the tags %x", layer.features[1], want)
+	}
+}
+
+func TestEncodeValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{name: "string", value: "ab", want: "0a026162"},
+		{name: "int", value: 3, want: "3006"},
+		{name: "negative int", value: -3, want: "3005"},
+		{name: "int64", value: int64(300), want: "30d804"},
+		{name: "float", value: 1.5, want: "19000000000000f83f"},
+		{name: "true", value: true, want: "3801"},
+		{name: "false", value: false, want: "3800"},
+		{name: "other types as strings", value: uint8(7), want: "0a0137"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hex.EncodeToString(encodeValue(tt.value)); got != tt.want {
+				t.Errorf("encodeValue(%v) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestZigzag(t *testing.T) {
+	for n, want := range map[int64]uint64{0: 0, -1: 1, 1: 2, -2: 3, 2: 4, 4096: 8192, math.MinInt64: math.MaxUint64} {
+		if got := zigzag(n); got != want {
+			t.Errorf("zigzag(%d) = %d, want %d", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package nmea
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// knotsToKmh converts the speed over ground reported by the sentences into km/h.
+const knotsToKmh = 1.852
+
+var (
+	ErrChecksum    = errors.New("nmea checksum mismatch")
+	ErrUnsupported = errors.New("unsupported nmea sentence")
+	ErrNoFix       = errors.New("the receiver has no valid fix")
+	ErrNoSpeed     = errors.New("the fix has no speed, the rmc sentence of its epoch is missing")
+)
+
+// Fix is a position decoded from a RMC or GGA sentence.
+type Fix struct {
+	// Type is the sentence type without the talker, RMC or GGA.
+	Type string
+	// Timestamp is the UTC time of the fix. GGA sentences carry no date,
+	// so their timestamp is on the day provided to the parser.
+	Timestamp time.Time
+	Valid     bool
+	Latitude  float64
+	Longitude float64
+	// Speed is the speed over ground in km/h, only reported by RMC sentences.
+	Speed    float64
+	HasSpeed bool
+	Course   float64
+	// Satellites and Altitude are only reported by GGA sentences.
+	Satellites int
+	Altitude   float64
+	// Sentence is the first sentence of the fix and Line its position in the input, starting at 1.
+	Sentence string
+	Line     int
+}
+
+// Parse parses a RMC or GGA sentence from any talker, like $GPRMC or $GNGGA, verifying its checksum.
+// The day is used as the date of the GGA sentences.
+func Parse(line string, day time.Time) (*Fix, error) {
+	fields, err := split(line)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields[0]) != 5 {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, fields[0])
+	}
+
+	switch fields[0][2:] {
+	case "RMC":
+		return parseRMC(fields)
+	case "GGA":
+		return parseGGA(fields, day)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, fields[0])
+	}
+}
+
+// SentenceError is the error of a sentence that could not be decoded,
+// with its position in the input, starting at 1.
+type SentenceError struct {
+	Sentence string
+	Line     int
+	Err      error
+}
+
+func (e *SentenceError) Error() string {
+	return fmt.Sprintf("line %d %s: %s", e.Line, e.Sentence, e.Err.Error())
+}
+
+func (e *SentenceError) Unwrap() error {
+	return e.Err
+}
+
+// Decode parses the sentences forwarded by a receiver, in the order they were emitted.
+// The RMC and GGA sentences of the same epoch are merged into a single fix, the sentence
+// types not supported are ignored and the ones that fail to parse are returned as errors.
+func Decode(sentences []string, day time.Time) ([]*Fix, []*SentenceError) {
+	fixes := make([]*Fix, 0, len(sentences))
+	errs := make([]*SentenceError, 0)
+
+	for i, sentence := range sentences {
+		fix, err := Parse(sentence, day)
+		if errors.Is(err, ErrUnsupported) {
+			continue
+		} else if err != nil {
+			errs = append(errs, &SentenceError{Sentence: sentence, Line: i + 1, Err: err})
+			continue
+		}
+		fix.Sentence, fix.Line = sentence, i+1
+
+		if n := len(fixes); n > 0 && fixes[n-1].Type != fix.Type && fixes[n-1].sameEpoch(fix) {
+			fixes[n-1].merge(fix)
+			continue
+		}
+		fixes = append(fixes, fix)
+	}
+
+	return fixes, errs
+}
+
+// sameEpoch reports if both fixes have the same time of day, as the GGA sentences have no date.
+func (f *Fix) sameEpoch(other *Fix) bool {
+	h1, m1, s1 := f.Timestamp.Clock()
+	h2, m2, s2 := other.Timestamp.Clock()
+	return h1 == h2 && m1 == m2 && s1 == s2 && f.Timestamp.Nanosecond() == other.Timestamp.Nanosecond()
+}
+
+// merge completes a fix with the data of the other sentence type of the same epoch.
+func (f *Fix) merge(other *Fix) {
+	rmc, gga := f, other
+	if f.Type == "GGA" {
+		rmc, gga = other, f
+	}
+
+	*f = Fix{
+		Type:       "RMC",
+		Timestamp:  rmc.Timestamp,
+		Valid:      rmc.Valid && gga.Valid,
+		Latitude:   rmc.Latitude,
+		Longitude:  rmc.Longitude,
+		Speed:      rmc.Speed,
+		HasSpeed:   rmc.HasSpeed,
+		Course:     rmc.Course,
+		Satellites: gga.Satellites,
+		Altitude:   gga.Altitude,
+		Sentence:   f.Sentence,
+		Line:       f.Line,
+	}
+}
+
+// Check reports why a fix can't be saved as a location: the receiver had no fix,
+// or the fix comes from a GGA sentence alone, without the speed to derive the status.
+func (f *Fix) Check() error {
+	if !f.Valid {
+		return ErrNoFix
+	}
+	if !f.HasSpeed {
+		return ErrNoSpeed
+	}
+	return nil
+}
+
+// split verifies the checksum of a sentence and returns its comma separated fields, without the $.
+// The checksum is the XOR of the characters between $ and *, written as two hexadecimal digits.
+func split(line string) ([]string, error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "$") {
+		return nil, fmt.Errorf("invalid nmea sentence %q, it must start with $", line)
+	}
+
+	body, checksum, found := strings.Cut(line[1:], "*")
+	if !found || len(checksum) != 2 {
+		return nil, fmt.Errorf("invalid nmea sentence %q, it must end with *<checksum>", line)
+	}
+
+	expected, err := strconv.ParseUint(checksum, 16, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nmea checksum %q", checksum)
+	}
+
+	var sum byte
+	for i := 0; i < len(body); i++ {
+		sum ^= body[i]
+	}
+	if sum != byte(expected) {
+		return nil, fmt.Errorf("%w: computed %02X, sentence has %02X", ErrChecksum, sum, expected)
+	}
+
+	return strings.Split(body, ","), nil
+}
+
+// parseRMC parses $--RMC,hhmmss.ss,A,ddmm.mmmm,N,dddmm.mmmm,E,knots,course,ddmmyy,...
+func parseRMC(fields []string) (*Fix, error) {
+	if len(fields) < 10 {
+		return nil, fmt.Errorf("invalid rmc sentence, expected at least 10 fields, got %d", len(fields))
+	}
+
+	timestamp, err := parseTime(fields[9], fields[1])
+	if err != nil {
+		return nil, err
+	}
+
+	fix := &Fix{Type: "RMC", Timestamp: timestamp, Valid: fields[2] == "A"}
+	if fix.Latitude, fix.Longitude, err = parseCoordinates(fields[3:7]); err != nil {
+		return nil, err
+	}
+	fix.Valid = fix.Valid && fields[3] != "" && fields[5] != ""
+
+	if fields[7] != "" {
+		knots, err := strconv.ParseFloat(fields[7], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rmc speed %q", fields[7])
+		}
+		fix.Speed = knots * knotsToKmh
+		fix.HasSpeed = true
+	}
+
+	if fields[8] != "" {
+		if fix.Course, err = strconv.ParseFloat(fields[8], 64); err != nil {
+			return nil, fmt.Errorf("invalid rmc course %q", fields[8])
+		}
+	}
+
+	return fix, nil
+}
+
+// parseGGA parses $--GGA,hhmmss.ss,ddmm.mmmm,N,dddmm.mmmm,E,quality,satellites,hdop,altitude,M,...
+func parseGGA(fields []string, day time.Time) (*Fix, error) {
+	if len(fields) < 10 {
+		return nil, fmt.Errorf("invalid gga sentence, expected at least 10 fields, got %d", len(fields))
+	}
+
+	timestamp, err := parseTime(day.UTC().Format("020106"), fields[1])
+	if err != nil {
+		return nil, err
+	}
+
+	fix := &Fix{Type: "GGA", Timestamp: timestamp, Valid: fields[6] != "" && fields[6] != "0"}
+	if fix.Latitude, fix.Longitude, err = parseCoordinates(fields[2:6]); err != nil {
+		return nil, err
+	}
+	fix.Valid = fix.Valid && fields[2] != "" && fields[4] != ""
+
+	if fields[7] != "" {
+		if fix.Satellites, err = strconv.Atoi(fields[7]); err != nil {
+			return nil, fmt.Errorf("invalid gga satellites %q", fields[7])
+		}
+	}
+
+	if fields[9] != "" {
+		if fix.Altitude, err = strconv.ParseFloat(fields[9], 64); err != nil {
+			return nil, fmt.Errorf("invalid gga altitude %q", fields[9])
+		}
+	}
+
+	return fix, nil
+}
+
+func parseTime(date, clock string) (time.Time, error) {
+	if len(clock) < 6 {
+		return time.Time{}, fmt.Errorf("invalid nmea time %q", clock)
+	}
+
+	timestamp, err := time.Parse("020106150405", date+clock[:6])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid nmea date time %q %q", date, clock)
+	}
+
+	if len(clock) > 7 && clock[6] == '.' {
+		fraction, err := strconv.ParseFloat("0"+clock[6:], 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid nmea time %q", clock)
+		}
+		timestamp = timestamp.Add(time.Duration(fraction * float64(time.Second)))
+	}
+
+	return timestamp, nil
+}
+
+// parseCoordinates parses the latitude, its hemisphere, the longitude and its hemisphere.
+// The fields are empty when the receiver has no fix.
+func parseCoordinates(fields []string) (float64, float64, error) {
+	if fields[0] == "" || fields[2] == "" {
+		return 0, 0, nil
+	}
+
+	latitude, err := DegreesMinutesToDecimal(fields[0], fields[1])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	longitude, err := DegreesMinutesToDecimal(fields[2], fields[3])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return latitude, longitude, nil
+}
+
+// DegreesMinutesToDecimal converts a coordinate in the (d)ddmm.mmmm format
+// used by NMEA and ASCII trackers, with its hemisphere (N, S, E or W), into decimal degrees.
+func DegreesMinutesToDecimal(value, hemisphere string) (float64, error) {
+	raw, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid coordinate %q: %w", value, err)
+	}
+
+	degrees := math.Floor(raw / 100)
+	decimal := degrees + (raw-degrees*100)/60
+
+	switch strings.ToUpper(hemisphere) {
+	case "N", "E":
+		return decimal, nil
+	case "S", "W":
+		return -decimal, nil
+	default:
+		return 0, fmt.Errorf("invalid hemisphere %q", hemisphere)
+	}
+}
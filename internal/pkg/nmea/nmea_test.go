@@ -0,0 +1,212 @@
+package nmea
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// The sentences of the NMEA 0183 reference, for the epoch 12:35:19 of 1994-03-23.
+const (
+	sampleRMC = "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A"
+	sampleGGA = "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47"
+)
+
+var sampleDay = time.Date(1994, 3, 23, 18, 0, 0, 0, time.UTC)
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name           string
+		sentence       string
+		wantType       string
+		wantTimestamp  time.Time
+		wantValid      bool
+		wantLatitude   float64
+		wantLongitude  float64
+		wantSpeed      float64
+		wantHasSpeed   bool
+		wantCourse     float64
+		wantSatellites int
+		wantAltitude   float64
+	}{
+		{
+			name:          "rmc",
+			sentence:      sampleRMC,
+			wantType:      "RMC",
+			wantTimestamp: time.Date(1994, 3, 23, 12, 35, 19, 0, time.UTC),
+			wantValid:     true,
+			wantLatitude:  48 + 7.038/60,
+			wantLongitude: 11 + 31.0/60,
+			wantSpeed:     22.4 * knotsToKmh,
+			wantHasSpeed:  true,
+			wantCourse:    84.4,
+		},
+		{
+			name:           "gga",
+			sentence:       sampleGGA,
+			wantType:       "GGA",
+			wantTimestamp:  time.Date(1994, 3, 23, 12, 35, 19, 0, time.UTC),
+			wantValid:      true,
+			wantLatitude:   48 + 7.038/60,
+			wantLongitude:  11 + 31.0/60,
+			wantSatellites: 8,
+			wantAltitude:   545.4,
+		},
+		{
+			name:          "rmc of another talker with fraction of second",
+			sentence:      "$GNRMC,001031.50,A,2232.9828,S,04627.9297,W,0.0,,010125,,,A*68",
+			wantType:      "RMC",
+			wantTimestamp: time.Date(2025, 1, 1, 0, 10, 31, 500000000, time.UTC),
+			wantValid:     true,
+			wantLatitude:  -(22 + 32.9828/60),
+			wantLongitude: -(46 + 27.9297/60),
+			wantHasSpeed:  true,
+		},
+		{
+			name:          "rmc without fix",
+			sentence:      "$GPRMC,123519,V,,,,,,,230394,,*33",
+			wantType:      "RMC",
+			wantTimestamp: time.Date(1994, 3, 23, 12, 35, 19, 0, time.UTC),
+		},
+		{
+			name:          "gga without fix",
+			sentence:      "$GPGGA,123519,,,,,0,00,,,M,,M,,*6B",
+			wantType:      "GGA",
+			wantTimestamp: time.Date(1994, 3, 23, 12, 35, 19, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fix, err := Parse(tt.sentence, sampleDay)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if fix.Type != tt.wantType || fix.Valid != tt.wantValid || !fix.Timestamp.Equal(tt.wantTimestamp) {
+				t.Errorf("Parse() = %s, %v, %v, want %s, %v, %v", fix.Type, fix.Valid, fix.Timestamp, tt.wantType, tt.wantValid, tt.wantTimestamp)
+			}
+			if !closeTo(fix.Latitude, tt.wantLatitude) || !closeTo(fix.Longitude, tt.wantLongitude) {
+				t.Errorf("coordinates = %f, %f, want %f, %f", fix.Latitude, fix.Longitude, tt.wantLatitude, tt.wantLongitude)
+			}
+			if !closeTo(fix.Speed, tt.wantSpeed) || fix.HasSpeed != tt.wantHasSpeed || !closeTo(fix.Course, tt.wantCourse) {
+				t.Errorf("speed = %f, %v, course = %f, want %f, %v, %f", fix.Speed, fix.HasSpeed, fix.Course, tt.wantSpeed, tt.wantHasSpeed, tt.wantCourse)
+			}
+			if fix.Satellites != tt.wantSatellites || !closeTo(fix.Altitude, tt.wantAltitude) {
+				t.Errorf("satellites = %d, altitude = %f, want %d, %f", fix.Satellites, fix.Altitude, tt.wantSatellites, tt.wantAltitude)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		sentence string
+		wantErr  error
+	}{
+		{name: "checksum mismatch", sentence: "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6B", wantErr: ErrChecksum},
+		{name: "unsupported sentence", sentence: "$GPGSA,A,3,04,05,,09,12,,,24,,,,,2.5,1.3,2.1*39", wantErr: ErrUnsupported},
+		{name: "missing dollar", sentence: "GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A"},
+		{name: "missing checksum", sentence: "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W"},
+		{name: "invalid checksum", sentence: "$GPRMC,123519*ZZ"},
+		{name: "short rmc", sentence: "$GPRMC,123519,A*07"},
+		{name: "invalid hemisphere", sentence: "$GPRMC,123519,A,4807.038,X,01131.000,E,022.4,084.4,230394,003.1,W*7C"},
+		{name: "invalid date", sentence: "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,320394,003.1,W*6A"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.sentence, sampleDay)
+			if err == nil {
+				t.Fatal("Parse() error = nil, want an error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	sentences := []string{
+		sampleGGA,
+		"$GPGSA,A,3,04,05,,09,12,,,24,,,,,2.5,1.3,2.1*39",
+		sampleRMC,
+		"$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*00",
+		"$GPGGA,123520,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*4D",
+	}
+
+	fixes, errs := Decode(sentences, sampleDay)
+
+	if len(errs) != 1 {
+		t.Fatalf("errors = %d, want 1", len(errs))
+	}
+	if errs[0].Line != 4 || errs[0].Sentence != sentences[3] || !errors.Is(errs[0], ErrChecksum) {
+		t.Errorf("error = %v, want the checksum mismatch of the line 4", errs[0])
+	}
+
+	if len(fixes) != 2 {
+		t.Fatalf("fixes = %d, want 2", len(fixes))
+	}
+
+	merged := fixes[0]
+	if merged.Type != "RMC" || !merged.HasSpeed || merged.Satellites != 8 || !closeTo(merged.Altitude, 545.4) {
+		t.Errorf("merged fix = %+v, want the RMC completed by the GGA", merged)
+	}
+	if merged.Line != 1 || merged.Sentence != sampleGGA {
+		t.Errorf("merged fix line = %d %q, want the line 1", merged.Line, merged.Sentence)
+	}
+	if err := merged.Check(); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	ggaOnly := fixes[1]
+	if ggaOnly.Line != 5 || ggaOnly.Sentence != sentences[4] {
+		t.Errorf("gga fix line = %d %q, want the line 5", ggaOnly.Line, ggaOnly.Sentence)
+	}
+	if err := ggaOnly.Check(); !errors.Is(err, ErrNoSpeed) {
+		t.Errorf("Check() error = %v, want %v", err, ErrNoSpeed)
+	}
+}
+
+func TestFixCheckNoFix(t *testing.T) {
+	fix, err := Parse("$GPRMC,123519,V,,,,,,,230394,,*33", sampleDay)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if err := fix.Check(); !errors.Is(err, ErrNoFix) {
+		t.Errorf("Check() error = %v, want %v", err, ErrNoFix)
+	}
+}
+
+func TestDegreesMinutesToDecimal(t *testing.T) {
+	tests := []struct {
+		value      string
+		hemisphere string
+		want       float64
+		wantErr    bool
+	}{
+		{value: "4807.038", hemisphere: "N", want: 48.1173},
+		{value: "01131.000", hemisphere: "E", want: 11.516666666},
+		{value: "2232.9828", hemisphere: "s", want: -22.54971333},
+		{value: "04627.9297", hemisphere: "W", want: -46.465495},
+		{value: "4807.038", hemisphere: "X", wantErr: true},
+		{value: "48O7.038", hemisphere: "N", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := DegreesMinutesToDecimal(tt.value, tt.hemisphere)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("DegreesMinutesToDecimal(%q, %q) error = %v, want error %v", tt.value, tt.hemisphere, err, tt.wantErr)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("DegreesMinutesToDecimal(%q, %q) = %f, want %f", tt.value, tt.hemisphere, got, tt.want)
+		}
+	}
+}
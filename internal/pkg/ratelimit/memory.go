@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the idle buckets and the expired counters are removed from the MemoryStore.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+type counter struct {
+	value int64
+	reset time.Time
+}
+
+// MemoryStore is a Store that keeps the state in the memory of the instance.
+// The buckets are dropped once they are full again, since a new one starts full.
+type MemoryStore struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	counters map[string]*counter
+	swept    time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:  make(map[string]*bucket),
+		counters: make(map[string]*counter),
+	}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit, n float64, now time.Time) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: limit.Burst, updated: now}
+		s.buckets[key] = b
+	}
+	b.refill(limit, now)
+
+	if b.tokens < n {
+		return b.wait(limit, n), nil
+	}
+
+	b.tokens -= n
+	b.full = now.Add(time.Duration((limit.Burst - b.tokens) / limit.Rate * float64(time.Second)))
+	return 0, nil
+}
+
+func (s *MemoryStore) Wait(key string, limit Limit, n float64, now time.Time) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		return 0, nil
+	}
+	b.refill(limit, now)
+
+	if b.tokens < n {
+		return b.wait(limit, n), nil
+	}
+	return 0, nil
+}
+
+func (s *MemoryStore) Consume(key string, n, max int64, reset, now time.Time) (int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	c, ok := s.counters[key]
+	if !ok || !now.Before(c.reset) {
+		c = &counter{reset: reset}
+		s.counters[key] = c
+	}
+
+	if c.value+n > max {
+		return c.value, false, nil
+	}
+	c.value += n
+	return c.value, true, nil
+}
+
+func (s *MemoryStore) Release(key string, n int64, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.counters[key]; ok && now.Before(c.reset) {
+		c.value = max(0, c.value-n)
+	}
+	return nil
+}
+
+// refill adds the tokens of the time elapsed since the bucket was updated, up to the burst.
+func (b *bucket) refill(limit Limit, now time.Time) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(limit.Burst, b.tokens+elapsed*limit.Rate)
+		b.updated = now
+	}
+}
+
+// wait returns how long until the bucket has n tokens.
+func (b *bucket) wait(limit Limit, n float64) time.Duration {
+	return time.Duration((n - b.tokens) / limit.Rate * float64(time.Second))
+}
+
+// sweep removes the full buckets and the expired counters, at most once every sweepInterval.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.swept) < sweepInterval {
+		return
+	}
+	s.swept = now
+
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, c := range s.counters {
+		if !now.Before(c.reset) {
+			delete(s.counters, key)
+		}
+	}
+}
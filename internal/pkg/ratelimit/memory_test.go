@@ -0,0 +1,149 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Rate: 2, Burst: 4}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// The bucket starts full, with the burst.
+	for i := range 4 {
+		if wait, _ := store.Take("a", limit, 1, now); wait != 0 {
+			t.Fatalf("Take() request %d = %v, want 0", i+1, wait)
+		}
+	}
+
+	// The empty bucket gets a token every half second.
+	if wait, _ := store.Take("a", limit, 1, now); wait != 500*time.Millisecond {
+		t.Errorf("Take() with an empty bucket = %v, want 500ms", wait)
+	}
+	if wait, _ := store.Take("a", limit, 1, now.Add(250*time.Millisecond)); wait != 250*time.Millisecond {
+		t.Errorf("Take() with half a token = %v, want 250ms", wait)
+	}
+	if wait, _ := store.Take("a", limit, 1, now.Add(500*time.Millisecond)); wait != 0 {
+		t.Errorf("Take() after the refill = %v, want 0", wait)
+	}
+
+	// The refill is capped by the burst.
+	later := now.Add(time.Hour)
+	for i := range 4 {
+		if wait, _ := store.Take("a", limit, 1, later); wait != 0 {
+			t.Fatalf("Take() request %d after an hour = %v, want 0", i+1, wait)
+		}
+	}
+	if wait, _ := store.Take("a", limit, 1, later); wait == 0 {
+		t.Errorf("Take() over the burst after an hour = 0, want a wait")
+	}
+
+	if wait, _ := store.Take("b", limit, 1, now); wait != 0 {
+		t.Errorf("Take() of another key = %v, want 0", wait)
+	}
+}
+
+func TestMemoryStoreWait(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Rate: 1, Burst: 1}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if wait, _ := store.Wait("a", limit, 1, now); wait != 0 {
+		t.Errorf("Wait() without a bucket = %v, want 0", wait)
+	}
+	if len(store.buckets) != 0 {
+		t.Errorf("Wait() created %d buckets, want none", len(store.buckets))
+	}
+
+	if wait, _ := store.Take("a", limit, 1, now); wait != 0 {
+		t.Fatalf("Take() = %v, want 0", wait)
+	}
+	for range 3 {
+		if wait, _ := store.Wait("a", limit, 1, now); wait != time.Second {
+			t.Errorf("Wait() with an empty bucket = %v, want 1s", wait)
+		}
+	}
+	if wait, _ := store.Wait("a", limit, 1, now.Add(time.Second)); wait != 0 {
+		t.Errorf("Wait() after the refill = %v, want 0", wait)
+	}
+	if wait, _ := store.Take("a", limit, 1, now.Add(time.Second)); wait != 0 {
+		t.Errorf("Take() after Wait() = %v, want 0, since Wait() takes nothing", wait)
+	}
+}
+
+func TestMemoryStoreConsume(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	reset := now.Add(24 * time.Hour)
+
+	if value, ok, _ := store.Consume("a", 6, 10, reset, now); !ok || value != 6 {
+		t.Errorf("Consume() = %d, %v, want 6, true", value, ok)
+	}
+	if value, ok, _ := store.Consume("a", 5, 10, reset, now); ok || value != 6 {
+		t.Errorf("Consume() over the max = %d, %v, want 6, false", value, ok)
+	}
+	if value, ok, _ := store.Consume("a", 4, 10, reset, now); !ok || value != 10 {
+		t.Errorf("Consume() up to the max = %d, %v, want 10, true", value, ok)
+	}
+	if value, ok, _ := store.Consume("a", 1, 10, reset.Add(24*time.Hour), reset); !ok || value != 1 {
+		t.Errorf("Consume() after the reset = %d, %v, want 1, true", value, ok)
+	}
+}
+
+func TestMemoryStoreRelease(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	reset := now.Add(24 * time.Hour)
+
+	_, _, _ = store.Consume("a", 10, 10, reset, now)
+	if err := store.Release("a", 4, now); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if value, ok, _ := store.Consume("a", 4, 10, reset, now); !ok || value != 10 {
+		t.Errorf("Consume() after the release = %d, %v, want 10, true", value, ok)
+	}
+
+	_ = store.Release("a", 20, now)
+	if value, ok, _ := store.Consume("a", 0, 10, reset, now); !ok || value != 0 {
+		t.Errorf("Consume() after releasing more than the counter = %d, %v, want 0, true", value, ok)
+	}
+
+	// A counter already reset and an unknown one are not changed.
+	_, _, _ = store.Consume("a", 5, 10, reset, now)
+	_ = store.Release("a", 5, reset)
+	_ = store.Release("b", 5, now)
+	if value, ok, _ := store.Consume("a", 0, 10, reset.Add(24*time.Hour), now); !ok || value != 5 {
+		t.Errorf("Consume() after a release past the reset = %d, %v, want 5, true", value, ok)
+	}
+	if value, ok, _ := store.Consume("b", 1, 10, reset, now); !ok || value != 1 {
+		t.Errorf("Consume() of a key not counted before = %d, %v, want 1, true", value, ok)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Rate: 0.1, Burst: 10}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// The first bucket is full again after 10s, the second one after 100s.
+	_, _ = store.Take("refilled", limit, 1, now)
+	_, _ = store.Take("empty", limit, 10, now)
+	_, _, _ = store.Consume("counter", 1, 10, now.Add(time.Second), now)
+
+	store.sweep(now.Add(30 * time.Second))
+	if len(store.buckets) != 2 || len(store.counters) != 1 {
+		t.Errorf("sweep() before the sweep interval removed %d buckets and %d counters, want none", 2-len(store.buckets), 1-len(store.counters))
+	}
+
+	store.sweep(now.Add(sweepInterval))
+	if _, ok := store.buckets["refilled"]; ok {
+		t.Errorf("sweep() kept a full bucket")
+	}
+	if _, ok := store.buckets["empty"]; !ok {
+		t.Errorf("sweep() removed a bucket that is not full yet")
+	}
+	if _, ok := store.counters["counter"]; ok {
+		t.Errorf("sweep() kept an expired counter")
+	}
+}
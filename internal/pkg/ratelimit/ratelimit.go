@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket: it holds up to Burst tokens, refilled at Rate tokens per second.
+// Every request takes a token, so Rate is the sustained requests per second and Burst the peak.
+type Limit struct {
+	Rate  float64
+	Burst float64
+}
+
+// units are the periods of the limits, in the format <requests>/<unit>.
+var units = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+}
+
+// ParseLimit parses a limit in the format <requests>/<s|m|h>[:<burst>], like 10/s:20 or 600/m.
+// The burst is the requests of the period when omitted.
+func ParseLimit(value string) (Limit, error) {
+	rate, burst, hasBurst := strings.Cut(strings.TrimSpace(value), ":")
+
+	requests, unit, found := strings.Cut(rate, "/")
+	period, ok := units[unit]
+	if !found || !ok {
+		return Limit{}, fmt.Errorf("invalid limit %q, expected <requests>/<s|m|h>[:<burst>]", value)
+	}
+
+	count, err := strconv.ParseFloat(requests, 64)
+	if err != nil || count <= 0 || math.IsInf(count, 0) {
+		return Limit{}, fmt.Errorf("invalid limit %q, the requests must be a positive number", value)
+	}
+
+	limit := Limit{Rate: count / period.Seconds(), Burst: math.Max(count, 1)}
+	if hasBurst {
+		if limit.Burst, err = strconv.ParseFloat(burst, 64); err != nil || limit.Burst < 1 || math.IsInf(limit.Burst, 0) {
+			return Limit{}, fmt.Errorf("invalid limit %q, the burst must be at least 1", value)
+		}
+	}
+	return limit, nil
+}
+
+// Store keeps the state of the token buckets and of the quota counters. The state lives in memory with
+// the MemoryStore, another implementation can share it between the instances of the API, like on Redis.
+type Store interface {
+	// Take takes n tokens from the bucket of the key. It returns 0 when the bucket had them,
+	// or how long until it has them, taking nothing.
+	Take(key string, limit Limit, n float64, now time.Time) (time.Duration, error)
+	// Wait returns 0 when the bucket of the key has n tokens, or how long until it has them, taking nothing.
+	Wait(key string, limit Limit, n float64, now time.Time) (time.Duration, error)
+	// Consume adds n to the counter of the key, which is reset at the reset time, when it doesn't go over max.
+	// It returns the counter and false, without adding n, when it would go over max.
+	Consume(key string, n, max int64, reset, now time.Time) (int64, bool, error)
+	// Release subtracts n from the counter of the key, down to 0, like when what was counted didn't happen.
+	// The counters already reset are not changed.
+	Release(key string, n int64, now time.Time) error
+}
+
+// Limiter applies the limits of the groups, like the routes of the API, to the clients of every group.
+type Limiter struct {
+	store  Store
+	limits map[string]Limit
+}
+
+// NewLimiter creates a Limiter from entries in the format <group>=<limit>, like ingest=10/s:20.
+// The groups must be one of the known groups, and the groups without an entry are not limited.
+func NewLimiter(store Store, entries []string, groups ...string) (*Limiter, error) {
+	limiter := &Limiter{store: store, limits: make(map[string]Limit, len(entries))}
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		group, value, found := strings.Cut(entry, "=")
+		group = strings.TrimSpace(group)
+		if !found || !slices.Contains(groups, group) {
+			return nil, fmt.Errorf("invalid rate limit %q, expected <group>=<limit> with the groups %s", entry, strings.Join(groups, ", "))
+		}
+
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return nil, err
+		}
+		limiter.limits[group] = limit
+	}
+	return limiter, nil
+}
+
+// Enabled reports if any group is limited.
+func (l *Limiter) Enabled() bool {
+	return l != nil && len(l.limits) != 0
+}
+
+// Allow takes a token of the client identified by the key in the group. It returns 0 when the request is allowed,
+// or how long the client must wait to retry. The groups without a limit, or a nil Limiter, allow every request.
+func (l *Limiter) Allow(group, key string) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+
+	limit, ok := l.limits[group]
+	if !ok {
+		return 0, nil
+	}
+	return l.store.Take(group+":"+key, limit, 1, time.Now())
+}
+
+// Wait returns 0 when the client identified by the key in the group has a token, or how long it must wait
+// before its next request, without taking the token. The groups without a limit, or a nil Limiter, never wait.
+func (l *Limiter) Wait(group, key string) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+
+	limit, ok := l.limits[group]
+	if !ok {
+		return 0, nil
+	}
+	return l.store.Wait(group+":"+key, limit, 1, time.Now())
+}
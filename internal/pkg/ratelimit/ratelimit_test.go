@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Limit
+		wantErr bool
+	}{
+		{value: "10/s", want: Limit{Rate: 10, Burst: 10}},
+		{value: "10/s:20", want: Limit{Rate: 10, Burst: 20}},
+		{value: "600/m", want: Limit{Rate: 10, Burst: 600}},
+		{value: "3600/h:1", want: Limit{Rate: 1, Burst: 1}},
+		{value: " 0.5/s ", want: Limit{Rate: 0.5, Burst: 1}},
+		{value: "10", wantErr: true},
+		{value: "10/d", wantErr: true},
+		{value: "0/s", wantErr: true},
+		{value: "-1/s", wantErr: true},
+		{value: "Inf/s", wantErr: true},
+		{value: "a/s", wantErr: true},
+		{value: "10/s:0", wantErr: true},
+		{value: "10/s:a", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseLimit(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimit() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLimiter(t *testing.T) {
+	groups := []string{"read", "write"}
+
+	tests := []struct {
+		name        string
+		entries     []string
+		wantEnabled bool
+		wantErr     bool
+	}{
+		{name: "no limits"},
+		{name: "empty entries", entries: []string{"", " "}},
+		{name: "limits", entries: []string{"read=10/s", " write = 1/m:5"}, wantEnabled: true},
+		{name: "unknown group", entries: []string{"admin=10/s"}, wantErr: true},
+		{name: "missing limit", entries: []string{"read"}, wantErr: true},
+		{name: "invalid limit", entries: []string{"read=10/d"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, err := NewLimiter(NewMemoryStore(), tt.entries, groups...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewLimiter() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && limiter.Enabled() != tt.wantEnabled {
+				t.Errorf("Enabled() = %v, want %v", limiter.Enabled(), tt.wantEnabled)
+			}
+		})
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	limiter, err := NewLimiter(NewMemoryStore(), []string{"read=1/m:2"}, "read", "write")
+	if err != nil {
+		t.Fatalf("NewLimiter() error = %v", err)
+	}
+
+	for i := range 2 {
+		if wait, _ := limiter.Allow("read", "a"); wait != 0 {
+			t.Fatalf("Allow() request %d = %v, want 0", i+1, wait)
+		}
+	}
+	if wait, _ := limiter.Allow("read", "a"); wait <= 0 || wait > time.Minute {
+		t.Errorf("Allow() over the burst = %v, want up to a minute", wait)
+	}
+	if wait, _ := limiter.Allow("read", "b"); wait != 0 {
+		t.Errorf("Allow() of another client = %v, want 0", wait)
+	}
+	if wait, _ := limiter.Allow("write", "a"); wait != 0 {
+		t.Errorf("Allow() of a group without a limit = %v, want 0", wait)
+	}
+
+	var disabled *Limiter
+	if wait, _ := disabled.Allow("read", "a"); wait != 0 || disabled.Enabled() {
+		t.Errorf("nil Limiter Allow() = %v, want 0 and disabled", wait)
+	}
+}
+
+func TestLimiterWait(t *testing.T) {
+	limiter, err := NewLimiter(NewMemoryStore(), []string{"auth=1/m:1"}, "auth")
+	if err != nil {
+		t.Fatalf("NewLimiter() error = %v", err)
+	}
+
+	for range 3 {
+		if wait, _ := limiter.Wait("auth", "a"); wait != 0 {
+			t.Fatalf("Wait() = %v, want 0 before any token is taken", wait)
+		}
+	}
+	if wait, _ := limiter.Allow("auth", "a"); wait != 0 {
+		t.Fatalf("Allow() = %v, want 0", wait)
+	}
+	if wait, _ := limiter.Wait("auth", "a"); wait <= 0 || wait > time.Minute {
+		t.Errorf("Wait() with an empty bucket = %v, want up to a minute", wait)
+	}
+
+	var disabled *Limiter
+	if wait, _ := disabled.Wait("auth", "a"); wait != 0 {
+		t.Errorf("nil Limiter Wait() = %v, want 0", wait)
+	}
+}
@@ -0,0 +1,192 @@
+package trackfile
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Point is a position of a track, with the attributes written along with it.
+type Point struct {
+	Latitude  float64
+	Longitude float64
+	Timestamp time.Time
+	Speed     int
+	Status    string
+}
+
+// Writer writes a track file point by point, so a track can be streamed without being held in memory.
+type Writer interface {
+	// WritePoint writes the next point of the track.
+	WritePoint(point *Point) error
+	// Close writes the end of the file and flushes it. It doesn't close the underlying writer.
+	Close() error
+}
+
+type format struct {
+	contentType string
+	newWriter   func(w *bufio.Writer, name string) (Writer, error)
+}
+
+var formats = map[string]format{
+	"gpx":     {contentType: "application/gpx+xml", newWriter: newGPXWriter},
+	"kml":     {contentType: "application/vnd.google-earth.kml+xml", newWriter: newKMLWriter},
+	"geojson": {contentType: "application/geo+json", newWriter: newGeoJSONWriter},
+}
+
+// ContentType returns the media type of a format, like application/gpx+xml for gpx.
+func ContentType(name string) string {
+	return formats[name].contentType
+}
+
+// NewWriter creates a Writer of the format (gpx, kml or geojson) and writes the beginning of the file.
+// The name is used as the track name on the formats that support it.
+func NewWriter(name string, w io.Writer, trackName string) (Writer, error) {
+	f, ok := formats[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported track format %q", name)
+	}
+	return f.newWriter(bufio.NewWriter(w), trackName)
+}
+
+func escapeXML(value string) string {
+	escaped := new(strings.Builder)
+	_ = xml.EscapeText(escaped, []byte(value))
+	return escaped.String()
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// GPXNamespace is the namespace of the speed, in km/h, and status extensions of the GPX track points.
+const GPXNamespace = "https://github.com/allansbo/goapi/gpx/1"
+
+// gpxWriter writes a GPX 1.1 track, with the speed and status as extensions of the points
+// in the GPXNamespace, prefixed with goapi.
+type gpxWriter struct {
+	w *bufio.Writer
+}
+
+func newGPXWriter(w *bufio.Writer, name string) (Writer, error) {
+	_, err := fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="goapi" xmlns="http://www.topografix.com/GPX/1/1" xmlns:goapi="%s">
+<trk><name>%s</name><trkseg>
+`, GPXNamespace, escapeXML(name))
+	return &gpxWriter{w: w}, err
+}
+
+func (g *gpxWriter) WritePoint(p *Point) error {
+	_, err := fmt.Fprintf(g.w,
+		"<trkpt lat=\"%s\" lon=\"%s\"><time>%s</time><extensions><goapi:speed>%d</goapi:speed><goapi:status>%s</goapi:status></extensions></trkpt>\n",
+		formatFloat(p.Latitude), formatFloat(p.Longitude), p.Timestamp.UTC().Format(time.RFC3339), p.Speed, escapeXML(p.Status),
+	)
+	return err
+}
+
+func (g *gpxWriter) Close() error {
+	if _, err := g.w.WriteString("</trkseg></trk>\n</gpx>\n"); err != nil {
+		return err
+	}
+	return g.w.Flush()
+}
+
+// kmlWriter writes a KML document with a time stamped placemark for every point,
+// with the speed and status as extended data.
+type kmlWriter struct {
+	w *bufio.Writer
+}
+
+func newKMLWriter(w *bufio.Writer, name string) (Writer, error) {
+	_, err := fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document><name>%s</name>
+`, escapeXML(name))
+	return &kmlWriter{w: w}, err
+}
+
+func (k *kmlWriter) WritePoint(p *Point) error {
+	timestamp := p.Timestamp.UTC().Format(time.RFC3339)
+	_, err := fmt.Fprintf(k.w,
+		"<Placemark><name>%s</name><TimeStamp><when>%s</when></TimeStamp>"+
+			"<ExtendedData><Data name=\"speed\"><value>%d</value></Data><Data name=\"status\"><value>%s</value></Data></ExtendedData>"+
+			"<Point><coordinates>%s,%s</coordinates></Point></Placemark>\n",
+		timestamp, timestamp, p.Speed, escapeXML(p.Status), formatFloat(p.Longitude), formatFloat(p.Latitude),
+	)
+	return err
+}
+
+func (k *kmlWriter) Close() error {
+	if _, err := k.w.WriteString("</Document>\n</kml>\n"); err != nil {
+		return err
+	}
+	return k.w.Flush()
+}
+
+// geoJSONWriter writes a feature collection with a point feature for every point,
+// with the timestamp, speed and status as properties.
+type geoJSONWriter struct {
+	w     *bufio.Writer
+	count int
+}
+
+type geoJSONFeature struct {
+	Type     string `json:"type"`
+	Geometry struct {
+		Type        string     `json:"type"`
+		Coordinates [2]float64 `json:"coordinates"`
+	} `json:"geometry"`
+	Properties struct {
+		Timestamp time.Time `json:"timestamp"`
+		Speed     int       `json:"speed"`
+		Status    string    `json:"status"`
+	} `json:"properties"`
+}
+
+func newGeoJSONWriter(w *bufio.Writer, name string) (Writer, error) {
+	nameJSON, err := json.Marshal(name)
+	if err != nil {
+		return nil, err
+	}
+	_, err = fmt.Fprintf(w, `{"type":"FeatureCollection","name":%s,"features":[`, nameJSON)
+	return &geoJSONWriter{w: w}, err
+}
+
+func (g *geoJSONWriter) WritePoint(p *Point) error {
+	feature := geoJSONFeature{Type: "Feature"}
+	feature.Geometry.Type = "Point"
+	feature.Geometry.Coordinates = [2]float64{p.Longitude, p.Latitude}
+	feature.Properties.Timestamp = p.Timestamp.UTC()
+	feature.Properties.Speed = p.Speed
+	feature.Properties.Status = p.Status
+
+	featureJSON, err := json.Marshal(feature)
+	if err != nil {
+		return err
+	}
+
+	if g.count > 0 {
+		if err := g.w.WriteByte(','); err != nil {
+			return err
+		}
+	}
+	g.count++
+
+	if err := g.w.WriteByte('\n'); err != nil {
+		return err
+	}
+	_, err = g.w.Write(featureJSON)
+	return err
+}
+
+func (g *geoJSONWriter) Close() error {
+	if _, err := g.w.WriteString("\n]}\n"); err != nil {
+		return err
+	}
+	return g.w.Flush()
+}
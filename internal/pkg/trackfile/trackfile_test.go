@@ -0,0 +1,159 @@
+package trackfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testPoints = []*Point{
+	{Latitude: -23.55052, Longitude: -46.633308, Timestamp: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC), Speed: 80, Status: "moving"},
+	{Latitude: -23.5, Longitude: -46.6, Timestamp: time.Date(2025, 1, 1, 9, 5, 0, 0, time.FixedZone("BRT", -3*3600)), Speed: 0, Status: "stopped"},
+}
+
+func writeTrack(t *testing.T, format, name string) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+
+	w, err := NewWriter(format, buf, name)
+	if err != nil {
+		t.Fatalf("NewWriter(%q) error = %v", format, err)
+	}
+	for _, point := range testPoints {
+		if err := w.WritePoint(point); err != nil {
+			t.Fatalf("WritePoint() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestGPXWriter(t *testing.T) {
+	data := writeTrack(t, "gpx", "ABC1234 <trip>")
+
+	var gpx struct {
+		XMLName xml.Name `xml:"http://www.topografix.com/GPX/1/1 gpx"`
+		Track   struct {
+			Name   string `xml:"name"`
+			Points []struct {
+				Latitude   float64 `xml:"lat,attr"`
+				Longitude  float64 `xml:"lon,attr"`
+				Time       string  `xml:"time"`
+				Extensions struct {
+					Speed  int    `xml:"https://github.com/allansbo/goapi/gpx/1 speed"`
+					Status string `xml:"https://github.com/allansbo/goapi/gpx/1 status"`
+				} `xml:"extensions"`
+			} `xml:"trkseg>trkpt"`
+		} `xml:"trk"`
+	}
+	if err := xml.Unmarshal(data, &gpx); err != nil {
+		t.Fatalf("invalid gpx: %v\n%s", err, data)
+	}
+
+	if gpx.Track.Name != "ABC1234 <trip>" {
+		t.Errorf("name = %q, want the escaped track name", gpx.Track.Name)
+	}
+	if len(gpx.Track.Points) != len(testPoints) {
+		t.Fatalf("points = %d, want %d", len(gpx.Track.Points), len(testPoints))
+	}
+
+	point := gpx.Track.Points[1]
+	if point.Latitude != -23.5 || point.Longitude != -46.6 || point.Time != "2025-01-01T12:05:00Z" {
+		t.Errorf("point = %+v, want the second point in UTC", point)
+	}
+	if point.Extensions.Speed != 0 || point.Extensions.Status != "stopped" {
+		t.Errorf("extensions = %+v, want 0, stopped in the goapi namespace", point.Extensions)
+	}
+	if extensions := gpx.Track.Points[0].Extensions; extensions.Speed != 80 || extensions.Status != "moving" {
+		t.Errorf("extensions = %+v, want 80, moving in the goapi namespace", extensions)
+	}
+}
+
+func TestKMLWriter(t *testing.T) {
+	data := writeTrack(t, "kml", "ABC1234")
+
+	var kml struct {
+		Document struct {
+			Name       string `xml:"name"`
+			Placemarks []struct {
+				When        string   `xml:"TimeStamp>when"`
+				Data        []string `xml:"ExtendedData>Data>value"`
+				Coordinates string   `xml:"Point>coordinates"`
+			} `xml:"Placemark"`
+		} `xml:"Document"`
+	}
+	if err := xml.Unmarshal(data, &kml); err != nil {
+		t.Fatalf("invalid kml: %v\n%s", err, data)
+	}
+
+	if kml.Document.Name != "ABC1234" || len(kml.Document.Placemarks) != len(testPoints) {
+		t.Fatalf("document = %q with %d placemarks", kml.Document.Name, len(kml.Document.Placemarks))
+	}
+
+	placemark := kml.Document.Placemarks[0]
+	if placemark.Coordinates != "-46.633308,-23.55052" {
+		t.Errorf("coordinates = %q, want the longitude first", placemark.Coordinates)
+	}
+	if placemark.When != "2025-01-01T12:00:00Z" || strings.Join(placemark.Data, ",") != "80,moving" {
+		t.Errorf("placemark = %+v", placemark)
+	}
+}
+
+func TestGeoJSONWriter(t *testing.T) {
+	data := writeTrack(t, "geojson", `ABC1234 "trip"`)
+
+	var collection struct {
+		Type     string           `json:"type"`
+		Name     string           `json:"name"`
+		Features []geoJSONFeature `json:"features"`
+	}
+	if err := json.Unmarshal(data, &collection); err != nil {
+		t.Fatalf("invalid geojson: %v\n%s", err, data)
+	}
+
+	if collection.Type != "FeatureCollection" || collection.Name != `ABC1234 "trip"` {
+		t.Errorf("collection = %q %q", collection.Type, collection.Name)
+	}
+	if len(collection.Features) != len(testPoints) {
+		t.Fatalf("features = %d, want %d", len(collection.Features), len(testPoints))
+	}
+
+	feature := collection.Features[1]
+	if feature.Geometry.Coordinates != [2]float64{-46.6, -23.5} {
+		t.Errorf("coordinates = %v, want the longitude first", feature.Geometry.Coordinates)
+	}
+	if !feature.Properties.Timestamp.Equal(testPoints[1].Timestamp) || feature.Properties.Status != "stopped" {
+		t.Errorf("properties = %+v", feature.Properties)
+	}
+}
+
+func TestGeoJSONWriterEmpty(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w, err := NewWriter("geojson", buf, "ABC1234")
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var collection map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &collection); err != nil {
+		t.Fatalf("invalid geojson: %v\n%s", err, buf.Bytes())
+	}
+}
+
+func TestNewWriterUnsupported(t *testing.T) {
+	if _, err := NewWriter("shp", new(bytes.Buffer), "ABC1234"); err == nil {
+		t.Error("NewWriter(shp) error = nil, want an error")
+	}
+	if got := ContentType("gpx"); got != "application/gpx+xml" {
+		t.Errorf("ContentType(gpx) = %q", got)
+	}
+}
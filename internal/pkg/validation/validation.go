@@ -0,0 +1,83 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type (
+	ErrorResponse struct {
+		Error       bool
+		FailedField string
+		Tag         string
+		Value       interface{}
+	}
+
+	XValidator struct {
+		validator *validator.Validate
+	}
+)
+
+var validate = validator.New()
+
+// tenantPattern matches the tenant IDs, which are also part of the database names in the database per tenant mode.
+var tenantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+func init() {
+	_ = validate.RegisterValidation("tenant", func(fl validator.FieldLevel) bool {
+		return IsTenant(fl.Field().String())
+	})
+}
+
+// IsTenant reports if the value is a valid tenant ID: up to 32 lowercase letters, digits, hyphens and underscores.
+func IsTenant(value string) bool {
+	return tenantPattern.MatchString(value)
+}
+
+func (v XValidator) Validate(data interface{}) []ErrorResponse {
+	var validationErrors []ErrorResponse
+
+	errs := v.validator.Struct(data)
+	if errs != nil {
+		for _, err := range errs.(validator.ValidationErrors) {
+			var elem ErrorResponse
+
+			elem.FailedField = err.Field() // Export struct field name
+			elem.Tag = err.Tag()           // Export struct tag
+			elem.Value = err.Value()       // Export field value
+			elem.Error = true
+
+			validationErrors = append(validationErrors, elem)
+		}
+	}
+
+	return validationErrors
+}
+
+// Validate validates the data by the rules of its struct tags.
+// It is shared by the HTTP handlers and the transports that don't go through fiber,
+// so the same data is accepted everywhere. The failures are joined in the error message.
+func Validate(data any) error {
+	dataValidator := &XValidator{validator: validate}
+
+	if errs := dataValidator.Validate(data); len(errs) > 0 && errs[0].Error {
+		errMsgs := make([]string, 0)
+
+		for _, err := range errs {
+			errMsgs = append(errMsgs, fmt.Sprintf(
+				"[%s]: '%v' | Needs to implement '%s'",
+				err.FailedField,
+				err.Value,
+				err.Tag,
+			))
+		}
+
+		return errors.New(strings.Join(errMsgs, " and "))
+	}
+
+	return nil
+}
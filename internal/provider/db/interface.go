@@ -1,6 +1,8 @@
 package db
 
 import (
+	"time"
+
 	"github.com/allansbo/goapi/internal/app/server/dto"
 )
 
@@ -8,9 +10,39 @@ import (
 type Repository interface {
 	Ping() error
 	Stop()
+	EnsureIndexes() error
+	Tenant(id string) Repository
+	Tenants() ([]string, error)
 	InsertOne(location *dto.LocationOutDB) (string, error)
-	GetOne(id string) (*dto.LocationInDB, error)
+	InsertMany(locations []*dto.LocationOutDB) ([]string, error)
+	GetOne(id string, includeDeleted bool) (*dto.LocationInDB, error)
+	GetPrevious(vehicleID string, timestamp time.Time) (*dto.LocationInDB, error)
 	GetAll(query *dto.QueryLocationOutDB) (*dto.QueryLocationInDB, error)
-	UpdateOne(id string, location *dto.LocationOutDB) (bool, error)
-	DeleteOne(id string) (bool, error)
+	Stream(query *dto.QueryLocationOutDB, fn func(location *dto.LocationInDB) error) error
+	GetLatest(query *dto.QueryLocationOutDB) ([]*dto.LocationInDB, error)
+	UpdateOne(id string, version int64, location *dto.LocationOutDB) (bool, error)
+	PatchOne(id string, version int64, patch *dto.LocationPatchOutDB) (bool, error)
+	DeleteOne(id string, version int64, at time.Time) (bool, error)
+	RestoreOne(id string) (bool, error)
+	PurgeDeleted(before time.Time) (int64, error)
+	SaveDailyStats(stats []*dto.DailyStatsOutDB) error
+	GetDailyStats(query *dto.QueryDailyStatsOutDB) ([]*dto.DailyStatsInDB, error)
+	InsertTransition(transition *dto.StatusTransitionOutDB) (string, error)
+	GetTransitions(query *dto.QueryStatusTransitionOutDB) ([]*dto.StatusTransitionInDB, error)
+	GetLastTransition(vehicleID string, before time.Time) (*dto.StatusTransitionInDB, error)
+	InsertAPIKey(apiKey *dto.APIKeyOutDB) (string, error)
+	GetAPIKey(id string) (*dto.APIKeyInDB, error)
+	GetAPIKeyByPrefix(prefix string) (*dto.APIKeyInDB, error)
+	GetAPIKeys() ([]*dto.APIKeyInDB, error)
+	RotateAPIKey(id, prefix, hash string) (bool, error)
+	RevokeAPIKey(id string, at time.Time) (bool, error)
+	InsertAuditEntries(entries []*dto.AuditEntryOutDB) error
+	GetAuditEntries(query *dto.QueryAuditOutDB) (*dto.QueryAuditInDB, error)
+}
+
+// DailyStatsAggregator is implemented by the repositories that can compute the daily statistics
+// on the database. The other repositories have them computed from the streamed locations.
+type DailyStatsAggregator interface {
+	// AggregateDailyStats computes and saves the statistics of every vehicle in the day starting at the given time.
+	AggregateDailyStats(day time.Time) error
 }
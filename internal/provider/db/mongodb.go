@@ -2,23 +2,41 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/allansbo/goapi/internal/app/server/dto"
 	"github.com/allansbo/goapi/internal/config"
+	"github.com/allansbo/goapi/internal/pkg/geo"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+	"log/slog"
+	"sync"
+	"time"
 )
 
 // MongoDBRepository implements the Repository interface for MongoDB operations.
+// The repository returned by Tenant stamps the tenant on the saved documents and limits
+// every filter to it, optionally on a database of the tenant. The repository created by
+// NewMongoDBRepository is not limited to a tenant and saves the documents on the default one.
 type MongoDBRepository struct {
-	client       *mongo.Client
-	ctx          context.Context
-	cancel       context.CancelFunc
-	uri          string
-	dbName       string
-	dbCollection string
+	client        *mongo.Client
+	ctx           context.Context
+	cancel        context.CancelFunc
+	uri           string
+	dbRoot        string
+	dbName        string
+	dbCollection  string
+	dbStats       string
+	dbTransition  string
+	dbAPIKeys     string
+	dbAudit       string
+	dbTenants     string
+	tenant        string
+	defaultTenant string
+	dbPerTenant   bool
+	indexed       *sync.Map
 }
 
 // NewMongoDBRepository creates a new instance of MongoDBRepository with the provided configuration.
@@ -34,15 +52,98 @@ func NewMongoDBRepository(cfg *config.EnvConfig) *MongoDBRepository {
 	client, _ := mongo.Connect(clientOpts)
 
 	return &MongoDBRepository{
-		client:       client,
-		dbName:       cfg.DBName,
-		dbCollection: cfg.DBCollection,
-		uri:          uri,
-		ctx:          ctx,
-		cancel:       cancel,
+		client:        client,
+		dbRoot:        cfg.DBName,
+		dbName:        cfg.DBName,
+		dbCollection:  cfg.DBCollection,
+		dbStats:       cfg.DBStatsCollection,
+		dbTransition:  cfg.DBTransitionsCollection,
+		dbAPIKeys:     cfg.DBAPIKeysCollection,
+		dbAudit:       cfg.DBAuditCollection,
+		dbTenants:     cfg.DBTenants,
+		defaultTenant: cfg.DefaultTenant,
+		dbPerTenant:   cfg.TenantDatabases,
+		indexed:       new(sync.Map),
+		uri:           uri,
+		ctx:           ctx,
+		cancel:        cancel,
 	}
 }
 
+// Tenant returns the repository of the tenant, the default one when empty. In the database per tenant mode,
+// the documents of the tenants other than the default one are saved on the <DB_NAME>_<tenant> database,
+// which is registered with its indexes on its first use.
+func (m *MongoDBRepository) Tenant(id string) Repository {
+	if id == "" {
+		id = m.defaultTenant
+	}
+
+	tenant := *m
+	tenant.tenant = id
+	if m.dbPerTenant && id != m.defaultTenant {
+		tenant.dbName = m.dbRoot + "_" + id
+		if _, done := m.indexed.Load(tenant.dbName); !done {
+			if err := tenant.registerTenant(); err != nil {
+				slog.Error("error registering tenant", "error", err.Error(), "tenant", id)
+			} else if err := tenant.EnsureIndexes(); err != nil {
+				slog.Error("error creating tenant indexes", "error", err.Error(), "tenant", id)
+			}
+		}
+	}
+	return &tenant
+}
+
+// Tenants returns the tenants that have locations. In the database per tenant mode, they are
+// the tenants registered with a database, and the documents saved before the tenants belong to the default one.
+func (m *MongoDBRepository) Tenants() ([]string, error) {
+	tenants := []string{m.defaultTenant}
+
+	collection, field := m.rootCollection(), "tenant_id"
+	if m.dbPerTenant {
+		collection, field = m.tenantsCollection(), "_id"
+	}
+
+	var ids []string
+	if err := collection.Distinct(m.ctx, field, bson.M{field: bson.M{"$nin": bson.A{nil, m.defaultTenant}}}).Decode(&ids); err != nil {
+		return nil, err
+	}
+	return append(tenants, ids...), nil
+}
+
+// registerTenant records the tenant of the repository in the registry of the tenants with a database, if it is not there.
+func (m *MongoDBRepository) registerTenant() error {
+	_, err := m.tenantsCollection().UpdateOne(m.ctx,
+		bson.M{"_id": m.tenant},
+		bson.M{"$setOnInsert": bson.M{"database": m.dbName, "created_at": time.Now()}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("mongodb tenant registration failed: %w", err)
+	}
+	return nil
+}
+
+// tenantID returns the tenant stamped on the documents saved by the repository.
+func (m *MongoDBRepository) tenantID() string {
+	if m.tenant == "" {
+		return m.defaultTenant
+	}
+	return m.tenant
+}
+
+// tenantFilter limits a filter to the tenant of the repository. The documents saved before the tenants,
+// without one, belong to the default tenant. The repository without a tenant doesn't limit the filters.
+func (m *MongoDBRepository) tenantFilter(filter bson.M) bson.M {
+	switch m.tenant {
+	case "":
+	case m.defaultTenant:
+		filter["tenant_id"] = bson.M{"$in": bson.A{m.tenant, nil}}
+	default:
+		filter["tenant_id"] = m.tenant
+	}
+	return filter
+}
+
 func (m *MongoDBRepository) Stop() {
 	m.cancel()
 }
@@ -54,12 +155,90 @@ func (m *MongoDBRepository) Ping() error {
 	return nil
 }
 
+// EnsureIndexes creates the indexes used by the queries, if they don't exist.
+func (m *MongoDBRepository) EnsureIndexes() error {
+	_, err := m.collection().Indexes().CreateMany(m.ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "vehicle_id", Value: 1}, {Key: "timestamp", Value: 1}}},
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "timestamp", Value: 1}}},
+		{Keys: bson.D{{Key: "deleted_at", Value: 1}}, Options: options.Index().SetSparse(true)},
+	})
+	if err != nil {
+		return fmt.Errorf("mongodb indexes creation failed: %w", err)
+	}
+
+	// The unique index of the tenant, the vehicle and the day is also required by the $merge stage of the roll-up.
+	_, err = m.statsCollection().Indexes().CreateOne(m.ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "vehicle_id", Value: 1}, {Key: "day", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("mongodb indexes creation failed: %w", err)
+	}
+
+	_, err = m.transitionsCollection().Indexes().CreateOne(m.ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "vehicle_id", Value: 1}, {Key: "at", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("mongodb indexes creation failed: %w", err)
+	}
+
+	_, err = m.auditCollection().Indexes().CreateMany(m.ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "resource", Value: 1}, {Key: "resource_id", Value: 1}, {Key: "at", Value: -1}}},
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "at", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("mongodb indexes creation failed: %w", err)
+	}
+
+	_, err = m.apiKeysCollection().Indexes().CreateOne(m.ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "prefix", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("mongodb indexes creation failed: %w", err)
+	}
+
+	m.indexed.Store(m.dbName, true)
+	return nil
+}
+
 func (m *MongoDBRepository) collection() *mongo.Collection {
 	return m.client.Database(m.dbName).Collection(m.dbCollection)
 }
 
-// InsertOne inserts a document into the collection.
+func (m *MongoDBRepository) statsCollection() *mongo.Collection {
+	return m.client.Database(m.dbName).Collection(m.dbStats)
+}
+
+func (m *MongoDBRepository) transitionsCollection() *mongo.Collection {
+	return m.client.Database(m.dbName).Collection(m.dbTransition)
+}
+
+// auditCollection decodes the nested documents of the entries as maps, like the documents before and after the changes.
+func (m *MongoDBRepository) auditCollection() *mongo.Collection {
+	bsonOptions := &options.BSONOptions{DefaultDocumentM: true}
+	return m.client.Database(m.dbName).Collection(m.dbAudit, options.Collection().SetBSONOptions(bsonOptions))
+}
+
+// rootCollection is the locations collection of the database of the default tenant.
+func (m *MongoDBRepository) rootCollection() *mongo.Collection {
+	return m.client.Database(m.dbRoot).Collection(m.dbCollection)
+}
+
+// tenantsCollection is the registry of the tenants with a database, in the database of the default tenant.
+func (m *MongoDBRepository) tenantsCollection() *mongo.Collection {
+	return m.client.Database(m.dbRoot).Collection(m.dbTenants)
+}
+
+// apiKeysCollection is shared by the tenants, since the keys are the credentials that resolve them.
+func (m *MongoDBRepository) apiKeysCollection() *mongo.Collection {
+	return m.client.Database(m.dbRoot).Collection(m.dbAPIKeys)
+}
+
+// InsertOne inserts a document into the collection, on its first version.
 func (m *MongoDBRepository) InsertOne(location *dto.LocationOutDB) (string, error) {
+	location.TenantId = m.tenantID()
+	location.Version = 1
 	res, err := m.collection().InsertOne(m.ctx, location)
 	if err != nil {
 		return "", err
@@ -68,15 +247,49 @@ func (m *MongoDBRepository) InsertOne(location *dto.LocationOutDB) (string, erro
 	return id, nil
 }
 
+// InsertMany inserts the documents into the collection in a single unordered bulk operation, on their first version.
+// When some documents fail, the others are still inserted, and their IDs are returned with the error.
+func (m *MongoDBRepository) InsertMany(locations []*dto.LocationOutDB) ([]string, error) {
+	for _, location := range locations {
+		location.TenantId = m.tenantID()
+		location.Version = 1
+	}
+
+	res, err := m.collection().InsertMany(m.ctx, locations, options.InsertMany().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if err != nil && (res == nil || !errors.As(err, &bulkErr)) {
+		return nil, err
+	}
+
+	failed := make(map[int]bool, len(bulkErr.WriteErrors))
+	for _, writeErr := range bulkErr.WriteErrors {
+		failed[writeErr.Index] = true
+	}
+
+	ids := make([]string, 0, len(res.InsertedIDs))
+	for i, insertedID := range res.InsertedIDs {
+		if !failed[i] {
+			ids = append(ids, insertedID.(bson.ObjectID).Hex())
+		}
+	}
+	return ids, err
+}
+
 // GetOne retrieves a single document by its ID from the collection.
-func (m *MongoDBRepository) GetOne(id string) (*dto.LocationInDB, error) {
+// The deleted documents are not found unless includeDeleted is set.
+func (m *MongoDBRepository) GetOne(id string, includeDeleted bool) (*dto.LocationInDB, error) {
 	objectID, err := bson.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, err
 	}
 
+	filter := bson.M{"_id": objectID}
+	if !includeDeleted {
+		filter["deleted_at"] = nil
+	}
+
 	var res bson.M
-	err = m.collection().FindOne(m.ctx, bson.M{"_id": objectID}).Decode(&res)
+	err = m.collection().FindOne(m.ctx, m.tenantFilter(filter)).Decode(&res)
 	if err != nil {
 		return nil, err
 	}
@@ -93,6 +306,23 @@ func (m *MongoDBRepository) GetOne(id string) (*dto.LocationInDB, error) {
 	return locationInDb, nil
 }
 
+// GetPrevious retrieves the latest document of a vehicle recorded until the timestamp.
+// It returns nil when the vehicle has no document before it.
+func (m *MongoDBRepository) GetPrevious(vehicleID string, timestamp time.Time) (*dto.LocationInDB, error) {
+	filter := m.tenantFilter(bson.M{"vehicle_id": vehicleID, "timestamp": bson.M{"$lte": timestamp}, "deleted_at": nil})
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "timestamp", Value: -1}})
+
+	locationInDB := new(dto.LocationInDB)
+	err := m.collection().FindOne(m.ctx, filter, findOptions).Decode(locationInDB)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return locationInDB, nil
+}
+
 // GetAll retrieves all documents from the collection, limited
 // by the specified count  and filtered by the provided filter.
 func (m *MongoDBRepository) GetAll(query *dto.QueryLocationOutDB) (*dto.QueryLocationInDB, error) {
@@ -103,13 +333,7 @@ func (m *MongoDBRepository) GetAll(query *dto.QueryLocationOutDB) (*dto.QueryLoc
 		query.Limit = 10
 	}
 
-	filter := bson.M{}
-	if query.VehicleId != "" {
-		filter["vehicle_id"] = query.VehicleId
-	}
-	if query.Status != "" {
-		filter["status"] = query.Status
-	}
+	filter := m.tenantFilter(locationFilter(query))
 
 	findOptions := options.Find()
 	findOptions.SetSkip(int64((query.Page - 1) * query.Limit)).SetLimit(int64(query.Limit))
@@ -136,16 +360,121 @@ func (m *MongoDBRepository) GetAll(query *dto.QueryLocationOutDB) (*dto.QueryLoc
 	return qLocationsInDB, nil
 }
 
-// UpdateOne updates a single document by its ID in the collection.
-func (m *MongoDBRepository) UpdateOne(id string, location *dto.LocationOutDB) (bool, error) {
+// Stream iterates over all documents matching the query filters, ordered by timestamp,
+// calling fn for each one. The documents are decoded one at a time from the cursor,
+// so the result is never loaded entirely in memory. The pagination of the query is ignored.
+func (m *MongoDBRepository) Stream(query *dto.QueryLocationOutDB, fn func(location *dto.LocationInDB) error) error {
+	findOptions := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}})
+
+	cursor, err := m.collection().Find(m.ctx, m.tenantFilter(locationFilter(query)), findOptions)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(m.ctx)
+
+	for cursor.Next(m.ctx) {
+		location := new(dto.LocationInDB)
+		if err := cursor.Decode(location); err != nil {
+			return err
+		}
+		if err := fn(location); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// GetLatest retrieves the latest document of every vehicle in the period of the query.
+// The status and bounds filters are applied to the latest documents, so a vehicle
+// is returned only when its current location matches them.
+func (m *MongoDBRepository) GetLatest(query *dto.QueryLocationOutDB) ([]*dto.LocationInDB, error) {
+	current := &dto.QueryLocationOutDB{Status: query.Status, Bounds: query.Bounds}
+	period := *query
+	period.Status, period.Bounds = "", nil
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: m.tenantFilter(locationFilter(&period))}},
+		{{Key: "$sort", Value: bson.D{{Key: "vehicle_id", Value: 1}, {Key: "timestamp", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$vehicle_id", "location": bson.M{"$first": "$$ROOT"}}}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$location"}}},
+		{{Key: "$match", Value: locationFilter(current)}},
+	}
+
+	cursor, err := m.collection().Aggregate(m.ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	locations := make([]*dto.LocationInDB, 0)
+	if err := cursor.All(m.ctx, &locations); err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+// locationFilter builds the filter of the documents matching the query.
+// The deleted documents, which have the deleted_at field, are excluded unless the query includes them.
+func locationFilter(query *dto.QueryLocationOutDB) bson.M {
+	filter := bson.M{}
+	if !query.IncludeDeleted {
+		filter["deleted_at"] = nil
+	}
+	if query.VehicleId != "" {
+		filter["vehicle_id"] = query.VehicleId
+	} else if query.VehicleIds != nil {
+		filter["vehicle_id"] = bson.M{"$in": query.VehicleIds}
+	}
+	if query.Status != "" {
+		filter["status"] = query.Status
+	}
+
+	period := bson.M{}
+	if !query.From.IsZero() {
+		period["$gte"] = query.From
+	}
+	if !query.To.IsZero() {
+		period["$lte"] = query.To
+	}
+	if len(period) > 0 {
+		filter["timestamp"] = period
+	}
+
+	if query.Flag != "" {
+		filter["flags"] = query.Flag
+	} else if query.Flagged {
+		filter["flags.0"] = bson.M{"$exists": true}
+	}
+
+	// The coordinates are saved as strings, so they are converted to be compared.
+	if query.Bounds != nil {
+		latitude := bson.M{"$toDouble": "$location.latitude"}
+		longitude := bson.M{"$toDouble": "$location.longitude"}
+		filter["$expr"] = bson.M{"$and": bson.A{
+			bson.M{"$gte": bson.A{latitude, query.Bounds.MinLatitude}},
+			bson.M{"$lte": bson.A{latitude, query.Bounds.MaxLatitude}},
+			bson.M{"$gte": bson.A{longitude, query.Bounds.MinLongitude}},
+			bson.M{"$lte": bson.A{longitude, query.Bounds.MaxLongitude}},
+		}}
+	}
+
+	return filter
+}
+
+// UpdateOne replaces a single document by its ID in the collection, if it is still on the version,
+// on the next version. The fields left out of the location, as the attributes and the flags, are removed.
+// It returns false when the document doesn't exist or is on another version.
+func (m *MongoDBRepository) UpdateOne(id string, version int64, location *dto.LocationOutDB) (bool, error) {
 	objectID, err := bson.ObjectIDFromHex(id)
 	if err != nil {
 		return false, err
 	}
 
-	data := map[string]interface{}{"$set": location}
+	location.ID = ""
+	location.TenantId = m.tenantID()
+	location.Version = version + 1
 
-	res, err := m.collection().UpdateOne(m.ctx, bson.M{"_id": objectID}, data)
+	res, err := m.collection().ReplaceOne(m.ctx, m.tenantFilter(versionFilter(bson.M{"_id": objectID, "deleted_at": nil}, version)), location)
 	if err != nil {
 		return false, err
 	}
@@ -153,17 +482,455 @@ func (m *MongoDBRepository) UpdateOne(id string, location *dto.LocationOutDB) (b
 	return res.ModifiedCount == 1, nil
 }
 
-// DeleteOne deletes a single document by its ID from the collection.
-func (m *MongoDBRepository) DeleteOne(id string) (bool, error) {
+// PatchOne sets only the changed fields of a single document by its ID in the collection, if it is still on the version,
+// and increments its version. It returns false when the document doesn't exist or is on another version.
+func (m *MongoDBRepository) PatchOne(id string, version int64, patch *dto.LocationPatchOutDB) (bool, error) {
 	objectID, err := bson.ObjectIDFromHex(id)
 	if err != nil {
 		return false, err
 	}
 
-	res, err := m.collection().DeleteOne(m.ctx, bson.M{"_id": objectID})
+	set := bson.M{}
+	if patch.VehicleId != nil {
+		set["vehicle_id"] = *patch.VehicleId
+	}
+	if patch.Latitude != nil {
+		set["location.latitude"] = *patch.Latitude
+	}
+	if patch.Longitude != nil {
+		set["location.longitude"] = *patch.Longitude
+	}
+	if patch.Speed != nil {
+		set["speed"] = *patch.Speed
+	}
+	if patch.Status != nil {
+		set["status"] = *patch.Status
+	}
+	for key, value := range patch.SetAttributes {
+		set["attributes."+key] = value
+	}
+
+	update := bson.M{"$inc": bson.M{"version": 1}}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(patch.RemoveAttributes) > 0 {
+		unset := bson.M{}
+		for _, key := range patch.RemoveAttributes {
+			unset["attributes."+key] = ""
+		}
+		update["$unset"] = unset
+	}
+
+	res, err := m.collection().UpdateOne(m.ctx, m.tenantFilter(versionFilter(bson.M{"_id": objectID, "deleted_at": nil}, version)), update)
+	if err != nil {
+		return false, err
+	}
+
+	return res.ModifiedCount == 1, nil
+}
+
+// DeleteOne marks a single document as deleted at the time, if it is still on the version,
+// keeping it until it is purged. It returns false when the document doesn't exist,
+// is already deleted or is on another version.
+func (m *MongoDBRepository) DeleteOne(id string, version int64, at time.Time) (bool, error) {
+	objectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+
+	filter := m.tenantFilter(versionFilter(bson.M{"_id": objectID, "deleted_at": nil}, version))
+	update := bson.M{"$set": bson.M{"deleted_at": at}, "$inc": bson.M{"version": 1}}
+
+	res, err := m.collection().UpdateOne(m.ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return res.ModifiedCount == 1, nil
+}
+
+// RestoreOne removes the deletion mark of a single document.
+// It returns false when the document doesn't exist or is not deleted.
+func (m *MongoDBRepository) RestoreOne(id string) (bool, error) {
+	objectID, err := bson.ObjectIDFromHex(id)
 	if err != nil {
 		return false, err
 	}
 
-	return res.DeletedCount == 1, nil
+	filter := m.tenantFilter(bson.M{"_id": objectID, "deleted_at": bson.M{"$ne": nil}})
+	update := bson.M{"$unset": bson.M{"deleted_at": ""}, "$inc": bson.M{"version": 1}}
+
+	res, err := m.collection().UpdateOne(m.ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return res.ModifiedCount == 1, nil
+}
+
+// versionFilter limits the filter to the documents on the version.
+// The documents saved before the versions have none, and are on the version 0.
+func versionFilter(filter bson.M, version int64) bson.M {
+	if version == 0 {
+		filter["version"] = nil
+	} else {
+		filter["version"] = version
+	}
+	return filter
+}
+
+// PurgeDeleted permanently removes the documents deleted before the time and returns how many were removed.
+func (m *MongoDBRepository) PurgeDeleted(before time.Time) (int64, error) {
+	res, err := m.collection().DeleteMany(m.ctx, m.tenantFilter(bson.M{"deleted_at": bson.M{"$lt": before}}))
+	if err != nil {
+		return 0, err
+	}
+
+	return res.DeletedCount, nil
+}
+
+// SaveDailyStats replaces the statistics of the vehicles in the days, inserting the ones that don't exist.
+func (m *MongoDBRepository) SaveDailyStats(stats []*dto.DailyStatsOutDB) error {
+	if len(stats) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, 0, len(stats))
+	for _, s := range stats {
+		s.TenantId = m.tenantID()
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"tenant_id": s.TenantId, "vehicle_id": s.VehicleId, "day": s.Day}).
+			SetReplacement(s).
+			SetUpsert(true))
+	}
+
+	_, err := m.statsCollection().BulkWrite(m.ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// GetDailyStats retrieves the statistics of the days in the period, ordered by day and vehicle.
+func (m *MongoDBRepository) GetDailyStats(query *dto.QueryDailyStatsOutDB) ([]*dto.DailyStatsInDB, error) {
+	filter := m.tenantFilter(bson.M{"day": bson.M{"$gte": query.From, "$lte": query.To}})
+	if query.VehicleId != "" {
+		filter["vehicle_id"] = query.VehicleId
+	} else if query.VehicleIds != nil {
+		filter["vehicle_id"] = bson.M{"$in": query.VehicleIds}
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "day", Value: 1}, {Key: "vehicle_id", Value: 1}})
+
+	cursor, err := m.statsCollection().Find(m.ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]*dto.DailyStatsInDB, 0)
+	if err := cursor.All(m.ctx, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// AggregateDailyStats computes the statistics of every vehicle in the day with an aggregation pipeline
+// and merges them into the statistics collection. The time between a location and the next one of the
+// same vehicle is counted on the status of the first, and the distance is the sum of the haversine
+// distances between the consecutive locations.
+func (m *MongoDBRepository) AggregateDailyStats(day time.Time) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: m.tenantFilter(bson.M{"timestamp": bson.M{"$gte": day, "$lt": day.Add(24 * time.Hour)}, "deleted_at": nil})}},
+		{{Key: "$addFields", Value: bson.M{
+			"lat": bson.M{"$toDouble": "$location.latitude"},
+			"lon": bson.M{"$toDouble": "$location.longitude"},
+		}}},
+		{{Key: "$setWindowFields", Value: bson.M{
+			"partitionBy": "$vehicle_id",
+			"sortBy":      bson.M{"timestamp": 1},
+			"output": bson.M{
+				"next_timestamp": bson.M{"$shift": bson.M{"output": "$timestamp", "by": 1}},
+				"prev_lat":       bson.M{"$shift": bson.M{"output": "$lat", "by": -1}},
+				"prev_lon":       bson.M{"$shift": bson.M{"output": "$lon", "by": -1}},
+			},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"vehicle_id": 1,
+			"timestamp":  1,
+			"speed":      1,
+			"status":     1,
+			"duration": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$next_timestamp", nil}},
+				0,
+				bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{"$next_timestamp", "$timestamp"}}, 1000}},
+			}},
+			"distance": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$prev_lat", nil}},
+				0,
+				haversineExpression("$prev_lat", "$prev_lon", "$lat", "$lon"),
+			}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":              "$vehicle_id",
+			"points":           bson.M{"$sum": 1},
+			"distance":         bson.M{"$sum": "$distance"},
+			"max_speed":        bson.M{"$max": "$speed"},
+			"avg_speed":        bson.M{"$avg": "$speed"},
+			"moving_duration":  statusDurationExpression("moving"),
+			"stopped_duration": statusDurationExpression("stopped"),
+			"offline_duration": statusDurationExpression("offline"),
+			"first_seen":       bson.M{"$min": "$timestamp"},
+			"last_seen":        bson.M{"$max": "$timestamp"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":              0,
+			"tenant_id":        bson.M{"$literal": m.tenantID()},
+			"vehicle_id":       "$_id",
+			"day":              day,
+			"points":           1,
+			"distance":         1,
+			"max_speed":        1,
+			"avg_speed":        1,
+			"moving_duration":  bson.M{"$toLong": bson.M{"$floor": "$moving_duration"}},
+			"stopped_duration": bson.M{"$toLong": bson.M{"$floor": "$stopped_duration"}},
+			"offline_duration": bson.M{"$toLong": bson.M{"$floor": "$offline_duration"}},
+			"first_seen":       1,
+			"last_seen":        1,
+			"updated_at":       "$$NOW",
+		}}},
+		{{Key: "$merge", Value: bson.M{
+			"into":           m.dbStats,
+			"on":             bson.A{"tenant_id", "vehicle_id", "day"},
+			"whenMatched":    "replace",
+			"whenNotMatched": "insert",
+		}}},
+	}
+
+	cursor, err := m.collection().Aggregate(m.ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cursor.Close(m.ctx)
+}
+
+// haversineExpression is the aggregation expression of the distance in meters between two coordinates.
+func haversineExpression(lat1, lon1, lat2, lon2 string) bson.M {
+	halfDelta := func(a, b string) bson.M {
+		return bson.M{"$divide": bson.A{bson.M{"$degreesToRadians": bson.M{"$subtract": bson.A{b, a}}}, 2}}
+	}
+	squaredSin := func(angle bson.M) bson.M {
+		return bson.M{"$pow": bson.A{bson.M{"$sin": angle}, 2}}
+	}
+
+	h := bson.M{"$add": bson.A{
+		squaredSin(halfDelta(lat1, lat2)),
+		bson.M{"$multiply": bson.A{
+			bson.M{"$cos": bson.M{"$degreesToRadians": lat1}},
+			bson.M{"$cos": bson.M{"$degreesToRadians": lat2}},
+			squaredSin(halfDelta(lon1, lon2)),
+		}},
+	}}
+
+	return bson.M{"$multiply": bson.A{
+		2 * geo.EarthRadius,
+		bson.M{"$asin": bson.M{"$min": bson.A{1, bson.M{"$sqrt": h}}}},
+	}}
+}
+
+// statusDurationExpression is the accumulator of the durations of the locations with the status.
+func statusDurationExpression(status string) bson.M {
+	return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$status", status}}, "$duration", 0}}}
+}
+
+// InsertTransition inserts a status transition into the transitions collection.
+func (m *MongoDBRepository) InsertTransition(transition *dto.StatusTransitionOutDB) (string, error) {
+	transition.TenantId = m.tenantID()
+	res, err := m.transitionsCollection().InsertOne(m.ctx, transition)
+	if err != nil {
+		return "", err
+	}
+	return res.InsertedID.(bson.ObjectID).Hex(), nil
+}
+
+// GetTransitions retrieves the status transitions of a vehicle in the period, ordered by time.
+func (m *MongoDBRepository) GetTransitions(query *dto.QueryStatusTransitionOutDB) ([]*dto.StatusTransitionInDB, error) {
+	filter := m.tenantFilter(bson.M{"vehicle_id": query.VehicleId, "at": bson.M{"$gte": query.From, "$lte": query.To}})
+	findOptions := options.Find().SetSort(bson.D{{Key: "at", Value: 1}})
+
+	cursor, err := m.transitionsCollection().Find(m.ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	transitions := make([]*dto.StatusTransitionInDB, 0)
+	if err := cursor.All(m.ctx, &transitions); err != nil {
+		return nil, err
+	}
+	return transitions, nil
+}
+
+// GetLastTransition retrieves the latest status transition of a vehicle before the time.
+// It returns nil when the vehicle has no transition before it.
+func (m *MongoDBRepository) GetLastTransition(vehicleID string, before time.Time) (*dto.StatusTransitionInDB, error) {
+	filter := m.tenantFilter(bson.M{"vehicle_id": vehicleID, "at": bson.M{"$lt": before}})
+	findOptions := options.FindOne().SetSort(bson.D{{Key: "at", Value: -1}})
+
+	transition := new(dto.StatusTransitionInDB)
+	err := m.transitionsCollection().FindOne(m.ctx, filter, findOptions).Decode(transition)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return transition, nil
+}
+
+// InsertAPIKey inserts an API key into the keys collection.
+func (m *MongoDBRepository) InsertAPIKey(apiKey *dto.APIKeyOutDB) (string, error) {
+	apiKey.Tenant = m.tenantID()
+	res, err := m.apiKeysCollection().InsertOne(m.ctx, apiKey)
+	if err != nil {
+		return "", err
+	}
+	return res.InsertedID.(bson.ObjectID).Hex(), nil
+}
+
+// GetAPIKey retrieves an API key by its ID.
+func (m *MongoDBRepository) GetAPIKey(id string) (*dto.APIKeyInDB, error) {
+	objectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey := new(dto.APIKeyInDB)
+	if err := m.apiKeysCollection().FindOne(m.ctx, m.tenantFilter(bson.M{"_id": objectID})).Decode(apiKey); err != nil {
+		return nil, err
+	}
+	return apiKey, nil
+}
+
+// GetAPIKeyByPrefix retrieves an API key by the prefix of the key, of any tenant, since the key resolves it.
+// It returns nil when no key has the prefix.
+func (m *MongoDBRepository) GetAPIKeyByPrefix(prefix string) (*dto.APIKeyInDB, error) {
+	apiKey := new(dto.APIKeyInDB)
+	err := m.apiKeysCollection().FindOne(m.ctx, bson.M{"prefix": prefix}).Decode(apiKey)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+// GetAPIKeys retrieves all the API keys, ordered by creation.
+func (m *MongoDBRepository) GetAPIKeys() ([]*dto.APIKeyInDB, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+
+	cursor, err := m.apiKeysCollection().Find(m.ctx, m.tenantFilter(bson.M{}), findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys := make([]*dto.APIKeyInDB, 0)
+	if err := cursor.All(m.ctx, &apiKeys); err != nil {
+		return nil, err
+	}
+	return apiKeys, nil
+}
+
+// RotateAPIKey replaces the prefix and the hash of an API key that is not revoked.
+func (m *MongoDBRepository) RotateAPIKey(id, prefix, hash string) (bool, error) {
+	objectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+
+	filter := m.tenantFilter(bson.M{"_id": objectID, "revoked_at": bson.M{"$exists": false}})
+	update := bson.M{"$set": bson.M{"prefix": prefix, "hash": hash}}
+
+	res, err := m.apiKeysCollection().UpdateOne(m.ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// RevokeAPIKey sets the revocation time of an API key that is not revoked.
+func (m *MongoDBRepository) RevokeAPIKey(id string, at time.Time) (bool, error) {
+	objectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+
+	filter := m.tenantFilter(bson.M{"_id": objectID, "revoked_at": bson.M{"$exists": false}})
+	update := bson.M{"$set": bson.M{"revoked_at": at}}
+
+	res, err := m.apiKeysCollection().UpdateOne(m.ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// InsertAuditEntries appends the entries to the audit log in a single bulk operation.
+// The repository has no method to change or delete them.
+func (m *MongoDBRepository) InsertAuditEntries(entries []*dto.AuditEntryOutDB) error {
+	for _, entry := range entries {
+		entry.TenantId = m.tenantID()
+	}
+	_, err := m.auditCollection().InsertMany(m.ctx, entries)
+	return err
+}
+
+// GetAuditEntries retrieves the entries of the audit log matching the query, from the newest.
+func (m *MongoDBRepository) GetAuditEntries(query *dto.QueryAuditOutDB) (*dto.QueryAuditInDB, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 {
+		query.Limit = 10
+	}
+
+	filter := bson.M{}
+	if query.Action != "" {
+		filter["action"] = query.Action
+	}
+	if query.Resource != "" {
+		filter["resource"] = query.Resource
+	}
+	if query.ResourceId != "" {
+		filter["resource_id"] = query.ResourceId
+	}
+	if query.Actor != "" {
+		filter["actor.subject"] = query.Actor
+	}
+	if query.RequestId != "" {
+		filter["request_id"] = query.RequestId
+	}
+	at := bson.M{}
+	if !query.From.IsZero() {
+		at["$gte"] = query.From
+	}
+	if !query.To.IsZero() {
+		at["$lte"] = query.To
+	}
+	if len(at) > 0 {
+		filter["at"] = at
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((query.Page - 1) * query.Limit)).
+		SetLimit(int64(query.Limit))
+
+	cursor, err := m.auditCollection().Find(m.ctx, m.tenantFilter(filter), findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*dto.AuditEntryInDB, 0)
+	if err := cursor.All(m.ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	return &dto.QueryAuditInDB{Limit: query.Limit, Page: query.Page, Data: entries}, nil
 }